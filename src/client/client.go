@@ -0,0 +1,50 @@
+package client
+
+import (
+	"context"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
+)
+
+// Client is a pooled client, safe for concurrent use.
+type Client struct {
+	commands
+
+	pool *Pool
+}
+
+func New(address string, opts *Options) *Client {
+	c := &Client{
+		pool: NewPool(address, opts),
+	}
+	c.commands.do = c.Do
+	return c
+}
+
+// Do runs one command on a pooled connection.
+func (c *Client) Do(ctx context.Context, args ...any) (redis.RedisObject, error) {
+	return c.pool.Do(ctx, args...)
+}
+
+// Pipeline queues the commands issued by f on one pooled connection and
+// sends them in a single write.
+func (c *Client) Pipeline(ctx context.Context, f func(p *Pipeline)) ([]redis.RedisObject, error) {
+	conn, err := c.pool.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.pool.Put(conn)
+
+	pipeline := conn.Pipeline()
+	f(pipeline)
+	return pipeline.Exec(ctx)
+}
+
+// Pool exposes the connection pool, for commands which hold a connection.
+func (c *Client) Pool() *Pool {
+	return c.pool
+}
+
+func (c *Client) Close() error {
+	return c.pool.Close()
+}
@@ -0,0 +1,367 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
+)
+
+// commands implements the typed helpers on top of a Do function, shared
+// by Conn and Client.
+type commands struct {
+	do func(ctx context.Context, args ...any) (redis.RedisObject, error)
+}
+
+func (c commands) Ping(ctx context.Context) error {
+	rsp, err := c.do(ctx, "PING")
+	if err != nil {
+		return err
+	}
+	var msg string
+	if err := redis.Unmarshal(rsp, &msg); err != nil {
+		return err
+	} else if msg != "PONG" {
+		return fmt.Errorf("unexpected response: %s", msg)
+	}
+	return nil
+}
+
+func (c commands) Echo(ctx context.Context, message string) (string, error) {
+	return c.string(c.do(ctx, "ECHO", message))
+}
+
+// Get returns the value of key, or ErrNil when the key does not exist.
+func (c commands) Get(ctx context.Context, key string) ([]byte, error) {
+	rsp, err := c.do(ctx, "GET", key)
+	if err != nil {
+		return nil, err
+	} else if isNil(rsp) {
+		return nil, ErrNil
+	}
+	var value []byte
+	err = redis.Unmarshal(rsp, &value)
+	return value, err
+}
+
+// Set stores value at key, expiring after ttl when it is positive.
+func (c commands) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	args := []any{"SET", key, value}
+	if ttl > 0 {
+		args = append(args, "PX", ttl.Milliseconds())
+	}
+	_, err := c.string(c.do(ctx, args...))
+	return err
+}
+
+// Info returns the raw text of an INFO section.
+func (c commands) Info(ctx context.Context, section string) (string, error) {
+	return c.string(c.do(ctx, "INFO", section))
+}
+
+// ReplConf sends REPLCONF with option-value pairs during a replica
+// handshake.
+func (c commands) ReplConf(ctx context.Context, pairs ...string) error {
+	args := make([]any, 0, len(pairs)+1)
+	args = append(args, "REPLCONF")
+	for _, pair := range pairs {
+		args = append(args, pair)
+	}
+	_, err := c.string(c.do(ctx, args...))
+	return err
+}
+
+// PsyncReply is the answer of a master to PSYNC.
+type PsyncReply struct {
+	// FullResync is true for +FULLRESYNC, false for +CONTINUE
+	FullResync bool
+	Replid     string
+	Offset     int64
+}
+
+// Psync asks the master to start replication from replid and offset, where
+// "?" and -1 request a full resynchronization.
+func (c commands) Psync(ctx context.Context, replid string, offset int64) (*PsyncReply, error) {
+	line, err := c.string(c.do(ctx, "PSYNC", replid, offset))
+	if err != nil {
+		return nil, err
+	}
+	return ParsePsyncReply(line)
+}
+
+// ParsePsyncReply parses "FULLRESYNC <replid> <offset>" and
+// "CONTINUE [<replid>]".
+func ParsePsyncReply(line string) (*PsyncReply, error) {
+	fields := strings.Fields(line)
+	switch {
+	case len(fields) == 3 && fields[0] == "FULLRESYNC":
+		offset, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected offset in %q: %w", line, err)
+		}
+		return &PsyncReply{FullResync: true, Replid: fields[1], Offset: offset}, nil
+	case len(fields) >= 1 && len(fields) <= 2 && fields[0] == "CONTINUE":
+		reply := &PsyncReply{Offset: -1}
+		if len(fields) == 2 {
+			reply.Replid = fields[1]
+		}
+		return reply, nil
+	}
+	return nil, fmt.Errorf("unexpected response: %s", line)
+}
+
+// Wait blocks until numReplicas replicas acknowledged the writes of the
+// connection so far, or timeout passes, and returns how many did. A
+// timeout of 0 waits forever.
+func (c commands) Wait(ctx context.Context, numReplicas int, timeout time.Duration) (int64, error) {
+	return c.int64(c.do(ctx, "WAIT", numReplicas, timeout.Milliseconds()))
+}
+
+// Select switches the connection to database db. On a Client it only
+// applies to the pooled connection it runs on.
+func (c commands) Select(ctx context.Context, db int) error {
+	_, err := c.string(c.do(ctx, "SELECT", db))
+	return err
+}
+
+// Move moves key to database db, and tells false when key does not exist
+// or db already holds it.
+func (c commands) Move(ctx context.Context, key string, db int) (bool, error) {
+	n, err := c.int64(c.do(ctx, "MOVE", key, db))
+	return n == 1, err
+}
+
+// SwapDB exchanges the keys of databases a and b.
+func (c commands) SwapDB(ctx context.Context, a, b int) error {
+	_, err := c.string(c.do(ctx, "SWAPDB", a, b))
+	return err
+}
+
+// FlushDB deletes the keys of the selected database.
+func (c commands) FlushDB(ctx context.Context, async bool) error {
+	_, err := c.string(c.do(ctx, flushArgs("FLUSHDB", async)...))
+	return err
+}
+
+// FlushAll deletes the keys of every database.
+func (c commands) FlushAll(ctx context.Context, async bool) error {
+	_, err := c.string(c.do(ctx, flushArgs("FLUSHALL", async)...))
+	return err
+}
+
+func flushArgs(name string, async bool) []any {
+	if async {
+		return []any{name, "ASYNC"}
+	}
+	return []any{name, "SYNC"}
+}
+
+// DBSize returns the number of keys of the selected database.
+func (c commands) DBSize(ctx context.Context) (int64, error) {
+	return c.int64(c.do(ctx, "DBSIZE"))
+}
+
+// Del deletes keys and returns how many existed.
+func (c commands) Del(ctx context.Context, keys ...string) (int64, error) {
+	args := make([]any, 0, len(keys)+1)
+	args = append(args, "DEL")
+	for _, key := range keys {
+		args = append(args, key)
+	}
+	return c.int64(c.do(ctx, args...))
+}
+
+// Dump returns the serialized value of key, or ErrNil when the key does
+// not exist.
+func (c commands) Dump(ctx context.Context, key string) ([]byte, error) {
+	rsp, err := c.do(ctx, "DUMP", key)
+	if err != nil {
+		return nil, err
+	} else if isNil(rsp) {
+		return nil, ErrNil
+	}
+	var payload []byte
+	err = redis.Unmarshal(rsp, &payload)
+	return payload, err
+}
+
+// Restore creates key from a payload of Dump, expiring after ttl when it
+// is positive. replace overwrites an existing key.
+func (c commands) Restore(ctx context.Context, key string, ttl time.Duration, payload []byte, replace bool) error {
+	args := []any{"RESTORE", key, ttl.Milliseconds(), payload}
+	if replace {
+		args = append(args, "REPLACE")
+	}
+	_, err := c.string(c.do(ctx, args...))
+	return err
+}
+
+// MigrateOptions are the options of Migrate.
+type MigrateOptions struct {
+	// Copy keeps the keys on the source
+	Copy bool
+	// Replace overwrites existing keys on the target
+	Replace bool
+	// Password authenticates to the target when set
+	Password string
+}
+
+// Migrate moves keys to database db of the server at host and port, and
+// tells false when none of them exists. opts may be nil.
+func (c commands) Migrate(ctx context.Context, host string, port int, db int, timeout time.Duration, opts *MigrateOptions, keys ...string) (bool, error) {
+	key := ""
+	if len(keys) == 1 {
+		key = keys[0]
+	}
+	args := []any{"MIGRATE", host, port, key, db, timeout.Milliseconds()}
+	if opts != nil && opts.Copy {
+		args = append(args, "COPY")
+	}
+	if opts != nil && opts.Replace {
+		args = append(args, "REPLACE")
+	}
+	if opts != nil && opts.Password != "" {
+		args = append(args, "AUTH", opts.Password)
+	}
+	if len(keys) > 1 {
+		args = append(args, "KEYS")
+		for _, key := range keys {
+			args = append(args, key)
+		}
+	}
+	status, err := c.string(c.do(ctx, args...))
+	return status == "OK", err
+}
+
+// Save writes a snapshot in the foreground.
+func (c commands) Save(ctx context.Context) error {
+	_, err := c.string(c.do(ctx, "SAVE"))
+	return err
+}
+
+// BgSave starts a snapshot in the background and returns the status of
+// the server.
+func (c commands) BgSave(ctx context.Context) (string, error) {
+	return c.string(c.do(ctx, "BGSAVE"))
+}
+
+// LastSave returns when the last snapshot succeeded.
+func (c commands) LastSave(ctx context.Context) (time.Time, error) {
+	sec, err := c.int64(c.do(ctx, "LASTSAVE"))
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(sec, 0), nil
+}
+
+// BgRewriteAOF starts an append only file rewrite and returns the status
+// of the server.
+func (c commands) BgRewriteAOF(ctx context.Context) (string, error) {
+	return c.string(c.do(ctx, "BGREWRITEAOF"))
+}
+
+// ReplicaOf makes the server replicate the master at host and port.
+func (c commands) ReplicaOf(ctx context.Context, host string, port int) error {
+	_, err := c.string(c.do(ctx, "REPLICAOF", host, port))
+	return err
+}
+
+// ReplicaOfNoOne promotes the server to a master.
+func (c commands) ReplicaOfNoOne(ctx context.Context) error {
+	_, err := c.string(c.do(ctx, "REPLICAOF", "NO", "ONE"))
+	return err
+}
+
+// ObjectEncoding returns the encoding of the value of key, or ErrNil when
+// the key does not exist.
+func (c commands) ObjectEncoding(ctx context.Context, key string) (string, error) {
+	rsp, err := c.do(ctx, "OBJECT", "ENCODING", key)
+	if err == nil && isNil(rsp) {
+		return "", ErrNil
+	}
+	return c.string(rsp, err)
+}
+
+// ObjectIdleTime returns how long key was not accessed, or ErrNil when the
+// key does not exist.
+func (c commands) ObjectIdleTime(ctx context.Context, key string) (time.Duration, error) {
+	sec, err := c.nilInt64(c.do(ctx, "OBJECT", "IDLETIME", key))
+	return time.Duration(sec) * time.Second, err
+}
+
+// ObjectFreq returns the access counter of key, or ErrNil when the key
+// does not exist.
+func (c commands) ObjectFreq(ctx context.Context, key string) (int64, error) {
+	return c.nilInt64(c.do(ctx, "OBJECT", "FREQ", key))
+}
+
+// ObjectRefCount returns the number of references to the value of key, or
+// ErrNil when the key does not exist.
+func (c commands) ObjectRefCount(ctx context.Context, key string) (int64, error) {
+	return c.nilInt64(c.do(ctx, "OBJECT", "REFCOUNT", key))
+}
+
+// MemoryUsage returns the bytes key takes, sizing samples elements of an
+// aggregate value, all of them with 0, or ErrNil when the key does not
+// exist.
+func (c commands) MemoryUsage(ctx context.Context, key string, samples int) (int64, error) {
+	return c.nilInt64(c.do(ctx, "MEMORY", "USAGE", key, "SAMPLES", samples))
+}
+
+// MemoryStats returns the memory figures of the server by name.
+func (c commands) MemoryStats(ctx context.Context) (map[string]redis.RedisObject, error) {
+	rsp, err := c.do(ctx, "MEMORY", "STATS")
+	if err != nil {
+		return nil, err
+	}
+	var stats map[string]redis.RedisObject
+	err = redis.Unmarshal(rsp, &stats)
+	return stats, err
+}
+
+// MemoryDoctor returns the report of the server on its memory.
+func (c commands) MemoryDoctor(ctx context.Context) (string, error) {
+	return c.string(c.do(ctx, "MEMORY", "DOCTOR"))
+}
+
+func (c commands) string(rsp redis.RedisObject, err error) (string, error) {
+	if err != nil {
+		return "", err
+	}
+	var value string
+	err = redis.Unmarshal(rsp, &value)
+	return value, err
+}
+
+func (c commands) int64(rsp redis.RedisObject, err error) (int64, error) {
+	if err != nil {
+		return 0, err
+	}
+	var value int64
+	err = redis.Unmarshal(rsp, &value)
+	return value, err
+}
+
+// nilInt64 is int64 for the replies which are nil when the key does not
+// exist.
+func (c commands) nilInt64(rsp redis.RedisObject, err error) (int64, error) {
+	if err == nil && isNil(rsp) {
+		return 0, ErrNil
+	}
+	return c.int64(rsp, err)
+}
+
+func isNil(rsp redis.RedisObject) bool {
+	switch rsp := rsp.(type) {
+	case *redis.Null:
+		return true
+	case *redis.BulkString:
+		return rsp.IsNull()
+	case *redis.Array:
+		return rsp.IsNull()
+	}
+	return false
+}
@@ -0,0 +1,198 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/src/client"
+	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
+)
+
+func TestCommands(t *testing.T) {
+	addr := startServer(t)
+	c := client.New(addr, nil)
+	defer c.Close()
+	ctx := context.Background()
+
+	if err := c.Ping(ctx); err != nil {
+		t.Errorf("ping: unexpected error: %v", err)
+	}
+	if msg, err := c.Echo(ctx, "hello"); err != nil || msg != "hello" {
+		t.Errorf("echo: expected=hello, actual=%s err=%v", msg, err)
+	}
+	if _, err := c.Get(ctx, "missing"); !errors.Is(err, client.ErrNil) {
+		t.Errorf("get: expected ErrNil, actual=%v", err)
+	}
+	if err := c.Set(ctx, "key", []byte("value"), 0); err != nil {
+		t.Errorf("set: unexpected error: %v", err)
+	}
+	if value, err := c.Get(ctx, "key"); err != nil || string(value) != "value" {
+		t.Errorf("get: expected=value, actual=%s err=%v", value, err)
+	}
+	if err := c.Set(ctx, "expiring", []byte("value"), 10*time.Millisecond); err != nil {
+		t.Errorf("set: unexpected error: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if _, err := c.Get(ctx, "expiring"); !errors.Is(err, client.ErrNil) {
+		t.Errorf("get: expected expired key, actual=%v", err)
+	}
+	if info, err := c.Info(ctx, "replication"); err != nil || !strings.Contains(info, "role:") {
+		t.Errorf("info: expected role, actual=%q err=%v", info, err)
+	}
+
+	var replyErr *redis.ReplyError
+	if _, err := c.Do(ctx, "NOSUCHCOMMAND"); !errors.As(err, &replyErr) {
+		t.Errorf("do: expected reply error, actual=%v", err)
+	}
+
+	replies, err := c.Pipeline(ctx, func(p *client.Pipeline) {
+		p.Do("ECHO", "a").Do("ECHO", "b")
+	})
+	if err != nil || len(replies) != 2 || replies[1].String() != "BulkString{b}" {
+		t.Errorf("pipeline: unexpected replies %v err=%v", replies, err)
+	}
+}
+
+func TestCommands_Keyspace(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c, err := client.Dial(ctx, startServer(t), &client.Options{Protocol: client.RESP2})
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer c.Close()
+
+	if err := c.Set(ctx, "a", []byte("1"), 0); err != nil {
+		t.Fatalf("set: unexpected error: %v", err)
+	}
+	if moved, err := c.Move(ctx, "a", 1); err != nil || !moved {
+		t.Errorf("move: expected moved, actual=%v err=%v", moved, err)
+	}
+	if err := c.Select(ctx, 1); err != nil {
+		t.Errorf("select: unexpected error: %v", err)
+	}
+	if n, err := c.DBSize(ctx); err != nil || n != 1 {
+		t.Errorf("dbsize: expected 1, actual=%d err=%v", n, err)
+	}
+	if err := c.SwapDB(ctx, 0, 1); err != nil {
+		t.Errorf("swapdb: unexpected error: %v", err)
+	} else if n, _ := c.DBSize(ctx); n != 0 {
+		t.Errorf("swapdb: expected db 1 to be empty, actual=%d", n)
+	}
+	if err := c.Select(ctx, 0); err != nil {
+		t.Errorf("select: unexpected error: %v", err)
+	}
+
+	payload, err := c.Dump(ctx, "a")
+	if err != nil {
+		t.Fatalf("dump: unexpected error: %v", err)
+	} else if _, err := c.Dump(ctx, "missing"); !errors.Is(err, client.ErrNil) {
+		t.Errorf("dump: expected ErrNil, actual=%v", err)
+	}
+	if err := c.Restore(ctx, "b", time.Hour, payload, false); err != nil {
+		t.Errorf("restore: unexpected error: %v", err)
+	} else if err := c.Restore(ctx, "b", 0, payload, false); err == nil {
+		t.Errorf("restore: expected busy key")
+	}
+	if encoding, err := c.ObjectEncoding(ctx, "b"); err != nil || encoding != "int" {
+		t.Errorf("object encoding: expected int, actual=%s err=%v", encoding, err)
+	}
+	if idle, err := c.ObjectIdleTime(ctx, "b"); err != nil || idle != 0 {
+		t.Errorf("object idletime: expected 0, actual=%v err=%v", idle, err)
+	}
+	if _, err := c.ObjectFreq(ctx, "b"); err == nil {
+		t.Errorf("object freq: expected an error without an lfu policy")
+	}
+	if n, err := c.ObjectRefCount(ctx, "missing"); !errors.Is(err, client.ErrNil) {
+		t.Errorf("object refcount: expected ErrNil, actual=%d err=%v", n, err)
+	}
+	if size, err := c.MemoryUsage(ctx, "b", 0); err != nil || size <= 0 {
+		t.Errorf("memory usage: expected a size, actual=%d err=%v", size, err)
+	}
+	if stats, err := c.MemoryStats(ctx); err != nil || stats["keys.count"] == nil {
+		t.Errorf("memory stats: expected keys.count, actual=%v err=%v", stats, err)
+	}
+	if report, err := c.MemoryDoctor(ctx); err != nil || !strings.HasPrefix(report, "Hi Sam") {
+		t.Errorf("memory doctor: unexpected report %q err=%v", report, err)
+	}
+	if n, err := c.Wait(ctx, 0, 0); err != nil || n != 0 {
+		t.Errorf("wait: expected 0, actual=%d err=%v", n, err)
+	}
+	if _, err := c.LastSave(ctx); err != nil {
+		t.Errorf("lastsave: unexpected error: %v", err)
+	}
+
+	target := startServer(t)
+	host, portStr, _ := net.SplitHostPort(target)
+	port, _ := strconv.Atoi(portStr)
+	if ok, err := c.Migrate(ctx, host, port, 0, time.Second, &client.MigrateOptions{Copy: true}, "a", "b"); err != nil || !ok {
+		t.Errorf("migrate: expected ok, actual=%v err=%v", ok, err)
+	} else if ok, err := c.Migrate(ctx, host, port, 0, time.Second, nil, "missing"); err != nil || ok {
+		t.Errorf("migrate: expected nokey, actual=%v err=%v", ok, err)
+	}
+	if n, err := c.Del(ctx, "a", "missing"); err != nil || n != 1 {
+		t.Errorf("del: expected 1, actual=%d err=%v", n, err)
+	}
+	if err := c.FlushDB(ctx, false); err != nil {
+		t.Errorf("flushdb: unexpected error: %v", err)
+	} else if err := c.FlushAll(ctx, true); err != nil {
+		t.Errorf("flushall: unexpected error: %v", err)
+	} else if n, _ := c.DBSize(ctx); n != 0 {
+		t.Errorf("flushall: expected no key, actual=%d", n)
+	}
+}
+
+func TestParsePsyncReply(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		expected client.PsyncReply
+		isError  bool
+	}{
+		{
+			name:     "full resync",
+			line:     "FULLRESYNC 8371b4fb1155b71f4a04d3e1bc3e18c4a990aeeb 42",
+			expected: client.PsyncReply{FullResync: true, Replid: "8371b4fb1155b71f4a04d3e1bc3e18c4a990aeeb", Offset: 42},
+		},
+		{
+			name:     "continue with replid",
+			line:     "CONTINUE 8371b4fb1155b71f4a04d3e1bc3e18c4a990aeeb",
+			expected: client.PsyncReply{Replid: "8371b4fb1155b71f4a04d3e1bc3e18c4a990aeeb", Offset: -1},
+		},
+		{
+			name:     "continue",
+			line:     "CONTINUE",
+			expected: client.PsyncReply{Offset: -1},
+		},
+		{
+			name:    "malformed offset",
+			line:    "FULLRESYNC id x",
+			isError: true,
+		},
+		{
+			name:    "unexpected",
+			line:    "OK",
+			isError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reply, err := client.ParsePsyncReply(tt.line)
+			if tt.isError {
+				if err == nil {
+					t.Errorf("case %s: expected error but got %v", tt.name, reply)
+				}
+			} else if err != nil {
+				t.Errorf("case %s: unexpected error: %v", tt.name, err)
+			} else if *reply != tt.expected {
+				t.Errorf("case %s: expected=%v, actual=%v", tt.name, tt.expected, *reply)
+			}
+		})
+	}
+}
@@ -0,0 +1,544 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
+)
+
+var (
+	ErrNil    = errors.New("redis: nil")
+	ErrClosed = errors.New("redis: connection closed")
+)
+
+const (
+	RESP2 = 2
+	RESP3 = 3
+)
+
+// Options configures connections. The zero value dials RESP3 and falls
+// back to RESP2 when the server does not understand HELLO.
+type Options struct {
+	// Protocol is the RESP version negotiated with HELLO, RESP2 skips it.
+	Protocol int
+	// DialTimeout bounds connecting when the context has no deadline.
+	DialTimeout time.Duration
+	// PushHandler receives RESP3 push messages read between replies.
+	PushHandler func(push *redis.Push)
+	// PoolSize is the maximum number of open connections of a pool.
+	PoolSize int
+	// MaxIdle is the maximum number of idle connections a pool keeps.
+	MaxIdle int
+}
+
+func (o *Options) protocol() int {
+	if o == nil || o.Protocol == 0 {
+		return RESP3
+	}
+	return o.Protocol
+}
+
+func (o *Options) dialTimeout() time.Duration {
+	if o == nil || o.DialTimeout <= 0 {
+		return 5 * time.Second
+	}
+	return o.DialTimeout
+}
+
+func (o *Options) poolSize() int {
+	if o == nil || o.PoolSize <= 0 {
+		return 10
+	}
+	return o.PoolSize
+}
+
+func (o *Options) maxIdle() int {
+	if o == nil || o.MaxIdle <= 0 {
+		return o.poolSize()
+	}
+	return o.MaxIdle
+}
+
+// Conn is a single connection to a server. It is not safe for concurrent
+// use; share a Pool or a Client instead.
+type Conn struct {
+	commands
+
+	conn     net.Conn
+	received *countingReader
+	reader   *bufio.Reader
+	encoder  *redis.Encoder
+	protocol int
+	onPush   func(push *redis.Push)
+
+	// pending counts requests sent whose replies are not read yet
+	pending int
+	err     error
+
+	// pool handed the connection out while out is set, guarded by the
+	// mutex of pool
+	pool *Pool
+	out  bool
+}
+
+// Dial connects to address and negotiates the protocol with HELLO.
+func Dial(ctx context.Context, address string, opts *Options) (*Conn, error) {
+	dialer := net.Dialer{}
+	if _, ok := ctx.Deadline(); !ok {
+		dialer.Timeout = opts.dialTimeout()
+	}
+	netConn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial %s: %w", address, err)
+	}
+
+	c := NewConn(netConn, opts)
+	if protocol := opts.protocol(); protocol != RESP2 {
+		if err := c.hello(ctx, protocol); err != nil {
+			_ = c.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// NewConn wraps an established connection speaking RESP2.
+func NewConn(netConn net.Conn, opts *Options) *Conn {
+	received := &countingReader{reader: netConn}
+	c := &Conn{
+		conn:     netConn,
+		received: received,
+		reader:   bufio.NewReader(received),
+		encoder:  redis.NewEncoder(netConn),
+		protocol: RESP2,
+	}
+	if opts != nil {
+		c.onPush = opts.PushHandler
+	}
+	c.commands.do = c.Do
+	return c
+}
+
+func (c *Conn) hello(ctx context.Context, protocol int) error {
+	rsp, err := c.Do(ctx, "HELLO", protocol)
+	var replyErr *redis.ReplyError
+	if errors.As(err, &replyErr) {
+		// servers before RESP3 reject HELLO, keep talking RESP2
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to negotiate protocol: %w", err)
+	}
+	if _, ok := rsp.(*redis.Map); ok {
+		c.protocol = protocol
+	}
+	return nil
+}
+
+// Protocol returns the negotiated RESP version.
+func (c *Conn) Protocol() int {
+	return c.protocol
+}
+
+// Err returns the error which broke the connection, if any.
+func (c *Conn) Err() error {
+	return c.err
+}
+
+// RemoteAddr returns the address of the server.
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// Reader exposes the buffered reader, for protocols like replication which
+// continue with raw payloads after a reply.
+func (c *Conn) Reader() *bufio.Reader {
+	return c.reader
+}
+
+// InputOffset returns how many bytes sent by the server have been
+// consumed, not counting what is buffered but not read yet.
+func (c *Conn) InputOffset() int64 {
+	return c.received.n - int64(c.reader.Buffered())
+}
+
+// RecordInput starts keeping the bytes sent by the server from the current
+// InputOffset on, for TakeInput.
+func (c *Conn) RecordInput() {
+	buffered, _ := c.reader.Peek(c.reader.Buffered())
+	c.received.recorded = append(c.received.recorded[:0], buffered...)
+	c.received.record = true
+}
+
+// TakeInput appends to buf the bytes consumed since the previous call, as
+// they were received, e.g. for a replica to proxy its master's stream.
+func (c *Conn) TakeInput(buf []byte) []byte {
+	n := len(c.received.recorded) - c.reader.Buffered()
+	buf = append(buf, c.received.recorded[:n]...)
+	c.received.recorded = append(c.received.recorded[:0], c.received.recorded[n:]...)
+	return buf
+}
+
+// NetConn exposes the underlying connection.
+func (c *Conn) NetConn() net.Conn {
+	return c.conn
+}
+
+func (c *Conn) Close() error {
+	if c.err == nil {
+		c.err = ErrClosed
+	}
+	return c.conn.Close()
+}
+
+// Do sends one command and waits for its reply. Error replies are returned
+// as *redis.ReplyError together with the reply object.
+func (c *Conn) Do(ctx context.Context, args ...any) (redis.RedisObject, error) {
+	if err := c.Send(args...); err != nil {
+		return nil, err
+	}
+	if err := c.Flush(ctx); err != nil {
+		return nil, err
+	}
+	return c.Receive(ctx)
+}
+
+// Send buffers a command without flushing it.
+func (c *Conn) Send(args ...any) error {
+	if c.err != nil {
+		return c.err
+	}
+	request, err := Request(args...)
+	if err != nil {
+		return err
+	}
+	c.pending++
+	return c.encoder.Encode(request)
+}
+
+// Flush writes the buffered commands.
+func (c *Conn) Flush(ctx context.Context) error {
+	if c.err != nil {
+		return c.err
+	}
+	return c.withContext(ctx, func() error {
+		return c.encoder.Flush()
+	})
+}
+
+// Receive reads the next reply, handing push messages to the push handler
+// on the way.
+func (c *Conn) Receive(ctx context.Context) (rsp redis.RedisObject, err error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	err = c.withContext(ctx, func() error {
+		for {
+			obj, readErr := redis.ReadObject(c.reader)
+			if readErr != nil {
+				return readErr
+			}
+			if push, ok := obj.(*redis.Push); ok {
+				if c.onPush != nil {
+					c.onPush(push)
+				}
+				continue
+			}
+			rsp = obj
+			return nil
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if c.pending > 0 {
+		c.pending--
+	}
+	return rsp, ReplyErr(rsp)
+}
+
+// ReceivePush blocks until the next push message, for connections which
+// only listen, such as subscribers.
+func (c *Conn) ReceivePush(ctx context.Context) (push *redis.Push, err error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	err = c.withContext(ctx, func() error {
+		obj, readErr := redis.ReadObject(c.reader)
+		if readErr != nil {
+			return readErr
+		}
+		var ok bool
+		if push, ok = obj.(*redis.Push); !ok {
+			return fmt.Errorf("unexpected reply %v while waiting for push", obj)
+		}
+		return nil
+	})
+	return push, err
+}
+
+// ReceiveSnapshot reads the RDB payload a master sends after +FULLRESYNC
+// into memory.
+func (c *Conn) ReceiveSnapshot(ctx context.Context) (payload []byte, err error) {
+	err = c.ReadSnapshot(ctx, func(reader io.Reader) error {
+		var readErr error
+		payload, readErr = io.ReadAll(reader)
+		return readErr
+	})
+	return payload, err
+}
+
+// ReadSnapshot hands the RDB payload a master sends after +FULLRESYNC to
+// load while it arrives. The payload is framed like a bulk string without
+// the trailing CRLF, or by diskless masters as $EOF:<mark> followed by the
+// payload and the mark again. Whatever load leaves unread is skipped, so
+// the connection continues with the replication stream.
+func (c *Conn) ReadSnapshot(ctx context.Context, load func(reader io.Reader) error) error {
+	if c.err != nil {
+		return c.err
+	}
+	return c.withContext(ctx, func() error {
+		line, readErr := c.reader.ReadString('\n')
+		// the master may send newlines to keep the link alive while the
+		// snapshot is being produced
+		for readErr == nil && line == "\n" {
+			line, readErr = c.reader.ReadString('\n')
+		}
+		if readErr != nil {
+			return readErr
+		}
+		line = strings.TrimSuffix(line, "\r\n")
+
+		var payload io.Reader
+		switch {
+		case strings.HasPrefix(line, "$EOF:") && len(line) == len("$EOF:")+EOFMarkSize:
+			payload = &eofMarkReader{reader: c.reader, mark: []byte(line[len("$EOF:"):])}
+		case strings.HasPrefix(line, "$"):
+			size, convErr := strconv.ParseInt(line[1:], 10, 64)
+			if convErr != nil || size < 0 {
+				return fmt.Errorf("unexpected snapshot header %q", line)
+			}
+			payload = &io.LimitedReader{R: c.reader, N: size}
+		default:
+			return fmt.Errorf("unexpected snapshot header %q", line)
+		}
+
+		if loadErr := load(payload); loadErr != nil {
+			return loadErr
+		}
+		if _, readErr = io.Copy(io.Discard, payload); readErr != nil {
+			return readErr
+		}
+		if limited, ok := payload.(*io.LimitedReader); ok && limited.N > 0 {
+			return io.ErrUnexpectedEOF
+		}
+		return nil
+	})
+}
+
+// EOFMarkSize is the length of the random mark ending a diskless snapshot.
+const EOFMarkSize = 40
+
+// eofMarkReader reads a payload up to its end mark, without consuming
+// anything past the mark.
+type eofMarkReader struct {
+	reader *bufio.Reader
+	mark   []byte
+	done   bool
+}
+
+func (r *eofMarkReader) Read(p []byte) (int, error) {
+	if r.done {
+		return 0, io.EOF
+	}
+	size := r.reader.Buffered()
+	if size < len(r.mark) {
+		size = len(r.mark)
+	}
+	window, err := r.reader.Peek(size)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, err
+	}
+	// the bytes before a possible start of the mark are payload
+	n := len(window) - len(r.mark) + 1
+	if i := bytes.Index(window, r.mark); i == 0 {
+		_, _ = r.reader.Discard(len(r.mark))
+		r.done = true
+		return 0, io.EOF
+	} else if i > 0 {
+		n = i
+	}
+	if n > len(p) {
+		n = len(p)
+	}
+	return r.reader.Read(p[:n])
+}
+
+// Pipeline starts a batch of commands sent with a single write.
+func (c *Conn) Pipeline() *Pipeline {
+	return &Pipeline{conn: c}
+}
+
+// withContext runs f with the connection deadline following ctx. An I/O
+// failure breaks the connection, since the reply stream is out of sync.
+func (c *Conn) withContext(ctx context.Context, f func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	deadline, _ := ctx.Deadline()
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		c.err = err
+		return err
+	}
+
+	var (
+		done    = make(chan struct{})
+		wg      sync.WaitGroup
+		expired bool
+	)
+	if ctx.Done() != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case <-ctx.Done():
+				expired = true
+				_ = c.conn.SetDeadline(time.Unix(1, 0))
+			case <-done:
+			}
+		}()
+	}
+	err := f()
+	close(done)
+	wg.Wait()
+
+	if err != nil {
+		if expired && ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		c.err = err
+		return err
+	}
+	return nil
+}
+
+// countingReader counts the bytes read from the connection, and keeps
+// them once recording.
+type countingReader struct {
+	reader   io.Reader
+	n        int64
+	record   bool
+	recorded []byte
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.n += int64(n)
+	if r.record {
+		r.recorded = append(r.recorded, p[:n]...)
+	}
+	return n, err
+}
+
+// Pipeline queues commands and sends them in one write on Exec.
+type Pipeline struct {
+	conn  *Conn
+	count int
+	err   error
+}
+
+// Do queues a command.
+func (p *Pipeline) Do(args ...any) *Pipeline {
+	if p.err == nil {
+		if p.err = p.conn.Send(args...); p.err == nil {
+			p.count++
+		}
+	}
+	return p
+}
+
+// Len returns the number of queued commands.
+func (p *Pipeline) Len() int {
+	return p.count
+}
+
+// Exec flushes the queued commands and reads their replies in order. Error
+// replies stay in the result, check them with ReplyErr.
+func (p *Pipeline) Exec(ctx context.Context) ([]redis.RedisObject, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	if err := p.conn.Flush(ctx); err != nil {
+		return nil, err
+	}
+	replies := make([]redis.RedisObject, 0, p.count)
+	for i := 0; i < p.count; i++ {
+		rsp, err := p.conn.Receive(ctx)
+		var replyErr *redis.ReplyError
+		if err != nil && !errors.As(err, &replyErr) {
+			return replies, err
+		}
+		replies = append(replies, rsp)
+	}
+	p.count = 0
+	return replies, nil
+}
+
+// ReplyErr returns the error carried by an error reply, or nil.
+func ReplyErr(rsp redis.RedisObject) error {
+	switch rsp.(type) {
+	case *redis.SimpleError, *redis.BulkError:
+		var value any
+		return redis.Unmarshal(rsp, &value)
+	}
+	return nil
+}
+
+// Request encodes command arguments as an array of bulk strings.
+func Request(args ...any) (*redis.Array, error) {
+	elements := make([]redis.RedisObject, len(args))
+	for i, arg := range args {
+		var buf []byte
+		switch arg := arg.(type) {
+		case string:
+			buf = []byte(arg)
+		case []byte:
+			buf = arg
+		case int:
+			buf = strconv.AppendInt(nil, int64(arg), 10)
+		case int64:
+			buf = strconv.AppendInt(nil, arg, 10)
+		case uint64:
+			buf = strconv.AppendUint(nil, arg, 10)
+		case float64:
+			buf = strconv.AppendFloat(nil, arg, 'f', -1, 64)
+		case bool:
+			if arg {
+				buf = []byte("1")
+			} else {
+				buf = []byte("0")
+			}
+		case fmt.Stringer:
+			buf = []byte(arg.String())
+		default:
+			return nil, fmt.Errorf("unsupported argument type %T", arg)
+		}
+		if buf == nil {
+			buf = []byte{}
+		}
+		elements[i] = redis.NewBulkString(buf)
+	}
+	return redis.NewArray(elements...), nil
+}
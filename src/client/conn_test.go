@@ -0,0 +1,332 @@
+package client_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/src/client"
+	"github.com/codecrafters-io/redis-starter-go/src/handler"
+	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
+)
+
+// startServer serves one CommandHandler on a loopback port.
+func startServer(t *testing.T) string {
+	t.Helper()
+	return serve(t, nil)
+}
+
+// serve accepts connections on a loopback port and hands them to f, or to
+// a shared CommandHandler when f is nil.
+func serve(t *testing.T, f func(conn net.Conn)) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+	shared := handler.NewCommandHandler()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			if f == nil {
+				go func() {
+					_ = shared.HandleConnection(context.Background(), conn)
+				}()
+			} else {
+				go f(conn)
+			}
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestDial_FallbackToRESP2(t *testing.T) {
+	addr := startServer(t)
+	conn, err := client.Dial(context.Background(), addr, nil)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	if conn.Protocol() != client.RESP2 {
+		t.Errorf("expected protocol %d, actual=%d", client.RESP2, conn.Protocol())
+	}
+	if err := conn.Ping(context.Background()); err != nil {
+		t.Errorf("expected connection usable after HELLO is rejected: %v", err)
+	}
+}
+
+func TestDial_HelloRESP3(t *testing.T) {
+	addr := serve(t, func(conn net.Conn) {
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		for {
+			req, err := redis.ReadObject(reader)
+			if err != nil {
+				return
+			}
+			var args []string
+			_ = redis.Unmarshal(req, &args)
+			switch args[0] {
+			case "HELLO":
+				rsp, _ := redis.Marshal(map[string]any{"server": "redis", "proto": 3})
+				_ = rsp.Write(conn)
+			case "GET":
+				// a push message arrives before the reply
+				_ = redis.NewPush(redis.NewBulkString([]byte("invalidate")), redis.NewArray(redis.NewBulkString([]byte(args[1])))).Write(conn)
+				_ = redis.NewBulkString([]byte("value")).Write(conn)
+			}
+		}
+	})
+
+	pushes := make(chan *redis.Push, 1)
+	conn, err := client.Dial(context.Background(), addr, &client.Options{
+		PushHandler: func(push *redis.Push) {
+			pushes <- push
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	if conn.Protocol() != client.RESP3 {
+		t.Errorf("expected protocol %d, actual=%d", client.RESP3, conn.Protocol())
+	}
+	if value, err := conn.Get(context.Background(), "key"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if string(value) != "value" {
+		t.Errorf("expected=value, actual=%s", value)
+	}
+	select {
+	case push := <-pushes:
+		if expected := "Push[BulkString{invalidate}, Array[BulkString{key}]]"; push.String() != expected {
+			t.Errorf("expected=%s, actual=%s", expected, push.String())
+		}
+	default:
+		t.Errorf("expected push message delivered")
+	}
+}
+
+func TestConn_Pipeline(t *testing.T) {
+	addr := startServer(t)
+	conn, err := client.Dial(context.Background(), addr, &client.Options{Protocol: client.RESP2})
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	pipeline := conn.Pipeline()
+	for i := 0; i < 200; i++ {
+		pipeline.Do("SET", fmt.Sprintf("key:%d", i), i).Do("GET", fmt.Sprintf("key:%d", i))
+	}
+	pipeline.Do("NOSUCHCOMMAND").Do("ECHO", "done")
+
+	replies, err := pipeline.Exec(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if len(replies) != 402 {
+		t.Fatalf("expected 402 replies, actual=%d", len(replies))
+	}
+	for i := 0; i < 200; i++ {
+		var value int
+		if err := redis.Unmarshal(replies[2*i+1], &value); err != nil || value != i {
+			t.Fatalf("reply %d expected=%d, actual=%v err=%v", 2*i+1, i, replies[2*i+1], err)
+		}
+	}
+	if err := client.ReplyErr(replies[400]); err == nil {
+		t.Errorf("expected error reply for unknown command, actual=%v", replies[400])
+	}
+	if replies[401].String() != "BulkString{done}" {
+		t.Errorf("expected=BulkString{done}, actual=%v", replies[401])
+	}
+}
+
+func TestConn_ReceiveSnapshot(t *testing.T) {
+	eofMark := strings.Repeat("0123456789", 4)
+	tests := []struct {
+		name     string
+		stream   string
+		expected string
+		isError  bool
+	}{
+		{
+			name:     "payload without trailing CRLF",
+			stream:   "$9\r\nREDIS0011+PONG\r\n",
+			expected: "REDIS0011",
+		},
+		{
+			name:     "keepalive newlines before the payload",
+			stream:   "\n\n$5\r\nhello+PONG\r\n",
+			expected: "hello",
+		},
+		{
+			name:     "diskless payload ended by the mark",
+			stream:   "$EOF:" + eofMark + "\r\nREDIS0011" + eofMark + "+PONG\r\n",
+			expected: "REDIS0011",
+		},
+		{
+			name:     "diskless payload longer than the mark",
+			stream:   "\n$EOF:" + eofMark + "\r\n" + strings.Repeat("x", 5000) + eofMark + "+PONG\r\n",
+			expected: strings.Repeat("x", 5000),
+		},
+		{
+			name:    "diskless header with a short mark",
+			stream:  "$EOF:0123\r\nREDIS0011",
+			isError: true,
+		},
+		{
+			name:    "not a bulk header",
+			stream:  "+OK\r\n",
+			isError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		addr := serve(t, func(conn net.Conn) {
+			defer conn.Close()
+			_, _ = conn.Write([]byte(tt.stream))
+			_, _ = bufio.NewReader(conn).ReadByte()
+		})
+		netConn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatalf("case %s: failed to dial: %v", tt.name, err)
+		}
+		conn := client.NewConn(netConn, nil)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		payload, err := conn.ReceiveSnapshot(ctx)
+		if tt.isError {
+			if err == nil {
+				t.Errorf("case %s: expected error, actual=nil", tt.name)
+			}
+		} else if err != nil {
+			t.Errorf("case %s: unexpected error: %v", tt.name, err)
+		} else if string(payload) != tt.expected {
+			t.Errorf("case %s: expected=%q, actual=%q", tt.name, tt.expected, payload)
+		} else if rsp, err := conn.Receive(ctx); err != nil || rsp.String() != "SimpleString{PONG}" {
+			t.Errorf("case %s: expected the stream to continue after the payload, actual=%v, %v", tt.name, rsp, err)
+		}
+		cancel()
+		_ = conn.Close()
+	}
+}
+
+func TestConn_InputOffset(t *testing.T) {
+	conn, err := client.Dial(context.Background(), startServer(t), &client.Options{Protocol: client.RESP2})
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	// +PONG\r\n, then $5\r\nhello\r\n
+	expected := []int64{7, 18}
+	if err := conn.Ping(ctx); err != nil {
+		t.Fatalf("failed to ping: %v", err)
+	} else if actual := conn.InputOffset(); actual != expected[0] {
+		t.Errorf("expected offset %d, actual=%d", expected[0], actual)
+	}
+	if _, err := conn.Echo(ctx, "hello"); err != nil {
+		t.Fatalf("failed to echo: %v", err)
+	} else if actual := conn.InputOffset(); actual != expected[1] {
+		t.Errorf("expected offset %d, actual=%d", expected[1], actual)
+	}
+}
+
+func TestConn_ContextTimeout(t *testing.T) {
+	addr := serve(t, func(conn net.Conn) {
+		// never replies
+		defer conn.Close()
+		_, _ = bufio.NewReader(conn).ReadByte()
+		time.Sleep(time.Second)
+	})
+	conn, err := client.Dial(context.Background(), addr, &client.Options{Protocol: client.RESP2})
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := conn.Ping(ctx); err == nil {
+		t.Errorf("expected timeout error but got nil")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected to give up after the deadline, took %v", elapsed)
+	}
+	if conn.Err() == nil {
+		t.Errorf("expected connection broken after timeout")
+	}
+
+	cancelCtx, cancelNow := context.WithCancel(context.Background())
+	cancelNow()
+	conn2, err := client.Dial(context.Background(), addr, &client.Options{Protocol: client.RESP2})
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn2.Close()
+	if _, err := conn2.Do(cancelCtx, "PING"); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context canceled, actual=%v", err)
+	}
+}
+
+func TestRequest(t *testing.T) {
+	request, err := client.Request("SET", []byte("k"), 12, int64(-3), 1.5, true, []byte(nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "Array[BulkString{SET}, BulkString{k}, BulkString{12}, BulkString{-3}, BulkString{1.5}, BulkString{1}, BulkString{}]"
+	if request.String() != expected {
+		t.Errorf("expected=%s, actual=%s", expected, request.String())
+	}
+	if _, err := client.Request(struct{}{}); err == nil {
+		t.Errorf("expected error for unsupported argument")
+	}
+}
+
+func TestConn_TakeInput(t *testing.T) {
+	conn, err := client.Dial(context.Background(), startServer(t), &client.Options{Protocol: client.RESP2})
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	// pipelined, so the second reply is buffered while reading the first
+	if _, err := conn.Pipeline().Do("PING").Do("ECHO", "hello").Exec(ctx); err != nil {
+		t.Fatalf("failed to exec: %v", err)
+	}
+	conn.RecordInput()
+	if err := conn.Send("ECHO", "world"); err != nil {
+		t.Fatalf("failed to send: %v", err)
+	} else if err := conn.Send("PING"); err != nil {
+		t.Fatalf("failed to send: %v", err)
+	} else if err := conn.Flush(ctx); err != nil {
+		t.Fatalf("failed to flush: %v", err)
+	}
+
+	expected := []string{"$5\r\nworld\r\n", "+PONG\r\n"}
+	for i, want := range expected {
+		if _, err := conn.Receive(ctx); err != nil {
+			t.Fatalf("failed to receive: %v", err)
+		}
+		if actual := string(conn.TakeInput(nil)); actual != want {
+			t.Errorf("reply %d: expected %q, actual=%q", i, want, actual)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package client
+
+import (
+	"context"
+	"sync"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
+)
+
+// Pool keeps up to Options.PoolSize connections to one server, reusing
+// idle connections and dropping broken ones.
+type Pool struct {
+	address string
+	opts    *Options
+
+	// slots holds one token per connection allowed to be open
+	slots chan struct{}
+
+	mu     sync.Mutex
+	idle   []*Conn
+	closed bool
+}
+
+func NewPool(address string, opts *Options) *Pool {
+	return &Pool{
+		address: address,
+		opts:    opts,
+		slots:   make(chan struct{}, opts.poolSize()),
+	}
+}
+
+// Get returns an idle connection or dials a new one, waiting for a free
+// slot while the pool is exhausted.
+func (p *Pool) Get(ctx context.Context) (*Conn, error) {
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		<-p.slots
+		return nil, ErrClosed
+	}
+	if n := len(p.idle); n > 0 {
+		c := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		c.out = true
+		p.mu.Unlock()
+		return c, nil
+	}
+	p.mu.Unlock()
+
+	c, err := Dial(ctx, p.address, p.opts)
+	if err != nil {
+		<-p.slots
+		return nil, err
+	}
+	p.mu.Lock()
+	c.pool, c.out = p, true
+	p.mu.Unlock()
+	return c, nil
+}
+
+// Put gives a connection back to the pool. Broken connections and those
+// with unread replies are closed instead of reused. A connection the pool
+// did not hand out, or one already put back, is ignored.
+func (p *Pool) Put(c *Conn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if c.pool != p || !c.out {
+		return
+	}
+	c.out = false
+	defer func() {
+		<-p.slots
+	}()
+
+	if p.closed || c.err != nil || c.pending > 0 || len(p.idle) >= p.opts.maxIdle() {
+		_ = c.Close()
+		return
+	}
+	p.idle = append(p.idle, c)
+}
+
+// Do runs one command on a pooled connection.
+func (p *Pool) Do(ctx context.Context, args ...any) (redis.RedisObject, error) {
+	c, err := p.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer p.Put(c)
+	return c.Do(ctx, args...)
+}
+
+// Idle returns the number of idle connections.
+func (p *Pool) Idle() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.idle)
+}
+
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	for _, c := range p.idle {
+		_ = c.Close()
+	}
+	p.idle = nil
+	return nil
+}
@@ -0,0 +1,136 @@
+package client_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/src/client"
+)
+
+func TestPool_Reuse(t *testing.T) {
+	addr := startServer(t)
+	pool := client.NewPool(addr, &client.Options{Protocol: client.RESP2, PoolSize: 2})
+	defer pool.Close()
+
+	ctx := context.Background()
+	first, err := pool.Get(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pool.Put(first)
+	if pool.Idle() != 1 {
+		t.Errorf("expected 1 idle connection, actual=%d", pool.Idle())
+	}
+	second, err := pool.Get(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if second != first {
+		t.Errorf("expected idle connection reused")
+	}
+
+	// the pool is exhausted until a connection is put back
+	third, err := pool.Get(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	timeoutCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err := pool.Get(timeoutCtx); err == nil {
+		t.Errorf("expected exhausted pool to time out")
+	}
+
+	// broken connections are dropped
+	_ = third.Close()
+	pool.Put(third)
+	pool.Put(second)
+	if pool.Idle() != 1 {
+		t.Errorf("expected 1 idle connection, actual=%d", pool.Idle())
+	}
+}
+
+func TestPool_PutForeign(t *testing.T) {
+	addr := startServer(t)
+	pool := client.NewPool(addr, &client.Options{Protocol: client.RESP2, PoolSize: 1})
+	defer pool.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	foreign, err := client.Dial(ctx, addr, &client.Options{Protocol: client.RESP2})
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer foreign.Close()
+	held, err := pool.Get(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// neither frees the slot the held connection takes
+	done := make(chan struct{})
+	go func() {
+		pool.Put(foreign)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		t.Fatalf("expected a foreign put not to block")
+	}
+	if pool.Idle() != 0 {
+		t.Errorf("expected the foreign connection to be ignored, actual=%d idle", pool.Idle())
+	}
+	timeoutCtx, timeoutCancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer timeoutCancel()
+	if _, err := pool.Get(timeoutCtx); err == nil {
+		t.Errorf("expected the pool to stay exhausted")
+	}
+
+	pool.Put(held)
+	pool.Put(held)
+	if pool.Idle() != 1 {
+		t.Errorf("expected a second put to be ignored, actual=%d idle", pool.Idle())
+	}
+	if c, err := pool.Get(ctx); err != nil || c != held {
+		t.Fatalf("expected the held connection back, actual=%v", err)
+	}
+	timeoutCtx, timeoutCancel = context.WithTimeout(ctx, 20*time.Millisecond)
+	defer timeoutCancel()
+	if _, err := pool.Get(timeoutCtx); err == nil {
+		t.Errorf("expected the duplicate put not to free a slot")
+	}
+}
+
+func TestClient_Concurrent(t *testing.T) {
+	addr := startServer(t)
+	c := client.New(addr, &client.Options{Protocol: client.RESP2, PoolSize: 4})
+	defer c.Close()
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	errs := make(chan error, 32)
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := fmt.Sprintf("key:%d", i)
+			if err := c.Set(ctx, key, []byte(key), 0); err != nil {
+				errs <- err
+			} else if value, err := c.Get(ctx, key); err != nil {
+				errs <- err
+			} else if string(value) != key {
+				errs <- fmt.Errorf("expected=%s, actual=%s", key, value)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	if idle := c.Pool().Idle(); idle > 4 {
+		t.Errorf("expected at most 4 idle connections, actual=%d", idle)
+	}
+}
@@ -0,0 +1,168 @@
+// Command redis-check-aof validates an append-only file, or all the files
+// of a manifest, reporting where it is corrupt, and summarizes the keyspace
+// it rebuilds. With -fix a damaged tail of the last file is cut off.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+	"github.com/codecrafters-io/redis-starter-go/src/model/aof"
+	"github.com/codecrafters-io/redis-starter-go/src/model/cmd"
+	"github.com/codecrafters-io/redis-starter-go/src/model/rdb"
+	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
+)
+
+func main() {
+	fix := flag.Bool("fix", false, "truncate the last file to its valid part")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [-fix] <file.aof|file.manifest>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := checkAOF(os.Stdout, flag.Arg(0), *fix, time.Now().UnixMilli()); err != nil {
+		os.Exit(1)
+	}
+}
+
+// checkAOF reads the AOF at path, a single file or a manifest, and writes
+// the report, the summary describes the keys as of now. The error tells
+// the AOF is not valid, or could not be fixed, it is reported already.
+func checkAOF(writer io.Writer, path string, fix bool, now int64) error {
+	paths, err := aofFiles(path)
+	if err != nil {
+		fmt.Fprintf(writer, "Cannot read %s: %v\n", path, err)
+		return err
+	}
+
+	checker := &checker{
+		writer:  writer,
+		storage: model.NewRedisStorage(model.DefaultDatabases),
+	}
+	for i, file := range paths {
+		last := i == len(paths)-1
+		valid, err := checker.checkFile(file)
+		if err == nil {
+			continue
+		} else if valid < 0 || !fix {
+			return err
+		} else if !last {
+			fmt.Fprintf(writer, "Cannot fix %s, only the last file of the AOF can be truncated\n", file)
+			return err
+		}
+		if err := os.Truncate(file, valid); err != nil {
+			fmt.Fprintf(writer, "Failed to truncate %s: %v\n", file, err)
+			return err
+		}
+		fmt.Fprintf(writer, "Successfully truncated %s to %d bytes\n", file, valid)
+	}
+
+	fmt.Fprintf(writer, "AOF %s is valid\n", path)
+	fmt.Fprintf(writer, "[info] %d commands replayed, %d skipped\n", checker.replayed, checker.skipped)
+	for db, mem := range checker.storage.Databases() {
+		if len(mem) == 0 {
+			continue
+		}
+		fmt.Fprintf(writer, "[info] db %d\n", db)
+		if err := model.Summarize(mem, now).Report(writer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// aofFiles returns the files of the AOF at path in load order.
+func aofFiles(path string) ([]string, error) {
+	if !strings.HasSuffix(path, ".manifest") {
+		return []string{path}, nil
+	}
+	dir := filepath.Dir(path)
+	manifest, err := aof.ReadManifest(dir, strings.TrimSuffix(filepath.Base(path), ".manifest"))
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, file := range manifest.Files() {
+		paths = append(paths, filepath.Join(dir, file.Name))
+	}
+	return paths, nil
+}
+
+// checker replays the files of an AOF into storage.
+type checker struct {
+	writer   io.Writer
+	storage  *model.RedisStorage
+	conf     model.CommandConf
+	replayed int
+	skipped  int
+}
+
+// checkFile replays the file at path. On error it returns where the valid
+// part of the file ends, or -1 when it cannot be fixed by truncation.
+func (c *checker) checkFile(path string) (int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintf(c.writer, "Cannot open %s: %v\n", path, err)
+		return -1, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		fmt.Fprintf(c.writer, "Cannot stat %s: %v\n", path, err)
+		return -1, err
+	}
+
+	// every file starts in the first database
+	c.storage.Select(0)
+	err = aof.Load(file, func(snapshot *rdb.Snapshot) error {
+		fmt.Fprintf(c.writer, "RDB preamble of %s is OK, version %d\n", path, snapshot.Version)
+		return c.storage.Reset(snapshot.DBs, time.Now().UnixMilli())
+	}, c.replay)
+
+	var (
+		truncated *aof.TruncatedError
+		corrupt   *aof.CorruptError
+		valid     = info.Size()
+	)
+	switch {
+	case errors.As(err, &truncated):
+		valid = truncated.Offset
+		fmt.Fprintf(c.writer, "AOF %s ends in a partial command after offset %d\n", path, valid)
+	case errors.As(err, &corrupt):
+		valid = corrupt.Offset
+		fmt.Fprintf(c.writer, "AOF %s has a bad format after offset %d: %v\n", path, valid, corrupt.Err)
+	case err != nil:
+		fmt.Fprintf(c.writer, "AOF %s is not valid: %v\n", path, err)
+		return -1, err
+	}
+	fmt.Fprintf(c.writer, "AOF analyzed: filename=%s, size=%d, ok_up_to=%d, diff=%d\n",
+		path, info.Size(), valid, info.Size()-valid)
+	return valid, err
+}
+
+// replay applies a command to the storage. Commands the server does not
+// know, or which fail, are skipped: the file format is still valid.
+func (c *checker) replay(args *redis.Array) error {
+	command, err := cmd.ParseCommand(args)
+	if err != nil {
+		c.skipped++
+		return nil
+	}
+	if _, err := command.Execute(io.Discard, c.storage, &c.conf); err != nil {
+		c.skipped++
+		return nil
+	}
+	c.replayed++
+	return nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model/aof"
+)
+
+const (
+	setA    = "*3\r\n$3\r\nSET\r\n$1\r\na\r\n$3\r\nabc\r\n"
+	unknown = "*2\r\n$3\r\nFOO\r\n$1\r\nx\r\n"
+	partial = "*3\r\n$3\r\nSET\r\n$1\r\nb\r\n$2"
+	bad     = "+OK\r\n"
+	select3 = "*2\r\n$6\r\nSELECT\r\n$1\r\n3\r\n"
+)
+
+func TestCheckAOF(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    []string
+		fix      bool
+		contains []string
+		// size of the last file after the check
+		size    int
+		isError bool
+	}{
+		{
+			name:     "valid",
+			files:    []string{setA + unknown},
+			contains: []string{"is valid", "1 commands replayed, 1 skipped", "largest 'a' of 3 bytes"},
+			size:     len(setA + unknown),
+		},
+		{
+			name:     "truncated",
+			files:    []string{setA + partial},
+			contains: []string{"partial command after offset 29", "ok_up_to=29"},
+			size:     len(setA + partial),
+			isError:  true,
+		},
+		{
+			name:     "truncated fixed",
+			files:    []string{setA + partial},
+			fix:      true,
+			contains: []string{"Successfully truncated", "is valid"},
+			size:     len(setA),
+		},
+		{
+			name:     "bad format fixed",
+			files:    []string{setA + bad + setA},
+			fix:      true,
+			contains: []string{"bad format after offset 29", "is valid"},
+			size:     len(setA),
+		},
+		{
+			name:     "databases",
+			files:    []string{setA + select3 + setA},
+			contains: []string{"is valid", "[info] db 0", "[info] db 3"},
+			size:     len(setA + select3 + setA),
+		},
+		{
+			name:     "manifest",
+			files:    []string{setA, unknown},
+			contains: []string{"is valid", "1 commands replayed, 1 skipped"},
+			size:     len(unknown),
+		},
+		{
+			name:     "manifest fixed",
+			files:    []string{setA, setA + partial},
+			fix:      true,
+			contains: []string{"is valid", "2 commands replayed"},
+			size:     len(setA),
+		},
+		{
+			name:     "manifest not last",
+			files:    []string{setA + partial, setA},
+			fix:      true,
+			contains: []string{"only the last file"},
+			size:     len(setA),
+			isError:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		dir := t.TempDir()
+		path, last := writeAOF(t, dir, tt.files)
+		var buf bytes.Buffer
+		err := checkAOF(&buf, path, tt.fix, 0)
+		if (err != nil) != tt.isError {
+			t.Errorf("case %s: expected error %v, actual=%v", tt.name, tt.isError, err)
+		}
+		for _, s := range tt.contains {
+			if !strings.Contains(buf.String(), s) {
+				t.Errorf("case %s: expected %q in report, actual=%s", tt.name, s, buf.String())
+			}
+		}
+		if info, err := os.Stat(last); err != nil {
+			t.Errorf("case %s: unexpected error %v", tt.name, err)
+		} else if info.Size() != int64(tt.size) {
+			t.Errorf("case %s: expected size %d, actual=%d", tt.name, tt.size, info.Size())
+		}
+	}
+}
+
+// writeAOF writes a single file AOF, or a manifest with a base and
+// incremental files, and returns the path to check and of the last file.
+func writeAOF(t *testing.T, dir string, files []string) (string, string) {
+	if len(files) == 1 {
+		path := filepath.Join(dir, "appendonly.aof")
+		if err := os.WriteFile(path, []byte(files[0]), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path, path
+	}
+	manifest := &aof.Manifest{}
+	var last string
+	for i, content := range files {
+		file := &aof.ManifestFile{Name: aof.IncrName("appendonly.aof", int64(i)), Seq: int64(i), Type: aof.TypeIncr}
+		if i == 0 {
+			file = &aof.ManifestFile{Name: aof.BaseName("appendonly.aof", 1, false), Seq: 1, Type: aof.TypeBase}
+			manifest.Base = file
+		} else {
+			manifest.Incrs = append(manifest.Incrs, file)
+		}
+		last = filepath.Join(dir, file.Name)
+		if err := os.WriteFile(last, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := aof.WriteManifest(dir, "appendonly.aof", manifest); err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(dir, aof.ManifestName("appendonly.aof")), last
+}
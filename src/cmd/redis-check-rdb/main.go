@@ -0,0 +1,92 @@
+// Command redis-check-rdb validates an RDB file, reporting where it is
+// corrupt, and summarizes the keyspace it holds.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+	"github.com/codecrafters-io/redis-starter-go/src/model/rdb"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s <rdb-file>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := checkRDB(os.Stdout, flag.Arg(0), time.Now().UnixMilli()); err != nil {
+		os.Exit(1)
+	}
+}
+
+// checkRDB reads the RDB file at path and writes the report, the summary
+// describes the keys as of now. The error tells the file is not valid, it
+// is reported already.
+func checkRDB(writer io.Writer, path string, now int64) error {
+	fmt.Fprintf(writer, "[offset 0] Checking RDB file %s\n", path)
+	file, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintf(writer, "[offset 0] Cannot open %s: %v\n", path, err)
+		return err
+	}
+	defer file.Close()
+
+	decoder := rdb.NewDecoder(file)
+	snapshot, err := decoder.Decode()
+	if err != nil {
+		offset := decoder.Offset()
+		var corrupt *rdb.CorruptError
+		if errors.As(err, &corrupt) {
+			offset = corrupt.Offset
+		}
+		fmt.Fprintf(writer, "--- RDB ERROR DETECTED ---\n")
+		fmt.Fprintf(writer, "[offset %d] %v\n", offset, err)
+		if key, ok := decoder.Key(); ok {
+			fmt.Fprintf(writer, "[additional info] Reading key '%s'\n", key)
+		}
+		return err
+	}
+
+	end := decoder.Offset()
+	fmt.Fprintf(writer, "[offset %d] RDB version %d\n", end, snapshot.Version)
+	aux := make([]string, 0, len(snapshot.Aux))
+	for field := range snapshot.Aux {
+		aux = append(aux, field)
+	}
+	sort.Strings(aux)
+	for _, field := range aux {
+		fmt.Fprintf(writer, "[offset %d] AUX FIELD %s = '%s'\n", end, field, snapshot.Aux[field])
+	}
+	fmt.Fprintf(writer, "[offset %d] Checksum OK\n", end)
+	fmt.Fprintf(writer, "[offset %d] \\o/ RDB looks OK! \\o/\n", end)
+
+	dbs := make([]int, 0, len(snapshot.DBs))
+	for db := range snapshot.DBs {
+		dbs = append(dbs, db)
+	}
+	sort.Ints(dbs)
+	for _, db := range dbs {
+		fmt.Fprintf(writer, "[info] db %d\n", db)
+		if err := model.Summarize(snapshot.DBs[db], now).Report(writer); err != nil {
+			return err
+		}
+	}
+	for _, skipped := range snapshot.Skipped {
+		fmt.Fprintf(writer, "[info] skipped key '%s' of module %s in db %d\n", skipped.Key, skipped.Module, skipped.DB)
+	}
+	if len(snapshot.Functions) > 0 {
+		fmt.Fprintf(writer, "[info] %d function libraries\n", len(snapshot.Functions))
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckRDB(t *testing.T) {
+	dir := t.TempDir()
+	corrupt := filepath.Join(dir, "corrupt.rdb")
+	if err := os.WriteFile(corrupt, []byte("REDIS0011\x00\x03foo\xc5"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		contains []string
+		isError  bool
+	}{
+		{
+			name: "valid",
+			path: "../../model/rdb/testdata/listpack.rdb",
+			contains: []string{
+				"RDB version 11",
+				"Checksum OK",
+				"[info] db 0",
+				"keys read",
+			},
+		},
+		{
+			name: "corrupt",
+			path: corrupt,
+			contains: []string{
+				"--- RDB ERROR DETECTED ---",
+				"[offset 15]",
+				"Reading key 'foo'",
+			},
+			isError: true,
+		},
+		{
+			name:    "missing",
+			path:    filepath.Join(dir, "missing.rdb"),
+			isError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		err := checkRDB(&buf, tt.path, 0)
+		if (err != nil) != tt.isError {
+			t.Errorf("case %s: expected error %v, actual=%v", tt.name, tt.isError, err)
+		}
+		for _, s := range tt.contains {
+			if !strings.Contains(buf.String(), s) {
+				t.Errorf("case %s: expected %q in report, actual=%s", tt.name, s, buf.String())
+			}
+		}
+	}
+}
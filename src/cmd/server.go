@@ -6,8 +6,11 @@ import (
 	"fmt"
 	"log"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/codecrafters-io/redis-starter-go/src/handler"
+	"github.com/codecrafters-io/redis-starter-go/src/model"
 	"github.com/codecrafters-io/redis-starter-go/src/server"
 )
 
@@ -16,36 +19,161 @@ func main() {
 	var (
 		addressFlag   = flag.String("address", "0.0.0.0", "address to bind to")
 		portFlag      = flag.Int("port", 6379, "port to bind to")
-		replicaofFlag = flag.String("replicaof", "", "replicaof address and port")
-		replicaofPort int
+		replicaofFlag = flag.String("replicaof", "", `master to replicate, as "host port"`)
+		readOnlyFlag  = flag.Bool("replica-read-only", true, "reject writes of clients on a replica")
+		serveStale    = flag.Bool("replica-serve-stale-data", true, "serve data while the link to the master is down")
+		disklessSync  = flag.Bool("repl-diskless-sync", false, "stream snapshots to replicas without producing them first")
+		disklessDelay = flag.Int("repl-diskless-sync-delay", 5, "seconds to wait for more replicas before a diskless transfer")
+		disklessLoad  = flag.String("repl-diskless-load", "disabled", `how replicas load snapshots: "disabled", "on-empty-db" or "swapdb"`)
+		dirFlag       = flag.String("dir", ".", "directory of the RDB file")
+		dbfilename    = flag.String("dbfilename", "dump.rdb", "name of the RDB file")
+		databases     = flag.Int("databases", model.DefaultDatabases, "number of databases")
+		appendOnly    = flag.Bool("appendonly", false, "log every write to the append-only file")
+		appendFile    = flag.String("appendfilename", "appendonly.aof", "base name of the append-only files")
+		appendDir     = flag.String("appenddirname", "appendonlydir", "directory of the append-only files, in dir")
+		appendFsync   = flag.String("appendfsync", "everysec", `when to sync the append-only file: "always", "everysec" or "no"`)
+		loadTruncated = flag.Bool("aof-load-truncated", true, "load an append-only file ending in a partial command")
+		rdbPreamble   = flag.Bool("aof-use-rdb-preamble", true, "write the base of a rewritten append-only file as an RDB snapshot")
+		autoRewrite   = flag.Int("auto-aof-rewrite-percentage", 100, "rewrite the append-only file once it grew by this percentage, 0 disables")
+		autoMinSize   = flag.Int64("auto-aof-rewrite-min-size", 64<<20, "bytes the append-only file needs before it is rewritten automatically")
+		maxmemory     = flag.String("maxmemory", "0", `memory bound of the keys, e.g. "100mb", 0 for no bound`)
+		policy        = flag.String("maxmemory-policy", model.PolicyNoEviction, "keys to evict over maxmemory, e.g. allkeys-lru or volatile-ttl")
+		samples       = flag.Int("maxmemory-samples", model.DefaultMaxmemorySamples, "keys sampled in each database to pick one to evict")
+		lfuLogFactor  = flag.Int("lfu-log-factor", model.DefaultLFULogFactor, "how slowly the access counters of the keys grow")
+		lfuDecayTime  = flag.Int("lfu-decay-time", model.DefaultLFUDecayTime, "minutes for the access counters to decrement, 0 never decays")
 	)
 
 	flag.Parse()
-	if len(*replicaofFlag) > 0 {
-		replicaofPort, _ = strconv.Atoi(flag.Args()[0])
+	replicaofHost, replicaofPort, err := parseReplicaof(*replicaofFlag, flag.Args())
+	if err != nil {
+		log.Fatalf("Invalid --replicaof: %v", err)
+	}
+
+	switch *disklessLoad {
+	case "disabled", "on-empty-db", "swapdb":
+	default:
+		log.Fatalf("Invalid --repl-diskless-load: %s", *disklessLoad)
+	}
+	if *disklessDelay < 0 {
+		log.Fatalf("Invalid --repl-diskless-sync-delay: %d", *disklessDelay)
+	}
+	if *databases < 1 {
+		log.Fatalf("Invalid --databases: %d", *databases)
+	}
+	maxmemoryBytes, err := parseMemory(*maxmemory)
+	if err != nil {
+		log.Fatalf("Invalid --maxmemory: %v", err)
+	} else if !model.IsEvictionPolicy(*policy) {
+		log.Fatalf("Invalid --maxmemory-policy: %s", *policy)
+	} else if *samples < 1 {
+		log.Fatalf("Invalid --maxmemory-samples: %d", *samples)
+	} else if *lfuLogFactor < 0 || *lfuDecayTime < 0 {
+		log.Fatalf("Invalid --lfu-log-factor or --lfu-decay-time: %d, %d", *lfuLogFactor, *lfuDecayTime)
+	}
+	if strings.ContainsAny(*appendFile, `/\`) || *appendFile == "" {
+		log.Fatalf("Invalid --appendfilename: %s", *appendFile)
+	} else if strings.ContainsAny(*appendDir, `/\`) || *appendDir == "" {
+		log.Fatalf("Invalid --appenddirname: %s", *appendDir)
+	} else if *autoRewrite < 0 {
+		log.Fatalf("Invalid --auto-aof-rewrite-percentage: %d", *autoRewrite)
 	}
 
 	ctx := context.Background()
 	log.Printf("Starting server on %s:%d\n", *addressFlag, *portFlag)
 	server := server.NewTCPServer(*addressFlag, *portFlag)
 	redisHandler := handler.NewCommandHandler()
-	redisHandler.Conf.ReplicaofAddress = *replicaofFlag
-	redisHandler.Conf.ReplicaofPort = replicaofPort
-	if len(redisHandler.Conf.ReplicaofAddress) > 0 && redisHandler.Conf.ReplicaofPort > 0 {
-		redisHandler.Conf.Role = "slave"
-	} else {
-		redisHandler.Conf.Role = "master"
-		redisHandler.Conf.MasterReplid = "8371b4fb1155b71f4a04d3e1bc3e18c4a990aeeb"
-		redisHandler.Conf.MasterReplOffset = 0
+	redisHandler.Storage = *model.NewRedisStorage(*databases)
+	redisHandler.Storage.LFULogFactor = *lfuLogFactor
+	redisHandler.Storage.LFUDecayTime = *lfuDecayTime
+	redisHandler.Conf.Port = *portFlag
+	redisHandler.Conf.ReplicaReadOnly = *readOnlyFlag
+	redisHandler.Conf.ReplicaServeStaleData = *serveStale
+	redisHandler.Conf.ReplDisklessSync = *disklessSync
+	redisHandler.Conf.ReplDisklessSyncDelay = time.Duration(*disklessDelay) * time.Second
+	redisHandler.Conf.ReplDisklessLoad = *disklessLoad
+	redisHandler.Conf.Dir = *dirFlag
+	redisHandler.Conf.DBFilename = *dbfilename
+	redisHandler.Conf.AppendOnly = *appendOnly
+	redisHandler.Conf.AppendFilename = *appendFile
+	redisHandler.Conf.AppendDirname = *appendDir
+	redisHandler.Conf.AppendFsync = *appendFsync
+	redisHandler.Conf.AOFLoadTruncated = *loadTruncated
+	redisHandler.Conf.AOFUseRDBPreamble = *rdbPreamble
+	redisHandler.Conf.AutoAOFRewritePercentage = *autoRewrite
+	redisHandler.Conf.AutoAOFRewriteMinSize = *autoMinSize
+	redisHandler.Conf.Maxmemory = maxmemoryBytes
+	redisHandler.Conf.MaxmemoryPolicy = *policy
+	redisHandler.Conf.MaxmemorySamples = *samples
+
+	// the append-only file is more recent than the snapshot when there is
+	// one, a new one starts from the snapshot
+	loaded := false
+	if *appendOnly {
+		if loaded, err = redisHandler.LoadAppendOnly(); err != nil {
+			log.Fatalf("Failed to load append-only file: %v", err)
+		}
+	}
+	if !loaded {
+		if err := redisHandler.LoadSnapshot(); err != nil {
+			log.Fatalf("Failed to load snapshot: %v", err)
+		}
+	}
+	if *appendOnly {
+		if err = redisHandler.OpenAppendOnly(ctx); err != nil {
+			log.Fatalf("Failed to open append-only file: %v", err)
+		}
+	}
+	if len(replicaofHost) > 0 {
+		redisHandler.ReplicaOf(ctx, replicaofHost, replicaofPort)
 	}
 	log.Printf("Redis conf: %v\n", &redisHandler.Conf)
 	server.SetHandler(redisHandler)
 
-	if redisHandler.Conf.Role == "slave" {
-		go redisHandler.Replicate(ctx)
-	}
-
 	if err := server.Loop(ctx); err != nil {
 		panic(fmt.Errorf("failed to start server: %v", err))
 	}
 }
+
+// parseReplicaof reads --replicaof "host port". The port may also be given
+// as the first positional argument, as in --replicaof host port.
+func parseReplicaof(value string, args []string) (string, int, error) {
+	fields := strings.Fields(value)
+	switch {
+	case len(fields) == 0:
+		return "", 0, nil
+	case len(fields) == 1 && len(args) > 0:
+		fields = append(fields, args[0])
+	case len(fields) != 2:
+		return "", 0, fmt.Errorf("expected \"host port\", got %q", value)
+	}
+	port, err := strconv.Atoi(fields[1])
+	if err != nil || port <= 0 || port > 65535 {
+		return "", 0, fmt.Errorf("invalid port %q", fields[1])
+	}
+	return fields[0], port, nil
+}
+
+// parseMemory reads a number of bytes with an optional unit as in redis.conf:
+// k, kb, m, mb, g or gb, where k is 1000 bytes and kb 1024, in any case.
+func parseMemory(value string) (int64, error) {
+	units := []struct {
+		suffix string
+		scale  int64
+	}{
+		{"kb", 1 << 10}, {"mb", 1 << 20}, {"gb", 1 << 30},
+		{"k", 1000}, {"m", 1000 * 1000}, {"g", 1000 * 1000 * 1000},
+		{"b", 1},
+	}
+	number, scale := strings.ToLower(value), int64(1)
+	for _, unit := range units {
+		if strings.HasSuffix(number, unit.suffix) {
+			number, scale = strings.TrimSuffix(number, unit.suffix), unit.scale
+			break
+		}
+	}
+	n, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid memory %q", value)
+	}
+	return n * scale, nil
+}
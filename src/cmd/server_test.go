@@ -0,0 +1,91 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestParseReplicaof(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		args    []string
+		host    string
+		port    int
+		isError bool
+	}{
+		{
+			name: "not a replica",
+		},
+		{
+			name:  "redis format",
+			value: "localhost 6379",
+			host:  "localhost",
+			port:  6379,
+		},
+		{
+			name:  "port as argument",
+			value: "localhost",
+			args:  []string{"6380"},
+			host:  "localhost",
+			port:  6380,
+		},
+		{
+			name:    "missing port",
+			value:   "localhost",
+			isError: true,
+		},
+		{
+			name:    "invalid port",
+			value:   "localhost port",
+			isError: true,
+		},
+		{
+			name:    "too many fields",
+			value:   "localhost 6379 6380",
+			isError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		host, port, err := parseReplicaof(tt.value, tt.args)
+		if tt.isError {
+			if err == nil {
+				t.Errorf("case %s: expected error but got nil", tt.name)
+			}
+		} else if err != nil {
+			t.Errorf("case %s: unexpected error: %v", tt.name, err)
+		} else if host != tt.host || port != tt.port {
+			t.Errorf("case %s: expected %s:%d but got %s:%d", tt.name, tt.host, tt.port, host, port)
+		}
+	}
+}
+
+func TestParseMemory(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		bytes   int64
+		isError bool
+	}{
+		{name: "bytes", value: "100", bytes: 100},
+		{name: "kb", value: "2kb", bytes: 2048},
+		{name: "k", value: "2k", bytes: 2000},
+		{name: "mb in upper case", value: "100MB", bytes: 100 << 20},
+		{name: "gb", value: "1gb", bytes: 1 << 30},
+		{name: "unknown unit", value: "1tb", isError: true},
+		{name: "negative", value: "-1", isError: true},
+	}
+
+	for _, tt := range tests {
+		bytes, err := parseMemory(tt.value)
+		if tt.isError {
+			if err == nil {
+				t.Errorf("case %s: expected error but got nil", tt.name)
+			}
+		} else if err != nil {
+			t.Errorf("case %s: unexpected error: %v", tt.name, err)
+		} else if bytes != tt.bytes {
+			t.Errorf("case %s: expected %d but got %d", tt.name, tt.bytes, bytes)
+		}
+	}
+}
@@ -11,3 +11,11 @@ type AsBytes interface {
 type AsInt64 interface {
 	AsInt64() int64
 }
+
+type AsFloat64 interface {
+	AsFloat64() float64
+}
+
+type AsBool interface {
+	AsBool() bool
+}
@@ -0,0 +1,392 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+	"github.com/codecrafters-io/redis-starter-go/src/model/aof"
+	"github.com/codecrafters-io/redis-starter-go/src/model/cmd"
+	"github.com/codecrafters-io/redis-starter-go/src/model/rdb"
+	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
+)
+
+// LoadAppendOnly replays the AOF of Conf, if there is one, and tells if it
+// did: the base file then the incremental files of its manifest. A single
+// file AOF is first moved into the AOF directory as the base. A partial
+// last command is cut off the last file when AOFLoadTruncated allows it.
+func (h *CommandHandler) LoadAppendOnly() (bool, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	dir, name := h.Conf.AOFDir(), h.Conf.AppendFilename
+	manifest, err := aof.ReadManifest(dir, name)
+	if errors.Is(err, os.ErrNotExist) {
+		if manifest, err = h.upgradeAppendOnly(); manifest == nil || err != nil {
+			return false, err
+		}
+	} else if err != nil {
+		return false, fmt.Errorf("failed to read the manifest of %s: %w", dir, err)
+	}
+	if err := h.resumeUpgrade(manifest); err != nil {
+		return false, err
+	}
+
+	files := manifest.Files()
+	for i, file := range files {
+		if err := h.loadAppendOnlyFile(filepath.Join(dir, file.Name), i == len(files)-1); err != nil {
+			return false, err
+		}
+	}
+	h.aofManifest = manifest
+	return true, nil
+}
+
+// upgradeAppendOnly moves a single file AOF into the AOF directory as the
+// base file, keeping its name, and returns the manifest listing it. The
+// manifest is written first, so resumeUpgrade finishes the move should
+// the server stop in between. It returns nil without such a file.
+func (h *CommandHandler) upgradeAppendOnly() (*aof.Manifest, error) {
+	if _, err := os.Stat(h.Conf.AOFPath()); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	dir, name := h.Conf.AOFDir(), h.Conf.AppendFilename
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	manifest := &aof.Manifest{
+		Base: &aof.ManifestFile{Name: name, Seq: 1, Type: aof.TypeBase},
+	}
+	if err := aof.WriteManifest(dir, name, manifest); err != nil {
+		return nil, fmt.Errorf("failed to write the manifest of %s: %w", dir, err)
+	}
+	log.Printf("Info moving %s into %s", h.Conf.AOFPath(), dir)
+	return manifest, nil
+}
+
+func (h *CommandHandler) resumeUpgrade(manifest *aof.Manifest) error {
+	if manifest.Base == nil || manifest.Base.Name != h.Conf.AppendFilename {
+		return nil
+	}
+	base := filepath.Join(h.Conf.AOFDir(), manifest.Base.Name)
+	if _, err := os.Stat(base); !errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err := os.Rename(h.Conf.AOFPath(), base); err != nil {
+		return fmt.Errorf("failed to move %s into %s: %w", h.Conf.AOFPath(), h.Conf.AOFDir(), err)
+	}
+	return aof.SyncDir(h.Conf.AOFDir())
+}
+
+func (h *CommandHandler) loadAppendOnlyFile(path string, last bool) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	// every file starts in the first database, as its writer did
+	n, db := 0, 0
+	err = aof.Load(file, func(snapshot *rdb.Snapshot) error {
+		return h.Storage.Reset(liveKeys(snapshot), time.Now().UnixMilli())
+	}, func(args *redis.Array) error {
+		command, err := cmd.ParseCommand(args)
+		if err != nil {
+			return err
+		}
+		n++
+		h.Storage.Select(db)
+		defer h.Storage.Select(0)
+		_, err = command.Execute(io.Discard, &h.Storage, &h.Conf)
+		db = h.Storage.DB
+		return err
+	})
+	// only the last file can end in a partial command, the others were
+	// complete when the next one was started
+	var truncated *aof.TruncatedError
+	if errors.As(err, &truncated) && last && h.Conf.AOFLoadTruncated {
+		log.Printf("Error %s is truncated, dropping the partial command after offset %d", path, truncated.Offset)
+		if err := os.Truncate(path, truncated.Offset); err != nil {
+			return fmt.Errorf("failed to truncate %s: %w", path, err)
+		}
+	} else if err != nil {
+		return fmt.Errorf("failed to load %s: %w", path, err)
+	}
+	log.Printf("Info replayed %d commands from %s", n, path)
+	return nil
+}
+
+// OpenAppendOnly starts logging writes to the last incremental file of the
+// AOF of Conf, starting a new AOF from the keyspace when there is none.
+// Under everysec the file is synced once a second until ctx is done.
+func (h *CommandHandler) OpenAppendOnly(ctx context.Context) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	dir, name := h.Conf.AOFDir(), h.Conf.AppendFilename
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	manifest := h.aofManifest
+	if manifest == nil {
+		var err error
+		if manifest, err = aof.ReadManifest(dir, name); errors.Is(err, os.ErrNotExist) {
+			manifest = &aof.Manifest{}
+		} else if err != nil {
+			return fmt.Errorf("failed to read the manifest of %s: %w", dir, err)
+		}
+	}
+	if manifest.Base == nil && len(manifest.Incrs) == 0 && h.Storage.Keys() > 0 {
+		// e.g. loaded from the RDB file, which the AOF must start from
+		manifest.Base = h.newAOFFile(manifest.NextBaseSeq(), aof.TypeBase)
+		if err := h.saveAOFBase(filepath.Join(dir, manifest.Base.Name), h.view()); err != nil {
+			return err
+		}
+	}
+	// a new file is replayed from the first database, an older one may
+	// end in any
+	incr, db := manifest.LastIncr(), -1
+	if incr == nil {
+		incr, db = h.newAOFFile(manifest.NextIncrSeq(), aof.TypeIncr), 0
+		manifest.Incrs = append(manifest.Incrs, incr)
+	}
+	writer, err := aof.Open(filepath.Join(dir, incr.Name), h.Conf.AppendFsync)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", incr.Name, err)
+	}
+	if err := aof.WriteManifest(dir, name, manifest); err != nil {
+		_ = writer.Close()
+		return fmt.Errorf("failed to write the manifest of %s: %w", dir, err)
+	}
+	h.aof, h.aofManifest, h.aofDB = writer, manifest, db
+	h.dropAOFHistory()
+	h.Conf.AOFCurrentSize = h.aofSize()
+	h.Conf.AOFBaseSize = h.Conf.AOFCurrentSize
+	if writer.Fsync() == aof.FsyncEverySec {
+		go h.syncAppendOnly(ctx)
+	}
+	return nil
+}
+
+func (h *CommandHandler) newAOFFile(seq int64, typ string) *aof.ManifestFile {
+	name := aof.IncrName(h.Conf.AppendFilename, seq)
+	if typ == aof.TypeBase {
+		name = aof.BaseName(h.Conf.AppendFilename, seq, h.Conf.AOFUseRDBPreamble)
+	}
+	return &aof.ManifestFile{Name: name, Seq: seq, Type: typ}
+}
+
+func (h *CommandHandler) saveAOFBase(path string, view *model.RedisStorage) error {
+	save := aof.SaveFile
+	if filepath.Ext(path) == ".rdb" {
+		save = rdb.SaveFile
+	}
+	if err := save(path, view); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// aofSize returns the size of the files of the AOF, it must be called
+// under h.mu.
+func (h *CommandHandler) aofSize() int64 {
+	var size int64
+	for _, file := range h.aofManifest.Files() {
+		if info, err := os.Stat(filepath.Join(h.Conf.AOFDir(), file.Name)); err == nil {
+			size += info.Size()
+		}
+	}
+	return size
+}
+
+// dropAOFHistory deletes the files a rewrite left behind, then lists them
+// no more. It must be called under h.mu.
+func (h *CommandHandler) dropAOFHistory() {
+	manifest := h.aofManifest
+	if len(manifest.History) == 0 {
+		return
+	}
+	for _, file := range manifest.History {
+		if err := os.Remove(filepath.Join(h.Conf.AOFDir(), file.Name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("Error failed to delete %s: %v", file.Name, err)
+			return
+		}
+	}
+	next := &aof.Manifest{Base: manifest.Base, Incrs: manifest.Incrs}
+	if err := aof.WriteManifest(h.Conf.AOFDir(), h.Conf.AppendFilename, next); err != nil {
+		log.Printf("Error failed to write the aof manifest: %v", err)
+		return
+	}
+	h.aofManifest = next
+}
+
+// rewriteAppendOnly compacts the AOF, it must be called under h.mu with
+// AOFRewriteInProgress set. Writes go to a new incremental file right
+// away, then the keyspace as of now is written in the background as the
+// new base, which replaces the older files. The manifest is only ever
+// replaced whole, so after a crash it lists either the older files and
+// the new incremental one, or the new base and what followed it. A rewrite
+// started while another is in progress supersedes it.
+func (h *CommandHandler) rewriteAppendOnly() {
+	if h.aof == nil {
+		h.Conf.AOFRewriteInProgress = false
+		return
+	}
+	dir, name := h.Conf.AOFDir(), h.Conf.AppendFilename
+	manifest := h.aofManifest
+	incr := h.newAOFFile(manifest.NextIncrSeq(), aof.TypeIncr)
+	writer, err := aof.Open(filepath.Join(dir, incr.Name), h.Conf.AppendFsync)
+	if err != nil {
+		h.rewriteDone(fmt.Errorf("failed to open %s: %w", incr.Name, err))
+		return
+	}
+	next := &aof.Manifest{
+		Base:    manifest.Base,
+		Incrs:   append(manifest.Incrs[:len(manifest.Incrs):len(manifest.Incrs)], incr),
+		History: manifest.History,
+	}
+	if err := aof.WriteManifest(dir, name, next); err != nil {
+		_ = writer.Close()
+		_ = os.Remove(filepath.Join(dir, incr.Name))
+		h.rewriteDone(fmt.Errorf("failed to write the manifest of %s: %w", dir, err))
+		return
+	}
+	// the commands before the switch are on disk before the base replaces
+	// their file
+	if err := h.aof.Sync(); err != nil {
+		log.Printf("Error failed to sync the aof: %v", err)
+	}
+	_ = h.aof.Close()
+	h.aof, h.aofManifest, h.aofDB = writer, next, 0
+
+	view := h.view()
+	base := h.newAOFFile(next.NextBaseSeq(), aof.TypeBase)
+	if pending := h.aofRewriteBase; pending != nil && pending.Seq >= base.Seq {
+		base = h.newAOFFile(pending.Seq+1, aof.TypeBase)
+	}
+	h.aofRewriteBase = base
+	go func() {
+		err := h.saveAOFBase(filepath.Join(dir, base.Name), view)
+
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if h.aofRewriteBase != base {
+			// superseded, the later rewrite reports the outcome
+			_ = os.Remove(filepath.Join(dir, base.Name))
+			return
+		}
+		if err == nil {
+			err = h.switchAOFBase(base, incr.Seq)
+		}
+		h.rewriteDone(err)
+	}()
+}
+
+// switchAOFBase makes base the base of the AOF, followed by the
+// incremental files from firstIncr on. It must be called under h.mu.
+func (h *CommandHandler) switchAOFBase(base *aof.ManifestFile, firstIncr int64) error {
+	manifest := h.aofManifest
+	next := &aof.Manifest{Base: base, History: manifest.History}
+	for _, file := range manifest.Files() {
+		if file.Type == aof.TypeIncr && file.Seq >= firstIncr {
+			next.Incrs = append(next.Incrs, file)
+		} else {
+			next.History = append(next.History, &aof.ManifestFile{Name: file.Name, Seq: file.Seq, Type: aof.TypeHistory})
+		}
+	}
+	if err := aof.WriteManifest(h.Conf.AOFDir(), h.Conf.AppendFilename, next); err != nil {
+		_ = os.Remove(filepath.Join(h.Conf.AOFDir(), base.Name))
+		return fmt.Errorf("failed to write the manifest of %s: %w", h.Conf.AOFDir(), err)
+	}
+	h.aofManifest = next
+	h.dropAOFHistory()
+	h.Conf.AOFCurrentSize = h.aofSize()
+	h.Conf.AOFBaseSize = h.Conf.AOFCurrentSize
+	return nil
+}
+
+// maxAOFRetryInterval bounds the wait after failed rewrites.
+const maxAOFRetryInterval = time.Hour
+
+// rewriteDone ends a rewrite. After a failure automatic rewrites back off,
+// as a cause like a full disk would fail them all the same.
+func (h *CommandHandler) rewriteDone(err error) {
+	if err != nil {
+		delay := maxAOFRetryInterval
+		if h.aofRewriteFailures < 16 && h.aofRetryInterval<<h.aofRewriteFailures < delay {
+			delay = h.aofRetryInterval << h.aofRewriteFailures
+		}
+		h.aofRewriteFailures++
+		h.aofRetryAt = time.Now().Add(delay)
+		log.Printf("Error background aof rewrite failed: %v, next automatic one in %v", err, delay)
+	} else {
+		h.aofRewriteFailures, h.aofRetryAt = 0, time.Time{}
+		log.Printf("Info background aof rewrite done")
+	}
+	h.aofRewriteBase = nil
+	h.Conf.AOFRewriteInProgress = false
+	h.Conf.AOFLastBgrewriteOK = err == nil
+}
+
+// needsRewrite tells if the AOF grew enough since the last rewrite to be
+// rewritten, and no failed rewrite holds it back. It must be called under
+// h.mu.
+func (h *CommandHandler) needsRewrite() bool {
+	if h.Conf.AutoAOFRewritePercentage <= 0 || h.Conf.AOFRewriteInProgress ||
+		h.Conf.AOFCurrentSize < h.Conf.AutoAOFRewriteMinSize || time.Now().Before(h.aofRetryAt) {
+		return false
+	}
+	base := h.Conf.AOFBaseSize
+	if base == 0 {
+		base = 1
+	}
+	growth := (h.Conf.AOFCurrentSize - base) * 100 / base
+	return growth >= int64(h.Conf.AutoAOFRewritePercentage)
+}
+
+func (h *CommandHandler) syncAppendOnly(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		h.mu.Lock()
+		writer := h.aof
+		h.mu.Unlock()
+		// outside the lock, commands keep running while the disk syncs; a
+		// rewrite may have closed the file meanwhile, after syncing it
+		if err := writer.Sync(); err != nil && !errors.Is(err, os.ErrClosed) {
+			log.Printf("Error failed to sync the aof: %v", err)
+		}
+	}
+}
+
+// appendOnly logs an encoded write command, it must be called under h.mu.
+// A rewrite starts once the AOF grew enough.
+func (h *CommandHandler) appendOnly(p []byte) {
+	if h.aof == nil {
+		return
+	}
+	err := h.aof.Append(p)
+	if err != nil {
+		log.Printf("Error failed to write the aof: %v", err)
+	} else {
+		h.Conf.AOFCurrentSize += int64(len(p))
+	}
+	h.Conf.AOFLastWriteOK = err == nil
+	if h.needsRewrite() {
+		log.Printf("Info starting aof rewrite, %d bytes since %d", h.Conf.AOFCurrentSize, h.Conf.AOFBaseSize)
+		h.Conf.AOFRewriteInProgress = true
+		h.rewriteAppendOnly()
+	}
+}
@@ -0,0 +1,468 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+	"github.com/codecrafters-io/redis-starter-go/src/model/aof"
+	"github.com/codecrafters-io/redis-starter-go/src/model/rdb"
+	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
+)
+
+func TestCommandHandler_AppendOnly(t *testing.T) {
+	dir := t.TempDir()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	h := NewCommandHandler()
+	h.Conf.Dir = dir
+	h.Conf.AppendOnly = true
+	h.Conf.AppendFsync = aof.FsyncAlways
+	if err := h.OpenAppendOnly(ctx); err != nil {
+		t.Fatalf("failed to open: %v", err)
+	}
+	conn := dialTestClient(t, startTestServer(t, h))
+	if err := conn.Set(ctx, "foo", []byte("bar"), 0); err != nil {
+		t.Fatalf("failed to set: %v", err)
+	} else if err := conn.Set(ctx, "ttl", []byte("1"), time.Hour); err != nil {
+		t.Fatalf("failed to set: %v", err)
+	} else if _, err := conn.Do(ctx, "GET", "foo"); err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	h.mu.Lock()
+	ttl := h.Storage.Mem["ttl"].ExpireAt
+	h.mu.Unlock()
+
+	content, err := os.ReadFile(filepath.Join(h.Conf.AOFDir(), "appendonly.aof.1.incr.aof"))
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+	expected := "*3\r\n$3\r\nSET\r\n$3\r\nfoo\r\n$3\r\nbar\r\n" +
+		fmt.Sprintf("*5\r\n$3\r\nSET\r\n$3\r\nttl\r\n$1\r\n1\r\n$4\r\nPXAT\r\n$13\r\n%d\r\n", ttl)
+	if string(content) != expected {
+		t.Fatalf("expected the writes with an absolute expire time, actual=%q", content)
+	}
+
+	tests := []struct {
+		name      string
+		tail      string
+		truncated bool
+		isError   bool
+	}{
+		{
+			name: "complete",
+		},
+		{
+			name:      "partial last command",
+			tail:      "*3\r\n$3\r\nSET\r\n$4\r\nlost",
+			truncated: true,
+		},
+		{
+			name:    "partial last command without aof-load-truncated",
+			tail:    "*3\r\n$3\r\nSET\r\n$4\r\nlost",
+			isError: true,
+		},
+		{
+			name:    "garbage",
+			tail:    "garbage\r\n",
+			isError: true,
+		},
+	}
+	for _, tt := range tests {
+		replay := NewCommandHandler()
+		replay.Conf.Dir = t.TempDir()
+		replay.Conf.AOFLoadTruncated = tt.truncated
+		if err := os.WriteFile(replay.Conf.AOFPath(), []byte(expected+tt.tail), 0o644); err != nil {
+			t.Fatalf("case %s: failed to write: %v", tt.name, err)
+		}
+		loaded, err := replay.LoadAppendOnly()
+		if tt.isError {
+			if err == nil {
+				t.Errorf("case %s: expected error, actual=nil", tt.name)
+			}
+			continue
+		} else if err != nil || !loaded {
+			t.Errorf("case %s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if bucket := replay.Storage.Mem["foo"]; bucket == nil || string(bucket.Value) != "bar" {
+			t.Errorf("case %s: expected foo=bar, actual=%v", tt.name, bucket)
+		} else if bucket := replay.Storage.Mem["ttl"]; bucket == nil || bucket.ExpireAt != ttl {
+			t.Errorf("case %s: expected ttl to expire at %d, actual=%v", tt.name, ttl, bucket)
+		} else if _, ok := replay.Storage.Mem["lost"]; ok {
+			t.Errorf("case %s: expected the partial command to be dropped", tt.name)
+		}
+		// the single file is now the base of the AOF
+		if content, _ := os.ReadFile(filepath.Join(replay.Conf.AOFDir(), "appendonly.aof")); string(content) != expected {
+			t.Errorf("case %s: expected the file to end with the last complete command, actual=%q", tt.name, content)
+		}
+	}
+
+	missing := NewCommandHandler()
+	missing.Conf.Dir = t.TempDir()
+	if loaded, err := missing.LoadAppendOnly(); loaded || err != nil {
+		t.Errorf("expected nothing to load without a file, actual=%v, %v", loaded, err)
+	}
+}
+
+// TestCommandHandler_AppendOnly_Databases checks writes are logged after a
+// SELECT of their database whenever it changes, so the replay puts each key
+// back in its database.
+func TestCommandHandler_AppendOnly_Databases(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	h := NewCommandHandler()
+	h.Conf.Dir = t.TempDir()
+	h.Conf.AppendOnly = true
+	h.Conf.AppendFsync = aof.FsyncAlways
+	if err := h.OpenAppendOnly(ctx); err != nil {
+		t.Fatalf("failed to open: %v", err)
+	}
+	addr := startTestServer(t, h)
+	conn, other := dialTestClient(t, addr), dialTestClient(t, addr)
+	if _, err := conn.Do(ctx, "SELECT", "2"); err != nil {
+		t.Fatalf("failed to select: %v", err)
+	} else if err := conn.Set(ctx, "a", []byte("1"), 0); err != nil {
+		t.Fatalf("failed to set: %v", err)
+	} else if err := other.Set(ctx, "b", []byte("1"), 0); err != nil {
+		t.Fatalf("failed to set: %v", err)
+	} else if err := conn.Set(ctx, "c", []byte("1"), 0); err != nil {
+		t.Fatalf("failed to set: %v", err)
+	} else if err := conn.Set(ctx, "d", []byte("1"), 0); err != nil {
+		t.Fatalf("failed to set: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(h.Conf.AOFDir(), "appendonly.aof.1.incr.aof"))
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+	selectDB := func(db string) string {
+		return "*2\r\n$6\r\nSELECT\r\n$1\r\n" + db + "\r\n"
+	}
+	setKey := func(key string) string {
+		return "*3\r\n$3\r\nSET\r\n$1\r\n" + key + "\r\n$1\r\n1\r\n"
+	}
+	expected := selectDB("2") + setKey("a") + selectDB("0") + setKey("b") + selectDB("2") + setKey("c") + setKey("d")
+	if string(content) != expected {
+		t.Fatalf("expected %q, actual=%q", expected, content)
+	}
+
+	replay := NewCommandHandler()
+	replay.Conf = h.Conf
+	if _, err := replay.LoadAppendOnly(); err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	dbs := replay.Storage.Databases()
+	if len(dbs[0]) != 1 || dbs[0]["b"] == nil || len(dbs[2]) != 3 || replay.Storage.DB != 0 {
+		t.Errorf("expected b in db 0 and a, c, d in db 2, actual=%v", dbs)
+	}
+}
+
+func TestCommandHandler_Bgrewriteaof(t *testing.T) {
+	tests := []struct {
+		name        string
+		rdbPreamble bool
+		base        string
+	}{
+		{
+			name:        "rdb preamble",
+			rdbPreamble: true,
+			base:        "appendonly.aof.1.base.rdb",
+		},
+		{
+			name: "commands",
+			base: "appendonly.aof.1.base.aof",
+		},
+	}
+
+	for _, tt := range tests {
+		dir := t.TempDir()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		h := NewCommandHandler()
+		h.Conf.Dir = dir
+		h.Conf.AppendOnly = true
+		h.Conf.AppendFsync = aof.FsyncAlways
+		h.Conf.AOFUseRDBPreamble = tt.rdbPreamble
+		if err := h.OpenAppendOnly(ctx); err != nil {
+			t.Fatalf("case %s: failed to open: %v", tt.name, err)
+		}
+		conn := dialTestClient(t, startTestServer(t, h))
+		for _, value := range []string{"1", "2", "3"} {
+			if err := conn.Set(ctx, "foo", []byte(value), 0); err != nil {
+				t.Fatalf("case %s: failed to set: %v", tt.name, err)
+			}
+		}
+		// the write right after BGREWRITEAOF goes to the new incr file
+		replies, err := conn.Pipeline().Do("BGREWRITEAOF").Do("SET", "late", "1").Exec(ctx)
+		if err != nil {
+			t.Fatalf("case %s: failed to exec: %v", tt.name, err)
+		}
+		var status string
+		if _ = redis.Unmarshal(replies[0], &status); status != "Background append only file rewriting started" {
+			t.Errorf("case %s: unexpected reply %v", tt.name, replies[0])
+		}
+		waitFor(t, h, "aof rewrite", func() bool {
+			return !h.Conf.AOFRewriteInProgress
+		})
+		h.mu.Lock()
+		ok, currentSize, baseSize := h.Conf.AOFLastBgrewriteOK, h.Conf.AOFCurrentSize, h.Conf.AOFBaseSize
+		h.mu.Unlock()
+		if !ok {
+			t.Fatalf("case %s: expected the rewrite to succeed", tt.name)
+		}
+
+		aofDir := filepath.Join(dir, "appendonlydir")
+		manifest, err := os.ReadFile(filepath.Join(aofDir, "appendonly.aof.manifest"))
+		if err != nil {
+			t.Fatalf("case %s: failed to read the manifest: %v", tt.name, err)
+		}
+		expected := fmt.Sprintf("file %s seq 1 type b\nfile appendonly.aof.2.incr.aof seq 2 type i\n", tt.base)
+		if string(manifest) != expected {
+			t.Errorf("case %s: expected manifest %q, actual=%q", tt.name, expected, manifest)
+		}
+		entries, _ := os.ReadDir(aofDir)
+		if len(entries) != 3 {
+			t.Errorf("case %s: expected the older incr file to be deleted, actual=%v", tt.name, entries)
+		}
+		incr, _ := os.ReadFile(filepath.Join(aofDir, "appendonly.aof.2.incr.aof"))
+		if string(incr) != "*3\r\n$3\r\nSET\r\n$4\r\nlate\r\n$1\r\n1\r\n" {
+			t.Errorf("case %s: unexpected incr file %q", tt.name, incr)
+		}
+		if currentSize == 0 || currentSize != baseSize {
+			t.Errorf("case %s: expected the sizes to restart from the rewrite, actual=%d, %d", tt.name, currentSize, baseSize)
+		}
+
+		replay := NewCommandHandler()
+		replay.Conf.Dir = dir
+		if loaded, err := replay.LoadAppendOnly(); err != nil || !loaded {
+			t.Fatalf("case %s: failed to load: %v", tt.name, err)
+		}
+		if bucket := replay.Storage.Mem["foo"]; bucket == nil || string(bucket.Value) != "3" {
+			t.Errorf("case %s: expected foo=3, actual=%v", tt.name, bucket)
+		} else if bucket := replay.Storage.Mem["late"]; bucket == nil || string(bucket.Value) != "1" {
+			t.Errorf("case %s: expected late=1, actual=%v", tt.name, bucket)
+		}
+		cancel()
+	}
+}
+
+func TestCommandHandler_AutoRewriteAppendOnly(t *testing.T) {
+	dir := t.TempDir()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	h := NewCommandHandler()
+	h.Conf.Dir = dir
+	h.Conf.AppendOnly = true
+	h.Conf.AutoAOFRewriteMinSize = 200
+	if err := h.OpenAppendOnly(ctx); err != nil {
+		t.Fatalf("failed to open: %v", err)
+	}
+	conn := dialTestClient(t, startTestServer(t, h))
+
+	value := make([]byte, 64)
+	for i := 0; i < 2; i++ {
+		if err := conn.Set(ctx, "foo", value, 0); err != nil {
+			t.Fatalf("failed to set: %v", err)
+		}
+	}
+	h.mu.Lock()
+	started := h.aofManifest.NextIncrSeq() > 2
+	h.mu.Unlock()
+	if started {
+		t.Fatalf("expected no rewrite under the minimum size")
+	}
+	if err := conn.Set(ctx, "foo", value, 0); err != nil {
+		t.Fatalf("failed to set: %v", err)
+	}
+	waitFor(t, h, "aof rewrite", func() bool {
+		return !h.Conf.AOFRewriteInProgress && h.aofManifest.Base != nil
+	})
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.needsRewrite() {
+		t.Errorf("expected no rewrite right after one, sizes %d, %d", h.Conf.AOFCurrentSize, h.Conf.AOFBaseSize)
+	}
+}
+
+func TestCommandHandler_AutoRewriteAppendOnly_Backoff(t *testing.T) {
+	dir := t.TempDir()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	h := NewCommandHandler()
+	h.Conf.Dir = dir
+	h.Conf.AppendOnly = true
+	h.Conf.AutoAOFRewriteMinSize = 1
+	h.aofRetryInterval = 100 * time.Millisecond
+	if err := h.OpenAppendOnly(ctx); err != nil {
+		t.Fatalf("failed to open: %v", err)
+	}
+	// the next incr file cannot be created, so rewrites fail
+	blocked := filepath.Join(h.Conf.AOFDir(), "appendonly.aof.2.incr.aof")
+	if err := os.Mkdir(blocked, 0o755); err != nil {
+		t.Fatalf("failed to create %s: %v", blocked, err)
+	}
+	conn := dialTestClient(t, startTestServer(t, h))
+	failures := func() int {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		return h.aofRewriteFailures
+	}
+
+	for i := 0; i < 5; i++ {
+		if err := conn.Set(ctx, "foo", []byte("bar"), 0); err != nil {
+			t.Fatalf("failed to set: %v", err)
+		}
+	}
+	if n := failures(); n != 1 {
+		t.Fatalf("expected the writes after a failure to wait, actual=%d failures", n)
+	}
+	time.Sleep(150 * time.Millisecond)
+	for i := 0; i < 5; i++ {
+		if err := conn.Set(ctx, "foo", []byte("bar"), 0); err != nil {
+			t.Fatalf("failed to set: %v", err)
+		}
+	}
+	h.mu.Lock()
+	n, wait := h.aofRewriteFailures, time.Until(h.aofRetryAt)
+	h.mu.Unlock()
+	if n != 2 || wait <= 100*time.Millisecond {
+		t.Fatalf("expected one more failure and a longer wait, actual=%d failures, %v", n, wait)
+	}
+
+	if err := os.Remove(blocked); err != nil {
+		t.Fatalf("failed to remove %s: %v", blocked, err)
+	}
+	time.Sleep(wait)
+	if err := conn.Set(ctx, "foo", []byte("bar"), 0); err != nil {
+		t.Fatalf("failed to set: %v", err)
+	}
+	waitFor(t, h, "aof rewrite", func() bool {
+		return !h.Conf.AOFRewriteInProgress && h.aofManifest.Base != nil
+	})
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.aofRewriteFailures != 0 || !h.Conf.AOFLastBgrewriteOK {
+		t.Errorf("expected the rewrite to succeed, actual=%d failures", h.aofRewriteFailures)
+	}
+}
+
+func TestCommandHandler_LoadAppendOnly(t *testing.T) {
+	const (
+		setFoo2 = "*3\r\n$3\r\nSET\r\n$3\r\nfoo\r\n$1\r\n2\r\n"
+		setBar3 = "*3\r\n$3\r\nSET\r\n$3\r\nbar\r\n$1\r\n3\r\n"
+		partial = "*3\r\n$3\r\nSET\r\n$4\r\nlost"
+	)
+	manifest := "file appendonly.aof.1.base.rdb seq 1 type b\n" +
+		"file appendonly.aof.1.incr.aof seq 1 type i\n" +
+		"file appendonly.aof.2.incr.aof seq 2 type i\n" +
+		"file appendonly.aof.1.incr.aof.old seq 0 type h\n"
+
+	tests := []struct {
+		name    string
+		files   map[string]string
+		legacy  string
+		foo     string
+		bar     string
+		isError bool
+	}{
+		{
+			name: "base and incrs, as left by a rewrite which has not finished",
+			files: map[string]string{
+				"appendonly.aof.1.incr.aof": setFoo2,
+				"appendonly.aof.2.incr.aof": setBar3,
+			},
+			foo: "2",
+			bar: "3",
+		},
+		{
+			name: "partial command in the last incr",
+			files: map[string]string{
+				"appendonly.aof.1.incr.aof": setFoo2,
+				"appendonly.aof.2.incr.aof": setBar3 + partial,
+			},
+			foo: "2",
+			bar: "3",
+		},
+		{
+			name: "partial command in an earlier incr",
+			files: map[string]string{
+				"appendonly.aof.1.incr.aof": setFoo2 + partial,
+				"appendonly.aof.2.incr.aof": setBar3,
+			},
+			isError: true,
+		},
+		{
+			name: "missing incr",
+			files: map[string]string{
+				"appendonly.aof.1.incr.aof": setFoo2,
+			},
+			isError: true,
+		},
+		{
+			name: "upgrade interrupted after the manifest",
+			files: map[string]string{
+				"appendonly.aof.manifest": "file appendonly.aof seq 1 type b\n",
+			},
+			legacy: setFoo2,
+			foo:    "2",
+		},
+	}
+
+	for _, tt := range tests {
+		h := NewCommandHandler()
+		h.Conf.Dir = t.TempDir()
+		aofDir := h.Conf.AOFDir()
+		if err := os.MkdirAll(aofDir, 0o755); err != nil {
+			t.Fatalf("case %s: failed to create: %v", tt.name, err)
+		}
+		files := map[string]string{"appendonly.aof.manifest": manifest}
+		for name, content := range tt.files {
+			files[name] = content
+		}
+		if files["appendonly.aof.manifest"] == manifest {
+			base := &model.RedisStorage{Mem: map[string]*model.RedisBucket{
+				"foo": {Value: []byte("1"), ExpireAt: math.MaxInt64},
+			}}
+			if err := rdb.SaveFile(filepath.Join(aofDir, "appendonly.aof.1.base.rdb"), base); err != nil {
+				t.Fatalf("case %s: failed to save: %v", tt.name, err)
+			}
+		}
+		for name, content := range files {
+			if err := os.WriteFile(filepath.Join(aofDir, name), []byte(content), 0o644); err != nil {
+				t.Fatalf("case %s: failed to write: %v", tt.name, err)
+			}
+		}
+		if tt.legacy != "" {
+			if err := os.WriteFile(h.Conf.AOFPath(), []byte(tt.legacy), 0o644); err != nil {
+				t.Fatalf("case %s: failed to write: %v", tt.name, err)
+			}
+		}
+
+		loaded, err := h.LoadAppendOnly()
+		if tt.isError {
+			if err == nil {
+				t.Errorf("case %s: expected error, actual=nil", tt.name)
+			}
+			continue
+		} else if err != nil || !loaded {
+			t.Errorf("case %s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		for key, value := range map[string]string{"foo": tt.foo, "bar": tt.bar} {
+			bucket := h.Storage.Mem[key]
+			if value == "" && bucket != nil {
+				t.Errorf("case %s: expected no %s, actual=%v", tt.name, key, bucket)
+			} else if value != "" && (bucket == nil || string(bucket.Value) != value) {
+				t.Errorf("case %s: expected %s=%s, actual=%v", tt.name, key, value, bucket)
+			}
+		}
+		if _, ok := h.Storage.Mem["lost"]; ok {
+			t.Errorf("case %s: expected the partial command to be dropped", tt.name)
+		}
+	}
+}
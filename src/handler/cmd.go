@@ -2,13 +2,19 @@ package handler
 
 import (
 	"bufio"
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/codecrafters-io/redis-starter-go/src/model"
+	"github.com/codecrafters-io/redis-starter-go/src/model/aof"
 	"github.com/codecrafters-io/redis-starter-go/src/model/cmd"
 	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
 )
@@ -19,92 +25,352 @@ var (
 	ErrServerStop = fmt.Errorf("server stop")
 )
 
+const (
+	// MaxPipelineBatch bounds how many pipelined commands are executed
+	// before their replies are flushed.
+	MaxPipelineBatch = 1024
+)
+
 type CommandHandler struct {
 	Conf    model.CommandConf
 	Storage model.RedisStorage
+
+	// mu serializes command execution across connections
+	mu sync.Mutex
+
+	// replicas attached after PSYNC, guarded by mu
+	replicas     map[*replicaLink]struct{}
+	propagateBuf []byte
+
+	// acked is closed when a replica acknowledges an offset, guarded by mu
+	acked    chan struct{}
+	cronOnce sync.Once
+	// replCancel stops the replication of a replica, guarded by mu
+	replCancel context.CancelFunc
+	// disklessTimer starts the next diskless transfer, guarded by mu
+	disklessTimer *time.Timer
+	// aof logs the writes when append only is on, to the last incremental
+	// file of aofManifest, guarded by mu
+	aof         *aof.Writer
+	aofManifest *aof.Manifest
+	// aofDB is the database the AOF last selected, -1 when unknown,
+	// guarded by mu
+	aofDB int
+	// aofRewriteBase is the base the rewrite in progress writes, a later
+	// rewrite supersedes it, guarded by mu
+	aofRewriteBase *aof.ManifestFile
+	// aofRewriteFailures counts the rewrites failed in a row, automatic
+	// rewrites wait until aofRetryAt after one, guarded by mu
+	aofRewriteFailures int
+	aofRetryAt         time.Time
+	// evictionPool keeps the candidates for eviction between writes,
+	// guarded by mu
+	evictionPool model.EvictionPool
+
+	replRetryInterval time.Duration
+	replAckPeriod     time.Duration
+	// aofRetryInterval is the wait after a failed rewrite, doubled by each
+	// further failure up to maxAOFRetryInterval
+	aofRetryInterval time.Duration
 }
 
 func NewCommandHandler() *CommandHandler {
-	return &CommandHandler{
-		Storage: model.RedisStorage{
-			Mem: make(map[string]*model.RedisBucket),
-		},
+	h := &CommandHandler{
+		Storage:           *model.NewRedisStorage(model.DefaultDatabases),
+		replRetryInterval: 5 * time.Second,
+		replAckPeriod:     time.Second,
+		aofRetryInterval:  time.Minute,
 	}
+	h.Conf.Role = "master"
+	h.Conf.ReplDisklessSyncDelay = 5 * time.Second
+	h.Conf.ReplDisklessLoad = "disabled"
+	h.Conf.ReplicaReadOnly = true
+	h.Conf.Dir = "."
+	h.Conf.DBFilename = "dump.rdb"
+	h.Conf.LastSave = time.Now()
+	h.Conf.LastBgsaveOK = true
+	h.Conf.AppendFilename = "appendonly.aof"
+	h.Conf.AppendDirname = "appendonlydir"
+	h.Conf.AppendFsync = aof.FsyncEverySec
+	h.Conf.AOFLoadTruncated = true
+	h.Conf.AOFLastWriteOK = true
+	h.Conf.AOFUseRDBPreamble = true
+	h.Conf.AutoAOFRewritePercentage = 100
+	h.Conf.AutoAOFRewriteMinSize = 64 << 20
+	h.Conf.AOFLastBgrewriteOK = true
+	h.Conf.ReplicaServeStaleData = true
+	h.Conf.MaxmemoryPolicy = model.PolicyNoEviction
+	h.Conf.MaxmemorySamples = model.DefaultMaxmemorySamples
+	h.Conf.MasterReplid = model.NewReplid()
+	h.Conf.ClearReplid2()
+	return h
 }
 
 func (h *CommandHandler) HandleConnection(ctx context.Context, conn net.Conn) (err error) {
 	var (
-		readerWriter = bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+		reader   = bufio.NewReader(conn)
+		encoder  = redis.NewEncoder(conn)
+		commands = make([]cmd.Command, 0, 16)
+		link     *replicaLink
+		linked   bool
+		// port announced by REPLCONF listening-port
+		replicaPort int
+		// the replica reads snapshots framed by an EOF mark
+		capaEOF bool
+		// replication offset right after the last write of this client
+		writeOffset int64
+		// database selected by this client
+		db int
 	)
 	defer conn.Close()
+	defer func() {
+		if link != nil {
+			h.mu.Lock()
+			h.removeReplica(link)
+			h.mu.Unlock()
+		}
+	}()
 
 	for {
 		select {
 		case <-ctx.Done():
 			log.Printf("Info server colse")
-			err = ErrServerStop
+			return ErrServerStop
 		default:
 		}
 
-		command, cmdErr := cmd.ReadCommand(readerWriter, &h.Storage, &h.Conf)
-		if cmdErr != nil {
-			errRsp := redis.NewSimpleError(fmt.Sprintf("ERR %s", cmdErr.Error()))
-			_ = errRsp.Write(conn)
-			err = fmt.Errorf("failed to parse command: %w", cmdErr)
-			break
+		var readErr error
+		commands, readErr = h.readPipeline(reader, commands[:0])
+
+		// execute in order, replies are buffered and flushed as one write
+		for _, command := range commands {
+			log.Printf("Info received command: %s", command.String())
+			if wait, ok := command.(*cmd.Wait); ok && h.role() == "master" {
+				// replies so far are not held back while blocking
+				if flushErr := encoder.Flush(); flushErr != nil {
+					return fmt.Errorf("failed to flush response: %w", flushErr)
+				}
+				wait.SetAcked(h.waitReplicas(ctx, writeOffset, wait.NumReplicas(), wait.Timeout()))
+			}
+
+			h.mu.Lock()
+			if refused := h.refuse(command); refused != nil {
+				_ = encoder.Encode(refused)
+				h.mu.Unlock()
+				continue
+			} else if oom := h.performEvictions(command); oom != nil {
+				_ = encoder.Encode(oom)
+				h.mu.Unlock()
+				continue
+			}
+			if psync, ok := command.(*cmd.Psync); ok {
+				psync.SetDiskless(h.Conf.ReplDisklessSync && capaEOF)
+			}
+			h.Storage.Select(db)
+			rsp, cmdErr := command.Execute(encoder, &h.Storage, &h.Conf)
+			db = h.Storage.DB
+			if cmdErr == nil {
+				switch command := command.(type) {
+				case cmd.WriteCommand:
+					h.propagate(command)
+					writeOffset = h.Conf.MasterReplOffset
+				case *cmd.ReplConf:
+					if port, ok := command.Option("listening-port"); ok {
+						replicaPort, _ = strconv.Atoi(port)
+					}
+					capaEOF = capaEOF || command.Capa("eof")
+					if offset, ok := command.Ack(); ok && link != nil {
+						h.ackReplica(link, offset)
+					}
+				case *cmd.Bgsave:
+					if command.Started() {
+						h.bgsave()
+					}
+				case *cmd.Bgrewriteaof:
+					if command.Started() {
+						h.rewriteAppendOnly()
+					}
+				case *cmd.ReplicaOf:
+					if command.Unchanged(&h.Conf) {
+						break
+					} else if command.NoOne() {
+						h.promote()
+					} else {
+						h.replicaOf(ctx, command.Host(), command.Port())
+					}
+				case *cmd.Psync:
+					// registered along with the snapshot, so the stream
+					// continues exactly where the snapshot stops
+					if link == nil {
+						link = h.addReplica(ctx, conn, replicaPort, command.IsFullResync(rsp) || command.Deferred())
+					}
+					if command.Deferred() {
+						h.waitDisklessSync(link)
+					} else if command.IsFullResync(rsp) {
+						h.diskSync(link)
+					}
+				}
+			}
+			h.Storage.Select(0)
+			h.mu.Unlock()
+			if cmdErr != nil {
+				errRsp := redis.NewSimpleError(fmt.Sprintf("ERR %s", cmdErr.Error()))
+				_ = encoder.Encode(errRsp)
+				_ = encoder.Flush()
+				return fmt.Errorf("failed to execute command %v: %w", command, cmdErr)
+			} else if rsp != nil {
+				// REPLCONF ACK and a deferred PSYNC reply nothing
+				reply, jsonErr := redis.EncodeJSON(rsp, redis.JSONPlain)
+				if jsonErr != nil {
+					reply = []byte(rsp.String())
+				}
+				log.Printf("Info response: %s", reply)
+			}
 		}
 
-		log.Printf("Info received command: %s", command.String())
-		if rsp, cmdErr := command.Execute(conn, &h.Storage, &h.Conf); cmdErr != nil {
-			errRsp := redis.NewSimpleError(fmt.Sprintf("ERR %s", cmdErr.Error()))
-			_ = errRsp.Write(conn)
-			_ = readerWriter.Flush()
-			err = fmt.Errorf("failed to execute command %v: %w", command, cmdErr)
-			break
-		} else if flushErr := readerWriter.Flush(); flushErr != nil {
-			err = fmt.Errorf("failed to flush response: %w", flushErr)
-			break
-		} else {
-			log.Printf("Info response: %s", rsp.String())
+		if readErr != nil {
+			if errors.Is(readErr, io.EOF) {
+				_ = encoder.Flush()
+				return nil
+			}
+			errRsp := redis.NewSimpleError(fmt.Sprintf("ERR %s", readErr.Error()))
+			_ = encoder.Encode(errRsp)
+			_ = encoder.Flush()
+			return fmt.Errorf("failed to parse command: %w", readErr)
+		} else if flushErr := encoder.Flush(); flushErr != nil {
+			return fmt.Errorf("failed to flush response: %w", flushErr)
+		}
+
+		if link != nil && !linked {
+			// the replica gets the stream from now on, replies to its own
+			// commands are not sent
+			encoder.Reset(io.Discard)
+			linked = true
+			go link.run()
 		}
 	}
+}
 
-	return
+func (h *CommandHandler) role() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.Conf.Role
 }
 
-func (h *CommandHandler) Replicate(ctx context.Context) {
-	log.Printf("Info start replicate")
+var (
+	readOnlyReplicaErr = redis.NewSimpleError("READONLY You can't write against a read only replica.")
+	masterDownErr      = redis.NewSimpleError("MASTERDOWN Link with MASTER is down and replica-serve-stale-data is set to 'no'.")
+	noMasterLinkErr    = redis.NewSimpleError("NOMASTERLINK Can't SYNC while not connected with my master")
+)
 
-	var masterConn net.Conn
-	var bufReader *bufio.Reader
-	var retryInterval time.Duration = time.Second * 5
-	var err error
+// refuse returns the error reply for a command a replica does not take
+// from its clients, it must be called under h.mu. The master link does
+// not go through here, so replicated writes always apply. Sub-replicas
+// cannot sync until this replica is in sync itself.
+func (h *CommandHandler) refuse(command cmd.Command) redis.RedisObject {
+	if h.Conf.Role != "slave" {
+		return nil
+	}
+	_, isWrite := command.(cmd.WriteCommand)
+	_, isRead := command.(cmd.ReadOnlyCommand)
+	_, isSync := command.(*cmd.Psync)
+	_, isStale := command.(cmd.StaleCommand)
+	switch {
+	case isSync && !h.Conf.MasterLinkUp:
+		return noMasterLinkErr
+	case isWrite && h.Conf.ReplicaReadOnly:
+		return readOnlyReplicaErr
+	case (isWrite || isRead && !isStale) && !h.Conf.MasterLinkUp && !h.Conf.ReplicaServeStaleData:
+		return masterDownErr
+	}
+	return nil
+}
 
-	// retry to connect to master
-labelConn:
-	masterConn, err = net.Dial("tcp", h.Conf.ReplicaofAddressAndPort())
-	if err != nil {
-		log.Printf("Error failed to connect to replica host %s: %v", h.Conf.ReplicaofAddressAndPort(), err)
-		log.Printf("Info retry in %v", retryInterval)
-		select {
-		case <-ctx.Done():
-			log.Printf("Info stop replicate")
-			return
-		case <-time.After(retryInterval):
-			goto labelConn
+// readPipeline blocks until one command arrives, then keeps reading the
+// commands the client has already pipelined into the socket buffer. A
+// command only partly received is left for the next batch, so replies to
+// the complete ones are not held back until the rest arrives.
+func (h *CommandHandler) readPipeline(reader *bufio.Reader, commands []cmd.Command) ([]cmd.Command, error) {
+	for {
+		command, err := cmd.ReadCommand(reader, &h.Storage, &h.Conf)
+		var commandErr *cmd.CommandError
+		if errors.As(err, &commandErr) {
+			// the request was consumed, reply the error in order
+			command = &rejectedCommand{err: commandErr}
+		} else if err != nil {
+			return commands, err
+		}
+		commands = append(commands, command)
+		if len(commands) >= MaxPipelineBatch || !bufferedRequest(reader) {
+			return commands, nil
+		}
+	}
+}
+
+// bufferedRequest tells if the reader holds a whole request, an array of
+// bulk strings, so reading it does not wait for the client. A malformed
+// request counts as whole, reading it reports the error.
+func bufferedRequest(reader *bufio.Reader) bool {
+	buf, _ := reader.Peek(reader.Buffered())
+	count, buf, ok := scanHeader(buf, '*')
+	if !ok {
+		return false
+	} else if count < 0 {
+		return true
+	}
+	for i := 0; i < count; i++ {
+		var size int
+		if size, buf, ok = scanHeader(buf, '$'); !ok {
+			return false
+		} else if size < 0 {
+			return true
+		} else if len(buf) < size+2 {
+			return false
 		}
-	} else {
-		bufReader = bufio.NewReader(masterConn)
+		buf = buf[size+2:]
 	}
+	return true
+}
 
-	// ack to master
-	ackCmd := cmd.NewPing()
-	if _, err := ackCmd.Send(masterConn, bufReader); err != nil {
-		log.Printf("Error failed to send ack to master: %v", err)
-		_ = masterConn.Close()
-		log.Printf("Info close connect and wait %v to retry", retryInterval)
-		<-time.After(retryInterval)
-		goto labelConn
+// scanHeader reads the size of a "<lead><size>\r\n" line at the start of
+// buf and returns what follows. ok is false when the line is not complete,
+// a malformed line gives a size of -1.
+func scanHeader(buf []byte, lead byte) (size int, rest []byte, ok bool) {
+	if len(buf) == 0 {
+		return 0, nil, false
+	} else if buf[0] != lead {
+		return -1, nil, true
+	}
+	end := bytes.Index(buf, []byte("\r\n"))
+	if end < 0 {
+		return 0, nil, false
+	}
+	size, err := strconv.Atoi(string(buf[1:end]))
+	if err != nil || size < 0 {
+		return -1, nil, true
 	}
+	return size, buf[end+2:], true
+}
+
+// rejectedCommand stands in for a request which could not be read as a
+// command, so its error reply keeps its place in the pipeline.
+type rejectedCommand struct {
+	err error
+}
+
+func (*rejectedCommand) Name() string {
+	return ""
+}
+
+func (r *rejectedCommand) String() string {
+	return fmt.Sprintf("REJECTED[%v]", r.err)
+}
+
+func (*rejectedCommand) Read(*redis.Array) error {
+	return nil
+}
 
+func (r *rejectedCommand) Execute(writer io.Writer, _ *model.RedisStorage, _ *model.CommandConf) (redis.RedisObject, error) {
+	rsp := redis.NewSimpleError(fmt.Sprintf("ERR %s", r.err.Error()))
+	return rsp, redis.WriteObject(writer, rsp)
 }
@@ -0,0 +1,280 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
+)
+
+// startTestServer serves h on a loopback port until the test ends.
+func startTestServer(t *testing.T, h ConnectionHandler) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		cancel()
+		_ = listener.Close()
+	})
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_ = h.HandleConnection(ctx, conn)
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func encodeCommand(args ...string) []byte {
+	elements := make([]redis.RedisObject, len(args))
+	for i, arg := range args {
+		elements[i] = redis.NewBulkString([]byte(arg))
+	}
+	return redis.NewArray(elements...).Append(nil)
+}
+
+func TestCommandHandler_HandleConnection_Pipeline(t *testing.T) {
+	tests := []struct {
+		name  string
+		count int
+	}{
+		{
+			name:  "single command",
+			count: 1,
+		},
+		{
+			name:  "hundreds of commands",
+			count: 500,
+		},
+		{
+			name:  "more than one batch",
+			count: MaxPipelineBatch*2 + 7,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr := startTestServer(t, NewCommandHandler())
+			conn, err := net.Dial("tcp", addr)
+			if err != nil {
+				t.Fatalf("case %s: failed to dial: %v", tt.name, err)
+			}
+			defer conn.Close()
+			_ = conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+			var (
+				request  []byte
+				expected []string
+			)
+			for i := 0; i < tt.count; i++ {
+				key := fmt.Sprintf("key:%d", i)
+				value := fmt.Sprintf("value:%d", i)
+				request = append(request, encodeCommand("SET", key, value)...)
+				expected = append(expected, "SimpleString{OK}")
+				request = append(request, encodeCommand("GET", key)...)
+				expected = append(expected, fmt.Sprintf("BulkString{%s}", value))
+				request = append(request, encodeCommand("ECHO", fmt.Sprint(i))...)
+				expected = append(expected, fmt.Sprintf("BulkString{%d}", i))
+			}
+			request = append(request, encodeCommand("PING")...)
+			expected = append(expected, "SimpleString{PONG}")
+
+			// the whole pipeline is sent with one write
+			if _, err := conn.Write(request); err != nil {
+				t.Fatalf("case %s: failed to write: %v", tt.name, err)
+			}
+
+			reader := bufio.NewReader(conn)
+			for i, exp := range expected {
+				rsp, err := redis.ReadObject(reader)
+				if err != nil {
+					t.Fatalf("case %s: failed to read reply %d: %v", tt.name, i, err)
+				} else if actual := rsp.String(); actual != exp {
+					t.Fatalf("case %s: reply %d expected=%s, actual=%s", tt.name, i, exp, actual)
+				}
+			}
+		})
+	}
+}
+
+func TestCommandHandler_readPipeline(t *testing.T) {
+	var input []byte
+	for i := 0; i < 10; i++ {
+		input = append(input, encodeCommand("ECHO", fmt.Sprint(i))...)
+	}
+	h := NewCommandHandler()
+	reader := bufio.NewReader(bytes.NewReader(input))
+	commands, err := h.readPipeline(reader, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(commands) != 10 {
+		t.Fatalf("expected 10 commands in one batch, actual=%d", len(commands))
+	}
+	for i, command := range commands {
+		if expected := fmt.Sprintf("ECHO[%d]", i); command.String() != expected {
+			t.Errorf("command %d expected=%s, actual=%s", i, expected, command.String())
+		}
+	}
+}
+
+func TestCommandHandler_readPipeline_Partial(t *testing.T) {
+	tests := []struct {
+		name  string
+		tail  string
+		count int
+	}{
+		{name: "in the array header", tail: "*2\r", count: 2},
+		{name: "in a bulk string header", tail: "*2\r\n$4\r\nECHO\r\n$1", count: 2},
+		{name: "in a bulk string", tail: "*2\r\n$4\r\nECHO\r\n$3\r\nab", count: 2},
+		{name: "before the last line ending", tail: "*2\r\n$4\r\nECHO\r\n$1\r\n2\r", count: 2},
+		{name: "complete", tail: "*2\r\n$4\r\nECHO\r\n$1\r\n2\r\n", count: 3},
+	}
+
+	for _, tt := range tests {
+		input := append(encodeCommand("ECHO", "0"), encodeCommand("ECHO", "1")...)
+		// the rest never arrives, reading it would block
+		reader := bufio.NewReader(io.MultiReader(bytes.NewReader(append(input, tt.tail...)), blockingReader{}))
+		if _, err := reader.Peek(len(input) + len(tt.tail)); err != nil {
+			t.Fatalf("case %s: failed to buffer: %v", tt.name, err)
+		}
+		commands, err := NewCommandHandler().readPipeline(reader, nil)
+		if err != nil {
+			t.Errorf("case %s: unexpected error: %v", tt.name, err)
+		} else if len(commands) != tt.count {
+			t.Errorf("case %s: expected %d commands, actual=%d", tt.name, tt.count, len(commands))
+		}
+	}
+}
+
+// blockingReader never returns.
+type blockingReader struct{}
+
+func (blockingReader) Read([]byte) (int, error) {
+	select {}
+}
+
+func TestCommandHandler_HandleConnection_PartialCommand(t *testing.T) {
+	conn, err := net.Dial("tcp", startTestServer(t, NewCommandHandler()))
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	// the reply to PING comes before the rest of ECHO is sent
+	echo := encodeCommand("ECHO", "hello")
+	if _, err := conn.Write(append(encodeCommand("PING"), echo[:10]...)); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+	reader := bufio.NewReader(conn)
+	if rsp, err := redis.ReadObject(reader); err != nil || rsp.String() != "SimpleString{PONG}" {
+		t.Fatalf("expected PONG, actual=%v, %v", rsp, err)
+	}
+	if _, err := conn.Write(echo[10:]); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+	if rsp, err := redis.ReadObject(reader); err != nil || rsp.String() != "BulkString{hello}" {
+		t.Fatalf("expected hello, actual=%v, %v", rsp, err)
+	}
+}
+
+func TestCommandHandler_Migrate(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	source, target := NewCommandHandler(), NewCommandHandler()
+	host, port, _ := net.SplitHostPort(startTestServer(t, target))
+	conn := dialTestClient(t, startTestServer(t, source))
+	targetConn := dialTestClient(t, startTestServer(t, target))
+
+	expireAt := time.Now().Add(time.Hour).UnixMilli()
+	source.Storage.Mem["a"] = &model.RedisBucket{Value: []byte("1"), ExpireAt: math.MaxInt64}
+	source.Storage.Mem["b"] = &model.RedisBucket{Type: model.TypeList, List: [][]byte{[]byte("x")}, ExpireAt: expireAt}
+	source.Storage.Mem["c"] = &model.RedisBucket{Value: []byte("new"), ExpireAt: math.MaxInt64}
+	if err := targetConn.Set(ctx, "c", []byte("old"), 0); err != nil {
+		t.Fatalf("failed to set: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		args   []any
+		output string
+		// keys expected on each side after the command
+		source []string
+		target map[string]string
+	}{
+		{
+			name:   "busy key on the target",
+			args:   []any{"", 0, 1000, "KEYS", "a", "b", "c"},
+			output: "ERR Target instance replied with error: BUSYKEY Target key name already exists.",
+			source: []string{"c"},
+			target: map[string]string{"a": "1", "c": "old"},
+		},
+		{
+			name:   "copy and replace",
+			args:   []any{"c", 0, 1000, "COPY", "REPLACE"},
+			output: "OK",
+			source: []string{"c"},
+			target: map[string]string{"a": "1", "c": "new"},
+		},
+		{
+			name:   "no key",
+			args:   []any{"missing", 0, 1000},
+			output: "NOKEY",
+			source: []string{"c"},
+			target: map[string]string{"a": "1", "c": "new"},
+		},
+	}
+
+	for _, tt := range tests {
+		rsp, err := conn.Do(ctx, append([]any{"MIGRATE", host, port}, tt.args...)...)
+		var actual string
+		if err != nil {
+			actual = err.Error()
+		} else {
+			_ = redis.Unmarshal(rsp, &actual)
+		}
+		if actual != tt.output {
+			t.Errorf("case %s: expected %q, actual=%q", tt.name, tt.output, actual)
+		}
+		source.mu.Lock()
+		if len(source.Storage.Mem) != len(tt.source) {
+			t.Errorf("case %s: expected keys %v, actual=%d keys", tt.name, tt.source, len(source.Storage.Mem))
+		}
+		for _, key := range tt.source {
+			if _, ok := source.Storage.Mem[key]; !ok {
+				t.Errorf("case %s: expected %s to stay", tt.name, key)
+			}
+		}
+		source.mu.Unlock()
+		for key, value := range tt.target {
+			if actual, err := targetConn.Get(ctx, key); err != nil || string(actual) != value {
+				t.Errorf("case %s: expected %s=%s on the target, actual=%s, %v", tt.name, key, value, actual, err)
+			}
+		}
+	}
+
+	target.mu.Lock()
+	defer target.mu.Unlock()
+	if b := target.Storage.Mem["b"]; b == nil || b.Type != model.TypeList {
+		t.Errorf("expected the list to be migrated, actual=%+v", b)
+	} else if b.ExpireAt < expireAt || b.ExpireAt > expireAt+1000 {
+		t.Errorf("expected the list to expire at %d, actual=%d", expireAt, b.ExpireAt)
+	}
+}
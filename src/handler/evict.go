@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"log"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model/cmd"
+	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
+)
+
+var (
+	oomErr = redis.NewSimpleError("OOM command not allowed when used memory > 'maxmemory'.")
+)
+
+// performEvictions evicts keys until the memory is under maxmemory, before
+// a command of a client runs. It returns the error reply of a command which
+// may take more memory while the memory stays over, e.g. under noeviction.
+// Replicas leave the eviction to their master, whose DELs they apply. It
+// must be called under h.mu.
+func (h *CommandHandler) performEvictions(command cmd.Command) redis.RedisObject {
+	if h.Conf.Maxmemory <= 0 || h.Conf.Role == "slave" {
+		return nil
+	}
+	now := time.Now().UnixMilli()
+	for h.Storage.Used > h.Conf.Maxmemory {
+		db, key, ok := h.evictionPool.Evict(&h.Storage, h.Conf.MaxmemoryPolicy, h.Conf.MaxmemorySamples, now)
+		if !ok {
+			if _, denyOOM := command.(cmd.DenyOOMCommand); denyOOM {
+				return oomErr
+			}
+			return nil
+		}
+		h.Conf.EvictedKeys++
+		log.Printf("Info evicted key %s of db %d, used memory %d", key, db, h.Storage.Used)
+
+		// replicas and the AOF delete it as well
+		selected := h.Storage.DB
+		h.Storage.Select(db)
+		h.propagateArgs(redis.NewArray(
+			redis.NewBulkString([]byte("DEL")),
+			redis.NewBulkString([]byte(key)),
+		))
+		h.Storage.Select(selected)
+	}
+	return nil
+}
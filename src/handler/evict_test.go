@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+	"github.com/codecrafters-io/redis-starter-go/src/model/aof"
+)
+
+func TestCommandHandler_Maxmemory_NoEviction(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	h := NewCommandHandler()
+	conn := dialTestClient(t, startTestServer(t, h))
+	if err := conn.Set(ctx, "a", []byte("1"), 0); err != nil {
+		t.Fatalf("failed to set: %v", err)
+	}
+	h.mu.Lock()
+	h.Conf.Maxmemory = h.Storage.Used - 1
+	h.mu.Unlock()
+
+	if _, err := conn.Do(ctx, "SET", "b", "1"); err == nil || !strings.HasPrefix(err.Error(), "OOM") {
+		t.Errorf("expected SET to be refused, actual=%v", err)
+	}
+	// reads and deletes go on
+	if _, err := conn.Do(ctx, "GET", "a"); err != nil {
+		t.Errorf("failed to get: %v", err)
+	} else if _, err := conn.Do(ctx, "DEL", "a"); err != nil {
+		t.Errorf("failed to del: %v", err)
+	} else if err := conn.Set(ctx, "b", []byte("1"), 0); err != nil {
+		t.Errorf("expected SET to work under maxmemory, actual=%v", err)
+	}
+}
+
+func TestCommandHandler_Maxmemory_Evict(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	h := NewCommandHandler()
+	h.Conf.Dir = t.TempDir()
+	h.Conf.AppendOnly = true
+	h.Conf.AppendFsync = aof.FsyncAlways
+	if err := h.OpenAppendOnly(ctx); err != nil {
+		t.Fatalf("failed to open: %v", err)
+	}
+	bucket := &model.RedisBucket{Value: []byte("1")}
+	h.Conf.Maxmemory = 3 * model.KeySize("k0", bucket)
+	h.Conf.MaxmemoryPolicy = model.PolicyAllKeysLRU
+
+	conn := dialTestClient(t, startTestServer(t, h))
+	// accessed a few milliseconds apart, k0 last
+	for i := 0; i < 3; i++ {
+		if err := conn.Set(ctx, fmt.Sprintf("k%d", i), []byte("1"), 0); err != nil {
+			t.Fatalf("failed to set: %v", err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if _, err := conn.Do(ctx, "GET", "k0"); err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	// over the bound after the SET, so the next command evicts
+	if err := conn.Set(ctx, "k3", []byte("1"), 0); err != nil {
+		t.Fatalf("failed to set: %v", err)
+	} else if _, err := conn.Do(ctx, "PING"); err != nil {
+		t.Fatalf("failed to ping: %v", err)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.Storage.Used > h.Conf.Maxmemory || h.Conf.EvictedKeys != 1 {
+		t.Errorf("expected one eviction, actual=%d of %d bytes", h.Conf.EvictedKeys, h.Storage.Used)
+	} else if _, ok := h.Storage.Mem["k1"]; ok {
+		t.Errorf("expected the least recently used k1 to be evicted, actual=%v", h.Storage.Mem)
+	}
+	content, err := os.ReadFile(filepath.Join(h.Conf.AOFDir(), "appendonly.aof.1.incr.aof"))
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	} else if del := "*2\r\n$3\r\nDEL\r\n$2\r\nk1\r\n"; !strings.HasSuffix(string(content), del) {
+		t.Errorf("expected the eviction to be logged, actual=%q", content)
+	}
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+	"github.com/codecrafters-io/redis-starter-go/src/model/rdb"
+)
+
+// LoadSnapshot replaces the keyspace with the RDB file of Conf, if there
+// is one. Keys which expired meanwhile are dropped.
+func (h *CommandHandler) LoadSnapshot() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	path := h.Conf.RDBPath()
+	snapshot, err := rdb.LoadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to load %s: %w", path, err)
+	}
+
+	if err := h.Storage.Reset(liveKeys(snapshot), time.Now().UnixMilli()); err != nil {
+		return fmt.Errorf("failed to load %s: %w", path, err)
+	}
+	h.Conf.LastSave = time.Now()
+	log.Printf("Info loaded %d keys from %s", h.Storage.Keys(), path)
+	return nil
+}
+
+// liveKeys returns the keys of a snapshot which have not expired, by
+// database. Keys of module types could not be loaded and are reported.
+func liveKeys(snapshot *rdb.Snapshot) map[int]map[string]*model.RedisBucket {
+	for _, skipped := range snapshot.Skipped {
+		log.Printf("Error skipped key %s of module type %s", skipped.Key, skipped.Module)
+	}
+	now := time.Now().UnixMilli()
+	for _, mem := range snapshot.DBs {
+		for key, bucket := range mem {
+			if bucket.ExpireAt < now {
+				delete(mem, key)
+			}
+		}
+	}
+	return snapshot.DBs
+}
+
+// snapshotKeys counts the keys of every database of a snapshot.
+func snapshotKeys(snapshot *rdb.Snapshot) int {
+	n := 0
+	for _, mem := range snapshot.DBs {
+		n += len(mem)
+	}
+	return n
+}
+
+// view returns the keyspace as of now, it must be called under h.mu.
+// Values are replaced rather than changed in place, so a copy of the maps
+// is a point-in-time view.
+func (h *CommandHandler) view() *model.RedisStorage {
+	dbs := h.Storage.Databases()
+	view := &model.RedisStorage{DBs: make([]map[string]*model.RedisBucket, len(dbs))}
+	for db, mem := range dbs {
+		view.DBs[db] = make(map[string]*model.RedisBucket, len(mem))
+		for key, bucket := range mem {
+			view.DBs[db][key] = bucket
+		}
+	}
+	view.Mem = view.DBs[0]
+	return view
+}
+
+// bgsave writes the keyspace as of now in the background, it must be
+// called under h.mu.
+func (h *CommandHandler) bgsave() {
+	view, path := h.view(), h.Conf.RDBPath()
+
+	go func() {
+		err := rdb.SaveFile(path, view)
+		if err != nil {
+			log.Printf("Error background save failed: %v", err)
+		} else {
+			log.Printf("Info background save of %d keys done", view.Keys())
+		}
+
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.Conf.BgsaveInProgress = false
+		h.Conf.LastBgsaveOK = err == nil
+		if err == nil {
+			h.Conf.LastSave = time.Now()
+		}
+	}()
+}
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+	"github.com/codecrafters-io/redis-starter-go/src/model/rdb"
+	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
+)
+
+func TestCommandHandler_Bgsave(t *testing.T) {
+	dir := t.TempDir()
+	h := NewCommandHandler()
+	h.Conf.Dir = dir
+	h.Conf.LastSave = time.Unix(0, 0)
+	conn := dialTestClient(t, startTestServer(t, h))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := conn.Set(ctx, "foo", []byte("bar"), 0); err != nil {
+		t.Fatalf("failed to set: %v", err)
+	}
+	// the write right after BGSAVE is not part of the snapshot
+	replies, err := conn.Pipeline().Do("BGSAVE").Do("SET", "late", "1").Exec(ctx)
+	if err != nil {
+		t.Fatalf("failed to exec: %v", err)
+	}
+	var status string
+	if _ = redis.Unmarshal(replies[0], &status); status != "Background saving started" {
+		t.Errorf("unexpected reply %v", replies[0])
+	}
+	waitFor(t, h, "background save", func() bool {
+		return !h.Conf.BgsaveInProgress
+	})
+
+	snapshot, err := rdb.LoadFile(filepath.Join(dir, "dump.rdb"))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	mem := snapshot.DB(0)
+	if bucket := mem["foo"]; bucket == nil || string(bucket.Value) != "bar" {
+		t.Errorf("expected foo=bar, actual=%v", bucket)
+	} else if _, ok := mem["late"]; ok {
+		t.Errorf("expected the snapshot to be taken at BGSAVE")
+	}
+	rsp, err := conn.Do(ctx, "LASTSAVE")
+	if err != nil {
+		t.Fatalf("failed to lastsave: %v", err)
+	}
+	var lastSave int64
+	if _ = redis.Unmarshal(rsp, &lastSave); lastSave == 0 {
+		t.Errorf("expected LASTSAVE to move after the save")
+	}
+}
+
+func TestCommandHandler_LoadSnapshot(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    []byte
+		keys    []string
+		isError bool
+	}{
+		{
+			name: "no file",
+		},
+		{
+			name: "saved keys without the expired ones",
+			file: func() []byte {
+				path := filepath.Join(t.TempDir(), "dump.rdb")
+				_ = rdb.SaveFile(path, &model.RedisStorage{Mem: map[string]*model.RedisBucket{
+					"foo": {Value: []byte("bar"), ExpireAt: math.MaxInt64},
+					"ttl": {Value: []byte("1"), ExpireAt: time.Now().Add(50 * time.Millisecond).UnixMilli()},
+				}})
+				b, _ := os.ReadFile(path)
+				// ttl expires before the load
+				time.Sleep(100 * time.Millisecond)
+				return b
+			}(),
+			keys: []string{"foo"},
+		},
+		{
+			name:    "corrupt file",
+			file:    []byte("REDIS0011\x00\x03foo"),
+			isError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		h := NewCommandHandler()
+		h.Conf.Dir = t.TempDir()
+		if tt.file != nil {
+			if err := os.WriteFile(h.Conf.RDBPath(), tt.file, 0o644); err != nil {
+				t.Fatalf("case %s: failed to write: %v", tt.name, err)
+			}
+		}
+		err := h.LoadSnapshot()
+		if tt.isError {
+			if err == nil {
+				t.Errorf("case %s: expected error, actual=nil", tt.name)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("case %s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if len(h.Storage.Mem) != len(tt.keys) {
+			t.Errorf("case %s: expected %d keys, actual=%d", tt.name, len(tt.keys), len(h.Storage.Mem))
+		}
+		for _, key := range tt.keys {
+			if _, ok := h.Storage.Mem[key]; !ok {
+				t.Errorf("case %s: expected key %s", tt.name, key)
+			}
+		}
+	}
+}
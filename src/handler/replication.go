@@ -0,0 +1,735 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/src/client"
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+	"github.com/codecrafters-io/redis-starter-go/src/model/aof"
+	"github.com/codecrafters-io/redis-starter-go/src/model/cmd"
+	"github.com/codecrafters-io/redis-starter-go/src/model/rdb"
+	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
+)
+
+// replicaLink is the output of the master to one replica. Writes are
+// buffered and sent by the link's own goroutine, so a slow replica never
+// blocks command execution.
+type replicaLink struct {
+	conn net.Conn
+	info *model.ReplicaInfo
+	// waiting for a diskless snapshot, it gets no stream yet, guarded by
+	// the mu of the handler
+	waiting bool
+
+	// started is closed once run owns the connection, synced once the
+	// snapshot of a full resynchronization went through it, the stream
+	// buffered meanwhile follows, and done once the link is closed
+	started chan struct{}
+	synced  chan struct{}
+	done    chan struct{}
+
+	mu     sync.Mutex
+	buf    []byte
+	ready  chan struct{}
+	closed bool
+}
+
+func newReplicaLink(conn net.Conn, info *model.ReplicaInfo) *replicaLink {
+	return &replicaLink{
+		conn:    conn,
+		info:    info,
+		started: make(chan struct{}),
+		synced:  make(chan struct{}),
+		done:    make(chan struct{}),
+		ready:   make(chan struct{}, 1),
+	}
+}
+
+// feed appends to the output of the replica.
+func (r *replicaLink) feed(p []byte) {
+	r.mu.Lock()
+	r.buf = append(r.buf, p...)
+	r.mu.Unlock()
+	select {
+	case r.ready <- struct{}{}:
+	default:
+	}
+}
+
+// run sends the output until the link is closed, once the snapshot is
+// sent. A write error closes the connection, which ends its
+// HandleConnection as well.
+func (r *replicaLink) run() {
+	close(r.started)
+	select {
+	case <-r.synced:
+	case <-r.done:
+		return
+	}
+
+	var out []byte
+	for {
+		r.mu.Lock()
+		out, r.buf = r.buf, out[:0]
+		closed := r.closed
+		r.mu.Unlock()
+		if closed {
+			return
+		}
+		if len(out) > 0 {
+			if _, err := r.conn.Write(out); err != nil {
+				log.Printf("Error failed to write to replica %s: %v", r.conn.RemoteAddr(), err)
+				_ = r.conn.Close()
+				return
+			}
+		}
+		if _, ok := <-r.ready; !ok {
+			return
+		}
+	}
+}
+
+// sync waits until run owns the connection and writes the snapshot with
+// write. The link is synced on success, its connection closed otherwise.
+func (r *replicaLink) sync(write func(io.Writer) error) error {
+	select {
+	case <-r.started:
+	case <-r.done:
+		return net.ErrClosed
+	}
+	if err := write(r.conn); err != nil {
+		_ = r.conn.Close()
+		return err
+	}
+	close(r.synced)
+	return nil
+}
+
+func (r *replicaLink) close() {
+	r.mu.Lock()
+	r.closed = true
+	r.mu.Unlock()
+	close(r.ready)
+	close(r.done)
+}
+
+// addReplica registers conn after a resynchronization, it must be called
+// under h.mu along with PSYNC so no write is missed.
+func (h *CommandHandler) addReplica(ctx context.Context, conn net.Conn, port int, fullResync bool) *replicaLink {
+	info := &model.ReplicaInfo{
+		Port:  port,
+		State: "online",
+		AckAt: time.Now(),
+	}
+	info.IP, _, _ = net.SplitHostPort(conn.RemoteAddr().String())
+	link := newReplicaLink(conn, info)
+	if !fullResync {
+		close(link.synced)
+	}
+	if h.replicas == nil {
+		h.replicas = make(map[*replicaLink]struct{})
+	}
+	if h.Conf.Backlog == nil {
+		// the backlog starts with the first replica
+		h.Conf.Backlog = model.NewReplBacklog(h.Conf.ReplBacklogSize, h.Conf.MasterReplOffset+1)
+	}
+	h.replicas[link] = struct{}{}
+	h.Conf.Replicas = append(h.Conf.Replicas, info)
+	h.cronOnce.Do(func() {
+		go h.replicationCron(ctx)
+	})
+	log.Printf("Info replica %s attached, full resync: %v", conn.RemoteAddr(), fullResync)
+	return link
+}
+
+// removeReplica must be called under h.mu.
+func (h *CommandHandler) removeReplica(link *replicaLink) {
+	if _, ok := h.replicas[link]; !ok {
+		return
+	}
+	delete(h.replicas, link)
+	for i, info := range h.Conf.Replicas {
+		if info == link.info {
+			h.Conf.Replicas = append(h.Conf.Replicas[:i], h.Conf.Replicas[i+1:]...)
+			break
+		}
+	}
+	link.close()
+	log.Printf("Info replica %s detached", link.conn.RemoteAddr())
+}
+
+// diskSync sends link the snapshot of its full resynchronization, as of
+// now, in the background. It must be called under h.mu along with PSYNC,
+// the stream fed to link meanwhile follows the snapshot.
+func (h *CommandHandler) diskSync(link *replicaLink) {
+	view, streamDB, dir := h.view(), h.Conf.ReplStreamDB, filepath.Dir(h.Conf.RDBPath())
+	link.info.State = "send_bulk"
+
+	go func() {
+		err := link.sync(func(writer io.Writer) error {
+			return cmd.WriteSnapshot(writer, view, streamDB, dir)
+		})
+		if err != nil {
+			log.Printf("Error failed to send snapshot to replica %s: %v", link.conn.RemoteAddr(), err)
+			return
+		}
+		h.mu.Lock()
+		link.info.State = "online"
+		h.mu.Unlock()
+	}()
+}
+
+// waitDisklessSync holds link back until the next diskless transfer, which
+// starts ReplDisklessSyncDelay after the first replica waits, so replicas
+// arriving meanwhile share it. It must be called under h.mu.
+func (h *CommandHandler) waitDisklessSync(link *replicaLink) {
+	link.waiting = true
+	link.info.State = "wait_bgsave"
+	if h.disklessTimer == nil {
+		h.disklessTimer = time.AfterFunc(h.Conf.ReplDisklessSyncDelay, h.disklessSync)
+	}
+}
+
+// disklessSync produces one snapshot for all the waiting replicas, written
+// to their connections as it is encoded, out of h.mu. The stream follows
+// right after it, from the offset the snapshot was taken at.
+func (h *CommandHandler) disklessSync() {
+	h.mu.Lock()
+	h.disklessTimer = nil
+	var targets []*replicaLink
+	for link := range h.replicas {
+		if link.waiting {
+			link.waiting = false
+			link.info.State = "send_bulk"
+			targets = append(targets, link)
+		}
+	}
+	if len(targets) == 0 {
+		h.mu.Unlock()
+		return
+	}
+	view, replid, offset, streamDB := h.view(), h.Conf.MasterReplid, h.Conf.MasterReplOffset, h.Conf.ReplStreamDB
+	h.mu.Unlock()
+
+	log.Printf("Info diskless sync to %d replicas at offset %d", len(targets), offset)
+	writer := &syncWriter{}
+	for _, link := range targets {
+		select {
+		case <-link.started:
+			writer.links = append(writer.links, link)
+		case <-link.done:
+		}
+	}
+	if err := cmd.WriteDisklessSync(writer, view, replid, offset, streamDB); err != nil {
+		log.Printf("Error failed to write diskless snapshot: %v", err)
+		for _, link := range writer.links {
+			_ = link.conn.Close()
+		}
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, link := range writer.links {
+		close(link.synced)
+		link.info.State = "online"
+	}
+}
+
+// syncWriter writes a snapshot to the connections of several replicas at
+// once. A replica failing is closed and left out, the others go on.
+type syncWriter struct {
+	links []*replicaLink
+}
+
+func (w *syncWriter) Write(p []byte) (int, error) {
+	links := w.links[:0]
+	for _, link := range w.links {
+		if _, err := link.conn.Write(p); err != nil {
+			log.Printf("Error failed to send snapshot to replica %s: %v", link.conn.RemoteAddr(), err)
+			_ = link.conn.Close()
+			continue
+		}
+		links = append(links, link)
+	}
+	w.links = links
+	if len(links) == 0 {
+		return 0, net.ErrClosed
+	}
+	return len(p), nil
+}
+
+// ackReplica records the offset a replica acknowledged and wakes up the
+// clients in WAIT, it must be called under h.mu.
+func (h *CommandHandler) ackReplica(link *replicaLink, offset int64) {
+	if offset > link.info.AckOffset {
+		link.info.AckOffset = offset
+	}
+	link.info.AckAt = time.Now()
+	if h.acked != nil {
+		close(h.acked)
+		h.acked = nil
+	}
+}
+
+// countAcked returns how many replicas acknowledged offset, it must be
+// called under h.mu.
+func (h *CommandHandler) countAcked(offset int64) int {
+	n := 0
+	for link := range h.replicas {
+		if link.info.AckOffset >= offset {
+			n++
+		}
+	}
+	return n
+}
+
+// waitReplicas blocks until numReplicas replicas acknowledged offset, or
+// timeout passed when it is positive, and returns how many did.
+func (h *CommandHandler) waitReplicas(ctx context.Context, offset int64, numReplicas int, timeout time.Duration) int {
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	n := h.countAcked(offset)
+	if n >= numReplicas {
+		return n
+	}
+	h.feedStream(getAckCommand)
+	for n < numReplicas {
+		if h.acked == nil {
+			h.acked = make(chan struct{})
+		}
+		acked := h.acked
+		h.mu.Unlock()
+		select {
+		case <-acked:
+		case <-expired:
+			h.mu.Lock()
+			return h.countAcked(offset)
+		case <-ctx.Done():
+			h.mu.Lock()
+			return h.countAcked(offset)
+		}
+		h.mu.Lock()
+		n = h.countAcked(offset)
+	}
+	return n
+}
+
+// replicationCron asks the replicas for their offsets once in a while, so
+// INFO shows fresh offsets even without WAIT.
+func (h *CommandHandler) replicationCron(ctx context.Context) {
+	ticker := time.NewTicker(h.replAckPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		h.mu.Lock()
+		// a replica proxies the stream of its master and adds nothing
+		if len(h.replicas) > 0 && h.Conf.Role == "master" {
+			h.feedStream(getAckCommand)
+		}
+		h.mu.Unlock()
+	}
+}
+
+var (
+	getAckCommand = redis.NewArray(
+		redis.NewBulkString([]byte("REPLCONF")),
+		redis.NewBulkString([]byte("GETACK")),
+		redis.NewBulkString([]byte("*")),
+	).Append(nil)
+)
+
+func ackCommand(offset int64) []byte {
+	return redis.NewArray(
+		redis.NewBulkString([]byte("REPLCONF")),
+		redis.NewBulkString([]byte("ACK")),
+		redis.NewBulkString([]byte(strconv.FormatInt(offset, 10))),
+	).Append(nil)
+}
+
+// propagate logs a successful write command to the AOF and, on a master,
+// sends it to every replica moving the replication offset past it. Writes
+// on a writable replica stay local. It must be called under h.mu.
+func (h *CommandHandler) propagate(command cmd.WriteCommand) {
+	if args := command.Propagate(); args != nil {
+		h.propagateArgs(args)
+	}
+}
+
+// propagateArgs sends the write args of the selected database on as
+// propagate does, it must be called under h.mu.
+func (h *CommandHandler) propagateArgs(args *redis.Array) {
+	p := args.Append(h.propagateBuf[:0])
+	h.propagateBuf = p
+	if h.aof != nil {
+		h.appendOnly(h.selectDB(&h.aofDB, p))
+	}
+	if h.Conf.Role == "master" {
+		h.feedStream(h.selectDB(&h.Conf.ReplStreamDB, p))
+	}
+}
+
+// selectDB returns p preceded by a SELECT of the database of the command
+// when selected, the database the destination last selected, differs. It
+// must be called under h.mu.
+func (h *CommandHandler) selectDB(selected *int, p []byte) []byte {
+	if *selected == h.Storage.DB {
+		return p
+	}
+	*selected = h.Storage.DB
+	return append(aof.SelectCommand(h.Storage.DB).Append(nil), p...)
+}
+
+// feedStream appends to the replication stream, it must be called under
+// h.mu.
+func (h *CommandHandler) feedStream(p []byte) {
+	if h.Conf.Backlog != nil {
+		_, _ = h.Conf.Backlog.Write(p)
+	}
+	for link := range h.replicas {
+		if !link.waiting {
+			link.feed(p)
+		}
+	}
+	h.Conf.MasterReplOffset += int64(len(p))
+}
+
+// ReplicaOf makes this server replicate host:port, replacing the
+// replication of a previous master. The replication stops with ctx.
+func (h *CommandHandler) ReplicaOf(ctx context.Context, host string, port int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.replicaOf(ctx, host, port)
+}
+
+// replicaOf must be called under h.mu.
+func (h *CommandHandler) replicaOf(ctx context.Context, host string, port int) {
+	h.stopReplication()
+	h.Conf.Role = "slave"
+	h.Conf.ReplicaofAddress = host
+	h.Conf.ReplicaofPort = port
+	h.Conf.MasterLinkUp = false
+	h.Conf.MasterSyncInProgress = false
+	// replicas of this server resync to learn about the new history
+	h.disconnectReplicas()
+
+	replCtx, cancel := context.WithCancel(ctx)
+	h.replCancel = cancel
+	go h.Replicate(replCtx)
+	log.Printf("Info replicate %s:%d", host, port)
+}
+
+// promote turns this replica into a master, it must be called under h.mu.
+// The history so far stays available as replid2, so the replicas of the
+// old master can continue with a partial resync.
+func (h *CommandHandler) promote() {
+	h.stopReplication()
+	h.Conf.Role = "master"
+	h.Conf.ReplicaofAddress = ""
+	h.Conf.ReplicaofPort = 0
+	h.Conf.MasterLinkUp = false
+	h.Conf.MasterSyncInProgress = false
+	h.Conf.ShiftReplid(model.NewReplid())
+	h.disconnectReplicas()
+	log.Printf("Info promoted to master, replid=%s replid2=%s", h.Conf.MasterReplid, h.Conf.MasterReplid2)
+}
+
+// stopReplication cancels the replication goroutine, which checks its
+// context under h.mu before every change, so it must be called under h.mu.
+func (h *CommandHandler) stopReplication() {
+	if h.replCancel != nil {
+		h.replCancel()
+		h.replCancel = nil
+	}
+}
+
+// disconnectReplicas must be called under h.mu.
+func (h *CommandHandler) disconnectReplicas() {
+	for link := range h.replicas {
+		_ = link.conn.Close()
+	}
+}
+
+// Replicate follows the master in Conf until ctx is done.
+func (h *CommandHandler) Replicate(ctx context.Context) {
+	log.Printf("Info start replicate")
+
+	var masterConn *client.Conn
+	var retryInterval = h.replRetryInterval
+	var err error
+
+	// the address only changes along with a new replication
+	h.mu.Lock()
+	masterAddr := h.Conf.ReplicaofAddressAndPort()
+	h.mu.Unlock()
+
+	// retry to connect to master
+labelConn:
+	masterConn, err = client.Dial(ctx, masterAddr, &client.Options{Protocol: client.RESP2})
+	if err != nil {
+		log.Printf("Error failed to connect to replica host %s: %v", masterAddr, err)
+		log.Printf("Info retry in %v", retryInterval)
+		select {
+		case <-ctx.Done():
+			log.Printf("Info stop replicate")
+			return
+		case <-time.After(retryInterval):
+			goto labelConn
+		}
+	}
+
+	if err := h.handshake(ctx, masterConn); err != nil {
+		log.Printf("Error failed to handshake with master: %v", err)
+		h.setMasterLink(ctx, false)
+		_ = masterConn.Close()
+		log.Printf("Info close connect and wait %v to retry", retryInterval)
+		select {
+		case <-ctx.Done():
+			log.Printf("Info stop replicate")
+			return
+		case <-time.After(retryInterval):
+			goto labelConn
+		}
+	}
+
+	h.setMasterLink(ctx, true)
+	if err := h.applyStream(ctx, masterConn); err != nil {
+		log.Printf("Error lost replication stream: %v", err)
+		h.setMasterLink(ctx, false)
+		_ = masterConn.Close()
+		select {
+		case <-ctx.Done():
+			log.Printf("Info stop replicate")
+			return
+		case <-time.After(retryInterval):
+			goto labelConn
+		}
+	}
+}
+
+// setMasterLink records whether the replication stream is flowing, for
+// INFO replication.
+func (h *CommandHandler) setMasterLink(ctx context.Context, up bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if ctx.Err() != nil {
+		return
+	}
+	h.Conf.MasterLinkUp = up
+	h.Conf.MasterSyncInProgress = false
+	h.Conf.MasterLastIOAt = time.Now()
+}
+
+// handshake introduces this replica to the master: PING, REPLCONF
+// listening-port, REPLCONF capa eof capa psync2 and PSYNC. A replica which
+// synced before asks to continue from its offset, otherwise it loads the
+// snapshot of a full resynchronization, straight from the socket when
+// ReplDisklessLoad allows.
+func (h *CommandHandler) handshake(ctx context.Context, masterConn *client.Conn) error {
+	if err := masterConn.Ping(ctx); err != nil {
+		return fmt.Errorf("failed to ping master: %w", err)
+	}
+	if err := masterConn.ReplConf(ctx, "listening-port", strconv.Itoa(h.Conf.Port)); err != nil {
+		return fmt.Errorf("failed to send listening-port: %w", err)
+	}
+	if err := masterConn.ReplConf(ctx, "capa", "eof", "capa", "psync2"); err != nil {
+		return fmt.Errorf("failed to send capa: %w", err)
+	}
+
+	replid, offset := "?", int64(-1)
+	h.mu.Lock()
+	if ctx.Err() != nil {
+		h.mu.Unlock()
+		return ctx.Err()
+	}
+	if h.Conf.Backlog != nil {
+		replid, offset = h.Conf.MasterReplid, h.Conf.MasterReplOffset+1
+	}
+	disklessLoad := h.Conf.ReplDisklessLoad == "swapdb" ||
+		h.Conf.ReplDisklessLoad == "on-empty-db" && h.Storage.Keys() == 0
+	h.Conf.MasterSyncInProgress = true
+	h.mu.Unlock()
+	reply, err := masterConn.Psync(ctx, replid, offset)
+	if err != nil {
+		return fmt.Errorf("failed to psync: %w", err)
+	} else if !reply.FullResync {
+		log.Printf("Info partial resync with master replid=%s offset=%d", replid, offset)
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if reply.Replid != "" && reply.Replid != h.Conf.MasterReplid {
+			// the master was promoted and switched to a new history, which
+			// sub-replicas learn when they reconnect
+			h.Conf.ShiftReplid(reply.Replid)
+			h.disconnectReplicas()
+		}
+		return nil
+	}
+	log.Printf("Info full resync with master replid=%s offset=%d", reply.Replid, reply.Offset)
+
+	var snapshot *rdb.Snapshot
+	if disklessLoad {
+		// parsed as it arrives, the keyspace in use stays until the swap
+		err = masterConn.ReadSnapshot(ctx, func(reader io.Reader) error {
+			var loadErr error
+			snapshot, loadErr = rdb.Load(reader)
+			return loadErr
+		})
+		if err != nil {
+			return fmt.Errorf("failed to load snapshot: %w", err)
+		}
+	} else {
+		payload, err := masterConn.ReceiveSnapshot(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to receive snapshot: %w", err)
+		}
+		if snapshot, err = rdb.Load(bytes.NewReader(payload)); err != nil {
+			return fmt.Errorf("failed to load snapshot: %w", err)
+		}
+	}
+	streamDB := 0
+	if aux, ok := snapshot.Aux[rdb.AuxReplStreamDB]; ok {
+		if streamDB, err = strconv.Atoi(aux); err != nil {
+			return fmt.Errorf("invalid %s %q: %w", rdb.AuxReplStreamDB, aux, err)
+		}
+	}
+	log.Printf("Info loaded snapshot of %d keys, diskless: %v", snapshotKeys(snapshot), disklessLoad)
+
+	// the keyspace is replaced at once, commands never see a partial load
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if ctx.Err() != nil {
+		// replaced by REPLICAOF meanwhile
+		return ctx.Err()
+	}
+	if err := h.Storage.Reset(snapshot.DBs, time.Now().UnixMilli()); err != nil {
+		return fmt.Errorf("failed to load snapshot: %w", err)
+	}
+	if h.aof != nil {
+		// the AOF holds the previous data set, the stream goes on from a
+		// base of this one
+		h.Conf.AOFRewriteInProgress = true
+		h.rewriteAppendOnly()
+		h.aofDB = -1
+	}
+	h.Conf.ReplStreamDB = streamDB
+	h.Conf.MasterReplid = reply.Replid
+	h.Conf.MasterReplOffset = reply.Offset
+	h.Conf.ClearReplid2()
+	h.Conf.Backlog = model.NewReplBacklog(h.Conf.ReplBacklogSize, reply.Offset+1)
+	// sub-replicas hold the previous data set and resync with this one
+	h.disconnectReplicas()
+	return nil
+}
+
+// applyStream executes the commands the master propagates, without
+// replying, and advances the replication offset by the bytes consumed.
+// Only REPLCONF GETACK is answered, and the offset is also acknowledged
+// once in a while. The stream goes on byte for byte to the backlog and the
+// sub-replicas, so they share the offsets and replid of the master.
+func (h *CommandHandler) applyStream(ctx context.Context, masterConn *client.Conn) error {
+	var ackMu sync.Mutex
+	sendAck := func(p []byte) {
+		ackMu.Lock()
+		defer ackMu.Unlock()
+		if _, err := masterConn.NetConn().Write(p); err != nil {
+			log.Printf("Error failed to ack master: %v", err)
+		}
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(h.replAckPeriod)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				_ = masterConn.Close()
+				return
+			case <-done:
+				return
+			case <-ticker.C:
+				h.mu.Lock()
+				offset := h.Conf.MasterReplOffset
+				h.mu.Unlock()
+				sendAck(ackCommand(offset))
+			}
+		}
+	}()
+
+	var (
+		reader = masterConn.Reader()
+		raw    []byte
+		ack    bytes.Buffer
+	)
+	masterConn.RecordInput()
+	for {
+		args, err := cmd.ReadArgs(reader)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("failed to read replicated command: %w", err)
+		}
+		command, err := cmd.ParseCommand(args)
+		if err != nil {
+			log.Printf("Error skip replicated command: %v", err)
+		}
+		// the bytes as received, which sub-replicas get unchanged
+		raw = masterConn.TakeInput(raw[:0])
+
+		var writer io.Writer = io.Discard
+		if replConf, ok := command.(*cmd.ReplConf); ok && replConf.GetAck() {
+			ack.Reset()
+			writer = &ack
+		}
+
+		h.mu.Lock()
+		if ctx.Err() != nil {
+			// replaced by REPLICAOF meanwhile, the command must not apply
+			h.mu.Unlock()
+			return ctx.Err()
+		}
+		if command != nil {
+			h.Storage.Select(h.Conf.ReplStreamDB)
+			_, execErr := command.Execute(writer, &h.Storage, &h.Conf)
+			h.Conf.ReplStreamDB = h.Storage.DB
+			if execErr != nil {
+				log.Printf("Error failed to apply replicated command %v: %v", command, execErr)
+			} else if write, ok := command.(cmd.WriteCommand); ok {
+				h.propagate(write)
+			}
+			h.Storage.Select(0)
+		}
+		h.feedStream(raw)
+		h.Conf.MasterLastIOAt = time.Now()
+		h.mu.Unlock()
+
+		if writer == &ack && ack.Len() > 0 {
+			sendAck(ack.Bytes())
+		}
+	}
+}
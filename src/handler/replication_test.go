@@ -0,0 +1,1017 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/src/client"
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+	"github.com/codecrafters-io/redis-starter-go/src/model/aof"
+	"github.com/codecrafters-io/redis-starter-go/src/model/rdb"
+	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
+)
+
+func newTestMaster(t *testing.T) (*CommandHandler, string) {
+	t.Helper()
+	master := NewCommandHandler()
+	master.Conf.Role = "master"
+	master.Conf.MasterReplid = "8371b4fb1155b71f4a04d3e1bc3e18c4a990aeeb"
+	master.Conf.MasterReplOffset = 0
+	return master, startTestServer(t, master)
+}
+
+// startTestReplica replicates the master at addr until the test ends.
+func startTestReplica(t *testing.T, addr string) *CommandHandler {
+	t.Helper()
+	host, portStr, _ := net.SplitHostPort(addr)
+	port, _ := strconv.Atoi(portStr)
+
+	replica := NewCommandHandler()
+	replica.Conf.Port = 6380
+	replica.replRetryInterval = 20 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	replica.ReplicaOf(ctx, host, port)
+	return replica
+}
+
+// waitFor polls f under the lock of h until it holds.
+func waitFor(t *testing.T, h *CommandHandler, msg string, f func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		h.mu.Lock()
+		ok := f()
+		h.mu.Unlock()
+		if ok {
+			return
+		} else if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %s", msg)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func dialTestClient(t *testing.T, addr string) *client.Conn {
+	t.Helper()
+	conn, err := client.Dial(context.Background(), addr, &client.Options{Protocol: client.RESP2})
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+	return conn
+}
+
+func TestCommandHandler_Replicate_FullResync(t *testing.T) {
+	master, addr := newTestMaster(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn := dialTestClient(t, addr)
+	for i := 0; i < 100; i++ {
+		if err := conn.Set(ctx, fmt.Sprintf("key:%d", i), []byte(fmt.Sprintf("value:%d", i)), 0); err != nil {
+			t.Fatalf("failed to set: %v", err)
+		}
+	}
+	if err := conn.Set(ctx, "ttl", []byte("1"), time.Hour); err != nil {
+		t.Fatalf("failed to set: %v", err)
+	}
+
+	host, portStr, _ := net.SplitHostPort(addr)
+	port, _ := strconv.Atoi(portStr)
+	replica := NewCommandHandler()
+	replica.Conf.Role = "slave"
+	replica.Conf.ReplicaofAddress = host
+	replica.Conf.ReplicaofPort = port
+	replica.Storage.Mem["stale"] = &model.RedisBucket{Value: []byte("stale"), ExpireAt: math.MaxInt64}
+	replicaCtx, replicaCancel := context.WithCancel(context.Background())
+	defer replicaCancel()
+	go replica.Replicate(replicaCtx)
+
+	master.mu.Lock()
+	masterTTL := master.Storage.Mem["ttl"].ExpireAt
+	master.mu.Unlock()
+
+	waitFor(t, replica, "full resync", func() bool {
+		return replica.Conf.MasterReplid == master.Conf.MasterReplid
+	})
+	replica.mu.Lock()
+	defer replica.mu.Unlock()
+	mem := replica.Storage.Mem
+	if len(mem) != 101 {
+		t.Fatalf("expected 101 keys after full resync, actual=%d", len(mem))
+	} else if _, ok := mem["stale"]; ok {
+		t.Fatalf("expected stale key to be dropped by full resync")
+	} else if bucket := mem["key:42"]; bucket == nil || string(bucket.Value) != "value:42" {
+		t.Fatalf("expected key:42=value:42, actual=%v", bucket)
+	} else if bucket := mem["ttl"]; bucket == nil || bucket.ExpireAt != masterTTL {
+		t.Fatalf("expected ttl to keep its expire time, actual=%v", bucket)
+	}
+}
+
+func TestCommandHandler_Propagate_Stream(t *testing.T) {
+	master, addr := newTestMaster(t)
+	// no periodic GETACK in the stream
+	master.replAckPeriod = time.Hour
+
+	// a bare connection plays the replica, to see the exact stream
+	replicaConn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer replicaConn.Close()
+	_ = replicaConn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := replicaConn.Write(encodeCommand("PSYNC", "?", "-1")); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+	reader := bufio.NewReader(replicaConn)
+	if line, _ := reader.ReadString('\n'); !strings.HasPrefix(line, "+FULLRESYNC ") {
+		t.Fatalf("unexpected psync reply %q", line)
+	}
+	header, _ := reader.ReadString('\n')
+	size, _ := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(header, "$")))
+	if _, err := reader.Discard(size); err != nil {
+		t.Fatalf("failed to skip snapshot: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn := dialTestClient(t, addr)
+	if _, err := conn.Get(ctx, "foo"); err != client.ErrNil {
+		t.Fatalf("expected nil, actual=%v", err)
+	}
+	if err := conn.Set(ctx, "foo", []byte("bar"), 0); err != nil {
+		t.Fatalf("failed to set: %v", err)
+	}
+	if _, err := conn.Echo(ctx, "hello"); err != nil {
+		t.Fatalf("failed to echo: %v", err)
+	}
+	if err := conn.Set(ctx, "baz", []byte("qux"), 0); err != nil {
+		t.Fatalf("failed to set: %v", err)
+	}
+
+	// only writes are propagated, and the stream carries no replies
+	expected := string(encodeCommand("SET", "foo", "bar")) + string(encodeCommand("SET", "baz", "qux"))
+	actual := make([]byte, len(expected))
+	if _, err := io.ReadFull(reader, actual); err != nil {
+		t.Fatalf("failed to read stream: %v", err)
+	}
+	if string(actual) != expected {
+		t.Fatalf("expected stream %q, actual=%q", expected, actual)
+	}
+
+	master.mu.Lock()
+	offset := master.Conf.MasterReplOffset
+	master.mu.Unlock()
+	if offset != int64(len(expected)) {
+		t.Errorf("expected master offset %d, actual=%d", len(expected), offset)
+	}
+}
+
+func TestCommandHandler_Propagate_DuringSnapshot(t *testing.T) {
+	master, addr := newTestMaster(t)
+	master.replAckPeriod = time.Hour
+	dir := t.TempDir()
+	master.mu.Lock()
+	master.Conf.Dir = dir
+	master.Storage.Set("old", &model.RedisBucket{Value: []byte("1"), ExpireAt: math.MaxInt64}, 0)
+	master.mu.Unlock()
+
+	replicaConn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer replicaConn.Close()
+	_ = replicaConn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := replicaConn.Write(encodeCommand("PSYNC", "?", "-1")); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+	reader := bufio.NewReader(replicaConn)
+	if line, _ := reader.ReadString('\n'); !strings.HasPrefix(line, "+FULLRESYNC ") {
+		t.Fatalf("unexpected psync reply %q", line)
+	}
+
+	// written while the snapshot is on its way, it comes after it
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := dialTestClient(t, addr).Set(ctx, "new", []byte("2"), 0); err != nil {
+		t.Fatalf("failed to set: %v", err)
+	}
+
+	header, _ := reader.ReadString('\n')
+	size, _ := strconv.ParseInt(strings.TrimSpace(strings.TrimPrefix(header, "$")), 10, 64)
+	snapshot, err := rdb.Load(io.LimitReader(reader, size))
+	if err != nil {
+		t.Fatalf("failed to load snapshot: %v", err)
+	} else if mem := snapshot.DB(0); mem["old"] == nil || mem["new"] != nil {
+		t.Errorf("expected the keys as of PSYNC in the snapshot, actual=%v", mem)
+	}
+	expected := string(encodeCommand("SET", "new", "2"))
+	actual := make([]byte, len(expected))
+	if _, err := io.ReadFull(reader, actual); err != nil {
+		t.Fatalf("failed to read stream: %v", err)
+	} else if string(actual) != expected {
+		t.Errorf("expected stream %q, actual=%q", expected, actual)
+	}
+
+	// the snapshot went through a temporary file, removed once sent
+	waitFor(t, master, "replica online", func() bool {
+		return len(master.Conf.Replicas) == 1 && master.Conf.Replicas[0].State == "online"
+	})
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("expected no file left in %s, actual=%d", dir, len(entries))
+	}
+}
+
+func TestCommandHandler_Replicate_Propagate(t *testing.T) {
+	master, addr := newTestMaster(t)
+	replica := startTestReplica(t, addr)
+	waitFor(t, replica, "full resync", func() bool {
+		return replica.Conf.MasterReplid == master.Conf.MasterReplid
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn := dialTestClient(t, addr)
+	for i := 0; i < 50; i++ {
+		if err := conn.Set(ctx, fmt.Sprintf("key:%d", i), []byte(fmt.Sprintf("value:%d", i)), 0); err != nil {
+			t.Fatalf("failed to set: %v", err)
+		}
+	}
+	if err := conn.Set(ctx, "ttl", []byte("1"), time.Hour); err != nil {
+		t.Fatalf("failed to set: %v", err)
+	}
+
+	master.mu.Lock()
+	masterOffset := master.Conf.MasterReplOffset
+	masterTTL := master.Storage.Mem["ttl"].ExpireAt
+	master.mu.Unlock()
+	if masterOffset == 0 {
+		t.Fatalf("expected master offset to advance")
+	}
+
+	waitFor(t, replica, "replica offset to catch up", func() bool {
+		return replica.Conf.MasterReplOffset == masterOffset
+	})
+	replica.mu.Lock()
+	defer replica.mu.Unlock()
+	if len(replica.Storage.Mem) != 51 {
+		t.Errorf("expected 51 keys on replica, actual=%d", len(replica.Storage.Mem))
+	}
+	if bucket := replica.Storage.Mem["key:7"]; bucket == nil || string(bucket.Value) != "value:7" {
+		t.Errorf("expected key:7=value:7, actual=%v", bucket)
+	}
+	if bucket := replica.Storage.Mem["ttl"]; bucket == nil || bucket.ExpireAt != masterTTL {
+		t.Errorf("expected replica to expire ttl at %d, actual=%v", masterTTL, bucket)
+	}
+}
+
+func TestCommandHandler_Replicate_PartialResync(t *testing.T) {
+	tests := []struct {
+		name string
+		// newReplid, when set, makes the master switch history while the
+		// replica is away, like a promoted replica does
+		newReplid string
+	}{
+		{
+			name: "same history",
+		},
+		{
+			name:      "history switched",
+			newReplid: "e0d3b8b33a0aab6c8c9d8f4f0a4e6d9e1f0c2b3a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			master, addr := newTestMaster(t)
+			replica := startTestReplica(t, addr)
+			oldReplid := master.Conf.MasterReplid
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			conn := dialTestClient(t, addr)
+			setKeys := func(from, to int) {
+				for i := from; i < to; i++ {
+					if err := conn.Set(ctx, fmt.Sprintf("key:%d", i), []byte(fmt.Sprintf("value:%d", i)), 0); err != nil {
+						t.Fatalf("case %s: failed to set: %v", tt.name, err)
+					}
+				}
+			}
+			waitSynced := func() {
+				waitFor(t, replica, "replica to sync", func() bool {
+					master.mu.Lock()
+					defer master.mu.Unlock()
+					return replica.Conf.MasterReplid == master.Conf.MasterReplid &&
+						replica.Conf.MasterReplOffset == master.Conf.MasterReplOffset &&
+						len(master.replicas) == 1
+				})
+			}
+
+			setKeys(0, 10)
+			waitSynced()
+
+			// a full resync would drop this key
+			replica.mu.Lock()
+			replica.Storage.Mem["marker"] = &model.RedisBucket{Value: []byte("1"), ExpireAt: math.MaxInt64}
+			replica.mu.Unlock()
+
+			master.mu.Lock()
+			for link := range master.replicas {
+				_ = link.conn.Close()
+			}
+			if tt.newReplid != "" {
+				master.Conf.ShiftReplid(tt.newReplid)
+			}
+			master.mu.Unlock()
+			setKeys(10, 20)
+			waitSynced()
+
+			replica.mu.Lock()
+			defer replica.mu.Unlock()
+			if _, ok := replica.Storage.Mem["marker"]; !ok {
+				t.Errorf("case %s: expected partial resync to keep the keyspace", tt.name)
+			}
+			if bucket := replica.Storage.Mem["key:19"]; bucket == nil || string(bucket.Value) != "value:19" {
+				t.Errorf("case %s: expected key:19 from the backlog, actual=%v", tt.name, bucket)
+			}
+			if tt.newReplid != "" && replica.Conf.MasterReplid2 != oldReplid {
+				t.Errorf("case %s: expected replid2 %s, actual=%s", tt.name, oldReplid, replica.Conf.MasterReplid2)
+			}
+		})
+	}
+}
+
+func TestCommandHandler_Wait(t *testing.T) {
+	master, addr := newTestMaster(t)
+	replicas := []*CommandHandler{startTestReplica(t, addr), startTestReplica(t, addr)}
+	waitFor(t, master, "replicas to attach", func() bool {
+		return len(master.replicas) == len(replicas)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn := dialTestClient(t, addr)
+	wait := func(numReplicas, timeout int) (int64, time.Duration) {
+		start := time.Now()
+		rsp, err := conn.Do(ctx, "WAIT", numReplicas, timeout)
+		if err != nil {
+			t.Fatalf("failed to wait: %v", err)
+		}
+		var n int64
+		if err := redis.Unmarshal(rsp, &n); err != nil {
+			t.Fatalf("unexpected reply %v: %v", rsp, err)
+		}
+		return n, time.Since(start)
+	}
+
+	// nothing written yet, every replica is up to date
+	if n, _ := wait(2, 0); n != 2 {
+		t.Errorf("expected 2 replicas before any write, actual=%d", n)
+	}
+
+	if err := conn.Set(ctx, "foo", []byte("bar"), 0); err != nil {
+		t.Fatalf("failed to set: %v", err)
+	}
+	if n, _ := wait(2, 2000); n != 2 {
+		t.Errorf("expected 2 replicas to ack the write, actual=%d", n)
+	}
+	for i, replica := range replicas {
+		replica.mu.Lock()
+		bucket := replica.Storage.Mem["foo"]
+		replica.mu.Unlock()
+		if bucket == nil || string(bucket.Value) != "bar" {
+			t.Errorf("expected replica %d to have foo=bar, actual=%v", i, bucket)
+		}
+	}
+
+	if err := conn.Set(ctx, "foo", []byte("baz"), 0); err != nil {
+		t.Fatalf("failed to set: %v", err)
+	}
+	if n, elapsed := wait(3, 200); n != 2 {
+		t.Errorf("expected 2 replicas after timeout, actual=%d", n)
+	} else if elapsed < 200*time.Millisecond {
+		t.Errorf("expected WAIT to block until timeout, returned after %v", elapsed)
+	}
+
+	info, err := conn.Info(ctx, "replication")
+	if err != nil {
+		t.Fatalf("failed to get info: %v", err)
+	}
+	if !strings.Contains(info, "connected_slaves:2\r\n") {
+		t.Errorf("expected 2 connected slaves in %q", info)
+	}
+	for i := range replicas {
+		prefix := fmt.Sprintf("slave%d:ip=127.0.0.1,port=6380,state=online,offset=", i)
+		if !strings.Contains(info, prefix) || strings.Contains(info, prefix+"0,") {
+			t.Errorf("expected acked offset of slave%d in %q", i, info)
+		}
+	}
+}
+
+func TestCommandHandler_Replicate_Ack(t *testing.T) {
+	// a fake master checks what the replica acknowledges
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+	replica := NewCommandHandler()
+	replica.Conf.Role = "slave"
+	replica.Conf.ReplicaofAddress = "127.0.0.1"
+	replica.Conf.ReplicaofPort = listener.Addr().(*net.TCPAddr).Port
+	// only GETACK is answered
+	replica.replAckPeriod = time.Hour
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go replica.Replicate(ctx)
+
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatalf("failed to accept: %v", err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	reader := bufio.NewReader(conn)
+	for _, reply := range []string{"+PONG\r\n", "+OK\r\n", "+OK\r\n"} {
+		if _, err := redis.ReadObject(reader); err != nil {
+			t.Fatalf("failed to read handshake: %v", err)
+		}
+		_, _ = conn.Write([]byte(reply))
+	}
+	if _, err := redis.ReadObject(reader); err != nil {
+		t.Fatalf("failed to read psync: %v", err)
+	}
+	var snapshot bytes.Buffer
+	if err := rdb.Dump(&snapshot, &model.RedisStorage{Mem: map[string]*model.RedisBucket{}}); err != nil {
+		t.Fatalf("failed to dump: %v", err)
+	}
+	_, _ = fmt.Fprintf(conn, "+FULLRESYNC %s 0\r\n$%d\r\n", "8371b4fb1155b71f4a04d3e1bc3e18c4a990aeeb", snapshot.Len())
+	_, _ = conn.Write(snapshot.Bytes())
+
+	getAck := encodeCommand("REPLCONF", "GETACK", "*")
+	ping := encodeCommand("PING")
+	set := encodeCommand("SET", "foo", "bar")
+	tests := []struct {
+		name   string
+		stream [][]byte
+		offset int
+	}{
+		{
+			name:   "nothing processed",
+			stream: [][]byte{getAck},
+			offset: 0,
+		},
+		{
+			name:   "previous getack counted",
+			stream: [][]byte{getAck},
+			offset: len(getAck),
+		},
+		{
+			name:   "ping and set counted",
+			stream: [][]byte{ping, set, getAck},
+			offset: len(getAck)*2 + len(ping) + len(set),
+		},
+	}
+
+	for _, tt := range tests {
+		for _, p := range tt.stream {
+			_, _ = conn.Write(p)
+		}
+		rsp, err := redis.ReadObject(reader)
+		if err != nil {
+			t.Fatalf("case %s: failed to read ack: %v", tt.name, err)
+		}
+		var args []string
+		_ = redis.Unmarshal(rsp, &args)
+		expected := []string{"REPLCONF", "ACK", strconv.Itoa(tt.offset)}
+		if strings.Join(args, " ") != strings.Join(expected, " ") {
+			t.Errorf("case %s: expected %v, actual=%v", tt.name, expected, args)
+		}
+	}
+}
+
+func TestCommandHandler_InfoReplication(t *testing.T) {
+	master, addr := newTestMaster(t)
+	replica := startTestReplica(t, addr)
+	waitFor(t, replica, "replica link up", func() bool {
+		return replica.Conf.MasterLinkUp
+	})
+	waitFor(t, master, "replica attached", func() bool {
+		return len(master.replicas) == 1
+	})
+
+	host, port, _ := net.SplitHostPort(addr)
+	tests := []struct {
+		name     string
+		h        *CommandHandler
+		expected []string
+	}{
+		{
+			name: "master",
+			h:    master,
+			expected: []string{
+				"role:master",
+				"connected_slaves:1",
+				"slave0:ip=127.0.0.1,port=6380,state=online",
+				"master_replid:" + master.Conf.MasterReplid,
+				"master_replid2:0000000000000000000000000000000000000000",
+				"second_repl_offset:-1",
+				"repl_backlog_active:1",
+				"repl_backlog_size:1048576",
+				"repl_backlog_first_byte_offset:1",
+			},
+		},
+		{
+			name: "replica",
+			h:    replica,
+			expected: []string{
+				"role:slave",
+				"master_host:" + host,
+				"master_port:" + port,
+				"master_link_status:up",
+				"master_last_io_seconds_ago:0",
+				"master_sync_in_progress:0",
+				"connected_slaves:0",
+				"master_replid:" + master.Conf.MasterReplid,
+				"repl_backlog_active:1",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		addr := startTestServer(t, tt.h)
+		info, err := dialTestClient(t, addr).Info(context.Background(), "replication")
+		if err != nil {
+			t.Fatalf("case %s: failed to get info: %v", tt.name, err)
+		}
+		for _, line := range tt.expected {
+			if !strings.Contains(info, line) {
+				t.Errorf("case %s: expected %q in %q", tt.name, line, info)
+			}
+		}
+	}
+}
+
+func TestCommandHandler_ReplicaOf(t *testing.T) {
+	masterA, addrA := newTestMaster(t)
+	masterB, addrB := newTestMaster(t)
+	masterB.Conf.MasterReplid = model.NewReplid()
+	server := NewCommandHandler()
+	server.replRetryInterval = 20 * time.Millisecond
+	conn := dialTestClient(t, startTestServer(t, server))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	replicaOf := func(args ...any) string {
+		rsp, err := conn.Do(ctx, append([]any{"REPLICAOF"}, args...)...)
+		if err != nil {
+			t.Fatalf("failed to replicaof %v: %v", args, err)
+		}
+		var status string
+		_ = redis.Unmarshal(rsp, &status)
+		return status
+	}
+	set := func(addr, key, value string) {
+		if err := dialTestClient(t, addr).Set(ctx, key, []byte(value), 0); err != nil {
+			t.Fatalf("failed to set: %v", err)
+		}
+	}
+
+	hostA, portA, _ := net.SplitHostPort(addrA)
+	if status := replicaOf(hostA, portA); status != "OK" {
+		t.Fatalf("unexpected reply %s", status)
+	}
+	set(addrA, "from", "a")
+	waitFor(t, server, "replicating a", func() bool {
+		bucket := server.Storage.Mem["from"]
+		return server.Conf.MasterReplid == masterA.Conf.MasterReplid && bucket != nil && string(bucket.Value) == "a"
+	})
+	if status := replicaOf(hostA, portA); status != "OK Already connected to specified master" {
+		t.Errorf("unexpected reply %s", status)
+	}
+
+	hostB, portB, _ := net.SplitHostPort(addrB)
+	replicaOf(hostB, portB)
+	set(addrB, "from", "b")
+	waitFor(t, server, "replicating b", func() bool {
+		bucket := server.Storage.Mem["from"]
+		return server.Conf.MasterReplid == masterB.Conf.MasterReplid && bucket != nil && string(bucket.Value) == "b"
+	})
+	waitFor(t, masterA, "a to lose its replica", func() bool {
+		return len(masterA.replicas) == 0
+	})
+
+	replicaOf("NO", "ONE")
+	set(addrB, "from", "b again")
+	if err := conn.Set(ctx, "own", []byte("write"), 0); err != nil {
+		t.Fatalf("failed to write to the promoted server: %v", err)
+	}
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if server.Conf.Role != "master" {
+		t.Errorf("expected role master, actual=%s", server.Conf.Role)
+	}
+	if server.Conf.MasterReplid2 != masterB.Conf.MasterReplid || server.Conf.MasterReplid == masterB.Conf.MasterReplid {
+		t.Errorf("expected replids to rotate, actual replid=%s replid2=%s", server.Conf.MasterReplid, server.Conf.MasterReplid2)
+	}
+	if bucket := server.Storage.Mem["from"]; bucket == nil || string(bucket.Value) != "b" {
+		t.Errorf("expected writes of the old master to stop, actual=%v", bucket)
+	}
+}
+
+func TestCommandHandler_ReplicaOf_Failover(t *testing.T) {
+	master, addr := newTestMaster(t)
+	replicas := []*CommandHandler{startTestReplica(t, addr), startTestReplica(t, addr)}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := dialTestClient(t, addr).Set(ctx, "foo", []byte("bar"), 0); err != nil {
+		t.Fatalf("failed to set: %v", err)
+	}
+	for _, replica := range replicas {
+		replica := replica
+		waitFor(t, replica, "replica to sync", func() bool {
+			return replica.Storage.Mem["foo"] != nil
+		})
+	}
+
+	// promote the first replica, and move the second one under it
+	promotedAddr := startTestServer(t, replicas[0])
+	promoted := dialTestClient(t, promotedAddr)
+	if _, err := promoted.Do(ctx, "REPLICAOF", "NO", "ONE"); err != nil {
+		t.Fatalf("failed to promote: %v", err)
+	}
+	replicas[1].mu.Lock()
+	// a full resync would drop this key
+	replicas[1].Storage.Mem["marker"] = &model.RedisBucket{Value: []byte("1"), ExpireAt: math.MaxInt64}
+	replicas[1].replRetryInterval = 20 * time.Millisecond
+	replicas[1].mu.Unlock()
+	host, port, _ := net.SplitHostPort(promotedAddr)
+	if _, err := dialTestClient(t, startTestServer(t, replicas[1])).Do(ctx, "REPLICAOF", host, port); err != nil {
+		t.Fatalf("failed to replicaof: %v", err)
+	}
+
+	if err := promoted.Set(ctx, "baz", []byte("qux"), 0); err != nil {
+		t.Fatalf("failed to set: %v", err)
+	}
+	// the promoted master no longer follows the old one
+	if err := dialTestClient(t, addr).Set(ctx, "foo", []byte("stale"), 0); err != nil {
+		t.Fatalf("failed to set: %v", err)
+	}
+	replicas[0].mu.Lock()
+	newReplid := replicas[0].Conf.MasterReplid
+	replicas[0].mu.Unlock()
+	waitFor(t, replicas[1], "replica to follow the promoted master", func() bool {
+		return replicas[1].Conf.MasterReplid == newReplid && replicas[1].Storage.Mem["baz"] != nil
+	})
+	replicas[0].mu.Lock()
+	if bucket := replicas[0].Storage.Mem["foo"]; bucket == nil || string(bucket.Value) != "bar" {
+		t.Errorf("expected writes of the old master to stop, actual=%v", bucket)
+	}
+	replicas[0].mu.Unlock()
+
+	replicas[1].mu.Lock()
+	defer replicas[1].mu.Unlock()
+	if _, ok := replicas[1].Storage.Mem["marker"]; !ok {
+		t.Errorf("expected a partial resync with the promoted master")
+	}
+	if replicas[1].Conf.MasterReplid2 != master.Conf.MasterReplid {
+		t.Errorf("expected replid2 %s, actual=%s", master.Conf.MasterReplid, replicas[1].Conf.MasterReplid2)
+	}
+}
+
+func TestCommandHandler_ReadOnlyReplica(t *testing.T) {
+	_, addr := newTestMaster(t)
+	replica := startTestReplica(t, addr)
+	conn := dialTestClient(t, startTestServer(t, replica))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := dialTestClient(t, addr).Set(ctx, "foo", []byte("bar"), 0); err != nil {
+		t.Fatalf("failed to set: %v", err)
+	}
+	waitFor(t, replica, "replica to sync", func() bool {
+		return replica.Storage.Mem["foo"] != nil
+	})
+	_, err := conn.Do(ctx, "SET", "foo", "local")
+	if err == nil || !strings.HasPrefix(err.Error(), "READONLY") {
+		t.Errorf("expected READONLY, actual=%v", err)
+	}
+	rsp, err := conn.Do(ctx, "GET", "foo")
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	var value string
+	if _ = redis.Unmarshal(rsp, &value); value != "bar" {
+		t.Errorf("expected bar, actual=%s", value)
+	}
+
+	replica.mu.Lock()
+	replica.Conf.ReplicaReadOnly = false
+	offset := replica.Conf.MasterReplOffset
+	replica.mu.Unlock()
+	if _, err := conn.Do(ctx, "SET", "local", "1"); err != nil {
+		t.Errorf("expected writes on a writable replica, actual=%v", err)
+	}
+	replica.mu.Lock()
+	defer replica.mu.Unlock()
+	if replica.Storage.Mem["local"] == nil {
+		t.Errorf("expected the local write to apply")
+	} else if replica.Conf.MasterReplOffset != offset {
+		t.Errorf("expected local writes to stay out of the stream, offset %d -> %d", offset, replica.Conf.MasterReplOffset)
+	}
+}
+
+func TestCommandHandler_MasterDown(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	// nothing answers on the master address
+	addr := listener.Addr().String()
+	_ = listener.Close()
+
+	replica := startTestReplica(t, addr)
+	replica.mu.Lock()
+	replica.Conf.ReplicaServeStaleData = false
+	replica.mu.Unlock()
+	conn := dialTestClient(t, startTestServer(t, replica))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tests := []struct {
+		name    string
+		args    []any
+		errHead string
+	}{
+		{name: "get", args: []any{"GET", "foo"}, errHead: "MASTERDOWN"},
+		{name: "set", args: []any{"SET", "foo", "bar"}, errHead: "READONLY"},
+		{name: "ping", args: []any{"PING"}},
+		{name: "info", args: []any{"INFO", "replication"}},
+		{name: "object", args: []any{"OBJECT", "ENCODING", "foo"}},
+		{name: "memory", args: []any{"MEMORY", "USAGE", "foo"}},
+	}
+	for _, test := range tests {
+		_, err := conn.Do(ctx, test.args...)
+		switch {
+		case test.errHead == "" && err != nil:
+			t.Errorf("case %s: unexpected error %v", test.name, err)
+		case test.errHead != "" && (err == nil || !strings.HasPrefix(err.Error(), test.errHead)):
+			t.Errorf("case %s: expected %s, actual=%v", test.name, test.errHead, err)
+		}
+	}
+}
+
+func TestCommandHandler_Replicate_Diskless(t *testing.T) {
+	master, addr := newTestMaster(t)
+	master.Conf.ReplDisklessSync = true
+	master.Conf.ReplDisklessSyncDelay = 200 * time.Millisecond
+	master.replAckPeriod = time.Hour
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn := dialTestClient(t, addr)
+	for i := 0; i < 100; i++ {
+		if err := conn.Set(ctx, fmt.Sprintf("key:%d", i), []byte(fmt.Sprintf("value:%d", i)), 0); err != nil {
+			t.Fatalf("failed to set: %v", err)
+		}
+	}
+
+	host, portStr, _ := net.SplitHostPort(addr)
+	port, _ := strconv.Atoi(portStr)
+	var replicas []*CommandHandler
+	for _, load := range []string{"disabled", "swapdb"} {
+		replica := NewCommandHandler()
+		replica.Conf.Port = 6380
+		replica.Conf.ReplDisklessLoad = load
+		replica.replRetryInterval = 20 * time.Millisecond
+		replicaCtx, replicaCancel := context.WithCancel(context.Background())
+		defer replicaCancel()
+		replica.ReplicaOf(replicaCtx, host, port)
+		replicas = append(replicas, replica)
+	}
+
+	// both replicas wait for the same transfer
+	waitFor(t, master, "replicas to wait for the snapshot", func() bool {
+		waiting := 0
+		for _, info := range master.Conf.Replicas {
+			if info.State == "wait_bgsave" {
+				waiting++
+			}
+		}
+		return waiting == 2
+	})
+	if err := conn.Set(ctx, "late", []byte("1"), 0); err != nil {
+		t.Fatalf("failed to set: %v", err)
+	}
+	if err := conn.Set(ctx, "key:0", []byte("changed"), 0); err != nil {
+		t.Fatalf("failed to set: %v", err)
+	}
+
+	for _, replica := range replicas {
+		replica := replica
+		waitFor(t, replica, "diskless sync", func() bool {
+			bucket := replica.Storage.Mem["key:0"]
+			return replica.Conf.MasterLinkUp && bucket != nil && string(bucket.Value) == "changed"
+		})
+	}
+	if err := conn.Set(ctx, "after", []byte("1"), 0); err != nil {
+		t.Fatalf("failed to set: %v", err)
+	}
+	master.mu.Lock()
+	offset := master.Conf.MasterReplOffset
+	for _, info := range master.Conf.Replicas {
+		if info.State != "online" {
+			t.Errorf("expected replica %s online, actual=%s", info, info.State)
+		}
+	}
+	master.mu.Unlock()
+	for i, replica := range replicas {
+		replica := replica
+		waitFor(t, replica, "the stream after the snapshot", func() bool {
+			return replica.Storage.Mem["after"] != nil
+		})
+		replica.mu.Lock()
+		if len(replica.Storage.Mem) != 102 {
+			t.Errorf("replica %d: expected 102 keys, actual=%d", i, len(replica.Storage.Mem))
+		} else if replica.Conf.MasterReplOffset != offset {
+			t.Errorf("replica %d: expected offset %d, actual=%d", i, offset, replica.Conf.MasterReplOffset)
+		}
+		replica.mu.Unlock()
+	}
+}
+
+func TestCommandHandler_Replicate_Chained(t *testing.T) {
+	master, addr := newTestMaster(t)
+	master.replAckPeriod = 20 * time.Millisecond
+	replica := startTestReplica(t, addr)
+	subReplica := startTestReplica(t, startTestServer(t, replica))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn := dialTestClient(t, addr)
+	if err := conn.Set(ctx, "foo", []byte("bar"), 0); err != nil {
+		t.Fatalf("failed to set: %v", err)
+	}
+	// the stream, GETACKs of the master included, reaches the sub-replica
+	// unchanged
+	waitFor(t, subReplica, "sub-replica to follow the master", func() bool {
+		master.mu.Lock()
+		defer master.mu.Unlock()
+		return subReplica.Conf.MasterReplid == master.Conf.MasterReplid &&
+			subReplica.Conf.MasterReplOffset == master.Conf.MasterReplOffset &&
+			subReplica.Storage.Mem["foo"] != nil
+	})
+	waitFor(t, replica, "sub-replica to ack", func() bool {
+		return len(replica.Conf.Replicas) == 1 && replica.Conf.Replicas[0].AckOffset > 0
+	})
+
+	// the master restarts with another history, the replica resyncs and so
+	// does the sub-replica
+	master.mu.Lock()
+	master.Conf.MasterReplid = model.NewReplid()
+	master.Conf.Backlog = nil
+	master.Storage.Mem = map[string]*model.RedisBucket{
+		"restarted": {Value: []byte("1"), ExpireAt: math.MaxInt64},
+	}
+	master.disconnectReplicas()
+	newReplid := master.Conf.MasterReplid
+	master.mu.Unlock()
+	waitFor(t, subReplica, "sub-replica to resync", func() bool {
+		return subReplica.Conf.MasterReplid == newReplid && subReplica.Storage.Mem["restarted"] != nil
+	})
+	subReplica.mu.Lock()
+	defer subReplica.mu.Unlock()
+	if _, ok := subReplica.Storage.Mem["foo"]; ok {
+		t.Errorf("expected the data set of the previous history to be dropped")
+	}
+}
+
+// TestCommandHandler_Replicate_Databases checks replicas follow the SELECTs
+// of the stream, and that a sub-replica synced in the middle of it starts in
+// the database the stream is in.
+func TestCommandHandler_Replicate_Databases(t *testing.T) {
+	_, addr := newTestMaster(t)
+	replica := startTestReplica(t, addr)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, other := dialTestClient(t, addr), dialTestClient(t, addr)
+	if _, err := conn.Do(ctx, "SELECT", "2"); err != nil {
+		t.Fatalf("failed to select: %v", err)
+	} else if err := conn.Set(ctx, "a", []byte("1"), 0); err != nil {
+		t.Fatalf("failed to set: %v", err)
+	} else if err := other.Set(ctx, "b", []byte("1"), 0); err != nil {
+		t.Fatalf("failed to set: %v", err)
+	} else if err := conn.Set(ctx, "c", []byte("1"), 0); err != nil {
+		t.Fatalf("failed to set: %v", err)
+	}
+	waitFor(t, replica, "replica to apply the writes", func() bool {
+		dbs := replica.Storage.Databases()
+		return dbs[2]["a"] != nil && dbs[0]["b"] != nil && dbs[2]["c"] != nil
+	})
+
+	// the stream stays in db 2, which the snapshot tells the sub-replica
+	subReplica := startTestReplica(t, startTestServer(t, replica))
+	waitFor(t, subReplica, "sub-replica to sync", func() bool {
+		return subReplica.Conf.MasterLinkUp
+	})
+	if err := conn.Set(ctx, "d", []byte("1"), 0); err != nil {
+		t.Fatalf("failed to set: %v", err)
+	}
+	waitFor(t, subReplica, "sub-replica to apply the write", func() bool {
+		return subReplica.Storage.Databases()[2]["d"] != nil
+	})
+	subReplica.mu.Lock()
+	defer subReplica.mu.Unlock()
+	if dbs := subReplica.Storage.Databases(); len(dbs[0]) != 1 || len(dbs[2]) != 3 {
+		t.Errorf("expected b in db 0 and a, c, d in db 2, actual=%v", dbs)
+	}
+}
+
+// TestCommandHandler_Replicate_AppendOnly checks the AOF of a replica
+// restarts from the data set of a full resync, not the one it replaced.
+func TestCommandHandler_Replicate_AppendOnly(t *testing.T) {
+	_, addr := newTestMaster(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn := dialTestClient(t, addr)
+	if err := conn.Set(ctx, "key", []byte("1"), 0); err != nil {
+		t.Fatalf("failed to set: %v", err)
+	}
+
+	dir := t.TempDir()
+	replica := NewCommandHandler()
+	replica.Conf.Dir = dir
+	replica.Conf.AppendOnly = true
+	replica.Conf.AppendFsync = aof.FsyncAlways
+	replica.replRetryInterval = 20 * time.Millisecond
+	if err := replica.OpenAppendOnly(ctx); err != nil {
+		t.Fatalf("failed to open: %v", err)
+	}
+	if err := dialTestClient(t, startTestServer(t, replica)).Set(ctx, "stale", []byte("1"), 0); err != nil {
+		t.Fatalf("failed to set: %v", err)
+	}
+	host, portStr, _ := net.SplitHostPort(addr)
+	port, _ := strconv.Atoi(portStr)
+	replicaCtx, replicaCancel := context.WithCancel(context.Background())
+	defer replicaCancel()
+	replica.ReplicaOf(replicaCtx, host, port)
+	waitFor(t, replica, "full resync", func() bool {
+		return replica.Conf.MasterLinkUp
+	})
+
+	// the stream goes on in another database than the new base
+	if _, err := conn.Do(ctx, "SELECT", "1"); err != nil {
+		t.Fatalf("failed to select: %v", err)
+	} else if err := conn.Set(ctx, "late", []byte("1"), 0); err != nil {
+		t.Fatalf("failed to set: %v", err)
+	}
+	waitFor(t, replica, "replica to apply the write and rewrite its aof", func() bool {
+		return replica.Storage.Databases()[1]["late"] != nil && !replica.Conf.AOFRewriteInProgress
+	})
+	replica.mu.Lock()
+	ok := replica.Conf.AOFLastBgrewriteOK
+	replica.mu.Unlock()
+	if !ok {
+		t.Fatalf("expected the aof rewrite to succeed")
+	}
+
+	restarted := NewCommandHandler()
+	restarted.Conf.Dir = dir
+	if loaded, err := restarted.LoadAppendOnly(); err != nil || !loaded {
+		t.Fatalf("failed to load: %v", err)
+	}
+	if dbs := restarted.Storage.Databases(); len(dbs[0]) != 1 || dbs[0]["key"] == nil || len(dbs[1]) != 1 || dbs[1]["late"] == nil {
+		t.Errorf("expected key in db 0 and late in db 1, actual=%v", dbs)
+	}
+}
+
+func TestCommandHandler_NoMasterLink(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	addr := listener.Addr().String()
+	_ = listener.Close()
+
+	replica := startTestReplica(t, addr)
+	conn := dialTestClient(t, startTestServer(t, replica))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = conn.Do(ctx, "PSYNC", "?", "-1")
+	if err == nil || !strings.HasPrefix(err.Error(), "NOMASTERLINK") {
+		t.Errorf("expected NOMASTERLINK, actual=%v", err)
+	}
+}
@@ -0,0 +1,258 @@
+package aof
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+	"github.com/codecrafters-io/redis-starter-go/src/model/cmd"
+	"github.com/codecrafters-io/redis-starter-go/src/model/rdb"
+	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
+)
+
+const (
+	FsyncAlways   = "always"
+	FsyncEverySec = "everysec"
+	FsyncNo       = "no"
+)
+
+// Writer appends write commands, encoded in RESP, to the append-only file.
+type Writer struct {
+	file  *os.File
+	fsync string
+}
+
+// Open opens path for appending, creating it if needed. fsync is one of
+// FsyncAlways, FsyncEverySec and FsyncNo; with FsyncEverySec the caller
+// runs Sync once a second.
+func Open(path string, fsync string) (*Writer, error) {
+	switch fsync {
+	case FsyncAlways, FsyncEverySec, FsyncNo:
+	default:
+		return nil, fmt.Errorf("unknown fsync policy %q", fsync)
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &Writer{file: file, fsync: fsync}, nil
+}
+
+func (w *Writer) Fsync() string {
+	return w.fsync
+}
+
+// Append writes one command, and syncs it to disk before returning under
+// FsyncAlways.
+func (w *Writer) Append(p []byte) error {
+	if _, err := w.file.Write(p); err != nil {
+		return err
+	}
+	if w.fsync == FsyncAlways {
+		return w.file.Sync()
+	}
+	return nil
+}
+
+// Sync flushes the appended commands to disk, it may run along with
+// Append.
+func (w *Writer) Sync() error {
+	return w.file.Sync()
+}
+
+func (w *Writer) Close() error {
+	return w.file.Close()
+}
+
+// TruncatedError means the file ends in the middle of a command, e.g. when
+// the server stopped while appending it. Offset is where the last complete
+// command ends.
+type TruncatedError struct {
+	Offset int64
+}
+
+func (e *TruncatedError) Error() string {
+	return fmt.Sprintf("aof truncated after offset %d", e.Offset)
+}
+
+// CorruptError means the command after Offset cannot be read.
+type CorruptError struct {
+	Offset int64
+	Err    error
+}
+
+func (e *CorruptError) Error() string {
+	return fmt.Sprintf("bad command after offset %d: %v", e.Offset, e.Err)
+}
+
+func (e *CorruptError) Unwrap() error {
+	return e.Err
+}
+
+// Load reads the commands of an append-only file and hands each to apply.
+// A file starting with an RDB preamble, as a base written by a rewrite may,
+// hands the snapshot to load first. A malformed command gives a
+// CorruptError, a partial last command a TruncatedError.
+func Load(reader io.Reader, load func(snapshot *rdb.Snapshot) error, apply func(args *redis.Array) error) error {
+	var (
+		counter  = &countingReader{reader: reader}
+		buffered = bufio.NewReader(counter)
+		valid    int64
+	)
+	if magic, _ := buffered.Peek(len(rdb.Magic)); string(magic) == rdb.Magic {
+		snapshot, err := rdb.Load(buffered)
+		if err != nil {
+			return fmt.Errorf("bad rdb preamble: %w", err)
+		} else if err := load(snapshot); err != nil {
+			return fmt.Errorf("failed to apply rdb preamble: %w", err)
+		}
+		valid = counter.n - int64(buffered.Buffered())
+	}
+	for {
+		args, err := cmd.ReadArgs(buffered)
+		consumed := counter.n - int64(buffered.Buffered())
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			if consumed == valid {
+				return nil
+			}
+			return &TruncatedError{Offset: valid}
+		} else if err != nil {
+			return &CorruptError{Offset: valid, Err: err}
+		}
+		if err := apply(args); err != nil {
+			return fmt.Errorf("failed to apply command after offset %d: %w", valid, err)
+		}
+		valid = consumed
+	}
+}
+
+// Dump writes storage as the commands which rebuild it, after a SELECT of
+// each database but the first: a SET per string with the expire time as an
+// absolute PXAT, a RESTORE ABSTTL of its DUMP payload per key of another
+// type. Expired keys are left out.
+func Dump(writer io.Writer, storage *model.RedisStorage) error {
+	var (
+		now = time.Now().UnixMilli()
+		buf []byte
+	)
+	for db, mem := range storage.Databases() {
+		keys := make([]string, 0, len(mem))
+		for key, bucket := range mem {
+			if bucket.ExpireAt >= now {
+				keys = append(keys, key)
+			}
+		}
+		if len(keys) == 0 {
+			continue
+		}
+		sort.Strings(keys)
+		if db != 0 {
+			buf = SelectCommand(db).Append(buf[:0])
+			if _, err := writer.Write(buf); err != nil {
+				return err
+			}
+		}
+
+		for _, key := range keys {
+			bucket := mem[key]
+			if bucket.Type != model.TypeString {
+				buf = restoreCommand(key, bucket).Append(buf[:0])
+				if _, err := writer.Write(buf); err != nil {
+					return err
+				}
+				continue
+			}
+			args := []redis.RedisObject{
+				redis.NewBulkString([]byte("SET")),
+				redis.NewBulkString([]byte(key)),
+				redis.NewBulkString(bucket.Value),
+			}
+			if bucket.ExpireAt != math.MaxInt64 {
+				args = append(args,
+					redis.NewBulkString([]byte("PXAT")),
+					redis.NewBulkString([]byte(strconv.FormatInt(bucket.ExpireAt, 10))),
+				)
+			}
+			buf = redis.NewArray(args...).Append(buf[:0])
+			if _, err := writer.Write(buf); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// restoreCommand returns the RESTORE which rebuilds a key of any type,
+// with 0 for no expire time.
+func restoreCommand(key string, bucket *model.RedisBucket) *redis.Array {
+	expireAt := int64(0)
+	if bucket.ExpireAt != math.MaxInt64 {
+		expireAt = bucket.ExpireAt
+	}
+	return redis.NewArray(
+		redis.NewBulkString([]byte("RESTORE")),
+		redis.NewBulkString([]byte(key)),
+		redis.NewBulkString([]byte(strconv.FormatInt(expireAt, 10))),
+		redis.NewBulkString(rdb.DumpValue(bucket)),
+		redis.NewBulkString([]byte("ABSTTL")),
+	)
+}
+
+// SelectCommand returns the SELECT of db which precedes the commands of
+// another database than the previous ones.
+func SelectCommand(db int) *redis.Array {
+	return redis.NewArray(
+		redis.NewBulkString([]byte("SELECT")),
+		redis.NewBulkString([]byte(strconv.Itoa(db))),
+	)
+}
+
+// SaveFile dumps storage to path as commands. Like rdb.SaveFile it writes
+// a temporary file and renames it over path once synced.
+func SaveFile(path string, storage *model.RedisStorage) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), fmt.Sprintf("temp-%d-*.aof", os.Getpid()))
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	writer := bufio.NewWriter(tmp)
+	if err = Dump(writer, storage); err != nil {
+		return fmt.Errorf("failed to write commands: %w", err)
+	} else if err = writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write commands: %w", err)
+	} else if err = tmp.Sync(); err != nil {
+		return fmt.Errorf("failed to sync commands: %w", err)
+	} else if err = tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close commands: %w", err)
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to rename commands: %w", err)
+	}
+	return nil
+}
+
+// countingReader counts the bytes read.
+type countingReader struct {
+	reader io.Reader
+	n      int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.n += int64(n)
+	return n, err
+}
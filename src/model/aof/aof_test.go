@@ -0,0 +1,236 @@
+package aof
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+	"github.com/codecrafters-io/redis-starter-go/src/model/cmd"
+	"github.com/codecrafters-io/redis-starter-go/src/model/rdb"
+	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
+)
+
+const (
+	setFoo = "*3\r\n$3\r\nSET\r\n$3\r\nfoo\r\n$3\r\nbar\r\n"
+	setBaz = "*5\r\n$3\r\nSET\r\n$3\r\nbaz\r\n$1\r\n1\r\n$4\r\nPXAT\r\n$13\r\n1700000000000\r\n"
+)
+
+func TestLoad(t *testing.T) {
+	var preamble bytes.Buffer
+	if err := rdb.Dump(&preamble, &model.RedisStorage{Mem: map[string]*model.RedisBucket{
+		"pre": {Value: []byte("1"), ExpireAt: math.MaxInt64},
+	}}); err != nil {
+		t.Fatalf("failed to dump: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		input     string
+		commands  []string
+		snapshot  []string
+		truncated int64
+		corrupt   int64
+		isError   bool
+	}{
+		{
+			name:  "empty",
+			input: "",
+		},
+		{
+			name:     "complete",
+			input:    setFoo + setBaz,
+			commands: []string{"SET foo bar", "SET baz 1 PXAT 1700000000000"},
+		},
+		{
+			name:      "truncated in a bulk string",
+			input:     setFoo + setBaz[:20],
+			commands:  []string{"SET foo bar"},
+			truncated: int64(len(setFoo)),
+		},
+		{
+			name:      "truncated in the array header",
+			input:     setFoo + "*3\r",
+			commands:  []string{"SET foo bar"},
+			truncated: int64(len(setFoo)),
+		},
+		{
+			name:      "truncated in a bulk string header",
+			input:     setFoo + "*3\r\n$3\r",
+			commands:  []string{"SET foo bar"},
+			truncated: int64(len(setFoo)),
+		},
+		{
+			name:      "truncated after the array header",
+			input:     setFoo + "*3\r\n",
+			commands:  []string{"SET foo bar"},
+			truncated: int64(len(setFoo)),
+		},
+		{
+			name:     "rdb preamble",
+			input:    preamble.String() + setFoo,
+			commands: []string{"SET foo bar"},
+			snapshot: []string{"pre"},
+		},
+		{
+			name:      "rdb preamble then truncated",
+			input:     preamble.String() + setFoo[:10],
+			snapshot:  []string{"pre"},
+			truncated: int64(preamble.Len()),
+		},
+		{
+			name:    "corrupt rdb preamble",
+			input:   preamble.String()[:preamble.Len()-1] + "x",
+			isError: true,
+		},
+		{
+			name:     "garbage",
+			input:    setFoo + "hello\r\n",
+			commands: []string{"SET foo bar"},
+			corrupt:  int64(len(setFoo)),
+			isError:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		var commands, snapshot []string
+		err := Load(strings.NewReader(tt.input), func(s *rdb.Snapshot) error {
+			for key := range s.DB(0) {
+				snapshot = append(snapshot, key)
+			}
+			return nil
+		}, func(args *redis.Array) error {
+			fields := make([]string, 0, args.Len())
+			for i := 0; i < args.Len(); i++ {
+				var field string
+				_ = redis.Unmarshal(args.Get(i), &field)
+				fields = append(fields, field)
+			}
+			commands = append(commands, strings.Join(fields, " "))
+			return nil
+		})
+
+		var truncated *TruncatedError
+		switch {
+		case tt.isError:
+			var corrupt *CorruptError
+			if err == nil || errors.As(err, &truncated) {
+				t.Errorf("case %s: expected a load error, actual=%v", tt.name, err)
+			} else if tt.corrupt > 0 && (!errors.As(err, &corrupt) || corrupt.Offset != tt.corrupt) {
+				t.Errorf("case %s: expected corrupt after %d, actual=%v", tt.name, tt.corrupt, err)
+			}
+		case tt.truncated > 0:
+			if !errors.As(err, &truncated) || truncated.Offset != tt.truncated {
+				t.Errorf("case %s: expected truncated at %d, actual=%v", tt.name, tt.truncated, err)
+			}
+		case err != nil:
+			t.Errorf("case %s: unexpected error: %v", tt.name, err)
+		}
+		if strings.Join(commands, ",") != strings.Join(tt.commands, ",") {
+			t.Errorf("case %s: expected commands %q, actual=%q", tt.name, tt.commands, commands)
+		}
+		if !tt.isError && strings.Join(snapshot, ",") != strings.Join(tt.snapshot, ",") {
+			t.Errorf("case %s: expected snapshot keys %q, actual=%q", tt.name, tt.snapshot, snapshot)
+		}
+	}
+}
+
+func TestSaveFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appendonly.aof.1.base.aof")
+	storage := &model.RedisStorage{Mem: map[string]*model.RedisBucket{
+		"foo":     {Value: []byte("bar"), ExpireAt: math.MaxInt64},
+		"baz":     {Value: []byte("1"), ExpireAt: math.MaxInt64 - 1},
+		"expired": {Value: []byte("x"), ExpireAt: 1},
+	}}
+	if err := SaveFile(path, storage); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+	expected := "*5\r\n$3\r\nSET\r\n$3\r\nbaz\r\n$1\r\n1\r\n$4\r\nPXAT\r\n$19\r\n9223372036854775806\r\n" + setFoo
+	if string(content) != expected {
+		t.Errorf("expected the keys as commands in order, actual=%q", content)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(filepath.Dir(path), "temp-*")); len(matches) > 0 {
+		t.Errorf("expected no temp file left, actual=%v", matches)
+	}
+}
+
+func TestDump_Databases(t *testing.T) {
+	storage := model.NewRedisStorage(3)
+	storage.DBs[0]["foo"] = &model.RedisBucket{Value: []byte("bar"), ExpireAt: math.MaxInt64}
+	storage.DBs[2]["foo"] = &model.RedisBucket{Value: []byte("bar"), ExpireAt: math.MaxInt64}
+	var buf bytes.Buffer
+	if err := Dump(&buf, storage); err != nil {
+		t.Fatalf("failed to dump: %v", err)
+	}
+	expected := setFoo + "*2\r\n$6\r\nSELECT\r\n$1\r\n2\r\n" + setFoo
+	if buf.String() != expected {
+		t.Errorf("expected=%q, actual=%q", expected, buf.String())
+	}
+}
+
+func TestDump_Types(t *testing.T) {
+	expireAt := time.Now().Add(time.Hour).UnixMilli()
+	storage := model.NewRedisStorage(1)
+	storage.Mem["foo"] = &model.RedisBucket{Value: []byte("bar"), ExpireAt: math.MaxInt64}
+	storage.Mem["list"] = &model.RedisBucket{Type: model.TypeList, List: [][]byte{[]byte("a"), []byte("b")}, ExpireAt: expireAt}
+	storage.Mem["hash"] = &model.RedisBucket{Type: model.TypeHash, Hash: map[string][]byte{"f": []byte("v")}, ExpireAt: math.MaxInt64}
+	var buf bytes.Buffer
+	if err := Dump(&buf, storage); err != nil {
+		t.Fatalf("failed to dump: %v", err)
+	}
+
+	replayed := model.NewRedisStorage(1)
+	if err := Load(&buf, nil, func(args *redis.Array) error {
+		command, err := cmd.ParseCommand(args)
+		if err != nil {
+			return err
+		}
+		_, err = command.Execute(io.Discard, replayed, &model.CommandConf{})
+		return err
+	}); err != nil {
+		t.Fatalf("failed to replay: %v", err)
+	}
+	for key, expected := range storage.Mem {
+		actual := replayed.Mem[key]
+		if actual == nil || actual.Type != expected.Type || actual.ExpireAt != expected.ExpireAt || actual.Len() != expected.Len() {
+			t.Errorf("expected %s=%+v, actual=%+v", key, expected, actual)
+		}
+	}
+}
+
+func TestWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+	if _, err := Open(path, "sometimes"); err == nil {
+		t.Errorf("expected an unknown policy to fail")
+	}
+	for _, fsync := range []string{FsyncAlways, FsyncEverySec, FsyncNo} {
+		writer, err := Open(path, fsync)
+		if err != nil {
+			t.Fatalf("failed to open with %s: %v", fsync, err)
+		}
+		if err := writer.Append([]byte(setFoo)); err != nil {
+			t.Errorf("failed to append with %s: %v", fsync, err)
+		} else if err := writer.Sync(); err != nil {
+			t.Errorf("failed to sync with %s: %v", fsync, err)
+		}
+		_ = writer.Close()
+	}
+
+	// every open appends to what is there
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	} else if string(content) != strings.Repeat(setFoo, 3) {
+		t.Errorf("unexpected content %q", content)
+	}
+}
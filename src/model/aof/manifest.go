@@ -0,0 +1,211 @@
+package aof
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	TypeBase    = "b"
+	TypeIncr    = "i"
+	TypeHistory = "h"
+)
+
+// ManifestFile is one file of a multi-part AOF.
+type ManifestFile struct {
+	Name string
+	Seq  int64
+	Type string
+}
+
+// Manifest lists the files of a multi-part AOF as Redis 7 does: the base
+// holds the data set as of the last rewrite, the incremental files the
+// writes since, in order. History files are left over by a rewrite and
+// only wait to be deleted.
+type Manifest struct {
+	Base    *ManifestFile
+	Incrs   []*ManifestFile
+	History []*ManifestFile
+}
+
+// ManifestName returns the name of the manifest of the AOF called name.
+func ManifestName(name string) string {
+	return name + ".manifest"
+}
+
+// BaseName returns the name of a base file, ending in .rdb when it is an
+// RDB snapshot.
+func BaseName(name string, seq int64, rdbPreamble bool) string {
+	if rdbPreamble {
+		return fmt.Sprintf("%s.%d.base.rdb", name, seq)
+	}
+	return fmt.Sprintf("%s.%d.base.aof", name, seq)
+}
+
+func IncrName(name string, seq int64) string {
+	return fmt.Sprintf("%s.%d.incr.aof", name, seq)
+}
+
+// Files returns the base and the incremental files, in the order to load.
+func (m *Manifest) Files() []*ManifestFile {
+	files := make([]*ManifestFile, 0, len(m.Incrs)+1)
+	if m.Base != nil {
+		files = append(files, m.Base)
+	}
+	return append(files, m.Incrs...)
+}
+
+// LastIncr returns the file new writes go to, nil when there is none.
+func (m *Manifest) LastIncr() *ManifestFile {
+	if len(m.Incrs) == 0 {
+		return nil
+	}
+	return m.Incrs[len(m.Incrs)-1]
+}
+
+// NextIncrSeq returns the sequence of the next incremental file.
+func (m *Manifest) NextIncrSeq() int64 {
+	if last := m.LastIncr(); last != nil {
+		return last.Seq + 1
+	}
+	return 1
+}
+
+// NextBaseSeq returns the sequence of the next base file.
+func (m *Manifest) NextBaseSeq() int64 {
+	if m.Base != nil {
+		return m.Base.Seq + 1
+	}
+	return 1
+}
+
+func (m *Manifest) Encode() []byte {
+	var buf bytes.Buffer
+	for _, file := range append(m.Files(), m.History...) {
+		fmt.Fprintf(&buf, "file %s seq %d type %s\n", file.Name, file.Seq, file.Type)
+	}
+	return buf.Bytes()
+}
+
+// ParseManifest reads lines of "file <name> seq <seq> type <type>", where
+// the keys may come in any order and # starts a comment.
+func ParseManifest(reader io.Reader) (*Manifest, error) {
+	var (
+		manifest = &Manifest{}
+		scanner  = bufio.NewScanner(reader)
+		line     = 0
+	)
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields)%2 != 0 {
+			return nil, fmt.Errorf("invalid manifest line %d: %q", line, text)
+		}
+		file := &ManifestFile{Seq: -1}
+		for i := 0; i < len(fields); i += 2 {
+			switch fields[i] {
+			case "file":
+				file.Name = fields[i+1]
+			case "seq":
+				seq, err := strconv.ParseInt(fields[i+1], 10, 64)
+				if err != nil || seq < 0 {
+					return nil, fmt.Errorf("invalid seq on manifest line %d: %q", line, text)
+				}
+				file.Seq = seq
+			case "type":
+				file.Type = fields[i+1]
+			}
+		}
+		if file.Name == "" || file.Seq < 0 || strings.ContainsAny(file.Name, `/\`) {
+			return nil, fmt.Errorf("invalid manifest line %d: %q", line, text)
+		}
+
+		switch file.Type {
+		case TypeBase:
+			if manifest.Base != nil {
+				return nil, fmt.Errorf("more than one base file on manifest line %d", line)
+			}
+			manifest.Base = file
+		case TypeIncr:
+			if last := manifest.LastIncr(); last != nil && file.Seq <= last.Seq {
+				return nil, fmt.Errorf("incr files out of order on manifest line %d", line)
+			}
+			manifest.Incrs = append(manifest.Incrs, file)
+		case TypeHistory:
+			manifest.History = append(manifest.History, file)
+		default:
+			return nil, fmt.Errorf("unknown file type on manifest line %d: %q", line, text)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if manifest.Base == nil && len(manifest.Incrs) == 0 {
+		return nil, errors.New("manifest lists no file")
+	}
+	return manifest, nil
+}
+
+// ReadManifest reads the manifest of the AOF called name in dir, errors
+// wrap os.ErrNotExist when there is none.
+func ReadManifest(dir, name string) (*Manifest, error) {
+	file, err := os.Open(filepath.Join(dir, ManifestName(name)))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParseManifest(file)
+}
+
+// WriteManifest replaces the manifest atomically: a crash leaves either
+// the previous manifest or this one, never a mix.
+func WriteManifest(dir, name string, manifest *Manifest) error {
+	return WriteFileAtomic(filepath.Join(dir, ManifestName(name)), manifest.Encode())
+}
+
+// WriteFileAtomic writes a temporary file next to path, syncs it and
+// renames it over path.
+func WriteFileAtomic(path string, content []byte) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "temp-"+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+	if _, err = tmp.Write(content); err != nil {
+		return err
+	} else if err = tmp.Sync(); err != nil {
+		return err
+	} else if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	return SyncDir(filepath.Dir(path))
+}
+
+// SyncDir makes the renames in dir durable.
+func SyncDir(dir string) error {
+	file, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return file.Sync()
+}
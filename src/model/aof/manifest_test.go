@@ -0,0 +1,114 @@
+package aof
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseManifest(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		output  string
+		isError bool
+	}{
+		{
+			name:   "base and incrs",
+			input:  "file appendonly.aof.1.base.rdb seq 1 type b\nfile appendonly.aof.1.incr.aof seq 1 type i\nfile appendonly.aof.2.incr.aof seq 2 type i\n",
+			output: "file appendonly.aof.1.base.rdb seq 1 type b\nfile appendonly.aof.1.incr.aof seq 1 type i\nfile appendonly.aof.2.incr.aof seq 2 type i\n",
+		},
+		{
+			name:   "keys in any order, comments and history",
+			input:  "# written by a rewrite\nseq 3 type i file appendonly.aof.3.incr.aof\nfile appendonly.aof.2.incr.aof seq 2 type h\ntype b file appendonly.aof.2.base.aof seq 2\n",
+			output: "file appendonly.aof.2.base.aof seq 2 type b\nfile appendonly.aof.3.incr.aof seq 3 type i\nfile appendonly.aof.2.incr.aof seq 2 type h\n",
+		},
+		{
+			name:   "incrs without base",
+			input:  "file appendonly.aof.1.incr.aof seq 1 type i\n",
+			output: "file appendonly.aof.1.incr.aof seq 1 type i\n",
+		},
+		{
+			name:    "empty",
+			input:   "\n",
+			isError: true,
+		},
+		{
+			name:    "two bases",
+			input:   "file a seq 1 type b\nfile b seq 2 type b\n",
+			isError: true,
+		},
+		{
+			name:    "incrs out of order",
+			input:   "file a seq 2 type i\nfile b seq 1 type i\n",
+			isError: true,
+		},
+		{
+			name:    "unknown type",
+			input:   "file a seq 1 type x\n",
+			isError: true,
+		},
+		{
+			name:    "bad seq",
+			input:   "file a seq one type i\n",
+			isError: true,
+		},
+		{
+			name:    "missing value",
+			input:   "file a seq 1 type\n",
+			isError: true,
+		},
+		{
+			name:    "path in file name",
+			input:   "file ../a seq 1 type i\n",
+			isError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		manifest, err := ParseManifest(strings.NewReader(tt.input))
+		if tt.isError {
+			if err == nil {
+				t.Errorf("case %s: expected error but got nil", tt.name)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("case %s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if actual := string(manifest.Encode()); actual != tt.output {
+			t.Errorf("case %s: expected %q but got %q", tt.name, tt.output, actual)
+		}
+	}
+}
+
+func TestWriteManifest(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := ReadManifest(dir, "appendonly.aof"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected no manifest, actual=%v", err)
+	}
+
+	manifest := &Manifest{
+		Base: &ManifestFile{Name: BaseName("appendonly.aof", 1, true), Seq: 1, Type: TypeBase},
+	}
+	manifest.Incrs = append(manifest.Incrs, &ManifestFile{
+		Name: IncrName("appendonly.aof", manifest.NextIncrSeq()), Seq: manifest.NextIncrSeq(), Type: TypeIncr,
+	})
+	if err := WriteManifest(dir, "appendonly.aof", manifest); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+	read, err := ReadManifest(dir, "appendonly.aof")
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+	expected := "file appendonly.aof.1.base.rdb seq 1 type b\nfile appendonly.aof.1.incr.aof seq 1 type i\n"
+	if actual := string(read.Encode()); actual != expected {
+		t.Errorf("expected %q but got %q", expected, actual)
+	} else if read.NextBaseSeq() != 2 || read.NextIncrSeq() != 2 {
+		t.Errorf("unexpected next seqs %d, %d", read.NextBaseSeq(), read.NextIncrSeq())
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("expected only the manifest in dir, actual=%v", entries)
+	}
+}
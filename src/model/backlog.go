@@ -0,0 +1,83 @@
+package model
+
+const (
+	DefaultReplBacklogSize = 1 << 20
+)
+
+// ReplBacklog keeps the tail of the replication stream in a circular
+// buffer, so a replica which reconnects can continue from its offset.
+type ReplBacklog struct {
+	buf     []byte
+	start   int
+	histlen int
+	// offset is the replication offset of the oldest byte kept
+	offset int64
+}
+
+// NewReplBacklog creates a backlog whose next byte has the replication
+// offset next, i.e. master_repl_offset+1.
+func NewReplBacklog(size int, next int64) *ReplBacklog {
+	if size <= 0 {
+		size = DefaultReplBacklogSize
+	}
+	return &ReplBacklog{
+		buf:    make([]byte, size),
+		offset: next,
+	}
+}
+
+func (b *ReplBacklog) Size() int {
+	return len(b.buf)
+}
+
+// Len returns how many bytes are kept.
+func (b *ReplBacklog) Len() int {
+	return b.histlen
+}
+
+// Offset returns the replication offset of the oldest byte kept.
+func (b *ReplBacklog) Offset() int64 {
+	return b.offset
+}
+
+// Write appends p, dropping the oldest bytes once the backlog is full.
+func (b *ReplBacklog) Write(p []byte) (int, error) {
+	n := len(p)
+	if n >= len(b.buf) {
+		// only the tail fits
+		b.offset += int64(b.histlen + n - len(b.buf))
+		copy(b.buf, p[n-len(b.buf):])
+		b.start, b.histlen = 0, len(b.buf)
+		return n, nil
+	}
+	end := (b.start + b.histlen) % len(b.buf)
+	copied := copy(b.buf[end:], p)
+	copy(b.buf, p[copied:])
+	if overflow := b.histlen + n - len(b.buf); overflow > 0 {
+		b.start = (b.start + overflow) % len(b.buf)
+		b.offset += int64(overflow)
+		b.histlen = len(b.buf)
+	} else {
+		b.histlen += n
+	}
+	return n, nil
+}
+
+// Contains tells if the stream can continue from offset, which may also be
+// the offset right after the newest byte.
+func (b *ReplBacklog) Contains(offset int64) bool {
+	return offset >= b.offset && offset <= b.offset+int64(b.histlen)
+}
+
+// Since copies the bytes from offset up to the newest one.
+func (b *ReplBacklog) Since(offset int64) []byte {
+	if !b.Contains(offset) {
+		return nil
+	}
+	skip := int(offset - b.offset)
+	out := make([]byte, b.histlen-skip)
+	from := (b.start + skip) % len(b.buf)
+	copied := copy(out, b.buf[from:])
+	copy(out[copied:], b.buf)
+	return out
+}
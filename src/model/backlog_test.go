@@ -0,0 +1,81 @@
+package model
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestReplBacklog(t *testing.T) {
+	tests := []struct {
+		name    string
+		size    int
+		writes  []string
+		offset  int64
+		since   int64
+		output  string
+		isError bool
+	}{
+		{
+			name:   "empty",
+			size:   8,
+			offset: 1,
+			since:  1,
+			output: "",
+		},
+		{
+			name:   "partial",
+			size:   8,
+			writes: []string{"abc", "de"},
+			offset: 1,
+			since:  3,
+			output: "cde",
+		},
+		{
+			name:   "wrapped",
+			size:   8,
+			writes: []string{"abcdef", "ghij"},
+			offset: 3,
+			since:  4,
+			output: "defghij",
+		},
+		{
+			name:   "larger than the backlog",
+			size:   4,
+			writes: []string{"ab", "cdefgh"},
+			offset: 5,
+			since:  5,
+			output: "efgh",
+		},
+		{
+			name:    "offset dropped",
+			size:    4,
+			writes:  []string{"abcdef"},
+			offset:  3,
+			since:   2,
+			isError: true,
+		},
+		{
+			name:    "offset in the future",
+			size:    8,
+			writes:  []string{"abc"},
+			offset:  1,
+			since:   5,
+			isError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		backlog := NewReplBacklog(tt.size, 1)
+		for _, w := range tt.writes {
+			_, _ = backlog.Write([]byte(w))
+		}
+		if backlog.Offset() != tt.offset {
+			t.Errorf("case %s: expected offset %d but got %d", tt.name, tt.offset, backlog.Offset())
+		}
+		if contains := backlog.Contains(tt.since); contains == tt.isError {
+			t.Errorf("case %s: expected contains %d to be %v", tt.name, tt.since, !tt.isError)
+		} else if actual := backlog.Since(tt.since); !tt.isError && !bytes.Equal(actual, []byte(tt.output)) {
+			t.Errorf("case %s: expected %q but got %q", tt.name, tt.output, actual)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/codecrafters-io/redis-starter-go/src/concept"
+	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
+)
+
+// argString reads a string argument, what names it in errors.
+func argString(arg redis.RedisObject, what string) (string, error) {
+	switch arg := arg.(type) {
+	case concept.AsString:
+		return arg.AsString(), nil
+	}
+	return "", &redis.SyntaxError{
+		Msg: fmt.Sprintf("unexpected argument type %T as %s", arg, what),
+	}
+}
+
+// argBytes reads a binary safe argument, what names it in errors.
+func argBytes(arg redis.RedisObject, what string) ([]byte, error) {
+	switch arg := arg.(type) {
+	case concept.AsBytes:
+		return arg.AsBytes(), nil
+	case concept.AsString:
+		return []byte(arg.AsString()), nil
+	}
+	return nil, &redis.SyntaxError{
+		Msg: fmt.Sprintf("unexpected argument type %T as %s", arg, what),
+	}
+}
+
+// argInt64 reads an integer argument, what names it in errors.
+func argInt64(arg redis.RedisObject, what string) (int64, error) {
+	switch arg := arg.(type) {
+	case concept.AsInt64:
+		return arg.AsInt64(), nil
+	case concept.AsString:
+		if n, err := strconv.ParseInt(arg.AsString(), 10, 64); err == nil {
+			return n, nil
+		}
+		return 0, &redis.SyntaxError{
+			Msg: fmt.Sprintf("value is not an integer as %s: %s", what, arg.AsString()),
+		}
+	}
+	return 0, &redis.SyntaxError{
+		Msg: fmt.Sprintf("unexpected argument type %T as %s", arg, what),
+	}
+}
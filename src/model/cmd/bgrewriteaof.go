@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"io"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
+)
+
+var (
+	_ Command = &Bgrewriteaof{}
+)
+
+func init() {
+	commandNameToBuilder[(&Bgrewriteaof{}).Name()] = func() Command {
+		return &Bgrewriteaof{}
+	}
+}
+
+var (
+	bgrewriteaofStarted       = redis.NewSimpleString("Background append only file rewriting started")
+	bgrewriteaofInProgressErr = redis.NewSimpleError("ERR Background append only file rewriting already in progress")
+	bgrewriteaofDisabledErr   = redis.NewSimpleError("ERR Append only file is disabled")
+)
+
+// Bgrewriteaof compacts the AOF in the background into a new base file.
+// Like Bgsave, Execute only marks the rewrite as started and the
+// connection handler does it when Started is true.
+type Bgrewriteaof struct {
+	started bool
+}
+
+func (*Bgrewriteaof) Name() string {
+	return "BGREWRITEAOF"
+}
+
+func (b *Bgrewriteaof) String() string {
+	return b.Name()
+}
+
+func (b *Bgrewriteaof) Started() bool {
+	return b.started
+}
+
+func (b *Bgrewriteaof) Execute(writer io.Writer, storage *model.RedisStorage, conf *model.CommandConf) (redis.RedisObject, error) {
+	b.started = false
+	if !conf.AppendOnly {
+		return bgrewriteaofDisabledErr, redis.WriteObject(writer, bgrewriteaofDisabledErr)
+	} else if conf.AOFRewriteInProgress {
+		return bgrewriteaofInProgressErr, redis.WriteObject(writer, bgrewriteaofInProgressErr)
+	}
+	conf.AOFRewriteInProgress = true
+	b.started = true
+
+	rsp := bgrewriteaofStarted
+	return rsp, redis.WriteObject(writer, rsp)
+}
+
+func (b *Bgrewriteaof) Read(args *redis.Array) error {
+	if args == nil || args.Len() != 1 {
+		return &redis.SyntaxError{
+			Msg: "wrong number of arguments",
+		}
+	}
+	return nil
+}
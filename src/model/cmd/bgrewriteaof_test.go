@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+)
+
+func TestBgrewriteaof_Execute(t *testing.T) {
+	tests := []struct {
+		name       string
+		conf       *model.CommandConf
+		output     string
+		started    bool
+		inProgress bool
+	}{
+		{
+			name:       "start",
+			conf:       &model.CommandConf{AppendOnly: true},
+			output:     "+Background append only file rewriting started\r\n",
+			started:    true,
+			inProgress: true,
+		},
+		{
+			name:       "already in progress",
+			conf:       &model.CommandConf{AppendOnly: true, AOFRewriteInProgress: true},
+			output:     "-ERR Background append only file rewriting already in progress\r\n",
+			inProgress: true,
+		},
+		{
+			name:   "append only off",
+			conf:   &model.CommandConf{},
+			output: "-ERR Append only file is disabled\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writer := &strings.Builder{}
+			command := &Bgrewriteaof{}
+			if _, err := command.Execute(writer, nil, tt.conf); err != nil {
+				t.Fatalf("case %s: failed to execute command: %v", tt.name, err)
+			} else if actual := writer.String(); actual != tt.output {
+				t.Errorf("case %s: expected %q but got %q", tt.name, tt.output, actual)
+			}
+			if command.Started() != tt.started {
+				t.Errorf("case %s: expected started %v", tt.name, tt.started)
+			} else if tt.conf.AOFRewriteInProgress != tt.inProgress {
+				t.Errorf("case %s: expected in progress %v", tt.name, tt.inProgress)
+			}
+		})
+	}
+}
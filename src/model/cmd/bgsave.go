@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"io"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
+)
+
+var (
+	_ Command = &Bgsave{}
+)
+
+func init() {
+	commandNameToBuilder[(&Bgsave{}).Name()] = func() Command {
+		return &Bgsave{}
+	}
+}
+
+var (
+	bgsaveStarted = redis.NewSimpleString("Background saving started")
+)
+
+// Bgsave writes the RDB file in the background. Execute only marks the
+// save as started, the connection handler then takes the point-in-time
+// view and writes it when Started is true.
+type Bgsave struct {
+	started bool
+}
+
+func (*Bgsave) Name() string {
+	return "BGSAVE"
+}
+
+func (b *Bgsave) String() string {
+	return b.Name()
+}
+
+func (b *Bgsave) Started() bool {
+	return b.started
+}
+
+func (b *Bgsave) Execute(writer io.Writer, storage *model.RedisStorage, conf *model.CommandConf) (redis.RedisObject, error) {
+	b.started = false
+	if conf.BgsaveInProgress {
+		return bgsaveInProgressErr, redis.WriteObject(writer, bgsaveInProgressErr)
+	}
+	conf.BgsaveInProgress = true
+	b.started = true
+
+	rsp := bgsaveStarted
+	return rsp, redis.WriteObject(writer, rsp)
+}
+
+func (b *Bgsave) Read(args *redis.Array) error {
+	if args == nil || args.Len() != 1 {
+		return &redis.SyntaxError{
+			Msg: "wrong number of arguments",
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+)
+
+func TestBgsave_Execute(t *testing.T) {
+	tests := []struct {
+		name    string
+		conf    *model.CommandConf
+		output  string
+		started bool
+	}{
+		{
+			name:    "start",
+			conf:    &model.CommandConf{},
+			output:  "+Background saving started\r\n",
+			started: true,
+		},
+		{
+			name:   "already in progress",
+			conf:   &model.CommandConf{BgsaveInProgress: true},
+			output: "-ERR Background save already in progress\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writer := &strings.Builder{}
+			command := &Bgsave{}
+			if _, err := command.Execute(writer, nil, tt.conf); err != nil {
+				t.Fatalf("case %s: failed to execute command: %v", tt.name, err)
+			} else if actual := writer.String(); actual != tt.output {
+				t.Errorf("case %s: expected %q but got %q", tt.name, tt.output, actual)
+			}
+			if command.Started() != tt.started {
+				t.Errorf("case %s: expected started %v", tt.name, tt.started)
+			} else if !tt.conf.BgsaveInProgress {
+				t.Errorf("case %s: expected the save to be in progress", tt.name)
+			}
+		})
+	}
+}
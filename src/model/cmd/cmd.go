@@ -17,22 +17,80 @@ type Command interface {
 	Execute(writer io.Writer, storage *model.RedisStorage, conf *model.CommandConf) (redis.RedisObject, error)
 }
 
+// WriteCommand is a command which changes the keyspace, so it is sent on
+// to replicas once it succeeds.
+type WriteCommand interface {
+	Command
+	// Propagate encodes the command as replicas should replay it, nil when
+	// it left the keyspace as it was
+	Propagate() *redis.Array
+}
+
+// DenyOOMCommand is a write which may take more memory, so it is refused
+// while the memory is over maxmemory and no key can be evicted.
+type DenyOOMCommand interface {
+	WriteCommand
+	DenyOOM()
+}
+
+// ReadOnlyCommand is a command which only reads the keyspace.
+type ReadOnlyCommand interface {
+	Command
+	ReadOnly()
+}
+
+// StaleCommand is a read which a replica serves even while its data is
+// stale and replica-serve-stale-data is off, e.g. introspection.
+type StaleCommand interface {
+	ReadOnlyCommand
+	Stale()
+}
+
 var (
 	commandNameToBuilder = make(map[string]func() Command)
 )
 
+// CommandError reports a request which was read completely but can not be
+// executed, so the connection is still in sync and may carry on.
+type CommandError struct {
+	Err error
+}
+
+func (e *CommandError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
 func ReadCommand(reader concept.Reader, storage *model.RedisStorage, conf *model.CommandConf) (Command, error) {
+	args, err := ReadArgs(reader)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCommand(args)
+}
+
+// ReadArgs reads the array of a request, an error means the stream is out
+// of sync.
+func ReadArgs(reader concept.Reader) (*redis.Array, error) {
 	var (
 		args redis.Array
 	)
 	if err := args.Read(reader); err != nil {
 		return nil, err
 	}
+	return &args, nil
+}
 
+// ParseCommand builds the command of a request, failures are returned as
+// CommandError.
+func ParseCommand(args *redis.Array) (Command, error) {
 	if args.Len() == 0 {
-		return nil, &redis.SyntaxError{
+		return nil, &CommandError{Err: &redis.SyntaxError{
 			Msg: "at least one argument is required",
-		}
+		}}
 	}
 	firstArg := args.Get(0)
 	commandName := ""
@@ -42,21 +100,21 @@ func ReadCommand(reader concept.Reader, storage *model.RedisStorage, conf *model
 	case *redis.SimpleString:
 		commandName = firstArg.AsString()
 	default:
-		return nil, &redis.SyntaxError{
+		return nil, &CommandError{Err: &redis.SyntaxError{
 			Msg: fmt.Sprintf("unexpected command name type %v", firstArg),
-		}
+		}}
 	}
 
 	builder, found := commandNameToBuilder[strings.ToUpper(commandName)]
 	if !found {
-		return nil, &redis.SyntaxError{
+		return nil, &CommandError{Err: &redis.SyntaxError{
 			Msg: fmt.Sprintf("unsupported command name %s, args %v", commandName, args),
-		}
+		}}
 	}
 
 	command := builder()
-	if err := command.Read(&args); err != nil {
-		return nil, fmt.Errorf("failed to read command %v: %w", &args, err)
+	if err := command.Read(args); err != nil {
+		return nil, &CommandError{Err: fmt.Errorf("failed to read command %v: %w", args, err)}
 	}
 
 	return command, nil
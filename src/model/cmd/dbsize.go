@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
+)
+
+var (
+	_ ReadOnlyCommand = &DBSize{}
+)
+
+func init() {
+	commandNameToBuilder[(&DBSize{}).Name()] = func() Command {
+		return &DBSize{}
+	}
+}
+
+// DBSize replies the number of keys of the selected database, DBSIZE,
+// counting those which expired but were not removed yet.
+type DBSize struct{}
+
+func (*DBSize) Name() string {
+	return "DBSIZE"
+}
+
+func (*DBSize) ReadOnly() {}
+
+func (d *DBSize) String() string {
+	return fmt.Sprintf("%s[]", d.Name())
+}
+
+func (d *DBSize) Execute(writer io.Writer, storage *model.RedisStorage, conf *model.CommandConf) (redis.RedisObject, error) {
+	rsp := redis.NewInteger(int64(len(storage.Mem)))
+	return rsp, redis.WriteObject(writer, rsp)
+}
+
+func (d *DBSize) Read(args *redis.Array) error {
+	if args == nil || args.Len() != 1 {
+		return &redis.SyntaxError{
+			Msg: "wrong number of arguments",
+		}
+	}
+	return nil
+}
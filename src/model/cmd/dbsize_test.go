@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+)
+
+func TestDBSize_Execute(t *testing.T) {
+	storage := databases(
+		map[string]*model.RedisBucket{"a": {Value: []byte("1"), ExpireAt: math.MaxInt64}},
+		map[string]*model.RedisBucket{
+			"b": {Value: []byte("2"), ExpireAt: math.MaxInt64},
+			"c": {Value: []byte("3"), ExpireAt: math.MaxInt64},
+		},
+	)
+	for db, expected := range []string{":1\r\n", ":2\r\n"} {
+		storage.Select(db)
+		writer := &strings.Builder{}
+		if _, err := (&DBSize{}).Execute(writer, storage, &model.CommandConf{}); err != nil {
+			t.Errorf("case db %d: failed to execute command: %v", db, err)
+		} else if actual := writer.String(); actual != expected {
+			t.Errorf("case db %d: expected %q but got %q", db, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
+)
+
+var (
+	_ WriteCommand = &Del{}
+)
+
+func init() {
+	commandNameToBuilder[(&Del{}).Name()] = func() Command {
+		return &Del{}
+	}
+}
+
+// Del removes keys, DEL <key> [key ...], and replies how many existed.
+type Del struct {
+	keys []string
+
+	// deleted holds the keys Execute removed
+	deleted []string
+}
+
+func (*Del) Name() string {
+	return "DEL"
+}
+
+func (d *Del) String() string {
+	return fmt.Sprintf("%s%v", d.Name(), d.keys)
+}
+
+func (d *Del) Execute(writer io.Writer, storage *model.RedisStorage, conf *model.CommandConf) (redis.RedisObject, error) {
+	d.deleted = d.deleted[:0]
+	now := time.Now().UnixMilli()
+	for _, key := range d.keys {
+		if bucket, found := storage.Mem[key]; found {
+			storage.Delete(key)
+			if bucket.ExpireAt >= now {
+				d.deleted = append(d.deleted, key)
+			}
+		}
+	}
+	rsp := redis.NewInteger(int64(len(d.deleted)))
+	return rsp, redis.WriteObject(writer, rsp)
+}
+
+// Propagate deletes the keys which were removed, nil when there were none.
+func (d *Del) Propagate() *redis.Array {
+	if len(d.deleted) == 0 {
+		return nil
+	}
+	args := make([]redis.RedisObject, 0, len(d.deleted)+1)
+	args = append(args, redis.NewBulkString([]byte(d.Name())))
+	for _, key := range d.deleted {
+		args = append(args, redis.NewBulkString([]byte(key)))
+	}
+	return redis.NewArray(args...)
+}
+
+func (d *Del) Read(args *redis.Array) error {
+	if args == nil || args.Len() < 2 {
+		return &redis.SyntaxError{
+			Msg: "wrong number of arguments",
+		}
+	}
+	d.keys = make([]string, 0, args.Len()-1)
+	for i := 1; i < args.Len(); i++ {
+		key, err := argString(args.Get(i), "key")
+		if err != nil {
+			return err
+		}
+		d.keys = append(d.keys, key)
+	}
+	return nil
+}
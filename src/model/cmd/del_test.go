@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
+)
+
+func TestDel_Read(t *testing.T) {
+	tests := []struct {
+		name    string
+		command *Del
+		input   string
+		output  string
+		isError bool
+	}{
+		{
+			name:    "normal",
+			command: &Del{},
+			input:   "*3\r\n$3\r\nDEL\r\n$1\r\na\r\n$1\r\nb\r\n",
+			output:  "DEL[a b]",
+		},
+		{
+			name:    "wrong number of arguments",
+			command: &Del{},
+			input:   "*1\r\n$3\r\nDEL\r\n",
+			isError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		reader := bufio.NewReader(bytes.NewBufferString(tt.input))
+		if obj, err := redis.ReadObject(reader, redis.ArrayLeading); err != nil {
+			t.Errorf("case %s: failed to read object: %v", tt.name, err)
+		} else if err := tt.command.Read(obj.(*redis.Array)); err != nil {
+			if !tt.isError {
+				t.Errorf("case %s: failed to read command: %v", tt.name, err)
+			}
+		} else if tt.isError {
+			t.Errorf("case %s: expected error but got nil", tt.name)
+		} else if actual := tt.command.String(); actual != tt.output {
+			t.Errorf("case %s: expected %s but got %s", tt.name, tt.output, actual)
+		}
+	}
+}
+
+func TestDel_Execute(t *testing.T) {
+	tests := []struct {
+		name      string
+		command   *Del
+		storage   *model.RedisStorage
+		output    string
+		propagate string
+	}{
+		{
+			name:    "normal",
+			command: &Del{keys: []string{"a", "missing", "b"}},
+			storage: &model.RedisStorage{Mem: map[string]*model.RedisBucket{
+				"a": {Value: []byte("1"), ExpireAt: math.MaxInt64},
+				"b": {Value: []byte("2"), ExpireAt: math.MaxInt64},
+			}},
+			output:    ":2\r\n",
+			propagate: "*3\r\n$3\r\nDEL\r\n$1\r\na\r\n$1\r\nb\r\n",
+		},
+		{
+			name:    "expired key",
+			command: &Del{keys: []string{"a"}},
+			storage: &model.RedisStorage{Mem: map[string]*model.RedisBucket{
+				"a": {Value: []byte("1"), ExpireAt: 1},
+			}},
+			output: ":0\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		writer := &strings.Builder{}
+		if _, err := tt.command.Execute(writer, tt.storage, &model.CommandConf{}); err != nil {
+			t.Errorf("case %s: failed to execute command: %v", tt.name, err)
+		} else if actual := writer.String(); actual != tt.output {
+			t.Errorf("case %s: expected %q but got %q", tt.name, tt.output, actual)
+		} else if len(tt.storage.Mem) != 0 {
+			t.Errorf("case %s: expected the keys to be deleted, actual=%v", tt.name, tt.storage.Mem)
+		}
+		propagate := ""
+		if args := tt.command.Propagate(); args != nil {
+			propagate = string(args.Append(nil))
+		}
+		if propagate != tt.propagate {
+			t.Errorf("case %s: expected to propagate %q, actual=%q", tt.name, tt.propagate, propagate)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+	"github.com/codecrafters-io/redis-starter-go/src/model/rdb"
+	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
+)
+
+var (
+	_ ReadOnlyCommand = &Dump{}
+)
+
+func init() {
+	commandNameToBuilder[(&Dump{}).Name()] = func() Command {
+		return &Dump{}
+	}
+}
+
+// Dump serializes the value of a key, DUMP <key>, in the payload format
+// RESTORE reads.
+type Dump struct {
+	key string
+}
+
+func (*Dump) Name() string {
+	return "DUMP"
+}
+
+func (*Dump) ReadOnly() {}
+
+func (d *Dump) String() string {
+	return fmt.Sprintf("%s[%s]", d.Name(), d.key)
+}
+
+func (d *Dump) Execute(writer io.Writer, storage *model.RedisStorage, conf *model.CommandConf) (redis.RedisObject, error) {
+	bucket := storage.Lookup(d.key, time.Now().UnixMilli())
+	if bucket == nil {
+		return nilString, redis.WriteObject(writer, nilString)
+	}
+	rsp := redis.NewBulkString(rdb.DumpValue(bucket))
+	return rsp, redis.WriteObject(writer, rsp)
+}
+
+func (d *Dump) Read(args *redis.Array) error {
+	if args == nil || args.Len() != 2 {
+		return &redis.SyntaxError{
+			Msg: "wrong number of arguments",
+		}
+	}
+	key, err := argString(args.Get(1), "key")
+	d.key = key
+	return err
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+	"github.com/codecrafters-io/redis-starter-go/src/model/rdb"
+	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
+)
+
+func TestDump_Read(t *testing.T) {
+	tests := []struct {
+		name    string
+		command *Dump
+		input   string
+		output  string
+		isError bool
+	}{
+		{
+			name:    "normal",
+			command: &Dump{},
+			input:   "*2\r\n$4\r\nDUMP\r\n$3\r\nkey\r\n",
+			output:  "DUMP[key]",
+		},
+		{
+			name:    "wrong number of arguments",
+			command: &Dump{},
+			input:   "*1\r\n$4\r\nDUMP\r\n",
+			isError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		reader := bufio.NewReader(bytes.NewBufferString(tt.input))
+		if obj, err := redis.ReadObject(reader, redis.ArrayLeading); err != nil {
+			t.Errorf("case %s: failed to read object: %v", tt.name, err)
+		} else if err := tt.command.Read(obj.(*redis.Array)); err != nil {
+			if !tt.isError {
+				t.Errorf("case %s: failed to read command: %v", tt.name, err)
+			}
+		} else if tt.isError {
+			t.Errorf("case %s: expected error but got nil", tt.name)
+		} else if actual := tt.command.String(); actual != tt.output {
+			t.Errorf("case %s: expected %s but got %s", tt.name, tt.output, actual)
+		}
+	}
+}
+
+func TestDump_Execute(t *testing.T) {
+	list := &model.RedisBucket{Type: model.TypeList, List: [][]byte{[]byte("a")}, ExpireAt: math.MaxInt64}
+	payload := rdb.DumpValue(list)
+	tests := []struct {
+		name    string
+		command *Dump
+		storage *model.RedisStorage
+		output  string
+	}{
+		{
+			name:    "normal",
+			command: &Dump{key: "key"},
+			storage: &model.RedisStorage{Mem: map[string]*model.RedisBucket{"key": list}},
+			output:  fmt.Sprintf("$%d\r\n%s\r\n", len(payload), payload),
+		},
+		{
+			name:    "missing key",
+			command: &Dump{key: "key"},
+			storage: &model.RedisStorage{Mem: map[string]*model.RedisBucket{}},
+			output:  "$-1\r\n",
+		},
+		{
+			name:    "expired key",
+			command: &Dump{key: "key"},
+			storage: &model.RedisStorage{Mem: map[string]*model.RedisBucket{"key": {Value: []byte("v"), ExpireAt: 1}}},
+			output:  "$-1\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		writer := &strings.Builder{}
+		if _, err := tt.command.Execute(writer, tt.storage, &model.CommandConf{}); err != nil {
+			t.Errorf("case %s: failed to execute command: %v", tt.name, err)
+		} else if actual := writer.String(); actual != tt.output {
+			t.Errorf("case %s: expected %q but got %q", tt.name, tt.output, actual)
+		}
+	}
+}
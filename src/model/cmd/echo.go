@@ -33,7 +33,7 @@ func (e *Echo) String() string {
 
 func (e *Echo) Execute(writer io.Writer, storage *model.RedisStorage, conf *model.CommandConf) (redis.RedisObject, error) {
 	rsp := redis.NewBulkString([]byte(e.message))
-	return rsp, rsp.Write(writer)
+	return rsp, redis.WriteObject(writer, rsp)
 }
 
 func (e *Echo) Read(args *redis.Array) error {
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
+)
+
+var (
+	_ WriteCommand = &FlushDB{}
+	_ WriteCommand = &FlushAll{}
+)
+
+func init() {
+	commandNameToBuilder[(&FlushDB{}).Name()] = func() Command {
+		return &FlushDB{}
+	}
+	commandNameToBuilder[(&FlushAll{}).Name()] = func() Command {
+		return &FlushAll{}
+	}
+}
+
+// FlushDB empties the selected database, FLUSHDB [ASYNC|SYNC]. The keys
+// are dropped at once either way, their memory is left to the garbage
+// collector which runs along with the server anyway.
+type FlushDB struct {
+	mode string
+}
+
+func (*FlushDB) Name() string {
+	return "FLUSHDB"
+}
+
+func (f *FlushDB) String() string {
+	return fmt.Sprintf("%s[%s]", f.Name(), f.mode)
+}
+
+func (f *FlushDB) Execute(writer io.Writer, storage *model.RedisStorage, conf *model.CommandConf) (redis.RedisObject, error) {
+	storage.FlushDB()
+	rsp := OK
+	return rsp, redis.WriteObject(writer, rsp)
+}
+
+func (f *FlushDB) Propagate() *redis.Array {
+	return redis.NewArray(redis.NewBulkString([]byte(f.Name())))
+}
+
+func (f *FlushDB) Read(args *redis.Array) (err error) {
+	f.mode, err = readFlushMode(args)
+	return err
+}
+
+// FlushAll empties every database, FLUSHALL [ASYNC|SYNC], see FlushDB.
+type FlushAll struct {
+	mode string
+}
+
+func (*FlushAll) Name() string {
+	return "FLUSHALL"
+}
+
+func (f *FlushAll) String() string {
+	return fmt.Sprintf("%s[%s]", f.Name(), f.mode)
+}
+
+func (f *FlushAll) Execute(writer io.Writer, storage *model.RedisStorage, conf *model.CommandConf) (redis.RedisObject, error) {
+	storage.FlushAll()
+	rsp := OK
+	return rsp, redis.WriteObject(writer, rsp)
+}
+
+func (f *FlushAll) Propagate() *redis.Array {
+	return redis.NewArray(redis.NewBulkString([]byte(f.Name())))
+}
+
+func (f *FlushAll) Read(args *redis.Array) (err error) {
+	f.mode, err = readFlushMode(args)
+	return err
+}
+
+func readFlushMode(args *redis.Array) (string, error) {
+	if args == nil || args.Len() > 2 {
+		return "", &redis.SyntaxError{
+			Msg: "wrong number of arguments",
+		}
+	} else if args.Len() == 1 {
+		return "SYNC", nil
+	}
+	mode, err := argString(args.Get(1), "mode")
+	if err != nil {
+		return "", err
+	}
+	switch mode = strings.ToUpper(mode); mode {
+	case "ASYNC", "SYNC":
+		return mode, nil
+	}
+	return "", &redis.SyntaxError{
+		Msg: fmt.Sprintf("unexpected mode %s", mode),
+	}
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
+)
+
+func TestFlush_Read(t *testing.T) {
+	tests := []struct {
+		name    string
+		command Command
+		input   string
+		output  string
+		isError bool
+	}{
+		{
+			name:    "flushdb",
+			command: &FlushDB{},
+			input:   "*1\r\n$7\r\nFLUSHDB\r\n",
+			output:  "FLUSHDB[SYNC]",
+		},
+		{
+			name:    "flushall async",
+			command: &FlushAll{},
+			input:   "*2\r\n$8\r\nFLUSHALL\r\n$5\r\nasync\r\n",
+			output:  "FLUSHALL[ASYNC]",
+		},
+		{
+			name:    "unknown mode",
+			command: &FlushAll{},
+			input:   "*2\r\n$8\r\nFLUSHALL\r\n$4\r\nlazy\r\n",
+			isError: true,
+		},
+		{
+			name:    "wrong number of arguments",
+			command: &FlushDB{},
+			input:   "*3\r\n$7\r\nFLUSHDB\r\n$4\r\nSYNC\r\n$4\r\nSYNC\r\n",
+			isError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		reader := bufio.NewReader(bytes.NewBufferString(tt.input))
+		if obj, err := redis.ReadObject(reader, redis.ArrayLeading); err != nil {
+			t.Errorf("case %s: failed to read object: %v", tt.name, err)
+		} else if err := tt.command.Read(obj.(*redis.Array)); err != nil {
+			if !tt.isError {
+				t.Errorf("case %s: failed to read command: %v", tt.name, err)
+			}
+		} else if tt.isError {
+			t.Errorf("case %s: expected error but got nil", tt.name)
+		} else if actual := tt.command.String(); actual != tt.output {
+			t.Errorf("case %s: expected %s but got %s", tt.name, tt.output, actual)
+		}
+	}
+}
+
+func TestFlush_Execute(t *testing.T) {
+	tests := []struct {
+		name      string
+		command   WriteCommand
+		propagate string
+		keys      int
+	}{
+		{
+			name:      "flushdb",
+			command:   &FlushDB{mode: "SYNC"},
+			propagate: "*1\r\n$7\r\nFLUSHDB\r\n",
+			keys:      1,
+		},
+		{
+			name:      "flushall",
+			command:   &FlushAll{mode: "ASYNC"},
+			propagate: "*1\r\n$8\r\nFLUSHALL\r\n",
+			keys:      0,
+		},
+	}
+
+	for _, tt := range tests {
+		storage := databases(
+			map[string]*model.RedisBucket{"a": {Value: []byte("1"), ExpireAt: math.MaxInt64}},
+			map[string]*model.RedisBucket{"b": {Value: []byte("2"), ExpireAt: math.MaxInt64}},
+		)
+		writer := &strings.Builder{}
+		if _, err := tt.command.Execute(writer, storage, &model.CommandConf{}); err != nil {
+			t.Errorf("case %s: failed to execute command: %v", tt.name, err)
+		} else if actual := writer.String(); actual != "+OK\r\n" {
+			t.Errorf("case %s: expected +OK but got %q", tt.name, actual)
+		} else if len(storage.Mem) != 0 || storage.Keys() != tt.keys {
+			t.Errorf("case %s: expected %d keys left, actual=%d", tt.name, tt.keys, storage.Keys())
+		}
+		if propagate := string(tt.command.Propagate().Append(nil)); propagate != tt.propagate {
+			t.Errorf("case %s: expected to propagate %q, actual=%q", tt.name, tt.propagate, propagate)
+		}
+	}
+}
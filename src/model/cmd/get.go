@@ -11,9 +11,11 @@ import (
 )
 
 var (
-	_ Command = &Get{}
+	_ ReadOnlyCommand = &Get{}
 
 	nilString = redis.NewBulkString(nil)
+	// wrongTypeErr answers a command on a key of another type
+	wrongTypeErr = redis.NewSimpleError("WRONGTYPE Operation against a key holding the wrong kind of value")
 )
 
 func init() {
@@ -30,20 +32,21 @@ func (*Get) Name() string {
 	return "GET"
 }
 
+func (*Get) ReadOnly() {}
+
 func (g *Get) String() string {
 	return fmt.Sprintf("%s[%s]", g.Name(), g.key)
 }
 
 func (g *Get) Execute(writer io.Writer, storage *model.RedisStorage, conf *model.CommandConf) (redis.RedisObject, error) {
-	value, found := storage.Mem[g.key]
-	if !found {
-		return nilString, nilString.Write(writer)
-	} else if value.ExpireAt < time.Now().UnixMilli() {
-		delete(storage.Mem, g.key)
-		return nilString, nilString.Write(writer)
+	value := storage.Lookup(g.key, time.Now().UnixMilli())
+	if value == nil {
+		return nilString, redis.WriteObject(writer, nilString)
+	} else if value.Type != model.TypeString {
+		return wrongTypeErr, redis.WriteObject(writer, wrongTypeErr)
 	}
 	rsp := redis.NewBulkString(value.Value)
-	return rsp, rsp.Write(writer)
+	return rsp, redis.WriteObject(writer, rsp)
 }
 
 func (g *Get) Read(args *redis.Array) error {
@@ -83,6 +83,16 @@ func TestGet_Execute(t *testing.T) {
 			memChecker: nil,
 			isError:    false,
 		},
+		{
+			name:    "wrong type",
+			command: &Get{key: "key"},
+			storage: &model.RedisStorage{Mem: map[string]*model.RedisBucket{
+				"key": {Type: model.TypeList, List: [][]byte{[]byte("a")}, ExpireAt: math.MaxInt64},
+			}},
+			conf:    &model.CommandConf{},
+			output:  "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n",
+			isError: false,
+		},
 		{
 			name:    "expire key",
 			command: &Get{key: "key"},
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"time"
 
 	"github.com/codecrafters-io/redis-starter-go/src/model"
 	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
@@ -56,6 +57,14 @@ func (i *Info) Read(args *redis.Array) error {
 	switch subCmdName {
 	case "replication":
 		i.subCommand = defaultInfoReplication
+	case "persistence":
+		i.subCommand = defaultInfoPersistence
+	case "keyspace":
+		i.subCommand = defaultInfoKeyspace
+	case "memory":
+		i.subCommand = defaultInfoMemory
+	case "stats":
+		i.subCommand = defaultInfoStats
 	default:
 		return &redis.SyntaxError{
 			Msg: fmt.Sprintf("unexpected sub-command name %s", subCmdName),
@@ -67,6 +76,10 @@ func (i *Info) Read(args *redis.Array) error {
 
 var (
 	defaultInfoReplication = &InfoReplication{}
+	defaultInfoPersistence = &InfoPersistence{}
+	defaultInfoKeyspace    = &InfoKeyspace{}
+	defaultInfoMemory      = &InfoMemory{}
+	defaultInfoStats       = &InfoStats{}
 )
 
 type InfoReplication struct {
@@ -88,9 +101,113 @@ func (i *InfoReplication) Execute(writer io.Writer, _ *model.RedisStorage, conf
 
 	rsp := redis.NewBulkString([]byte(builder.String()))
 
-	return rsp, rsp.Write(writer)
+	return rsp, redis.WriteObject(writer, rsp)
 }
 
 func (i *InfoReplication) Read(args *redis.Array) error {
 	return nil
 }
+
+type InfoPersistence struct {
+}
+
+func (*InfoPersistence) Name() string {
+	return "persistence"
+}
+
+func (i *InfoPersistence) String() string {
+	return i.Name()
+}
+
+func (i *InfoPersistence) Execute(writer io.Writer, _ *model.RedisStorage, conf *model.CommandConf) (redis.RedisObject, error) {
+	builder := strings.Builder{}
+	conf.VisitPersistence(func(name string, value interface{}) {
+		builder.WriteString(fmt.Sprintf("%s:%v\r\n", name, value))
+	})
+
+	rsp := redis.NewBulkString([]byte(builder.String()))
+
+	return rsp, redis.WriteObject(writer, rsp)
+}
+
+func (i *InfoPersistence) Read(args *redis.Array) error {
+	return nil
+}
+
+type InfoKeyspace struct {
+}
+
+func (*InfoKeyspace) Name() string {
+	return "keyspace"
+}
+
+func (i *InfoKeyspace) String() string {
+	return i.Name()
+}
+
+func (i *InfoKeyspace) Execute(writer io.Writer, storage *model.RedisStorage, _ *model.CommandConf) (redis.RedisObject, error) {
+	builder := strings.Builder{}
+	storage.VisitKeyspace(time.Now().UnixMilli(), func(name string, value interface{}) {
+		builder.WriteString(fmt.Sprintf("%s:%v\r\n", name, value))
+	})
+
+	rsp := redis.NewBulkString([]byte(builder.String()))
+
+	return rsp, redis.WriteObject(writer, rsp)
+}
+
+func (i *InfoKeyspace) Read(args *redis.Array) error {
+	return nil
+}
+
+type InfoMemory struct {
+}
+
+func (*InfoMemory) Name() string {
+	return "memory"
+}
+
+func (i *InfoMemory) String() string {
+	return i.Name()
+}
+
+func (i *InfoMemory) Execute(writer io.Writer, storage *model.RedisStorage, conf *model.CommandConf) (redis.RedisObject, error) {
+	builder := strings.Builder{}
+	conf.VisitMemory(storage.Used, storage.Peak, func(name string, value interface{}) {
+		builder.WriteString(fmt.Sprintf("%s:%v\r\n", name, value))
+	})
+
+	rsp := redis.NewBulkString([]byte(builder.String()))
+
+	return rsp, redis.WriteObject(writer, rsp)
+}
+
+func (i *InfoMemory) Read(args *redis.Array) error {
+	return nil
+}
+
+type InfoStats struct {
+}
+
+func (*InfoStats) Name() string {
+	return "stats"
+}
+
+func (i *InfoStats) String() string {
+	return i.Name()
+}
+
+func (i *InfoStats) Execute(writer io.Writer, _ *model.RedisStorage, conf *model.CommandConf) (redis.RedisObject, error) {
+	builder := strings.Builder{}
+	conf.VisitStats(func(name string, value interface{}) {
+		builder.WriteString(fmt.Sprintf("%s:%v\r\n", name, value))
+	})
+
+	rsp := redis.NewBulkString([]byte(builder.String()))
+
+	return rsp, redis.WriteObject(writer, rsp)
+}
+
+func (i *InfoStats) Read(args *redis.Array) error {
+	return nil
+}
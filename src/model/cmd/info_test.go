@@ -3,8 +3,11 @@ package cmd
 import (
 	"bufio"
 	"bytes"
+	"fmt"
+	"math"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/codecrafters-io/redis-starter-go/src/model"
 	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
@@ -25,6 +28,12 @@ func TestInfo_Read(t *testing.T) {
 			input:   "*2\r\n+INFO\r\n+replication\r\n",
 			output:  "INFO[replication]",
 		},
+		{
+			name:    "persistence",
+			command: &Info{},
+			input:   "*2\r\n$4\r\nINFO\r\n$11\r\npersistence\r\n",
+			output:  "INFO[persistence]",
+		},
 		{
 			name:    "wrong number of arguments",
 			command: &Info{},
@@ -58,6 +67,7 @@ func TestInfo_Execute(t *testing.T) {
 	tests := []struct {
 		name    string
 		command *Info
+		storage *model.RedisStorage
 		conf    *model.CommandConf
 		output  string
 		isError bool
@@ -72,15 +82,118 @@ func TestInfo_Execute(t *testing.T) {
 				ReplicaofAddress: "localhost",
 				ReplicaofPort:    456,
 			},
-			output:  "$12\r\nrole:slave\r\n\r\n",
+			output: infoBulk(
+				"role:slave",
+				"master_host:localhost",
+				"master_port:456",
+				"master_link_status:down",
+				"master_last_io_seconds_ago:-1",
+				"master_sync_in_progress:0",
+				"slave_repl_offset:123",
+				"connected_slaves:0",
+				"master_replid:id",
+				"master_replid2:",
+				"master_repl_offset:123",
+				"second_repl_offset:0",
+				"repl_backlog_active:0",
+				"repl_backlog_size:1048576",
+				"repl_backlog_first_byte_offset:0",
+				"repl_backlog_histlen:0",
+			),
 			isError: false,
 		},
+		{
+			name:    "master replication with a replica",
+			command: &Info{subCommand: &InfoReplication{}},
+			conf: &model.CommandConf{
+				Role:             "master",
+				MasterReplid:     "id",
+				MasterReplOffset: 154,
+				MasterReplid2:    "0000000000000000000000000000000000000000",
+				SecondReplOffset: -1,
+				Backlog:          backlogOf(1024, 101, 54),
+				Replicas: []*model.ReplicaInfo{
+					{IP: "127.0.0.1", Port: 6380, State: "online", AckOffset: 120, AckAt: time.Now()},
+				},
+			},
+			output: infoBulk(
+				"role:master",
+				"connected_slaves:1",
+				"slave0:ip=127.0.0.1,port=6380,state=online,offset=120,lag=0",
+				"master_replid:id",
+				"master_replid2:0000000000000000000000000000000000000000",
+				"master_repl_offset:154",
+				"second_repl_offset:-1",
+				"repl_backlog_active:1",
+				"repl_backlog_size:1024",
+				"repl_backlog_first_byte_offset:101",
+				"repl_backlog_histlen:54",
+			),
+			isError: false,
+		},
+		{
+			name:    "persistence",
+			command: &Info{subCommand: &InfoPersistence{}},
+			conf: &model.CommandConf{
+				LastSave:           time.Unix(1700000000, 0),
+				LastBgsaveOK:       true,
+				AppendOnly:         true,
+				AOFLastWriteOK:     false,
+				AOFLastBgrewriteOK: true,
+				AOFCurrentSize:     300,
+				AOFBaseSize:        100,
+			},
+			output: infoBulk(
+				"rdb_bgsave_in_progress:0",
+				"rdb_last_save_time:1700000000",
+				"rdb_last_bgsave_status:ok",
+				"aof_enabled:1",
+				"aof_rewrite_in_progress:0",
+				"aof_last_bgrewrite_status:ok",
+				"aof_last_write_status:err",
+				"aof_current_size:300",
+				"aof_base_size:100",
+			),
+			isError: false,
+		},
+		{
+			name:    "keyspace",
+			command: &Info{subCommand: &InfoKeyspace{}},
+			storage: databases(
+				map[string]*model.RedisBucket{"a": {ExpireAt: math.MaxInt64}, "b": {ExpireAt: math.MaxInt64}},
+				nil,
+				map[string]*model.RedisBucket{"c": {ExpireAt: math.MaxInt64}},
+			),
+			conf: &model.CommandConf{},
+			output: infoBulk(
+				"db0:keys=2,expires=0,avg_ttl=0",
+				"db2:keys=1,expires=0,avg_ttl=0",
+			),
+		},
+		{
+			name:    "memory",
+			command: &Info{subCommand: &InfoMemory{}},
+			storage: &model.RedisStorage{Used: 1024, Peak: 4096},
+			conf:    &model.CommandConf{Maxmemory: 2048, MaxmemoryPolicy: model.PolicyAllKeysLRU},
+			output: infoBulk(
+				"used_memory:1024",
+				"used_memory_peak:4096",
+				"maxmemory:2048",
+				"maxmemory_policy:allkeys-lru",
+			),
+		},
+		{
+			name:    "stats",
+			command: &Info{subCommand: &InfoStats{}},
+			conf:    &model.CommandConf{EvictedKeys: 3},
+			output:  infoBulk("evicted_keys:3"),
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			writer := &strings.Builder{}
-			if _, err := tt.command.Execute(writer, nil, tt.conf); err != nil {
+			if _, err := tt.command.Execute(writer, tt.storage, tt.conf); err != nil {
 				if tt.isError {
 					return
 				}
@@ -93,3 +206,15 @@ func TestInfo_Execute(t *testing.T) {
 		})
 	}
 }
+
+// infoBulk encodes INFO lines as the bulk string reply.
+func infoBulk(lines ...string) string {
+	body := strings.Join(lines, "\r\n") + "\r\n"
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(body), body)
+}
+
+func backlogOf(size int, next int64, histlen int) *model.ReplBacklog {
+	backlog := model.NewReplBacklog(size, next)
+	_, _ = backlog.Write(make([]byte, histlen))
+	return backlog
+}
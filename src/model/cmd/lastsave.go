@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"io"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
+)
+
+var (
+	_ Command = &Lastsave{}
+)
+
+func init() {
+	commandNameToBuilder[(&Lastsave{}).Name()] = func() Command {
+		return &Lastsave{}
+	}
+}
+
+// Lastsave replies the unix time of the last successful save.
+type Lastsave struct {
+}
+
+func (*Lastsave) Name() string {
+	return "LASTSAVE"
+}
+
+func (l *Lastsave) String() string {
+	return l.Name()
+}
+
+func (l *Lastsave) Execute(writer io.Writer, storage *model.RedisStorage, conf *model.CommandConf) (redis.RedisObject, error) {
+	rsp := redis.NewInteger(conf.LastSave.Unix())
+	return rsp, redis.WriteObject(writer, rsp)
+}
+
+func (l *Lastsave) Read(args *redis.Array) error {
+	if args == nil || args.Len() != 1 {
+		return &redis.SyntaxError{
+			Msg: "wrong number of arguments",
+		}
+	}
+	return nil
+}
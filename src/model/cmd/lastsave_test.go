@@ -0,0 +1,19 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+)
+
+func TestLastsave_Execute(t *testing.T) {
+	writer := &strings.Builder{}
+	conf := &model.CommandConf{LastSave: time.Unix(1700000000, 0)}
+	if _, err := (&Lastsave{}).Execute(writer, nil, conf); err != nil {
+		t.Fatalf("failed to execute command: %v", err)
+	} else if actual := writer.String(); actual != ":1700000000\r\n" {
+		t.Errorf("expected :1700000000 but got %q", actual)
+	}
+}
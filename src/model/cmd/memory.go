@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
+)
+
+var (
+	_ StaleCommand = &Memory{}
+)
+
+const (
+	// defaultMemorySamples is the number of elements MEMORY USAGE sizes
+	// by default.
+	defaultMemorySamples = 5
+	// doctorMinMemory is the memory under which MEMORY DOCTOR finds
+	// nothing worth reporting.
+	doctorMinMemory = 5 << 20
+)
+
+func init() {
+	commandNameToBuilder[(&Memory{}).Name()] = func() Command {
+		return &Memory{}
+	}
+}
+
+// Memory reports the memory of the keys, MEMORY USAGE <key> [SAMPLES
+// <count>] | MEMORY STATS | MEMORY DOCTOR. USAGE sizes count elements of
+// aggregate values, all of them with 0, without accessing the key.
+type Memory struct {
+	subCommand string
+	key        string
+	samples    int
+}
+
+func (*Memory) Name() string {
+	return "MEMORY"
+}
+
+func (*Memory) ReadOnly() {}
+
+func (*Memory) Stale() {}
+
+func (m *Memory) String() string {
+	if m.subCommand == "USAGE" {
+		return fmt.Sprintf("%s[%s %s %d]", m.Name(), m.subCommand, m.key, m.samples)
+	}
+	return fmt.Sprintf("%s[%s]", m.Name(), m.subCommand)
+}
+
+func (m *Memory) Execute(writer io.Writer, storage *model.RedisStorage, conf *model.CommandConf) (redis.RedisObject, error) {
+	var rsp redis.RedisObject
+	switch m.subCommand {
+	case "USAGE":
+		bucket, ok := storage.Mem[m.key]
+		if !ok || bucket.ExpireAt < time.Now().UnixMilli() {
+			return nilString, redis.WriteObject(writer, nilString)
+		}
+		rsp = redis.NewInteger(model.SampledKeySize(m.key, bucket, m.samples))
+	case "STATS":
+		rsp = memoryStats(storage)
+	default:
+		rsp = redis.NewBulkString([]byte(memoryDoctor(storage, conf)))
+	}
+	return rsp, redis.WriteObject(writer, rsp)
+}
+
+// memoryStats lists the memory of the keys as pairs of names and values.
+func memoryStats(storage *model.RedisStorage) *redis.Array {
+	var heap runtime.MemStats
+	runtime.ReadMemStats(&heap)
+	keys := int64(storage.Keys())
+	perKey := int64(0)
+	if keys > 0 {
+		perKey = storage.Used / keys
+	}
+	stats := []redis.RedisObject{
+		redis.NewBulkString([]byte("peak.allocated")), redis.NewInteger(storage.Peak),
+		redis.NewBulkString([]byte("total.allocated")), redis.NewInteger(storage.Used),
+	}
+	for db, mem := range storage.Databases() {
+		if len(mem) == 0 {
+			continue
+		}
+		expires := int64(0)
+		for _, bucket := range mem {
+			if bucket.ExpireAt != math.MaxInt64 {
+				expires++
+			}
+		}
+		stats = append(stats,
+			redis.NewBulkString([]byte(fmt.Sprintf("db.%d", db))),
+			redis.NewArray(
+				redis.NewBulkString([]byte("keys")), redis.NewInteger(int64(len(mem))),
+				redis.NewBulkString([]byte("expires")), redis.NewInteger(expires),
+			),
+		)
+	}
+	return redis.NewArray(append(stats,
+		redis.NewBulkString([]byte("keys.count")), redis.NewInteger(keys),
+		redis.NewBulkString([]byte("keys.bytes-per-key")), redis.NewInteger(perKey),
+		redis.NewBulkString([]byte("allocator.allocated")), redis.NewInteger(int64(heap.HeapAlloc)),
+		redis.NewBulkString([]byte("allocator.resident")), redis.NewInteger(int64(heap.HeapSys)),
+	)...)
+}
+
+// memoryDoctor reports the memory issues it finds in the words of Redis.
+func memoryDoctor(storage *model.RedisStorage, conf *model.CommandConf) string {
+	if storage.Used < doctorMinMemory {
+		return "Hi Sam, this instance is empty or is using very little memory, my issues detector can't be used in these conditions. Please, leave for your mission on Earth and fill it with some data. The new Sam and I will be back to our programming as soon as I finished rebooting."
+	}
+	var issues []string
+	if storage.Peak*2 > storage.Used*3 {
+		issues = append(issues, fmt.Sprintf(" * Peak memory: In the past this instance used more than 150%% the memory that is currently using (%d bytes against %d). The allocator is normally not able to release memory after a peak, so you can expect to see a big fragmentation ratio.", storage.Peak, storage.Used))
+	}
+	if conf.Maxmemory > 0 && conf.MaxmemoryPolicy == model.PolicyNoEviction && storage.Used*10 >= conf.Maxmemory*9 {
+		issues = append(issues, fmt.Sprintf(" * Maxmemory: This instance uses %d bytes of its maxmemory of %d without a maxmemory policy, so writes will soon be refused. Consider raising maxmemory or selecting an eviction policy.", storage.Used, conf.Maxmemory))
+	}
+	if len(issues) == 0 {
+		return "Hi Sam, I can't find any memory issue in your instance. I can only account for what occurs on this base."
+	}
+	return "Sam, I detected a few issues in this Redis instance memory implants:\n\n" +
+		strings.Join(issues, "\n\n") +
+		"\n\nI'm here to keep you safe, Sam. I want to help you.\n"
+}
+
+func (m *Memory) Read(args *redis.Array) error {
+	if args == nil || args.Len() < 2 {
+		return &redis.SyntaxError{
+			Msg: "wrong number of arguments",
+		}
+	}
+	subCommand, err := argString(args.Get(1), "sub-command")
+	if err != nil {
+		return err
+	}
+	switch m.subCommand = strings.ToUpper(subCommand); m.subCommand {
+	case "USAGE":
+		return m.readUsage(args)
+	case "STATS", "DOCTOR":
+		if args.Len() != 2 {
+			return &redis.SyntaxError{
+				Msg: "wrong number of arguments",
+			}
+		}
+		return nil
+	}
+	return &redis.SyntaxError{
+		Msg: fmt.Sprintf("unknown subcommand '%s'. Try MEMORY HELP.", subCommand),
+	}
+}
+
+func (m *Memory) readUsage(args *redis.Array) error {
+	if args.Len() != 3 && args.Len() != 5 {
+		return &redis.SyntaxError{
+			Msg: "wrong number of arguments",
+		}
+	}
+	key, err := argString(args.Get(2), "key")
+	if err != nil {
+		return err
+	}
+	m.key, m.samples = key, defaultMemorySamples
+	if args.Len() == 3 {
+		return nil
+	}
+	option, err := argString(args.Get(3), "option")
+	if err != nil {
+		return err
+	} else if !strings.EqualFold(option, "SAMPLES") {
+		return &redis.SyntaxError{
+			Msg: fmt.Sprintf("unexpected option %s", option),
+		}
+	}
+	samples, err := argInt64(args.Get(4), "samples")
+	if err != nil {
+		return err
+	} else if samples < 0 {
+		return &redis.SyntaxError{
+			Msg: fmt.Sprintf("negative samples %d", samples),
+		}
+	}
+	m.samples = int(samples)
+	return nil
+}
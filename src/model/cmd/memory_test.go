@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
+)
+
+func TestMemory_Read(t *testing.T) {
+	tests := []struct {
+		name    string
+		command *Memory
+		input   string
+		output  string
+		isError bool
+	}{
+		{
+			name:    "usage",
+			command: &Memory{},
+			input:   "*3\r\n$6\r\nMEMORY\r\n$5\r\nusage\r\n$3\r\nkey\r\n",
+			output:  "MEMORY[USAGE key 5]",
+		},
+		{
+			name:    "usage with samples",
+			command: &Memory{},
+			input:   "*5\r\n$6\r\nMEMORY\r\n$5\r\nUSAGE\r\n$3\r\nkey\r\n$7\r\nSAMPLES\r\n$1\r\n0\r\n",
+			output:  "MEMORY[USAGE key 0]",
+		},
+		{
+			name:    "stats",
+			command: &Memory{},
+			input:   "*2\r\n$6\r\nMEMORY\r\n$5\r\nSTATS\r\n",
+			output:  "MEMORY[STATS]",
+		},
+		{
+			name:    "doctor",
+			command: &Memory{},
+			input:   "*2\r\n$6\r\nMEMORY\r\n$6\r\ndoctor\r\n",
+			output:  "MEMORY[DOCTOR]",
+		},
+		{
+			name:    "negative samples",
+			command: &Memory{},
+			input:   "*5\r\n$6\r\nMEMORY\r\n$5\r\nUSAGE\r\n$3\r\nkey\r\n$7\r\nSAMPLES\r\n$2\r\n-1\r\n",
+			isError: true,
+		},
+		{
+			name:    "unknown option",
+			command: &Memory{},
+			input:   "*5\r\n$6\r\nMEMORY\r\n$5\r\nUSAGE\r\n$3\r\nkey\r\n$5\r\nCOUNT\r\n$1\r\n1\r\n",
+			isError: true,
+		},
+		{
+			name:    "unknown subcommand",
+			command: &Memory{},
+			input:   "*2\r\n$6\r\nMEMORY\r\n$6\r\nMALLOC\r\n",
+			isError: true,
+		},
+		{
+			name:    "wrong number of arguments",
+			command: &Memory{},
+			input:   "*3\r\n$6\r\nMEMORY\r\n$5\r\nSTATS\r\n$3\r\nkey\r\n",
+			isError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		reader := bufio.NewReader(bytes.NewBufferString(tt.input))
+		if obj, err := redis.ReadObject(reader, redis.ArrayLeading); err != nil {
+			t.Errorf("case %s: failed to read object: %v", tt.name, err)
+		} else if err := tt.command.Read(obj.(*redis.Array)); err != nil {
+			if !tt.isError {
+				t.Errorf("case %s: failed to read command: %v", tt.name, err)
+			}
+		} else if tt.isError {
+			t.Errorf("case %s: expected error but got nil", tt.name)
+		} else if actual := tt.command.String(); actual != tt.output {
+			t.Errorf("case %s: expected %s but got %s", tt.name, tt.output, actual)
+		}
+	}
+}
+
+func TestMemory_Execute(t *testing.T) {
+	var (
+		never = int64(math.MaxInt64)
+		value = &model.RedisBucket{Value: []byte("value"), ExpireAt: never}
+		list  = &model.RedisBucket{Type: model.TypeList, List: [][]byte{[]byte("a"), []byte("b")}, ExpireAt: 1}
+	)
+	storage := model.NewRedisStorage(2)
+	storage.Set("key", value, 0)
+	storage.Select(1)
+	storage.Set("list", list, 0)
+	storage.Select(0)
+	tests := []struct {
+		name    string
+		command *Memory
+		storage *model.RedisStorage
+		conf    *model.CommandConf
+		checker func(redis.RedisObject) error
+	}{
+		{
+			name:    "usage",
+			command: &Memory{subCommand: "USAGE", key: "key", samples: defaultMemorySamples},
+			storage: storage,
+			checker: func(rsp redis.RedisObject) error {
+				if size := model.KeySize("key", value); rsp.(*redis.Integer).AsInt64() != size {
+					return fmt.Errorf("expected %d bytes, actual=%v", size, rsp)
+				}
+				return nil
+			},
+		},
+		{
+			name:    "usage of a missing key",
+			command: &Memory{subCommand: "USAGE", key: "list", samples: defaultMemorySamples},
+			storage: storage,
+			checker: func(rsp redis.RedisObject) error {
+				if rsp != nilString {
+					return fmt.Errorf("expected nil, actual=%v", rsp)
+				}
+				return nil
+			},
+		},
+		{
+			name:    "stats",
+			command: &Memory{subCommand: "STATS"},
+			storage: storage,
+			checker: func(rsp redis.RedisObject) error {
+				stats := map[string]redis.RedisObject{}
+				array := rsp.(*redis.Array)
+				for i := 0; i+1 < array.Len(); i += 2 {
+					stats[array.Get(i).(*redis.BulkString).AsString()] = array.Get(i + 1)
+				}
+				if stats["total.allocated"].(*redis.Integer).AsInt64() != storage.Used {
+					return fmt.Errorf("expected total.allocated of %d, actual=%v", storage.Used, stats["total.allocated"])
+				} else if stats["keys.count"].(*redis.Integer).AsInt64() != 2 {
+					return fmt.Errorf("expected 2 keys, actual=%v", stats["keys.count"])
+				} else if db, ok := stats["db.1"].(*redis.Array); !ok || db.Get(3).(*redis.Integer).AsInt64() != 1 {
+					return fmt.Errorf("expected 1 expire in db 1, actual=%v", stats["db.1"])
+				}
+				return nil
+			},
+		},
+		{
+			name:    "doctor of an empty instance",
+			command: &Memory{subCommand: "DOCTOR"},
+			storage: storage,
+			conf:    &model.CommandConf{},
+			checker: expectDoctor("this instance is empty"),
+		},
+		{
+			name:    "doctor without issues",
+			command: &Memory{subCommand: "DOCTOR"},
+			storage: &model.RedisStorage{Used: doctorMinMemory, Peak: doctorMinMemory},
+			conf:    &model.CommandConf{},
+			checker: expectDoctor("I can't find any memory issue"),
+		},
+		{
+			name:    "doctor after a peak",
+			command: &Memory{subCommand: "DOCTOR"},
+			storage: &model.RedisStorage{Used: doctorMinMemory, Peak: 2 * doctorMinMemory},
+			conf:    &model.CommandConf{},
+			checker: expectDoctor("Peak memory"),
+		},
+		{
+			name:    "doctor near maxmemory",
+			command: &Memory{subCommand: "DOCTOR"},
+			storage: &model.RedisStorage{Used: doctorMinMemory, Peak: doctorMinMemory},
+			conf:    &model.CommandConf{Maxmemory: doctorMinMemory, MaxmemoryPolicy: model.PolicyNoEviction},
+			checker: expectDoctor("Maxmemory"),
+		},
+	}
+
+	for _, tt := range tests {
+		writer := &strings.Builder{}
+		if rsp, err := tt.command.Execute(writer, tt.storage, tt.conf); err != nil {
+			t.Errorf("case %s: failed to execute command: %v", tt.name, err)
+		} else if err := tt.checker(rsp); err != nil {
+			t.Errorf("case %s: %v", tt.name, err)
+		}
+	}
+}
+
+func expectDoctor(text string) func(redis.RedisObject) error {
+	return func(rsp redis.RedisObject) error {
+		if report := rsp.(*redis.BulkString).AsString(); !strings.Contains(report, text) {
+			return fmt.Errorf("expected %q in the report, actual=%q", text, report)
+		}
+		return nil
+	}
+}
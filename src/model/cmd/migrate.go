@@ -0,0 +1,243 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/src/client"
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+	"github.com/codecrafters-io/redis-starter-go/src/model/rdb"
+	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
+)
+
+var (
+	_ WriteCommand = &Migrate{}
+
+	noKey = redis.NewSimpleString("NOKEY")
+
+	connectErr = redis.NewSimpleError("IOERR error or timeout connecting to the client")
+	writeErr   = redis.NewSimpleError("IOERR error or timeout writing to target instance")
+	readErr    = redis.NewSimpleError("IOERR error or timeout reading from target instance")
+)
+
+func init() {
+	commandNameToBuilder[(&Migrate{}).Name()] = func() Command {
+		return &Migrate{}
+	}
+}
+
+// Migrate moves keys to another server, MIGRATE <host> <port> <key|"">
+// <db> <timeout> [COPY] [REPLACE] [AUTH password] [KEYS key ...]. The keys
+// are sent as RESTORE commands in one batch over a new connection, each
+// key restored on the target is deleted here unless COPY is given. The
+// server waits for the transfer, timeout bounds each network step in
+// milliseconds.
+type Migrate struct {
+	host     string
+	port     int
+	keys     []string
+	db       int64
+	timeout  time.Duration
+	copy     bool
+	replace  bool
+	password string
+	auth     bool
+
+	// deleted holds the keys Execute moved away
+	deleted []string
+}
+
+func (*Migrate) Name() string {
+	return "MIGRATE"
+}
+
+func (m *Migrate) String() string {
+	return fmt.Sprintf("%s[%s:%d, %v, %d]", m.Name(), m.host, m.port, m.keys, m.db)
+}
+
+func (m *Migrate) Execute(writer io.Writer, storage *model.RedisStorage, conf *model.CommandConf) (redis.RedisObject, error) {
+	m.deleted = m.deleted[:0]
+	var (
+		now     = time.Now().UnixMilli()
+		keys    = make([]string, 0, len(m.keys))
+		buckets = make([]*model.RedisBucket, 0, len(m.keys))
+	)
+	for _, key := range m.keys {
+		if bucket := storage.Lookup(key, now); bucket != nil {
+			keys = append(keys, key)
+			buckets = append(buckets, bucket)
+		}
+	}
+	if len(keys) == 0 {
+		return noKey, redis.WriteObject(writer, noKey)
+	}
+
+	rsp, restored := m.transfer(keys, buckets, now)
+	if !m.copy {
+		for _, key := range restored {
+			storage.Delete(key)
+		}
+		m.deleted = append(m.deleted, restored...)
+	}
+	return rsp, redis.WriteObject(writer, rsp)
+}
+
+// transfer restores the keys on the target, it returns the reply of
+// MIGRATE and the keys which were restored. A key the target refused does
+// not stop the others.
+func (m *Migrate) transfer(keys []string, buckets []*model.RedisBucket, now int64) (redis.RedisObject, []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+	conn, err := client.Dial(ctx, net.JoinHostPort(m.host, strconv.Itoa(m.port)), &client.Options{Protocol: client.RESP2})
+	if err != nil {
+		return connectErr, nil
+	}
+	defer conn.Close()
+
+	var requests [][]any
+	if m.auth {
+		requests = append(requests, []any{"AUTH", m.password})
+	}
+	if m.db != 0 {
+		requests = append(requests, []any{"SELECT", m.db})
+	}
+	setup := len(requests)
+	for i, key := range keys {
+		ttl := int64(0)
+		if buckets[i].ExpireAt != math.MaxInt64 {
+			ttl = buckets[i].ExpireAt - now + 1
+		}
+		request := []any{"RESTORE", key, ttl, rdb.DumpValue(buckets[i])}
+		if m.replace {
+			request = append(request, "REPLACE")
+		}
+		requests = append(requests, request)
+	}
+	for _, request := range requests {
+		if err := conn.Send(request...); err != nil {
+			return writeErr, nil
+		}
+	}
+	flushCtx, flushCancel := context.WithTimeout(context.Background(), m.timeout)
+	defer flushCancel()
+	if err := conn.Flush(flushCtx); err != nil {
+		return writeErr, nil
+	}
+
+	var (
+		rsp      redis.RedisObject = OK
+		restored []string
+	)
+	for i := range requests {
+		receiveCtx, receiveCancel := context.WithTimeout(context.Background(), m.timeout)
+		_, err := conn.Receive(receiveCtx)
+		receiveCancel()
+		var replyErr *redis.ReplyError
+		switch {
+		case errors.As(err, &replyErr):
+			if rsp == OK {
+				rsp = redis.NewSimpleError(fmt.Sprintf("ERR Target instance replied with error: %s", replyErr.Msg))
+			}
+			if i < setup {
+				// the keys would go to the wrong database, or nowhere
+				return rsp, nil
+			}
+		case err != nil:
+			return readErr, restored
+		case i >= setup:
+			restored = append(restored, keys[i-setup])
+		}
+	}
+	return rsp, restored
+}
+
+// Propagate deletes the keys which were moved, nil when none were.
+func (m *Migrate) Propagate() *redis.Array {
+	return (&Del{deleted: m.deleted}).Propagate()
+}
+
+func (m *Migrate) Read(args *redis.Array) error {
+	if args == nil || args.Len() < 6 {
+		return &redis.SyntaxError{
+			Msg: "wrong number of arguments",
+		}
+	}
+
+	var (
+		key     string
+		port    int64
+		timeout int64
+		err     error
+	)
+	if m.host, err = argString(args.Get(1), "host"); err != nil {
+		return err
+	} else if port, err = argInt64(args.Get(2), "port"); err != nil {
+		return err
+	} else if key, err = argString(args.Get(3), "key"); err != nil {
+		return err
+	} else if m.db, err = argInt64(args.Get(4), "db"); err != nil {
+		return err
+	} else if timeout, err = argInt64(args.Get(5), "timeout"); err != nil {
+		return err
+	}
+	if port <= 0 || port > 65535 {
+		return &redis.SyntaxError{
+			Msg: fmt.Sprintf("invalid port %d", port),
+		}
+	} else if m.db < 0 {
+		return &redis.SyntaxError{
+			Msg: "db is negative",
+		}
+	}
+	m.port = int(port)
+	if timeout <= 0 {
+		timeout = 1000
+	}
+	m.timeout = time.Duration(timeout) * time.Millisecond
+
+	m.copy, m.replace, m.auth = false, false, false
+	m.keys = []string{key}
+	for i := 6; i < args.Len(); i++ {
+		opt, err := argString(args.Get(i), "option")
+		if err != nil {
+			return err
+		}
+		switch opt = strings.ToUpper(opt); {
+		case opt == "COPY":
+			m.copy = true
+		case opt == "REPLACE":
+			m.replace = true
+		case opt == "AUTH" && i+1 < args.Len():
+			i++
+			if m.password, err = argString(args.Get(i), "password"); err != nil {
+				return err
+			}
+			m.auth = true
+		case opt == "KEYS":
+			if key != "" {
+				return &redis.SyntaxError{
+					Msg: "When using MIGRATE KEYS option, the key argument must be set to an empty string",
+				}
+			}
+			m.keys = m.keys[:0]
+			for i++; i < args.Len(); i++ {
+				if key, err = argString(args.Get(i), "key"); err != nil {
+					return err
+				}
+				m.keys = append(m.keys, key)
+			}
+		default:
+			return &redis.SyntaxError{
+				Msg: fmt.Sprintf("syntax error at option %s", opt),
+			}
+		}
+	}
+	return nil
+}
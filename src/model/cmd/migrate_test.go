@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"math"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
+)
+
+func TestMigrate_Read(t *testing.T) {
+	tests := []struct {
+		name    string
+		command *Migrate
+		input   string
+		output  string
+		isError bool
+	}{
+		{
+			name:    "single key",
+			command: &Migrate{},
+			input:   "*6\r\n$7\r\nMIGRATE\r\n$9\r\nlocalhost\r\n$4\r\n6380\r\n$3\r\nkey\r\n$1\r\n0\r\n$4\r\n1000\r\n",
+			output:  "MIGRATE[localhost:6380, [key], 0]",
+		},
+		{
+			name:    "keys with options",
+			command: &Migrate{},
+			input:   "*12\r\n$7\r\nMIGRATE\r\n$9\r\nlocalhost\r\n$4\r\n6380\r\n$0\r\n\r\n$1\r\n2\r\n$1\r\n0\r\n$4\r\nCOPY\r\n$7\r\nREPLACE\r\n$4\r\nAUTH\r\n$2\r\npw\r\n$4\r\nKEYS\r\n$1\r\na\r\n",
+			output:  "MIGRATE[localhost:6380, [a], 2]",
+		},
+		{
+			name:    "keys with a key",
+			command: &Migrate{},
+			input:   "*8\r\n$7\r\nMIGRATE\r\n$9\r\nlocalhost\r\n$4\r\n6380\r\n$3\r\nkey\r\n$1\r\n0\r\n$1\r\n0\r\n$4\r\nKEYS\r\n$1\r\na\r\n",
+			isError: true,
+		},
+		{
+			name:    "invalid port",
+			command: &Migrate{},
+			input:   "*6\r\n$7\r\nMIGRATE\r\n$9\r\nlocalhost\r\n$1\r\n0\r\n$3\r\nkey\r\n$1\r\n0\r\n$1\r\n0\r\n",
+			isError: true,
+		},
+		{
+			name:    "auth without password",
+			command: &Migrate{},
+			input:   "*7\r\n$7\r\nMIGRATE\r\n$9\r\nlocalhost\r\n$4\r\n6380\r\n$3\r\nkey\r\n$1\r\n0\r\n$1\r\n0\r\n$4\r\nAUTH\r\n",
+			isError: true,
+		},
+		{
+			name:    "wrong number of arguments",
+			command: &Migrate{},
+			input:   "*5\r\n$7\r\nMIGRATE\r\n$9\r\nlocalhost\r\n$4\r\n6380\r\n$3\r\nkey\r\n$1\r\n0\r\n",
+			isError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		reader := bufio.NewReader(bytes.NewBufferString(tt.input))
+		if obj, err := redis.ReadObject(reader, redis.ArrayLeading); err != nil {
+			t.Errorf("case %s: failed to read object: %v", tt.name, err)
+		} else if err := tt.command.Read(obj.(*redis.Array)); err != nil {
+			if !tt.isError {
+				t.Errorf("case %s: failed to read command: %v", tt.name, err)
+			}
+		} else if tt.isError {
+			t.Errorf("case %s: expected error but got nil", tt.name)
+		} else if actual := tt.command.String(); actual != tt.output {
+			t.Errorf("case %s: expected %s but got %s", tt.name, tt.output, actual)
+		}
+	}
+}
+
+func TestMigrate_Execute(t *testing.T) {
+	// a port nothing listens on any more
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	_ = listener.Close()
+
+	tests := []struct {
+		name    string
+		command *Migrate
+		output  string
+	}{
+		{
+			name:    "no key",
+			command: &Migrate{host: "127.0.0.1", port: port, keys: []string{"missing", "expired"}, timeout: time.Second},
+			output:  "+NOKEY\r\n",
+		},
+		{
+			name:    "target down",
+			command: &Migrate{host: "127.0.0.1", port: port, keys: []string{"key"}, timeout: time.Second},
+			output:  "-IOERR error or timeout connecting to the client\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		storage := &model.RedisStorage{Mem: map[string]*model.RedisBucket{
+			"key":     {Value: []byte("v"), ExpireAt: math.MaxInt64},
+			"expired": {Value: []byte("v"), ExpireAt: 1},
+		}}
+		writer := &strings.Builder{}
+		if _, err := tt.command.Execute(writer, storage, &model.CommandConf{}); err != nil {
+			t.Errorf("case %s: failed to execute command: %v", tt.name, err)
+		} else if actual := writer.String(); actual != tt.output {
+			t.Errorf("case %s: expected %q but got %q", tt.name, tt.output, actual)
+		} else if _, ok := storage.Mem["key"]; !ok {
+			t.Errorf("case %s: expected the key to stay", tt.name)
+		} else if tt.command.Propagate() != nil {
+			t.Errorf("case %s: expected nothing to propagate", tt.name)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
+)
+
+var (
+	_ WriteCommand = &Move{}
+
+	sameObjectErr = redis.NewSimpleError("ERR source and destination objects are the same")
+)
+
+func init() {
+	commandNameToBuilder[(&Move{}).Name()] = func() Command {
+		return &Move{}
+	}
+}
+
+// Move moves a key of the selected database to another one, MOVE <key>
+// <db>. It replies 1 when moved, 0 when the key is missing or the other
+// database has it already.
+type Move struct {
+	key string
+	db  int64
+
+	moved bool
+}
+
+func (*Move) Name() string {
+	return "MOVE"
+}
+
+func (m *Move) String() string {
+	return fmt.Sprintf("%s[%s, %d]", m.Name(), m.key, m.db)
+}
+
+func (m *Move) Execute(writer io.Writer, storage *model.RedisStorage, conf *model.CommandConf) (redis.RedisObject, error) {
+	m.moved = false
+	dbs := storage.Databases()
+	if m.db < 0 || m.db >= int64(len(dbs)) {
+		return dbRangeErr, redis.WriteObject(writer, dbRangeErr)
+	} else if int(m.db) == storage.DB {
+		return sameObjectErr, redis.WriteObject(writer, sameObjectErr)
+	}
+
+	now := time.Now().UnixMilli()
+	bucket := storage.Lookup(m.key, now)
+	found := bucket != nil
+	target := dbs[m.db]
+	if other, ok := target[m.key]; ok && other.ExpireAt < now {
+		storage.DeleteFrom(int(m.db), m.key)
+	} else if ok {
+		found = false
+	}
+
+	rsp := redis.NewInteger(0)
+	if found {
+		storage.MoveKey(m.key, int(m.db))
+		m.moved = true
+		rsp = redis.NewInteger(1)
+	}
+	return rsp, redis.WriteObject(writer, rsp)
+}
+
+// Propagate is nil when the key was not moved.
+func (m *Move) Propagate() *redis.Array {
+	if !m.moved {
+		return nil
+	}
+	return redis.NewArray(
+		redis.NewBulkString([]byte(m.Name())),
+		redis.NewBulkString([]byte(m.key)),
+		redis.NewBulkString([]byte(strconv.FormatInt(m.db, 10))),
+	)
+}
+
+func (m *Move) Read(args *redis.Array) error {
+	if args == nil || args.Len() != 3 {
+		return &redis.SyntaxError{
+			Msg: "wrong number of arguments",
+		}
+	}
+	var err error
+	if m.key, err = argString(args.Get(1), "key"); err != nil {
+		return err
+	}
+	m.db, err = argInt64(args.Get(2), "db")
+	return err
+}
@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
+)
+
+func TestMove_Read(t *testing.T) {
+	tests := []struct {
+		name    string
+		command *Move
+		input   string
+		output  string
+		isError bool
+	}{
+		{
+			name:    "normal",
+			command: &Move{},
+			input:   "*3\r\n$4\r\nMOVE\r\n$1\r\na\r\n$1\r\n1\r\n",
+			output:  "MOVE[a, 1]",
+		},
+		{
+			name:    "not an integer",
+			command: &Move{},
+			input:   "*3\r\n$4\r\nMOVE\r\n$1\r\na\r\n$1\r\nx\r\n",
+			isError: true,
+		},
+		{
+			name:    "wrong number of arguments",
+			command: &Move{},
+			input:   "*2\r\n$4\r\nMOVE\r\n$1\r\na\r\n",
+			isError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		reader := bufio.NewReader(bytes.NewBufferString(tt.input))
+		if obj, err := redis.ReadObject(reader, redis.ArrayLeading); err != nil {
+			t.Errorf("case %s: failed to read object: %v", tt.name, err)
+		} else if err := tt.command.Read(obj.(*redis.Array)); err != nil {
+			if !tt.isError {
+				t.Errorf("case %s: failed to read command: %v", tt.name, err)
+			}
+		} else if tt.isError {
+			t.Errorf("case %s: expected error but got nil", tt.name)
+		} else if actual := tt.command.String(); actual != tt.output {
+			t.Errorf("case %s: expected %s but got %s", tt.name, tt.output, actual)
+		}
+	}
+}
+
+func TestMove_Execute(t *testing.T) {
+	tests := []struct {
+		name      string
+		command   *Move
+		storage   *model.RedisStorage
+		output    string
+		propagate string
+		// keys of each database afterwards
+		keys []int
+	}{
+		{
+			name:    "normal",
+			command: &Move{key: "a", db: 1},
+			storage: databases(map[string]*model.RedisBucket{
+				"a": {Value: []byte("1"), ExpireAt: math.MaxInt64},
+			}, nil),
+			output:    ":1\r\n",
+			propagate: "*3\r\n$4\r\nMOVE\r\n$1\r\na\r\n$1\r\n1\r\n",
+			keys:      []int{0, 1},
+		},
+		{
+			name:    "missing",
+			command: &Move{key: "a", db: 1},
+			storage: databases(map[string]*model.RedisBucket{
+				"b": {Value: []byte("1"), ExpireAt: math.MaxInt64},
+			}, nil),
+			output: ":0\r\n",
+			keys:   []int{1, 0},
+		},
+		{
+			name:    "expired",
+			command: &Move{key: "a", db: 1},
+			storage: databases(map[string]*model.RedisBucket{
+				"a": {Value: []byte("1"), ExpireAt: 1},
+			}, nil),
+			output: ":0\r\n",
+			keys:   []int{0, 0},
+		},
+		{
+			name:    "exists in the target",
+			command: &Move{key: "a", db: 1},
+			storage: databases(
+				map[string]*model.RedisBucket{"a": {Value: []byte("1"), ExpireAt: math.MaxInt64}},
+				map[string]*model.RedisBucket{"a": {Value: []byte("2"), ExpireAt: math.MaxInt64}},
+			),
+			output: ":0\r\n",
+			keys:   []int{1, 1},
+		},
+		{
+			name:    "expired in the target",
+			command: &Move{key: "a", db: 1},
+			storage: databases(
+				map[string]*model.RedisBucket{"a": {Value: []byte("1"), ExpireAt: math.MaxInt64}},
+				map[string]*model.RedisBucket{"a": {Value: []byte("2"), ExpireAt: 1}},
+			),
+			output:    ":1\r\n",
+			propagate: "*3\r\n$4\r\nMOVE\r\n$1\r\na\r\n$1\r\n1\r\n",
+			keys:      []int{0, 1},
+		},
+		{
+			name:    "same db",
+			command: &Move{key: "a", db: 0},
+			storage: databases(map[string]*model.RedisBucket{
+				"a": {Value: []byte("1"), ExpireAt: math.MaxInt64},
+			}, nil),
+			output: "-ERR source and destination objects are the same\r\n",
+			keys:   []int{1, 0},
+		},
+		{
+			name:    "out of range",
+			command: &Move{key: "a", db: 2},
+			storage: databases(map[string]*model.RedisBucket{
+				"a": {Value: []byte("1"), ExpireAt: math.MaxInt64},
+			}, nil),
+			output: "-ERR DB index is out of range\r\n",
+			keys:   []int{1, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		writer := &strings.Builder{}
+		if _, err := tt.command.Execute(writer, tt.storage, &model.CommandConf{}); err != nil {
+			t.Errorf("case %s: failed to execute command: %v", tt.name, err)
+		} else if actual := writer.String(); actual != tt.output {
+			t.Errorf("case %s: expected %q but got %q", tt.name, tt.output, actual)
+		}
+		for db, mem := range tt.storage.Databases() {
+			if len(mem) != tt.keys[db] {
+				t.Errorf("case %s: expected %d keys in db %d, actual=%d", tt.name, tt.keys[db], db, len(mem))
+			}
+		}
+		propagate := ""
+		if args := tt.command.Propagate(); args != nil {
+			propagate = string(args.Append(nil))
+		}
+		if propagate != tt.propagate {
+			t.Errorf("case %s: expected to propagate %q, actual=%q", tt.name, tt.propagate, propagate)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
+)
+
+var (
+	_ StaleCommand = &Object{}
+
+	idleTimeLFUErr = redis.NewSimpleError("ERR An LFU maxmemory policy is selected, idle time not tracked. Please note that when switching between policies at runtime LRU and LFU data will take some time to adjust.")
+	freqNoLFUErr   = redis.NewSimpleError("ERR An LFU maxmemory policy is not selected, access frequency not tracked. Please note that when switching between policies at runtime LRU and LFU data will take some time to adjust.")
+)
+
+func init() {
+	commandNameToBuilder[(&Object{}).Name()] = func() Command {
+		return &Object{}
+	}
+}
+
+// Object inspects a key without accessing it, OBJECT <ENCODING | IDLETIME
+// | FREQ | REFCOUNT> <key>. IDLETIME is only tracked without an LFU
+// maxmemory policy and FREQ only with one, as in Redis.
+type Object struct {
+	subCommand string
+	key        string
+}
+
+func (*Object) Name() string {
+	return "OBJECT"
+}
+
+func (*Object) ReadOnly() {}
+
+func (*Object) Stale() {}
+
+func (o *Object) String() string {
+	return fmt.Sprintf("%s[%s %s]", o.Name(), o.subCommand, o.key)
+}
+
+func (o *Object) Execute(writer io.Writer, storage *model.RedisStorage, conf *model.CommandConf) (redis.RedisObject, error) {
+	now := time.Now().UnixMilli()
+	bucket, ok := storage.Mem[o.key]
+	if !ok || bucket.ExpireAt < now {
+		return nilString, redis.WriteObject(writer, nilString)
+	}
+	var rsp redis.RedisObject
+	switch o.subCommand {
+	case "ENCODING":
+		rsp = redis.NewBulkString([]byte(bucket.Encoding()))
+	case "IDLETIME":
+		if model.IsLFUPolicy(conf.MaxmemoryPolicy) {
+			return idleTimeLFUErr, redis.WriteObject(writer, idleTimeLFUErr)
+		}
+		rsp = redis.NewInteger((now - bucket.AccessAt) / 1000)
+	case "FREQ":
+		if !model.IsLFUPolicy(conf.MaxmemoryPolicy) {
+			return freqNoLFUErr, redis.WriteObject(writer, freqNoLFUErr)
+		}
+		rsp = redis.NewInteger(int64(bucket.LFU(now, storage.LFUDecayTime)))
+	default:
+		// values are never shared between keys
+		rsp = redis.NewInteger(1)
+	}
+	return rsp, redis.WriteObject(writer, rsp)
+}
+
+func (o *Object) Read(args *redis.Array) error {
+	if args == nil || args.Len() != 3 {
+		return &redis.SyntaxError{
+			Msg: "wrong number of arguments",
+		}
+	}
+	subCommand, err := argString(args.Get(1), "sub-command")
+	if err != nil {
+		return err
+	}
+	switch o.subCommand = strings.ToUpper(subCommand); o.subCommand {
+	case "ENCODING", "IDLETIME", "FREQ", "REFCOUNT":
+	default:
+		return &redis.SyntaxError{
+			Msg: fmt.Sprintf("unknown subcommand '%s'. Try OBJECT HELP.", subCommand),
+		}
+	}
+	o.key, err = argString(args.Get(2), "key")
+	return err
+}
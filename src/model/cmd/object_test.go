@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
+)
+
+func TestObject_Read(t *testing.T) {
+	tests := []struct {
+		name    string
+		command *Object
+		input   string
+		output  string
+		isError bool
+	}{
+		{
+			name:    "encoding",
+			command: &Object{},
+			input:   "*3\r\n$6\r\nOBJECT\r\n$8\r\nencoding\r\n$3\r\nkey\r\n",
+			output:  "OBJECT[ENCODING key]",
+		},
+		{
+			name:    "freq",
+			command: &Object{},
+			input:   "*3\r\n$6\r\nOBJECT\r\n$4\r\nFREQ\r\n$3\r\nkey\r\n",
+			output:  "OBJECT[FREQ key]",
+		},
+		{
+			name:    "unknown subcommand",
+			command: &Object{},
+			input:   "*3\r\n$6\r\nOBJECT\r\n$4\r\nSIZE\r\n$3\r\nkey\r\n",
+			isError: true,
+		},
+		{
+			name:    "wrong number of arguments",
+			command: &Object{},
+			input:   "*2\r\n$6\r\nOBJECT\r\n$8\r\nIDLETIME\r\n",
+			isError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		reader := bufio.NewReader(bytes.NewBufferString(tt.input))
+		if obj, err := redis.ReadObject(reader, redis.ArrayLeading); err != nil {
+			t.Errorf("case %s: failed to read object: %v", tt.name, err)
+		} else if err := tt.command.Read(obj.(*redis.Array)); err != nil {
+			if !tt.isError {
+				t.Errorf("case %s: failed to read command: %v", tt.name, err)
+			}
+		} else if tt.isError {
+			t.Errorf("case %s: expected error but got nil", tt.name)
+		} else if actual := tt.command.String(); actual != tt.output {
+			t.Errorf("case %s: expected %s but got %s", tt.name, tt.output, actual)
+		}
+	}
+}
+
+func TestObject_Execute(t *testing.T) {
+	var (
+		never   = int64(math.MaxInt64)
+		now     = time.Now().UnixMilli()
+		storage = &model.RedisStorage{Mem: map[string]*model.RedisBucket{
+			"int":     {Value: []byte("12345"), ExpireAt: never, AccessAt: now - 10_000, Freq: 20},
+			"raw":     {Value: bytes.Repeat([]byte("a"), 45), ExpireAt: never, AccessAt: now},
+			"intset":  {Type: model.TypeSet, Set: map[string]struct{}{"1": {}, "2": {}}, ExpireAt: never},
+			"set":     {Type: model.TypeSet, Set: map[string]struct{}{"1": {}, "a": {}}, ExpireAt: never},
+			"expired": {Value: []byte("v"), ExpireAt: 1},
+		}}
+		lru = &model.CommandConf{MaxmemoryPolicy: model.PolicyAllKeysLRU}
+		lfu = &model.CommandConf{MaxmemoryPolicy: model.PolicyAllKeysLFU}
+	)
+	tests := []struct {
+		name    string
+		command *Object
+		conf    *model.CommandConf
+		output  string
+	}{
+		{
+			name:    "int encoding",
+			command: &Object{subCommand: "ENCODING", key: "int"},
+			conf:    lru,
+			output:  "$3\r\nint\r\n",
+		},
+		{
+			name:    "raw encoding",
+			command: &Object{subCommand: "ENCODING", key: "raw"},
+			conf:    lru,
+			output:  "$3\r\nraw\r\n",
+		},
+		{
+			name:    "intset encoding",
+			command: &Object{subCommand: "ENCODING", key: "intset"},
+			conf:    lru,
+			output:  "$6\r\nintset\r\n",
+		},
+		{
+			name:    "listpack encoding",
+			command: &Object{subCommand: "ENCODING", key: "set"},
+			conf:    lru,
+			output:  "$8\r\nlistpack\r\n",
+		},
+		{
+			name:    "idletime",
+			command: &Object{subCommand: "IDLETIME", key: "int"},
+			conf:    lru,
+			output:  ":10\r\n",
+		},
+		{
+			name:    "idletime with lfu",
+			command: &Object{subCommand: "IDLETIME", key: "int"},
+			conf:    lfu,
+			output:  string(idleTimeLFUErr.Append(nil)),
+		},
+		{
+			name:    "freq",
+			command: &Object{subCommand: "FREQ", key: "int"},
+			conf:    lfu,
+			output:  ":20\r\n",
+		},
+		{
+			name:    "freq without lfu",
+			command: &Object{subCommand: "FREQ", key: "int"},
+			conf:    lru,
+			output:  string(freqNoLFUErr.Append(nil)),
+		},
+		{
+			name:    "refcount",
+			command: &Object{subCommand: "REFCOUNT", key: "raw"},
+			conf:    lru,
+			output:  ":1\r\n",
+		},
+		{
+			name:    "missing key",
+			command: &Object{subCommand: "ENCODING", key: "missing"},
+			conf:    lru,
+			output:  "$-1\r\n",
+		},
+		{
+			name:    "expired key",
+			command: &Object{subCommand: "REFCOUNT", key: "expired"},
+			conf:    lru,
+			output:  "$-1\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		writer := &strings.Builder{}
+		if _, err := tt.command.Execute(writer, storage, tt.conf); err != nil {
+			t.Errorf("case %s: failed to execute command: %v", tt.name, err)
+		} else if actual := writer.String(); actual != tt.output {
+			t.Errorf("case %s: expected %q but got %q", tt.name, tt.output, actual)
+		}
+	}
+	if storage.Mem["raw"].AccessAt != now {
+		t.Errorf("expected the keys not to be accessed")
+	}
+}
@@ -40,7 +40,7 @@ func (*Ping) String() string {
 }
 
 func (*Ping) Execute(writer io.Writer, storage *model.RedisStorage, conf *model.CommandConf) (redis.RedisObject, error) {
-	return pingRsp, pingRsp.Write(writer)
+	return pingRsp, redis.WriteObject(writer, pingRsp)
 }
 
 func (*Ping) Read(args *redis.Array) error {
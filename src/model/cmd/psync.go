@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/src/concept"
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+	"github.com/codecrafters-io/redis-starter-go/src/model/rdb"
+	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
+)
+
+var (
+	_ Command = &Psync{}
+)
+
+func init() {
+	commandNameToBuilder[(&Psync{}).Name()] = func() Command {
+		return &Psync{}
+	}
+}
+
+// Psync is the last step of the replica handshake, PSYNC <replid> <offset>,
+// where "PSYNC ? -1" asks for a full resynchronization.
+type Psync struct {
+	replid string
+	offset int64
+
+	diskless bool
+	deferred bool
+}
+
+func (*Psync) Name() string {
+	return "PSYNC"
+}
+
+func (p *Psync) String() string {
+	return fmt.Sprintf("%s[%s, %d]", p.Name(), p.replid, p.offset)
+}
+
+// SetDiskless lets a full resynchronization be deferred to a diskless
+// transfer, for replicas which announced capa eof.
+func (p *Psync) SetDiskless(diskless bool) {
+	p.diskless = diskless
+}
+
+// Deferred tells if Execute left the full resynchronization to a later
+// diskless transfer, see WriteDisklessSync.
+func (p *Psync) Deferred() bool {
+	return p.deferred
+}
+
+// Execute continues from the backlog when it still holds the requested
+// offset of a known history. Otherwise it answers with a full
+// resynchronization, the snapshot is left to WriteSnapshot so it is not
+// encoded while the storage is locked. A diskless full resynchronization
+// replies nothing yet.
+func (p *Psync) Execute(writer io.Writer, storage *model.RedisStorage, conf *model.CommandConf) (redis.RedisObject, error) {
+	p.deferred = false
+	if p.canContinue(conf) {
+		rsp := redis.NewSimpleString(fmt.Sprintf("CONTINUE %s", conf.MasterReplid))
+		if err := redis.WriteObject(writer, rsp); err != nil {
+			return rsp, err
+		}
+		_, err := writer.Write(conf.Backlog.Since(p.offset))
+		return rsp, err
+	} else if p.diskless {
+		p.deferred = true
+		return nil, nil
+	}
+
+	rsp := redis.NewSimpleString(fmt.Sprintf("FULLRESYNC %s %d", conf.MasterReplid, conf.MasterReplOffset))
+	return rsp, redis.WriteObject(writer, rsp)
+}
+
+// WriteSnapshot follows the +FULLRESYNC reply of Execute with view, a copy
+// of the storage taken along with PSYNC. The snapshot is dumped to a
+// temporary file in dir first, then sent like a bulk string without the
+// trailing CRLF.
+func WriteSnapshot(writer io.Writer, view *model.RedisStorage, streamDB int, dir string) error {
+	tmp, err := os.CreateTemp(dir, fmt.Sprintf("temp-sync-%d-*.rdb", os.Getpid()))
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer func() {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+	}()
+
+	buffered := bufio.NewWriter(tmp)
+	if err := rdb.DumpStream(buffered, view, streamDB); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	} else if err := buffered.Flush(); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	} else if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(writer, "$%d\r\n", size); err != nil {
+		return err
+	}
+	_, err = io.Copy(writer, tmp)
+	return err
+}
+
+// WriteDisklessSync answers the replicas waiting for a diskless full
+// resynchronization: +FULLRESYNC at offset of replid, then view as it is
+// encoded, framed by $EOF:<mark> before and the same random mark after.
+func WriteDisklessSync(writer io.Writer, view *model.RedisStorage, replid string, offset int64, streamDB int) error {
+	rsp := redis.NewSimpleString(fmt.Sprintf("FULLRESYNC %s %d", replid, offset))
+	if err := redis.WriteObject(writer, rsp); err != nil {
+		return err
+	}
+	mark := model.NewReplid()
+	if _, err := fmt.Fprintf(writer, "$EOF:%s\r\n", mark); err != nil {
+		return err
+	}
+	if err := rdb.DumpStream(writer, view, streamDB); err != nil {
+		return err
+	}
+	_, err := io.WriteString(writer, mark)
+	return err
+}
+
+// IsFullResync tells if rsp, returned by Execute, started a full
+// resynchronization.
+func (p *Psync) IsFullResync(rsp redis.RedisObject) bool {
+	status, ok := rsp.(*redis.SimpleString)
+	return ok && strings.HasPrefix(status.AsString(), "FULLRESYNC")
+}
+
+func (p *Psync) canContinue(conf *model.CommandConf) bool {
+	if conf.Backlog == nil {
+		return false
+	}
+	switch {
+	case p.replid == conf.MasterReplid:
+	case p.replid == conf.MasterReplid2 && p.offset <= conf.SecondReplOffset:
+	default:
+		return false
+	}
+	return conf.Backlog.Contains(p.offset)
+}
+
+func (p *Psync) Read(args *redis.Array) error {
+	if args == nil || args.Len() != 3 {
+		return &redis.SyntaxError{
+			Msg: "wrong number of arguments",
+		}
+	}
+
+	switch replid := args.Get(1).(type) {
+	case concept.AsString:
+		p.replid = replid.AsString()
+	default:
+		return &redis.SyntaxError{
+			Msg: fmt.Sprintf("unexpected argument type %T as replid", replid),
+		}
+	}
+
+	switch offset := args.Get(2).(type) {
+	case concept.AsInt64:
+		p.offset = offset.AsInt64()
+	case concept.AsString:
+		if n, err := strconv.ParseInt(offset.AsString(), 10, 64); err != nil {
+			return &redis.SyntaxError{
+				Msg: fmt.Sprintf("unexpected offset %s", offset.AsString()),
+			}
+		} else {
+			p.offset = n
+		}
+	default:
+		return &redis.SyntaxError{
+			Msg: fmt.Sprintf("unexpected argument type %T as offset", offset),
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,304 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+	"github.com/codecrafters-io/redis-starter-go/src/model/rdb"
+	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
+)
+
+func TestPsync_Read(t *testing.T) {
+	tests := []struct {
+		name    string
+		command *Psync
+		input   string
+		output  string
+		isError bool
+	}{
+		{
+			name:    "full resync",
+			command: &Psync{},
+			input:   "*3\r\n$5\r\nPSYNC\r\n$1\r\n?\r\n$2\r\n-1\r\n",
+			output:  "PSYNC[?, -1]",
+		},
+		{
+			name:    "malformed offset",
+			command: &Psync{},
+			input:   "*3\r\n$5\r\nPSYNC\r\n$1\r\n?\r\n$1\r\nx\r\n",
+			isError: true,
+		},
+		{
+			name:    "wrong number of arguments",
+			command: &Psync{},
+			input:   "*2\r\n$5\r\nPSYNC\r\n$1\r\n?\r\n",
+			isError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := bufio.NewReader(bytes.NewBufferString(tt.input))
+			if obj, err := redis.ReadObject(reader, redis.ArrayLeading); err != nil {
+				t.Errorf("case %s: failed to read object: %v", tt.name, err)
+			} else if args, ok := obj.(*redis.Array); !ok {
+				t.Errorf("case %s: expected *redis.Array but got %v", tt.name, obj)
+			} else if err := tt.command.Read(args); err != nil {
+				if tt.isError {
+					return
+				}
+				t.Errorf("case %s: failed to read command: %v", tt.name, err)
+			} else if tt.isError {
+				t.Errorf("case %s: expected error but got nil", tt.name)
+			} else if actual := tt.command.String(); actual != tt.output {
+				t.Errorf("case %s: expected %s but got %s", tt.name, tt.output, actual)
+			}
+		})
+	}
+}
+
+func TestPsync_Execute(t *testing.T) {
+	tests := []struct {
+		name    string
+		command *Psync
+		storage *model.RedisStorage
+		conf    *model.CommandConf
+		output  string
+		keys    map[string]string
+		isError bool
+	}{
+		{
+			name:    "full resync of empty storage",
+			command: &Psync{replid: "?", offset: -1},
+			storage: &model.RedisStorage{Mem: map[string]*model.RedisBucket{}},
+			conf: &model.CommandConf{
+				Role:             "master",
+				MasterReplid:     "8371b4fb1155b71f4a04d3e1bc3e18c4a990aeeb",
+				MasterReplOffset: 0,
+			},
+			output:  "+FULLRESYNC 8371b4fb1155b71f4a04d3e1bc3e18c4a990aeeb 0\r\n",
+			keys:    map[string]string{},
+			isError: false,
+		},
+		{
+			name:    "full resync with keys",
+			command: &Psync{replid: "?", offset: -1},
+			storage: &model.RedisStorage{Mem: map[string]*model.RedisBucket{
+				"foo": {Value: []byte("bar"), ExpireAt: math.MaxInt64},
+				"baz": {Value: []byte("qux"), ExpireAt: math.MaxInt64},
+			}},
+			conf: &model.CommandConf{
+				Role:             "master",
+				MasterReplid:     "8371b4fb1155b71f4a04d3e1bc3e18c4a990aeeb",
+				MasterReplOffset: 42,
+			},
+			output:  "+FULLRESYNC 8371b4fb1155b71f4a04d3e1bc3e18c4a990aeeb 42\r\n",
+			keys:    map[string]string{"foo": "bar", "baz": "qux"},
+			isError: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writer := &bytes.Buffer{}
+			if _, err := tt.command.Execute(writer, tt.storage, tt.conf); err != nil {
+				if tt.isError {
+					return
+				}
+				t.Fatalf("case %s: failed to execute command: %v", tt.name, err)
+			} else if tt.isError {
+				t.Fatalf("case %s: expected error but got nil", tt.name)
+			}
+
+			if actual := writer.String(); actual != tt.output {
+				t.Fatalf("case %s: expected %s but got %s", tt.name, tt.output, actual)
+			}
+			// the snapshot follows from a copy taken along with PSYNC
+			if err := WriteSnapshot(writer, tt.storage, 0, t.TempDir()); err != nil {
+				t.Fatalf("case %s: failed to write snapshot: %v", tt.name, err)
+			}
+
+			reader := bufio.NewReader(writer)
+			_, _ = reader.ReadString('\n')
+			header, _ := reader.ReadString('\n')
+			size, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(header, "$"), "\r\n"))
+			if err != nil || size != reader.Buffered() {
+				t.Fatalf("case %s: unexpected snapshot header %q with %d bytes", tt.name, header, reader.Buffered())
+			}
+			snapshot, err := rdb.Load(reader)
+			if err != nil {
+				t.Fatalf("case %s: failed to load snapshot: %v", tt.name, err)
+			}
+			mem := snapshot.DB(0)
+			if len(mem) != len(tt.keys) {
+				t.Errorf("case %s: expected %d keys but got %d", tt.name, len(tt.keys), len(mem))
+			}
+			for k, v := range tt.keys {
+				if bucket, ok := mem[k]; !ok || string(bucket.Value) != v {
+					t.Errorf("case %s: expected %s=%s but got %v", tt.name, k, v, bucket)
+				}
+			}
+		})
+	}
+}
+
+func TestPsync_Execute_Continue(t *testing.T) {
+	backlog := model.NewReplBacklog(64, 101)
+	_, _ = backlog.Write([]byte("0123456789"))
+	conf := &model.CommandConf{
+		Role:             "master",
+		MasterReplid:     "8371b4fb1155b71f4a04d3e1bc3e18c4a990aeeb",
+		MasterReplOffset: 110,
+		MasterReplid2:    "e0d3b8b33a0aab6c8c9d8f4f0a4e6d9e1f0c2b3a",
+		SecondReplOffset: 105,
+		Backlog:          backlog,
+	}
+
+	tests := []struct {
+		name    string
+		command *Psync
+		output  string
+	}{
+		{
+			name:    "continue from the backlog",
+			command: &Psync{replid: conf.MasterReplid, offset: 104},
+			output:  "+CONTINUE 8371b4fb1155b71f4a04d3e1bc3e18c4a990aeeb\r\n3456789",
+		},
+		{
+			name:    "continue with nothing to send",
+			command: &Psync{replid: conf.MasterReplid, offset: 111},
+			output:  "+CONTINUE 8371b4fb1155b71f4a04d3e1bc3e18c4a990aeeb\r\n",
+		},
+		{
+			name:    "continue the previous history",
+			command: &Psync{replid: conf.MasterReplid2, offset: 105},
+			output:  "+CONTINUE 8371b4fb1155b71f4a04d3e1bc3e18c4a990aeeb\r\n456789",
+		},
+		{
+			name:    "previous history past the switch",
+			command: &Psync{replid: conf.MasterReplid2, offset: 106},
+			output:  "+FULLRESYNC 8371b4fb1155b71f4a04d3e1bc3e18c4a990aeeb 110\r\n",
+		},
+		{
+			name:    "offset no longer in the backlog",
+			command: &Psync{replid: conf.MasterReplid, offset: 100},
+			output:  "+FULLRESYNC 8371b4fb1155b71f4a04d3e1bc3e18c4a990aeeb 110\r\n",
+		},
+		{
+			name:    "unknown replid",
+			command: &Psync{replid: "?", offset: -1},
+			output:  "+FULLRESYNC 8371b4fb1155b71f4a04d3e1bc3e18c4a990aeeb 110\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writer := &bytes.Buffer{}
+			storage := &model.RedisStorage{Mem: map[string]*model.RedisBucket{}}
+			rsp, err := tt.command.Execute(writer, storage, conf)
+			if err != nil {
+				t.Fatalf("case %s: failed to execute command: %v", tt.name, err)
+			}
+			full := tt.command.IsFullResync(rsp)
+			if full != strings.HasPrefix(tt.output, "+FULLRESYNC") {
+				t.Errorf("case %s: unexpected full resync %v", tt.name, full)
+			}
+			if full {
+				// followed by the snapshot
+				if actual := writer.String(); !strings.HasPrefix(actual, tt.output) {
+					t.Errorf("case %s: expected prefix %q but got %q", tt.name, tt.output, actual)
+				}
+			} else if actual := writer.String(); actual != tt.output {
+				t.Errorf("case %s: expected %q but got %q", tt.name, tt.output, actual)
+			}
+		})
+	}
+}
+
+func TestPsync_Execute_Diskless(t *testing.T) {
+	backlog := model.NewReplBacklog(64, 101)
+	_, _ = backlog.Write([]byte("0123456789"))
+	conf := &model.CommandConf{
+		Role:             "master",
+		MasterReplid:     "8371b4fb1155b71f4a04d3e1bc3e18c4a990aeeb",
+		MasterReplOffset: 110,
+		Backlog:          backlog,
+	}
+
+	tests := []struct {
+		name     string
+		command  *Psync
+		output   string
+		deferred bool
+	}{
+		{
+			name:    "continue is still sent at once",
+			command: &Psync{replid: conf.MasterReplid, offset: 108},
+			output:  "+CONTINUE 8371b4fb1155b71f4a04d3e1bc3e18c4a990aeeb\r\n789",
+		},
+		{
+			name:     "full resync is deferred",
+			command:  &Psync{replid: "?", offset: -1},
+			output:   "",
+			deferred: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writer := &bytes.Buffer{}
+			storage := &model.RedisStorage{Mem: map[string]*model.RedisBucket{}}
+			tt.command.SetDiskless(true)
+			if _, err := tt.command.Execute(writer, storage, conf); err != nil {
+				t.Fatalf("case %s: failed to execute command: %v", tt.name, err)
+			}
+			if actual := writer.String(); actual != tt.output {
+				t.Errorf("case %s: expected %q but got %q", tt.name, tt.output, actual)
+			}
+			if actual := tt.command.Deferred(); actual != tt.deferred {
+				t.Errorf("case %s: expected deferred %v but got %v", tt.name, tt.deferred, actual)
+			}
+		})
+	}
+}
+
+func TestWriteDisklessSync(t *testing.T) {
+	storage := &model.RedisStorage{Mem: map[string]*model.RedisBucket{
+		"foo": {Value: []byte("bar"), ExpireAt: math.MaxInt64},
+	}}
+	conf := &model.CommandConf{
+		Role:             "master",
+		MasterReplid:     "8371b4fb1155b71f4a04d3e1bc3e18c4a990aeeb",
+		MasterReplOffset: 42,
+	}
+	writer := &bytes.Buffer{}
+	if err := WriteDisklessSync(writer, storage, conf.MasterReplid, conf.MasterReplOffset, conf.ReplStreamDB); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	output := writer.Bytes()
+	reader := bufio.NewReader(bytes.NewReader(output))
+	if line, _ := reader.ReadString('\n'); line != "+FULLRESYNC 8371b4fb1155b71f4a04d3e1bc3e18c4a990aeeb 42\r\n" {
+		t.Fatalf("unexpected reply %q", line)
+	}
+	header, _ := reader.ReadString('\n')
+	mark := strings.TrimSuffix(strings.TrimPrefix(header, "$EOF:"), "\r\n")
+	if len(mark) != 40 {
+		t.Fatalf("unexpected snapshot header %q", header)
+	}
+	rest := output[len(output)-reader.Buffered():]
+	if !bytes.HasSuffix(rest, []byte(mark)) {
+		t.Fatalf("expected the snapshot to end with the mark %s", mark)
+	}
+	snapshot, err := rdb.Load(bytes.NewReader(rest[:len(rest)-len(mark)]))
+	if err != nil {
+		t.Fatalf("failed to load snapshot: %v", err)
+	} else if bucket := snapshot.DB(0)["foo"]; bucket == nil || string(bucket.Value) != "bar" {
+		t.Errorf("expected foo=bar but got %v", bucket)
+	}
+}
@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/src/concept"
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
+)
+
+var (
+	_ Command = &ReplConf{}
+)
+
+func init() {
+	commandNameToBuilder[(&ReplConf{}).Name()] = func() Command {
+		return &ReplConf{}
+	}
+}
+
+// ReplConf is sent by replicas during the handshake to describe themselves,
+// e.g. REPLCONF listening-port 6380 or REPLCONF capa psync2.
+type ReplConf struct {
+	options [][2]string
+}
+
+func (*ReplConf) Name() string {
+	return "REPLCONF"
+}
+
+func (r *ReplConf) String() string {
+	return fmt.Sprintf("%s%v", r.Name(), r.options)
+}
+
+// Option returns the last value given for name.
+func (r *ReplConf) Option(name string) (string, bool) {
+	for i := len(r.options) - 1; i >= 0; i-- {
+		if r.options[i][0] == name {
+			return r.options[i][1], true
+		}
+	}
+	return "", false
+}
+
+// Capa tells if the replica announced the capability, e.g. eof for
+// diskless snapshots. REPLCONF capa may repeat.
+func (r *ReplConf) Capa(name string) bool {
+	for _, option := range r.options {
+		if option[0] == "capa" && strings.EqualFold(option[1], name) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetAck tells if the master asks for the processed offset.
+func (r *ReplConf) GetAck() bool {
+	_, ok := r.Option("getack")
+	return ok
+}
+
+// Ack returns the offset a replica acknowledges.
+func (r *ReplConf) Ack() (int64, bool) {
+	value, ok := r.Option("ack")
+	if !ok {
+		return 0, false
+	}
+	offset, _ := strconv.ParseInt(value, 10, 64)
+	return offset, true
+}
+
+// Execute answers GETACK with REPLCONF ACK <offset>, where the offset does
+// not count the GETACK itself yet. ACK is never answered.
+func (r *ReplConf) Execute(writer io.Writer, storage *model.RedisStorage, conf *model.CommandConf) (redis.RedisObject, error) {
+	if r.GetAck() {
+		rsp := redis.NewArray(
+			redis.NewBulkString([]byte(r.Name())),
+			redis.NewBulkString([]byte("ACK")),
+			redis.NewBulkString([]byte(strconv.FormatInt(conf.MasterReplOffset, 10))),
+		)
+		return rsp, redis.WriteObject(writer, rsp)
+	} else if _, ok := r.Ack(); ok {
+		return nil, nil
+	}
+	rsp := OK
+	return rsp, redis.WriteObject(writer, rsp)
+}
+
+func (r *ReplConf) Read(args *redis.Array) error {
+	if args == nil || args.Len() < 3 || args.Len()%2 != 1 {
+		return &redis.SyntaxError{
+			Msg: "wrong number of arguments",
+		}
+	}
+
+	r.options = r.options[:0]
+	for i := 1; i < args.Len(); i += 2 {
+		var name, value string
+		switch nameObj := args.Get(i).(type) {
+		case concept.AsString:
+			name = strings.ToLower(nameObj.AsString())
+		default:
+			return &redis.SyntaxError{
+				Msg: fmt.Sprintf("unexpected argument type %T as option", nameObj),
+			}
+		}
+		switch valueObj := args.Get(i + 1).(type) {
+		case concept.AsString:
+			value = valueObj.AsString()
+		default:
+			return &redis.SyntaxError{
+				Msg: fmt.Sprintf("unexpected argument type %T as value", valueObj),
+			}
+		}
+
+		switch name {
+		case "listening-port":
+			if port, err := strconv.Atoi(value); err != nil || port < 0 || port > 65535 {
+				return &redis.SyntaxError{
+					Msg: fmt.Sprintf("invalid listening-port %s", value),
+				}
+			}
+		case "ack":
+			if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+				return &redis.SyntaxError{
+					Msg: fmt.Sprintf("invalid ack offset %s", value),
+				}
+			}
+		case "fack":
+			// the AOF offset Redis 7 replicas send along with ACK
+			continue
+		case "capa", "ip-address", "getack":
+		default:
+			return &redis.SyntaxError{
+				Msg: fmt.Sprintf("unrecognized REPLCONF option: %s", name),
+			}
+		}
+		r.options = append(r.options, [2]string{name, value})
+	}
+
+	return nil
+}
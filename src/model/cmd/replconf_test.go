@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
+)
+
+func TestReplConf_Read(t *testing.T) {
+	tests := []struct {
+		name    string
+		command *ReplConf
+		input   string
+		output  string
+		isError bool
+	}{
+		{
+			name:    "listening port",
+			command: &ReplConf{},
+			input:   "*3\r\n$8\r\nREPLCONF\r\n$14\r\nlistening-port\r\n$4\r\n6380\r\n",
+			output:  "REPLCONF[[listening-port 6380]]",
+		},
+		{
+			name:    "multiple capa",
+			command: &ReplConf{},
+			input:   "*5\r\n$8\r\nREPLCONF\r\n$4\r\ncapa\r\n$3\r\neof\r\n$4\r\ncapa\r\n$6\r\npsync2\r\n",
+			output:  "REPLCONF[[capa eof] [capa psync2]]",
+		},
+		{
+			name:    "invalid port",
+			command: &ReplConf{},
+			input:   "*3\r\n$8\r\nREPLCONF\r\n$14\r\nlistening-port\r\n$3\r\nabc\r\n",
+			isError: true,
+		},
+		{
+			name:    "getack",
+			command: &ReplConf{},
+			input:   "*3\r\n$8\r\nREPLCONF\r\n$6\r\nGETACK\r\n$1\r\n*\r\n",
+			output:  "REPLCONF[[getack *]]",
+		},
+		{
+			name:    "ack",
+			command: &ReplConf{},
+			input:   "*3\r\n$8\r\nREPLCONF\r\n$3\r\nACK\r\n$3\r\n154\r\n",
+			output:  "REPLCONF[[ack 154]]",
+		},
+		{
+			name:    "ack with fack",
+			command: &ReplConf{},
+			input:   "*5\r\n$8\r\nREPLCONF\r\n$3\r\nACK\r\n$3\r\n154\r\n$4\r\nFACK\r\n$2\r\n42\r\n",
+			output:  "REPLCONF[[ack 154]]",
+		},
+		{
+			name:    "invalid ack",
+			command: &ReplConf{},
+			input:   "*3\r\n$8\r\nREPLCONF\r\n$3\r\nACK\r\n$1\r\nx\r\n",
+			isError: true,
+		},
+		{
+			name:    "unknown option",
+			command: &ReplConf{},
+			input:   "*3\r\n$8\r\nREPLCONF\r\n$7\r\nunknown\r\n$1\r\n1\r\n",
+			isError: true,
+		},
+		{
+			name:    "wrong number of arguments",
+			command: &ReplConf{},
+			input:   "*2\r\n$8\r\nREPLCONF\r\n$4\r\ncapa\r\n",
+			isError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := bufio.NewReader(bytes.NewBufferString(tt.input))
+			if obj, err := redis.ReadObject(reader, redis.ArrayLeading); err != nil {
+				t.Errorf("case %s: failed to read object: %v", tt.name, err)
+			} else if args, ok := obj.(*redis.Array); !ok {
+				t.Errorf("case %s: expected *redis.Array but got %v", tt.name, obj)
+			} else if err := tt.command.Read(args); err != nil {
+				if tt.isError {
+					return
+				}
+				t.Errorf("case %s: failed to read command: %v", tt.name, err)
+			} else if tt.isError {
+				t.Errorf("case %s: expected error but got nil", tt.name)
+			} else if actual := tt.command.String(); actual != tt.output {
+				t.Errorf("case %s: expected %s but got %s", tt.name, tt.output, actual)
+			}
+		})
+	}
+}
+
+func TestReplConf_Execute(t *testing.T) {
+	tests := []struct {
+		name    string
+		command *ReplConf
+		conf    *model.CommandConf
+		output  string
+		isError bool
+	}{
+		{
+			name:    "normal",
+			command: &ReplConf{options: [][2]string{{"capa", "psync2"}}},
+			conf:    &model.CommandConf{},
+			output:  "+OK\r\n",
+			isError: false,
+		},
+		{
+			name:    "getack",
+			command: &ReplConf{options: [][2]string{{"getack", "*"}}},
+			conf:    &model.CommandConf{Role: "slave", MasterReplOffset: 154},
+			output:  "*3\r\n$8\r\nREPLCONF\r\n$3\r\nACK\r\n$3\r\n154\r\n",
+			isError: false,
+		},
+		{
+			name:    "ack is not answered",
+			command: &ReplConf{options: [][2]string{{"ack", "154"}}},
+			conf:    &model.CommandConf{},
+			output:  "",
+			isError: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writer := &strings.Builder{}
+			if _, err := tt.command.Execute(writer, nil, tt.conf); err != nil {
+				if tt.isError {
+					return
+				}
+				t.Errorf("case %s: failed to execute command: %v", tt.name, err)
+			} else if tt.isError {
+				t.Errorf("case %s: expected error but got nil", tt.name)
+			} else if actual := writer.String(); actual != tt.output {
+				t.Errorf("case %s: expected %s but got %s", tt.name, tt.output, actual)
+			}
+		})
+	}
+}
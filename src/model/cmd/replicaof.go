@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/src/concept"
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
+)
+
+var (
+	_ Command = &ReplicaOf{}
+)
+
+func init() {
+	for _, name := range []string{"REPLICAOF", "SLAVEOF"} {
+		name := name
+		commandNameToBuilder[name] = func() Command {
+			return &ReplicaOf{name: name}
+		}
+	}
+}
+
+// ReplicaOf switches the role at runtime, REPLICAOF host port starts
+// replicating another server and REPLICAOF NO ONE promotes a replica. The
+// switch itself is done by the connection handler, which owns the
+// replication goroutine.
+type ReplicaOf struct {
+	name  string
+	host  string
+	port  int
+	noOne bool
+}
+
+func (r *ReplicaOf) Name() string {
+	if r.name == "" {
+		return "REPLICAOF"
+	}
+	return r.name
+}
+
+func (r *ReplicaOf) String() string {
+	if r.noOne {
+		return fmt.Sprintf("%s[NO ONE]", r.Name())
+	}
+	return fmt.Sprintf("%s[%s, %d]", r.Name(), r.host, r.port)
+}
+
+// NoOne tells if the command promotes this server to a master.
+func (r *ReplicaOf) NoOne() bool {
+	return r.noOne
+}
+
+func (r *ReplicaOf) Host() string {
+	return r.host
+}
+
+func (r *ReplicaOf) Port() int {
+	return r.port
+}
+
+// Unchanged tells if conf already is in the requested role.
+func (r *ReplicaOf) Unchanged(conf *model.CommandConf) bool {
+	if r.noOne {
+		return conf.Role != "slave"
+	}
+	return conf.Role == "slave" && conf.ReplicaofAddress == r.host && conf.ReplicaofPort == r.port
+}
+
+func (r *ReplicaOf) Execute(writer io.Writer, storage *model.RedisStorage, conf *model.CommandConf) (redis.RedisObject, error) {
+	rsp := OK
+	if !r.noOne && r.Unchanged(conf) {
+		rsp = redis.NewSimpleString("OK Already connected to specified master")
+	}
+	return rsp, redis.WriteObject(writer, rsp)
+}
+
+func (r *ReplicaOf) Read(args *redis.Array) error {
+	if args == nil || args.Len() != 3 {
+		return &redis.SyntaxError{
+			Msg: "wrong number of arguments",
+		}
+	}
+
+	var values [2]string
+	for i := range values {
+		switch arg := args.Get(i + 1).(type) {
+		case concept.AsString:
+			values[i] = arg.AsString()
+		default:
+			return &redis.SyntaxError{
+				Msg: fmt.Sprintf("unexpected argument type %T", arg),
+			}
+		}
+	}
+
+	if strings.EqualFold(values[0], "no") && strings.EqualFold(values[1], "one") {
+		r.noOne, r.host, r.port = true, "", 0
+		return nil
+	}
+	port, err := strconv.Atoi(values[1])
+	if err != nil || port <= 0 || port > 65535 {
+		return &redis.SyntaxError{
+			Msg: fmt.Sprintf("invalid master port %s", values[1]),
+		}
+	}
+	r.noOne, r.host, r.port = false, values[0], port
+	return nil
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
+)
+
+func TestReplicaOf_Read(t *testing.T) {
+	tests := []struct {
+		name    string
+		command *ReplicaOf
+		input   string
+		output  string
+		isError bool
+	}{
+		{
+			name:    "replicate a master",
+			command: &ReplicaOf{},
+			input:   "*3\r\n$9\r\nREPLICAOF\r\n$9\r\nlocalhost\r\n$4\r\n6379\r\n",
+			output:  "REPLICAOF[localhost, 6379]",
+		},
+		{
+			name:    "promote",
+			command: &ReplicaOf{name: "SLAVEOF"},
+			input:   "*3\r\n$7\r\nSLAVEOF\r\n$2\r\nno\r\n$3\r\nOne\r\n",
+			output:  "SLAVEOF[NO ONE]",
+		},
+		{
+			name:    "invalid port",
+			command: &ReplicaOf{},
+			input:   "*3\r\n$9\r\nREPLICAOF\r\n$9\r\nlocalhost\r\n$5\r\n70000\r\n",
+			isError: true,
+		},
+		{
+			name:    "wrong number of arguments",
+			command: &ReplicaOf{},
+			input:   "*2\r\n$9\r\nREPLICAOF\r\n$9\r\nlocalhost\r\n",
+			isError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := bufio.NewReader(bytes.NewBufferString(tt.input))
+			if obj, err := redis.ReadObject(reader, redis.ArrayLeading); err != nil {
+				t.Errorf("case %s: failed to read object: %v", tt.name, err)
+			} else if args, ok := obj.(*redis.Array); !ok {
+				t.Errorf("case %s: expected *redis.Array but got %v", tt.name, obj)
+			} else if err := tt.command.Read(args); err != nil {
+				if tt.isError {
+					return
+				}
+				t.Errorf("case %s: failed to read command: %v", tt.name, err)
+			} else if tt.isError {
+				t.Errorf("case %s: expected error but got nil", tt.name)
+			} else if actual := tt.command.String(); actual != tt.output {
+				t.Errorf("case %s: expected %s but got %s", tt.name, tt.output, actual)
+			}
+		})
+	}
+}
+
+func TestReplicaOf_Execute(t *testing.T) {
+	replica := &model.CommandConf{Role: "slave", ReplicaofAddress: "localhost", ReplicaofPort: 6379}
+	tests := []struct {
+		name      string
+		command   *ReplicaOf
+		conf      *model.CommandConf
+		output    string
+		unchanged bool
+	}{
+		{
+			name:    "master to replica",
+			command: &ReplicaOf{host: "localhost", port: 6379},
+			conf:    &model.CommandConf{Role: "master"},
+			output:  "+OK\r\n",
+		},
+		{
+			name:      "same master",
+			command:   &ReplicaOf{host: "localhost", port: 6379},
+			conf:      replica,
+			output:    "+OK Already connected to specified master\r\n",
+			unchanged: true,
+		},
+		{
+			name:    "another master",
+			command: &ReplicaOf{host: "localhost", port: 6380},
+			conf:    replica,
+			output:  "+OK\r\n",
+		},
+		{
+			name:    "promote",
+			command: &ReplicaOf{noOne: true},
+			conf:    replica,
+			output:  "+OK\r\n",
+		},
+		{
+			name:      "promote a master",
+			command:   &ReplicaOf{noOne: true},
+			conf:      &model.CommandConf{Role: "master"},
+			output:    "+OK\r\n",
+			unchanged: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writer := &strings.Builder{}
+			if _, err := tt.command.Execute(writer, nil, tt.conf); err != nil {
+				t.Errorf("case %s: failed to execute command: %v", tt.name, err)
+			} else if actual := writer.String(); actual != tt.output {
+				t.Errorf("case %s: expected %q but got %q", tt.name, tt.output, actual)
+			} else if unchanged := tt.command.Unchanged(tt.conf); unchanged != tt.unchanged {
+				t.Errorf("case %s: expected unchanged %v but got %v", tt.name, tt.unchanged, unchanged)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+	"github.com/codecrafters-io/redis-starter-go/src/model/rdb"
+	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
+)
+
+var (
+	_ DenyOOMCommand = &Restore{}
+
+	busyKeyErr     = redis.NewSimpleError("BUSYKEY Target key name already exists.")
+	badPayloadErr  = redis.NewSimpleError("ERR DUMP payload version or checksum are wrong")
+	badDataFormErr = redis.NewSimpleError("ERR Bad data format")
+)
+
+func init() {
+	commandNameToBuilder[(&Restore{}).Name()] = func() Command {
+		return &Restore{}
+	}
+}
+
+// Restore creates a key from a DUMP payload, RESTORE <key> <ttl> <payload>
+// [REPLACE] [ABSTTL] [IDLETIME seconds] [FREQ frequency]. A ttl of 0 never
+// expires, otherwise it is in milliseconds, or a unix time in milliseconds
+// with ABSTTL. IDLETIME and FREQ set the access time and counter of the
+// key, see OBJECT.
+type Restore struct {
+	key      string
+	expireAt int64
+	payload  []byte
+	replace  bool
+	idle     int64
+	freq     int64
+
+	// restored tells if Execute changed the keyspace
+	restored bool
+}
+
+func (*Restore) Name() string {
+	return "RESTORE"
+}
+
+func (*Restore) DenyOOM() {}
+
+func (r *Restore) String() string {
+	return fmt.Sprintf("%s[%s, %d bytes, %v, %v]", r.Name(), r.key, len(r.payload), r.expireAt, r.replace)
+}
+
+// Execute checks the payload before touching the key. A key restored with
+// an expire time already passed is only deleted.
+func (r *Restore) Execute(writer io.Writer, storage *model.RedisStorage, conf *model.CommandConf) (redis.RedisObject, error) {
+	r.restored = false
+	now := time.Now().UnixMilli()
+	if old, found := storage.Mem[r.key]; found && old.ExpireAt >= now && !r.replace {
+		return busyKeyErr, redis.WriteObject(writer, busyKeyErr)
+	}
+	bucket, err := rdb.LoadValue(r.payload)
+	if errors.Is(err, rdb.ErrPayloadVersion) {
+		return badPayloadErr, redis.WriteObject(writer, badPayloadErr)
+	} else if err != nil {
+		return badDataFormErr, redis.WriteObject(writer, badDataFormErr)
+	}
+
+	r.restored = true
+	if r.expireAt < now {
+		storage.Delete(r.key)
+	} else {
+		bucket.ExpireAt = r.expireAt
+		storage.Set(r.key, bucket, now)
+		if r.idle >= 0 {
+			bucket.AccessAt = now - r.idle*1000
+		}
+		if r.freq >= 0 {
+			bucket.Freq = uint8(r.freq)
+		}
+	}
+	rsp := OK
+	return rsp, redis.WriteObject(writer, rsp)
+}
+
+// Propagate replaces the key with an absolute expire time, as the key was
+// replaced or created here. It is nil when nothing was restored.
+func (r *Restore) Propagate() *redis.Array {
+	if !r.restored {
+		return nil
+	}
+	ttl := r.expireAt
+	if ttl == math.MaxInt64 {
+		ttl = 0
+	}
+	args := []redis.RedisObject{
+		redis.NewBulkString([]byte(r.Name())),
+		redis.NewBulkString([]byte(r.key)),
+		redis.NewBulkString([]byte(strconv.FormatInt(ttl, 10))),
+		redis.NewBulkString(r.payload),
+		redis.NewBulkString([]byte("REPLACE")),
+		redis.NewBulkString([]byte("ABSTTL")),
+	}
+	if r.idle >= 0 {
+		args = append(args,
+			redis.NewBulkString([]byte("IDLETIME")),
+			redis.NewBulkString([]byte(strconv.FormatInt(r.idle, 10))),
+		)
+	}
+	if r.freq >= 0 {
+		args = append(args,
+			redis.NewBulkString([]byte("FREQ")),
+			redis.NewBulkString([]byte(strconv.FormatInt(r.freq, 10))),
+		)
+	}
+	return redis.NewArray(args...)
+}
+
+func (r *Restore) Read(args *redis.Array) error {
+	if args == nil || args.Len() < 4 {
+		return &redis.SyntaxError{
+			Msg: "wrong number of arguments",
+		}
+	}
+
+	var (
+		ttl    int64
+		absTTL bool
+		err    error
+	)
+	if r.key, err = argString(args.Get(1), "key"); err != nil {
+		return err
+	} else if ttl, err = argInt64(args.Get(2), "ttl"); err != nil {
+		return err
+	} else if r.payload, err = argBytes(args.Get(3), "payload"); err != nil {
+		return err
+	}
+	r.replace, r.idle, r.freq = false, -1, -1
+	for i := 4; i < args.Len(); i++ {
+		opt, err := argString(args.Get(i), "option")
+		if err != nil {
+			return err
+		}
+		switch opt = strings.ToUpper(opt); {
+		case opt == "REPLACE":
+			r.replace = true
+		case opt == "ABSTTL":
+			absTTL = true
+		case opt == "IDLETIME" && i+1 < args.Len() && r.freq < 0:
+			i++
+			if r.idle, err = argInt64(args.Get(i), "idletime"); err != nil {
+				return err
+			} else if r.idle < 0 {
+				return &redis.SyntaxError{
+					Msg: "Invalid IDLETIME value, must be >= 0",
+				}
+			}
+		case opt == "FREQ" && i+1 < args.Len() && r.idle < 0:
+			i++
+			if r.freq, err = argInt64(args.Get(i), "freq"); err != nil {
+				return err
+			} else if r.freq < 0 || r.freq > 255 {
+				return &redis.SyntaxError{
+					Msg: "Invalid FREQ value, must be >= 0 and <= 255",
+				}
+			}
+		default:
+			return &redis.SyntaxError{
+				Msg: fmt.Sprintf("syntax error at option %s", opt),
+			}
+		}
+	}
+
+	switch {
+	case ttl < 0:
+		return &redis.SyntaxError{
+			Msg: "Invalid TTL value, must be >= 0",
+		}
+	case ttl == 0:
+		r.expireAt = math.MaxInt64
+	case absTTL:
+		r.expireAt = ttl
+	default:
+		r.expireAt = time.Now().UnixMilli() + ttl
+	}
+	return nil
+}
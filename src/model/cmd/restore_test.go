@@ -0,0 +1,253 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+	"github.com/codecrafters-io/redis-starter-go/src/model/rdb"
+	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
+)
+
+func TestRestore_Read(t *testing.T) {
+	tests := []struct {
+		name    string
+		command *Restore
+		input   string
+		output  string
+		isError bool
+	}{
+		{
+			name:    "normal",
+			command: &Restore{},
+			input:   "*4\r\n$7\r\nRESTORE\r\n$3\r\nkey\r\n$1\r\n0\r\n$3\r\nabc\r\n",
+			output:  "RESTORE[key, 3 bytes, 9223372036854775807, false]",
+		},
+		{
+			name:    "absttl and replace",
+			command: &Restore{},
+			input:   "*6\r\n$7\r\nRESTORE\r\n$3\r\nkey\r\n$13\r\n1700000000000\r\n$3\r\nabc\r\n$6\r\nabsttl\r\n$7\r\nREPLACE\r\n",
+			output:  "RESTORE[key, 3 bytes, 1700000000000, true]",
+		},
+		{
+			name:    "idletime",
+			command: &Restore{},
+			input:   "*6\r\n$7\r\nRESTORE\r\n$3\r\nkey\r\n$1\r\n0\r\n$3\r\nabc\r\n$8\r\nIDLETIME\r\n$2\r\n10\r\n",
+			output:  "RESTORE[key, 3 bytes, 9223372036854775807, false]",
+		},
+		{
+			name:    "negative ttl",
+			command: &Restore{},
+			input:   "*4\r\n$7\r\nRESTORE\r\n$3\r\nkey\r\n$2\r\n-1\r\n$3\r\nabc\r\n",
+			isError: true,
+		},
+		{
+			name:    "idletime and freq",
+			command: &Restore{},
+			input:   "*8\r\n$7\r\nRESTORE\r\n$3\r\nkey\r\n$1\r\n0\r\n$3\r\nabc\r\n$8\r\nIDLETIME\r\n$1\r\n1\r\n$4\r\nFREQ\r\n$1\r\n1\r\n",
+			isError: true,
+		},
+		{
+			name:    "freq out of range",
+			command: &Restore{},
+			input:   "*6\r\n$7\r\nRESTORE\r\n$3\r\nkey\r\n$1\r\n0\r\n$3\r\nabc\r\n$4\r\nFREQ\r\n$3\r\n256\r\n",
+			isError: true,
+		},
+		{
+			name:    "unknown option",
+			command: &Restore{},
+			input:   "*5\r\n$7\r\nRESTORE\r\n$3\r\nkey\r\n$1\r\n0\r\n$3\r\nabc\r\n$2\r\nNX\r\n",
+			isError: true,
+		},
+		{
+			name:    "wrong number of arguments",
+			command: &Restore{},
+			input:   "*3\r\n$7\r\nRESTORE\r\n$3\r\nkey\r\n$1\r\n0\r\n",
+			isError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		reader := bufio.NewReader(bytes.NewBufferString(tt.input))
+		if obj, err := redis.ReadObject(reader, redis.ArrayLeading); err != nil {
+			t.Errorf("case %s: failed to read object: %v", tt.name, err)
+		} else if err := tt.command.Read(obj.(*redis.Array)); err != nil {
+			if !tt.isError {
+				t.Errorf("case %s: failed to read command: %v", tt.name, err)
+			}
+		} else if tt.isError {
+			t.Errorf("case %s: expected error but got nil", tt.name)
+		} else if actual := tt.command.String(); actual != tt.output {
+			t.Errorf("case %s: expected %s but got %s", tt.name, tt.output, actual)
+		}
+	}
+}
+
+func TestRestore_Execute(t *testing.T) {
+	var (
+		never   = int64(math.MaxInt64)
+		later   = time.Now().Add(time.Hour).UnixMilli()
+		payload = rdb.DumpValue(&model.RedisBucket{Type: model.TypeSet, Set: map[string]struct{}{"m": {}}})
+		corrupt = append(append([]byte{}, payload[:len(payload)-1]...), payload[len(payload)-1]^1)
+		// an intset of 2 bytes, too short for its header
+		badData = []byte{rdb.TypeSetIntset, 2, 'a', 'b', rdb.Version, 0}
+	)
+	badData = binary.LittleEndian.AppendUint64(badData, rdb.Checksum(0, badData))
+	tests := []struct {
+		name       string
+		command    *Restore
+		storage    *model.RedisStorage
+		output     string
+		restored   bool
+		memChecker func(*model.RedisStorage) error
+	}{
+		{
+			name:     "normal",
+			command:  &Restore{key: "key", payload: payload, expireAt: later},
+			storage:  &model.RedisStorage{Mem: map[string]*model.RedisBucket{}},
+			output:   "+OK\r\n",
+			restored: true,
+			memChecker: func(storage *model.RedisStorage) error {
+				if bucket, ok := storage.Mem["key"]; !ok {
+					return fmt.Errorf("key not found")
+				} else if _, ok := bucket.Set["m"]; bucket.Type != model.TypeSet || !ok {
+					return fmt.Errorf("unexpected value %+v", bucket)
+				} else if bucket.ExpireAt != later {
+					return fmt.Errorf("expected expireAt %d, actual=%d", later, bucket.ExpireAt)
+				}
+				return nil
+			},
+		},
+		{
+			name:     "idletime",
+			command:  &Restore{key: "key", payload: payload, expireAt: never, idle: 60, freq: -1},
+			storage:  &model.RedisStorage{Mem: map[string]*model.RedisBucket{}},
+			output:   "+OK\r\n",
+			restored: true,
+			memChecker: func(storage *model.RedisStorage) error {
+				if idle := time.Now().UnixMilli() - storage.Mem["key"].AccessAt; idle < 60_000 || idle > 61_000 {
+					return fmt.Errorf("expected an idle time of 60s, actual=%dms", idle)
+				} else if freq := storage.Mem["key"].Freq; freq != model.LFUInitVal {
+					return fmt.Errorf("expected the initial counter, actual=%d", freq)
+				}
+				return nil
+			},
+		},
+		{
+			name:     "freq",
+			command:  &Restore{key: "key", payload: payload, expireAt: never, idle: -1, freq: 100},
+			storage:  &model.RedisStorage{Mem: map[string]*model.RedisBucket{}},
+			output:   "+OK\r\n",
+			restored: true,
+			memChecker: func(storage *model.RedisStorage) error {
+				if freq := storage.Mem["key"].Freq; freq != 100 {
+					return fmt.Errorf("expected a counter of 100, actual=%d", freq)
+				}
+				return nil
+			},
+		},
+		{
+			name:    "busy key",
+			command: &Restore{key: "key", payload: payload, expireAt: never},
+			storage: &model.RedisStorage{Mem: map[string]*model.RedisBucket{"key": {Value: []byte("v"), ExpireAt: never}}},
+			output:  "-BUSYKEY Target key name already exists.\r\n",
+			memChecker: func(storage *model.RedisStorage) error {
+				if string(storage.Mem["key"].Value) != "v" {
+					return fmt.Errorf("expected the key to be kept")
+				}
+				return nil
+			},
+		},
+		{
+			name:     "expired key is not busy",
+			command:  &Restore{key: "key", payload: payload, expireAt: never},
+			storage:  &model.RedisStorage{Mem: map[string]*model.RedisBucket{"key": {Value: []byte("v"), ExpireAt: 1}}},
+			output:   "+OK\r\n",
+			restored: true,
+		},
+		{
+			name:     "replace",
+			command:  &Restore{key: "key", payload: payload, expireAt: never, replace: true},
+			storage:  &model.RedisStorage{Mem: map[string]*model.RedisBucket{"key": {Value: []byte("v"), ExpireAt: never}}},
+			output:   "+OK\r\n",
+			restored: true,
+			memChecker: func(storage *model.RedisStorage) error {
+				if storage.Mem["key"].Type != model.TypeSet {
+					return fmt.Errorf("expected the key to be replaced")
+				}
+				return nil
+			},
+		},
+		{
+			name:     "already expired",
+			command:  &Restore{key: "key", payload: payload, expireAt: 1, replace: true},
+			storage:  &model.RedisStorage{Mem: map[string]*model.RedisBucket{"key": {Value: []byte("v"), ExpireAt: never}}},
+			output:   "+OK\r\n",
+			restored: true,
+			memChecker: func(storage *model.RedisStorage) error {
+				if _, ok := storage.Mem["key"]; ok {
+					return fmt.Errorf("expected the key to be deleted")
+				}
+				return nil
+			},
+		},
+		{
+			name:    "wrong checksum",
+			command: &Restore{key: "key", payload: corrupt, expireAt: never},
+			storage: &model.RedisStorage{Mem: map[string]*model.RedisBucket{}},
+			output:  "-ERR DUMP payload version or checksum are wrong\r\n",
+		},
+		{
+			name:    "bad data",
+			command: &Restore{key: "key", payload: badData, expireAt: never},
+			storage: &model.RedisStorage{Mem: map[string]*model.RedisBucket{}},
+			output:  "-ERR Bad data format\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		writer := &strings.Builder{}
+		if _, err := tt.command.Execute(writer, tt.storage, &model.CommandConf{}); err != nil {
+			t.Errorf("case %s: failed to execute command: %v", tt.name, err)
+		} else if actual := writer.String(); actual != tt.output {
+			t.Errorf("case %s: expected %q but got %q", tt.name, tt.output, actual)
+		} else if restored := tt.command.Propagate() != nil; restored != tt.restored {
+			t.Errorf("case %s: expected propagated %v, actual=%v", tt.name, tt.restored, restored)
+		} else if tt.memChecker != nil {
+			if err := tt.memChecker(tt.storage); err != nil {
+				t.Errorf("case %s: %v", tt.name, err)
+			}
+		}
+	}
+}
+
+func TestRestore_Propagate(t *testing.T) {
+	tests := []struct {
+		name    string
+		command *Restore
+		output  string
+	}{
+		{
+			name:    "without expire",
+			command: &Restore{key: "k", payload: []byte("p"), expireAt: math.MaxInt64, idle: -1, freq: -1, restored: true},
+			output:  "*6\r\n$7\r\nRESTORE\r\n$1\r\nk\r\n$1\r\n0\r\n$1\r\np\r\n$7\r\nREPLACE\r\n$6\r\nABSTTL\r\n",
+		},
+		{
+			name:    "with expire and freq",
+			command: &Restore{key: "k", payload: []byte("p"), expireAt: 1700000000000, idle: -1, freq: 5, restored: true},
+			output:  "*8\r\n$7\r\nRESTORE\r\n$1\r\nk\r\n$13\r\n1700000000000\r\n$1\r\np\r\n$7\r\nREPLACE\r\n$6\r\nABSTTL\r\n$4\r\nFREQ\r\n$1\r\n5\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		if actual := string(tt.command.Propagate().Append(nil)); actual != tt.output {
+			t.Errorf("case %s: expected %q but got %q", tt.name, tt.output, actual)
+		}
+	}
+}
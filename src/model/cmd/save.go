@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+	"github.com/codecrafters-io/redis-starter-go/src/model/rdb"
+	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
+)
+
+var (
+	_ Command = &Save{}
+)
+
+func init() {
+	commandNameToBuilder[(&Save{}).Name()] = func() Command {
+		return &Save{}
+	}
+}
+
+var (
+	bgsaveInProgressErr = redis.NewSimpleError("ERR Background save already in progress")
+)
+
+// Save writes the RDB file synchronously, every other command waits for
+// it.
+type Save struct {
+}
+
+func (*Save) Name() string {
+	return "SAVE"
+}
+
+func (s *Save) String() string {
+	return s.Name()
+}
+
+func (s *Save) Execute(writer io.Writer, storage *model.RedisStorage, conf *model.CommandConf) (redis.RedisObject, error) {
+	if conf.BgsaveInProgress {
+		return bgsaveInProgressErr, redis.WriteObject(writer, bgsaveInProgressErr)
+	}
+	if err := rdb.SaveFile(conf.RDBPath(), storage); err != nil {
+		rsp := redis.NewSimpleError(fmt.Sprintf("ERR %v", err))
+		return rsp, redis.WriteObject(writer, rsp)
+	}
+	conf.LastSave = time.Now()
+
+	rsp := OK
+	return rsp, redis.WriteObject(writer, rsp)
+}
+
+func (s *Save) Read(args *redis.Array) error {
+	if args == nil || args.Len() != 1 {
+		return &redis.SyntaxError{
+			Msg: "wrong number of arguments",
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+	"github.com/codecrafters-io/redis-starter-go/src/model/rdb"
+)
+
+func TestSave_Execute(t *testing.T) {
+	storage := &model.RedisStorage{Mem: map[string]*model.RedisBucket{
+		"foo": {Value: []byte("bar"), ExpireAt: math.MaxInt64},
+	}}
+	tests := []struct {
+		name   string
+		conf   *model.CommandConf
+		output string
+		saved  bool
+	}{
+		{
+			name:   "save",
+			conf:   &model.CommandConf{Dir: t.TempDir(), DBFilename: "dump.rdb"},
+			output: "+OK\r\n",
+			saved:  true,
+		},
+		{
+			name:   "background save in progress",
+			conf:   &model.CommandConf{Dir: t.TempDir(), DBFilename: "dump.rdb", BgsaveInProgress: true},
+			output: "-ERR Background save already in progress\r\n",
+		},
+		{
+			name:   "missing directory",
+			conf:   &model.CommandConf{Dir: filepath.Join(t.TempDir(), "missing"), DBFilename: "dump.rdb"},
+			output: "-ERR failed to create temp file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writer := &strings.Builder{}
+			before := time.Now().Truncate(time.Second)
+			if _, err := (&Save{}).Execute(writer, storage, tt.conf); err != nil {
+				t.Fatalf("case %s: failed to execute command: %v", tt.name, err)
+			} else if actual := writer.String(); !strings.HasPrefix(actual, tt.output) {
+				t.Errorf("case %s: expected %q but got %q", tt.name, tt.output, actual)
+			}
+			snapshot, err := rdb.LoadFile(tt.conf.RDBPath())
+			if !tt.saved {
+				if err == nil {
+					t.Errorf("case %s: expected no snapshot", tt.name)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("case %s: failed to load snapshot: %v", tt.name, err)
+			}
+			if bucket := snapshot.DB(0)["foo"]; bucket == nil || string(bucket.Value) != "bar" {
+				t.Errorf("case %s: expected foo=bar but got %v", tt.name, bucket)
+			}
+			if tt.conf.LastSave.Before(before) {
+				t.Errorf("case %s: expected LastSave to move, got %v", tt.name, tt.conf.LastSave)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
+)
+
+var (
+	_ Command = &Select{}
+
+	dbRangeErr = redis.NewSimpleError("ERR DB index is out of range")
+)
+
+func init() {
+	commandNameToBuilder[(&Select{}).Name()] = func() Command {
+		return &Select{}
+	}
+}
+
+// Select changes the database of the connection, SELECT <index>. The
+// handler keeps the database selected when Execute returns.
+type Select struct {
+	db int64
+}
+
+func (*Select) Name() string {
+	return "SELECT"
+}
+
+func (s *Select) String() string {
+	return fmt.Sprintf("%s[%d]", s.Name(), s.db)
+}
+
+func (s *Select) Execute(writer io.Writer, storage *model.RedisStorage, conf *model.CommandConf) (redis.RedisObject, error) {
+	if !storage.Select(int(s.db)) {
+		return dbRangeErr, redis.WriteObject(writer, dbRangeErr)
+	}
+	rsp := OK
+	return rsp, redis.WriteObject(writer, rsp)
+}
+
+func (s *Select) Read(args *redis.Array) error {
+	if args == nil || args.Len() != 2 {
+		return &redis.SyntaxError{
+			Msg: "wrong number of arguments",
+		}
+	}
+	db, err := argInt64(args.Get(1), "index")
+	s.db = db
+	return err
+}
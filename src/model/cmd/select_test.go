@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
+)
+
+func TestSelect_Read(t *testing.T) {
+	tests := []struct {
+		name    string
+		command *Select
+		input   string
+		output  string
+		isError bool
+	}{
+		{
+			name:    "normal",
+			command: &Select{},
+			input:   "*2\r\n$6\r\nSELECT\r\n$1\r\n3\r\n",
+			output:  "SELECT[3]",
+		},
+		{
+			name:    "not an integer",
+			command: &Select{},
+			input:   "*2\r\n$6\r\nSELECT\r\n$1\r\nx\r\n",
+			isError: true,
+		},
+		{
+			name:    "wrong number of arguments",
+			command: &Select{},
+			input:   "*1\r\n$6\r\nSELECT\r\n",
+			isError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		reader := bufio.NewReader(bytes.NewBufferString(tt.input))
+		if obj, err := redis.ReadObject(reader, redis.ArrayLeading); err != nil {
+			t.Errorf("case %s: failed to read object: %v", tt.name, err)
+		} else if err := tt.command.Read(obj.(*redis.Array)); err != nil {
+			if !tt.isError {
+				t.Errorf("case %s: failed to read command: %v", tt.name, err)
+			}
+		} else if tt.isError {
+			t.Errorf("case %s: expected error but got nil", tt.name)
+		} else if actual := tt.command.String(); actual != tt.output {
+			t.Errorf("case %s: expected %s but got %s", tt.name, tt.output, actual)
+		}
+	}
+}
+
+func TestSelect_Execute(t *testing.T) {
+	tests := []struct {
+		name    string
+		command *Select
+		output  string
+		db      int
+	}{
+		{
+			name:    "normal",
+			command: &Select{db: 2},
+			output:  "+OK\r\n",
+			db:      2,
+		},
+		{
+			name:    "out of range",
+			command: &Select{db: 3},
+			output:  "-ERR DB index is out of range\r\n",
+		},
+		{
+			name:    "negative",
+			command: &Select{db: -1},
+			output:  "-ERR DB index is out of range\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		storage := model.NewRedisStorage(3)
+		writer := &strings.Builder{}
+		if _, err := tt.command.Execute(writer, storage, &model.CommandConf{}); err != nil {
+			t.Errorf("case %s: failed to execute command: %v", tt.name, err)
+		} else if actual := writer.String(); actual != tt.output {
+			t.Errorf("case %s: expected %q but got %q", tt.name, tt.output, actual)
+		} else if storage.DB != tt.db {
+			t.Errorf("case %s: expected db %d to be selected, actual=%d", tt.name, tt.db, storage.DB)
+		}
+	}
+}
+
+// databases returns a storage of the given databases, the first selected.
+func databases(mems ...map[string]*model.RedisBucket) *model.RedisStorage {
+	storage := model.NewRedisStorage(len(mems))
+	for db, mem := range mems {
+		if mem != nil {
+			storage.DBs[db] = mem
+		}
+	}
+	storage.Mem = storage.DBs[0]
+	return storage
+}
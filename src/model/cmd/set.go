@@ -14,7 +14,7 @@ import (
 )
 
 var (
-	_ Command = &Set{}
+	_ DenyOOMCommand = &Set{}
 )
 
 func init() {
@@ -37,6 +37,8 @@ func (*Set) Name() string {
 	return "SET"
 }
 
+func (*Set) DenyOOM() {}
+
 func (s *Set) String() string {
 	return fmt.Sprintf("%s[%s, %s, %v]", s.Name(), s.key, string(s.value), s.expireAt)
 }
@@ -46,10 +48,27 @@ func (s *Set) Execute(writer io.Writer, storage *model.RedisStorage, conf *model
 		Value:    s.value,
 		ExpireAt: s.expireAt,
 	}
-	storage.Mem[s.key] = &bucket
+	storage.Set(s.key, &bucket, time.Now().UnixMilli())
 
 	rsp := OK
-	return rsp, rsp.Write(writer)
+	return rsp, redis.WriteObject(writer, rsp)
+}
+
+// Propagate sends the expire time as an absolute PXAT, so replicas expire
+// the key at the same moment however late they apply it.
+func (s *Set) Propagate() *redis.Array {
+	args := []redis.RedisObject{
+		redis.NewBulkString([]byte(s.Name())),
+		redis.NewBulkString([]byte(s.key)),
+		redis.NewBulkString(s.value),
+	}
+	if s.expireAt != math.MaxInt64 {
+		args = append(args,
+			redis.NewBulkString([]byte("PXAT")),
+			redis.NewBulkString([]byte(strconv.FormatInt(s.expireAt, 10))),
+		)
+	}
+	return redis.NewArray(args...)
 }
 
 func (s *Set) Read(args *redis.Array) error {
@@ -81,7 +100,8 @@ func (s *Set) Read(args *redis.Array) error {
 				Msg: fmt.Sprintf("unexpected argument type %T as option", px),
 			}
 		}
-		if strings.ToUpper(opt) != "PX" {
+		opt = strings.ToUpper(opt)
+		if opt != "PX" && opt != "PXAT" {
 			return &redis.SyntaxError{
 				Msg: fmt.Sprintf("unexpected option %s", opt),
 			}
@@ -104,7 +124,11 @@ func (s *Set) Read(args *redis.Array) error {
 				Msg: fmt.Sprintf("unexpected argument type %v as expireAfter", px),
 			}
 		}
-		s.expireAt = time.Now().UnixMilli() + expireAfter
+		if opt == "PXAT" {
+			s.expireAt = expireAfter
+		} else {
+			s.expireAt = time.Now().UnixMilli() + expireAfter
+		}
 	}
 
 	return nil
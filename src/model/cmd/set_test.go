@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"fmt"
+	"math"
 	"strings"
 	"testing"
 	"time"
@@ -32,6 +33,12 @@ func TestSet_Read(t *testing.T) {
 			input:   "*5\r\n$3\r\nSET\r\n$3\r\nkey\r\n$5\r\nvalue\r\n$2\r\nPX\r\n$3\r\n100\r\n",
 			output:  fmt.Sprintf("SET[key, value, %d]", 100+time.Now().UnixNano()/int64(time.Millisecond)),
 		},
+		{
+			name:    "set with pxat option",
+			command: &Set{},
+			input:   "*5\r\n$3\r\nSET\r\n$3\r\nkey\r\n$5\r\nvalue\r\n$4\r\npxat\r\n$13\r\n1700000000000\r\n",
+			output:  "SET[key, value, 1700000000000]",
+		},
 		{
 			name:    "wrong number of arguments",
 			command: &Set{},
@@ -117,3 +124,28 @@ func TestSet_Execute(t *testing.T) {
 		})
 	}
 }
+
+func TestSet_Propagate(t *testing.T) {
+	tests := []struct {
+		name    string
+		command *Set
+		output  string
+	}{
+		{
+			name:    "without expire",
+			command: &Set{key: "key", value: []byte("value"), expireAt: math.MaxInt64},
+			output:  "*3\r\n$3\r\nSET\r\n$3\r\nkey\r\n$5\r\nvalue\r\n",
+		},
+		{
+			name:    "with expire",
+			command: &Set{key: "key", value: []byte("value"), expireAt: 1700000000000},
+			output:  "*5\r\n$3\r\nSET\r\n$3\r\nkey\r\n$5\r\nvalue\r\n$4\r\nPXAT\r\n$13\r\n1700000000000\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		if actual := string(tt.command.Propagate().Append(nil)); actual != tt.output {
+			t.Errorf("case %s: expected %q but got %q", tt.name, tt.output, actual)
+		}
+	}
+}
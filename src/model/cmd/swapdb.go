@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
+)
+
+var (
+	_ WriteCommand = &SwapDB{}
+)
+
+func init() {
+	commandNameToBuilder[(&SwapDB{}).Name()] = func() Command {
+		return &SwapDB{}
+	}
+}
+
+// SwapDB exchanges two databases, SWAPDB <index1> <index2>. Connections
+// which selected one of them see the keys of the other from then on.
+type SwapDB struct {
+	dbs [2]int64
+}
+
+func (*SwapDB) Name() string {
+	return "SWAPDB"
+}
+
+func (s *SwapDB) String() string {
+	return fmt.Sprintf("%s[%d, %d]", s.Name(), s.dbs[0], s.dbs[1])
+}
+
+func (s *SwapDB) Execute(writer io.Writer, storage *model.RedisStorage, conf *model.CommandConf) (redis.RedisObject, error) {
+	if !storage.SwapDB(int(s.dbs[0]), int(s.dbs[1])) {
+		return dbRangeErr, redis.WriteObject(writer, dbRangeErr)
+	}
+	rsp := OK
+	return rsp, redis.WriteObject(writer, rsp)
+}
+
+func (s *SwapDB) Propagate() *redis.Array {
+	return redis.NewArray(
+		redis.NewBulkString([]byte(s.Name())),
+		redis.NewBulkString([]byte(strconv.FormatInt(s.dbs[0], 10))),
+		redis.NewBulkString([]byte(strconv.FormatInt(s.dbs[1], 10))),
+	)
+}
+
+func (s *SwapDB) Read(args *redis.Array) error {
+	if args == nil || args.Len() != 3 {
+		return &redis.SyntaxError{
+			Msg: "wrong number of arguments",
+		}
+	}
+	for i := range s.dbs {
+		db, err := argInt64(args.Get(i+1), "index")
+		if err != nil {
+			return err
+		}
+		s.dbs[i] = db
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
+)
+
+func TestSwapDB_Read(t *testing.T) {
+	tests := []struct {
+		name    string
+		command *SwapDB
+		input   string
+		output  string
+		isError bool
+	}{
+		{
+			name:    "normal",
+			command: &SwapDB{},
+			input:   "*3\r\n$6\r\nSWAPDB\r\n$1\r\n0\r\n$1\r\n1\r\n",
+			output:  "SWAPDB[0, 1]",
+		},
+		{
+			name:    "not an integer",
+			command: &SwapDB{},
+			input:   "*3\r\n$6\r\nSWAPDB\r\n$1\r\n0\r\n$1\r\nx\r\n",
+			isError: true,
+		},
+		{
+			name:    "wrong number of arguments",
+			command: &SwapDB{},
+			input:   "*2\r\n$6\r\nSWAPDB\r\n$1\r\n0\r\n",
+			isError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		reader := bufio.NewReader(bytes.NewBufferString(tt.input))
+		if obj, err := redis.ReadObject(reader, redis.ArrayLeading); err != nil {
+			t.Errorf("case %s: failed to read object: %v", tt.name, err)
+		} else if err := tt.command.Read(obj.(*redis.Array)); err != nil {
+			if !tt.isError {
+				t.Errorf("case %s: failed to read command: %v", tt.name, err)
+			}
+		} else if tt.isError {
+			t.Errorf("case %s: expected error but got nil", tt.name)
+		} else if actual := tt.command.String(); actual != tt.output {
+			t.Errorf("case %s: expected %s but got %s", tt.name, tt.output, actual)
+		}
+	}
+}
+
+func TestSwapDB_Execute(t *testing.T) {
+	tests := []struct {
+		name    string
+		command *SwapDB
+		output  string
+		// keys of the selected database afterwards
+		keys int
+	}{
+		{
+			name:    "normal",
+			command: &SwapDB{dbs: [2]int64{1, 0}},
+			output:  "+OK\r\n",
+			keys:    0,
+		},
+		{
+			name:    "out of range",
+			command: &SwapDB{dbs: [2]int64{0, 2}},
+			output:  "-ERR DB index is out of range\r\n",
+			keys:    1,
+		},
+	}
+
+	for _, tt := range tests {
+		storage := databases(map[string]*model.RedisBucket{
+			"a": {Value: []byte("1"), ExpireAt: math.MaxInt64},
+		}, nil)
+		writer := &strings.Builder{}
+		if _, err := tt.command.Execute(writer, storage, &model.CommandConf{}); err != nil {
+			t.Errorf("case %s: failed to execute command: %v", tt.name, err)
+		} else if actual := writer.String(); actual != tt.output {
+			t.Errorf("case %s: expected %q but got %q", tt.name, tt.output, actual)
+		} else if len(storage.Mem) != tt.keys || storage.Keys() != 1 {
+			t.Errorf("case %s: expected %d keys in the selected db, actual=%d", tt.name, tt.keys, len(storage.Mem))
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/src/concept"
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
+)
+
+var (
+	_ Command = &Wait{}
+)
+
+func init() {
+	commandNameToBuilder[(&Wait{}).Name()] = func() Command {
+		return &Wait{}
+	}
+}
+
+// Wait blocks until numreplicas replicas acknowledged the writes of the
+// client, or timeout milliseconds passed, WAIT numreplicas timeout. The
+// waiting is done by the connection handler, which sets the result with
+// SetAcked before Execute replies it.
+type Wait struct {
+	numReplicas int
+	timeout     time.Duration
+	acked       int
+}
+
+func (*Wait) Name() string {
+	return "WAIT"
+}
+
+func (w *Wait) String() string {
+	return fmt.Sprintf("%s[%d, %v]", w.Name(), w.numReplicas, w.timeout)
+}
+
+func (w *Wait) NumReplicas() int {
+	return w.numReplicas
+}
+
+// Timeout returns how long to wait, zero means forever.
+func (w *Wait) Timeout() time.Duration {
+	return w.timeout
+}
+
+func (w *Wait) SetAcked(n int) {
+	w.acked = n
+}
+
+func (w *Wait) Execute(writer io.Writer, storage *model.RedisStorage, conf *model.CommandConf) (redis.RedisObject, error) {
+	if conf != nil && conf.Role == "slave" {
+		rsp := redis.NewSimpleError("ERR WAIT cannot be used with replica instances")
+		return rsp, redis.WriteObject(writer, rsp)
+	}
+	rsp := redis.NewInteger(int64(w.acked))
+	return rsp, redis.WriteObject(writer, rsp)
+}
+
+func (w *Wait) Read(args *redis.Array) error {
+	if args == nil || args.Len() != 3 {
+		return &redis.SyntaxError{
+			Msg: "wrong number of arguments",
+		}
+	}
+
+	values := [2]int64{}
+	for i := range values {
+		switch arg := args.Get(i + 1).(type) {
+		case concept.AsInt64:
+			values[i] = arg.AsInt64()
+		case concept.AsString:
+			if n, err := strconv.ParseInt(arg.AsString(), 10, 64); err != nil {
+				return &redis.SyntaxError{
+					Msg: fmt.Sprintf("value is not an integer: %s", arg.AsString()),
+				}
+			} else {
+				values[i] = n
+			}
+		default:
+			return &redis.SyntaxError{
+				Msg: fmt.Sprintf("unexpected argument type %T", arg),
+			}
+		}
+	}
+	if values[0] < 0 {
+		return &redis.SyntaxError{
+			Msg: "numreplicas is negative",
+		}
+	} else if values[1] < 0 {
+		return &redis.SyntaxError{
+			Msg: "timeout is negative",
+		}
+	}
+	w.numReplicas = int(values[0])
+	w.timeout = time.Duration(values[1]) * time.Millisecond
+	return nil
+}
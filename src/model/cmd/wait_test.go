@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+	"github.com/codecrafters-io/redis-starter-go/src/model/redis"
+)
+
+func TestWait_Read(t *testing.T) {
+	tests := []struct {
+		name    string
+		command *Wait
+		input   string
+		output  string
+		isError bool
+	}{
+		{
+			name:    "normal",
+			command: &Wait{},
+			input:   "*3\r\n$4\r\nWAIT\r\n$1\r\n2\r\n$3\r\n500\r\n",
+			output:  "WAIT[2, 500ms]",
+		},
+		{
+			name:    "block forever",
+			command: &Wait{},
+			input:   "*3\r\n$4\r\nWAIT\r\n$1\r\n1\r\n$1\r\n0\r\n",
+			output:  "WAIT[1, 0s]",
+		},
+		{
+			name:    "negative timeout",
+			command: &Wait{},
+			input:   "*3\r\n$4\r\nWAIT\r\n$1\r\n1\r\n$2\r\n-1\r\n",
+			isError: true,
+		},
+		{
+			name:    "not an integer",
+			command: &Wait{},
+			input:   "*3\r\n$4\r\nWAIT\r\n$1\r\nx\r\n$1\r\n0\r\n",
+			isError: true,
+		},
+		{
+			name:    "wrong number of arguments",
+			command: &Wait{},
+			input:   "*2\r\n$4\r\nWAIT\r\n$1\r\n1\r\n",
+			isError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := bufio.NewReader(bytes.NewBufferString(tt.input))
+			if obj, err := redis.ReadObject(reader, redis.ArrayLeading); err != nil {
+				t.Errorf("case %s: failed to read object: %v", tt.name, err)
+			} else if args, ok := obj.(*redis.Array); !ok {
+				t.Errorf("case %s: expected *redis.Array but got %v", tt.name, obj)
+			} else if err := tt.command.Read(args); err != nil {
+				if tt.isError {
+					return
+				}
+				t.Errorf("case %s: failed to read command: %v", tt.name, err)
+			} else if tt.isError {
+				t.Errorf("case %s: expected error but got nil", tt.name)
+			} else if actual := tt.command.String(); actual != tt.output {
+				t.Errorf("case %s: expected %s but got %s", tt.name, tt.output, actual)
+			}
+		})
+	}
+}
+
+func TestWait_Execute(t *testing.T) {
+	tests := []struct {
+		name    string
+		command *Wait
+		conf    *model.CommandConf
+		acked   int
+		output  string
+	}{
+		{
+			name:    "none acked",
+			command: &Wait{numReplicas: 1},
+			output:  ":0\r\n",
+		},
+		{
+			name:    "acked",
+			command: &Wait{numReplicas: 1},
+			acked:   3,
+			output:  ":3\r\n",
+		},
+		{
+			name:    "on a replica",
+			command: &Wait{numReplicas: 1},
+			conf:    &model.CommandConf{Role: "slave"},
+			output:  "-ERR WAIT cannot be used with replica instances\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writer := &strings.Builder{}
+			tt.command.SetAcked(tt.acked)
+			if _, err := tt.command.Execute(writer, nil, tt.conf); err != nil {
+				t.Errorf("case %s: failed to execute command: %v", tt.name, err)
+			} else if actual := writer.String(); actual != tt.output {
+				t.Errorf("case %s: expected %s but got %s", tt.name, tt.output, actual)
+			}
+		})
+	}
+}
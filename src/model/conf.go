@@ -1,20 +1,98 @@
 package model
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"sort"
+	"path/filepath"
 	"strings"
+	"time"
 )
 
 type CommandConf struct {
 	Role string
+	Port int
 
 	MasterReplid     string
 	MasterReplOffset int64
+	// MasterReplid2 is the previous replid, still accepted by PSYNC up to
+	// SecondReplOffset, so replicas of a promoted replica continue
+	MasterReplid2    string
+	SecondReplOffset int64
+
+	ReplBacklogSize int
+	Backlog         *ReplBacklog `json:"-"`
+	// Replicas attached to this server, in the order they attached
+	Replicas []*ReplicaInfo `json:"-"`
 
 	ReplicaofAddress string
 	ReplicaofPort    int
+
+	// ReplDisklessSync streams the snapshot of a full resync straight to
+	// the replicas, starting ReplDisklessSyncDelay after the first one asks
+	// so later replicas share the same transfer
+	ReplDisklessSync      bool
+	ReplDisklessSyncDelay time.Duration
+	// ReplDisklessLoad is how a replica loads the snapshot: "disabled"
+	// receives it whole before loading, "on-empty-db" loads from the socket
+	// when the keyspace is empty, "swapdb" always does
+	ReplDisklessLoad string
+
+	// ReplicaReadOnly rejects writes of clients on a replica
+	ReplicaReadOnly bool
+	// ReplicaServeStaleData keeps answering while the master link is down
+	ReplicaServeStaleData bool
+
+	// state of the link to the master, for replicas
+	MasterLinkUp         bool
+	MasterLastIOAt       time.Time
+	MasterSyncInProgress bool
+	// ReplStreamDB is the database the replication stream last selected,
+	// where replicas continue after a snapshot
+	ReplStreamDB int
+
+	// Dir and DBFilename locate the RDB file
+	Dir        string
+	DBFilename string
+	// LastSave is when the RDB file was last written or loaded
+	LastSave         time.Time
+	BgsaveInProgress bool
+	LastBgsaveOK     bool
+
+	// AppendOnly logs every write to the AOF named AppendFilename, made of
+	// the files listed by its manifest in AppendDirname under Dir, synced to
+	// disk as AppendFsync says: always, everysec or no
+	AppendOnly     bool
+	AppendFilename string
+	AppendDirname  string
+	AppendFsync    string
+	// AOFLoadTruncated loads an AOF ending in a partial command by
+	// dropping that command
+	AOFLoadTruncated bool
+	AOFLastWriteOK   bool
+	// AOFUseRDBPreamble writes the base file of a rewrite as an RDB
+	// snapshot rather than as commands
+	AOFUseRDBPreamble bool
+	// AutoAOFRewritePercentage rewrites the AOF once it grew by that much
+	// since the last rewrite, if it is at least AutoAOFRewriteMinSize
+	// bytes; 0 disables it
+	AutoAOFRewritePercentage int
+	AutoAOFRewriteMinSize    int64
+	AOFRewriteInProgress     bool
+	AOFLastBgrewriteOK       bool
+	// AOFCurrentSize and AOFBaseSize are the size of the AOF files, now and
+	// after the last rewrite or startup
+	AOFCurrentSize int64
+	AOFBaseSize    int64
+
+	// Maxmemory bounds the memory of the keys in bytes, 0 for no bound.
+	// Over it, keys are evicted as MaxmemoryPolicy says, sampling
+	// MaxmemorySamples keys of each database at a time
+	Maxmemory        int64
+	MaxmemoryPolicy  string
+	MaxmemorySamples int
+	EvictedKeys      int64
 }
 
 func (c CommandConf) String() string {
@@ -29,24 +107,146 @@ func (c CommandConf) ReplicaofAddressAndPort() string {
 	return fmt.Sprintf("%s:%d", c.ReplicaofAddress, c.ReplicaofPort)
 }
 
-type pairType struct {
-	name  string
-	value interface{}
+// RDBPath returns where snapshots are saved and loaded.
+func (c *CommandConf) RDBPath() string {
+	return filepath.Join(c.Dir, c.DBFilename)
+}
+
+// AOFPath returns where a single file AOF, written before AOFs came in
+// parts, is found.
+func (c *CommandConf) AOFPath() string {
+	return filepath.Join(c.Dir, c.AppendFilename)
+}
+
+// AOFDir returns the directory of the AOF files and their manifest.
+func (c *CommandConf) AOFDir() string {
+	return filepath.Join(c.Dir, c.AppendDirname)
+}
+
+// ShiftReplid switches to a new history, keeping the current one as the
+// secondary id up to the current offset.
+func (c *CommandConf) ShiftReplid(replid string) {
+	c.MasterReplid2 = c.MasterReplid
+	c.SecondReplOffset = c.MasterReplOffset + 1
+	c.MasterReplid = replid
+}
+
+// ClearReplid2 forgets the secondary id, e.g. after a full resync.
+func (c *CommandConf) ClearReplid2() {
+	c.MasterReplid2 = "0000000000000000000000000000000000000000"
+	c.SecondReplOffset = -1
+}
+
+// ReplicaInfo describes a replica attached to this server.
+type ReplicaInfo struct {
+	IP   string
+	Port int
+	// State is wait_bgsave until a diskless snapshot starts, then online
+	State string
+	// AckOffset is the last offset the replica acknowledged
+	AckOffset int64
+	AckAt     time.Time
 }
 
+func (r *ReplicaInfo) String() string {
+	return fmt.Sprintf("ip=%s,port=%d,state=%s,offset=%d,lag=%d",
+		r.IP, r.Port, r.State, r.AckOffset, int64(time.Since(r.AckAt).Seconds()))
+}
+
+// Visit reports the replication state in the order of INFO replication.
 func (c CommandConf) Visit(f func(name string, value interface{})) {
-	var pairs []*pairType
+	f("role", c.Role)
+	if c.Role == "slave" {
+		status, lastIO := "down", int64(-1)
+		if c.MasterLinkUp {
+			status, lastIO = "up", int64(time.Since(c.MasterLastIOAt).Seconds())
+		}
+		f("master_host", c.ReplicaofAddress)
+		f("master_port", c.ReplicaofPort)
+		f("master_link_status", status)
+		f("master_last_io_seconds_ago", lastIO)
+		f("master_sync_in_progress", boolToInt(c.MasterSyncInProgress))
+		f("slave_repl_offset", c.MasterReplOffset)
+	}
+	f("connected_slaves", len(c.Replicas))
+	for i, replica := range c.Replicas {
+		f(fmt.Sprintf("slave%d", i), replica)
+	}
+	f("master_replid", c.MasterReplid)
+	f("master_replid2", c.MasterReplid2)
+	f("master_repl_offset", c.MasterReplOffset)
+	f("second_repl_offset", c.SecondReplOffset)
+	if c.Backlog != nil {
+		f("repl_backlog_active", 1)
+		f("repl_backlog_size", c.Backlog.Size())
+		f("repl_backlog_first_byte_offset", c.Backlog.Offset())
+		f("repl_backlog_histlen", c.Backlog.Len())
+	} else {
+		size := c.ReplBacklogSize
+		if size <= 0 {
+			size = DefaultReplBacklogSize
+		}
+		f("repl_backlog_active", 0)
+		f("repl_backlog_size", size)
+		f("repl_backlog_first_byte_offset", 0)
+		f("repl_backlog_histlen", 0)
+	}
+}
 
-	pairs = append(pairs, &pairType{"role", c.Role})
-	if c.Role == "master" {
-		pairs = append(pairs, &pairType{"master_replid", c.MasterReplid})
-		pairs = append(pairs, &pairType{"master_repl_offset", c.MasterReplOffset})
+// VisitPersistence reports the snapshot state in the order of INFO
+// persistence.
+func (c CommandConf) VisitPersistence(f func(name string, value interface{})) {
+	status := "ok"
+	if !c.LastBgsaveOK {
+		status = "err"
+	}
+	f("rdb_bgsave_in_progress", boolToInt(c.BgsaveInProgress))
+	f("rdb_last_save_time", c.LastSave.Unix())
+	f("rdb_last_bgsave_status", status)
+	f("aof_enabled", boolToInt(c.AppendOnly))
+	f("aof_rewrite_in_progress", boolToInt(c.AOFRewriteInProgress))
+	status = "ok"
+	if !c.AOFLastBgrewriteOK {
+		status = "err"
+	}
+	f("aof_last_bgrewrite_status", status)
+	status = "ok"
+	if !c.AOFLastWriteOK {
+		status = "err"
+	}
+	f("aof_last_write_status", status)
+	if c.AppendOnly {
+		f("aof_current_size", c.AOFCurrentSize)
+		f("aof_base_size", c.AOFBaseSize)
 	}
-	sort.SliceStable(pairs, func(i, j int) bool {
-		return pairs[i].name < pairs[j].name
-	})
+}
+
+// VisitMemory reports the memory of the keys, used now and at the peak,
+// and its bound in the order of INFO memory.
+func (c CommandConf) VisitMemory(used, peak int64, f func(name string, value interface{})) {
+	f("used_memory", used)
+	f("used_memory_peak", peak)
+	f("maxmemory", c.Maxmemory)
+	f("maxmemory_policy", c.MaxmemoryPolicy)
+}
+
+// VisitStats reports the counters in the order of INFO stats.
+func (c CommandConf) VisitStats(f func(name string, value interface{})) {
+	f("evicted_keys", c.EvictedKeys)
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
 
-	for _, p := range pairs {
-		f(p.name, p.value)
+// NewReplid returns a random replication id of 40 hex characters.
+func NewReplid() string {
+	var buf [20]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		panic(fmt.Errorf("failed to generate replid: %w", err))
 	}
+	return hex.EncodeToString(buf[:])
 }
@@ -0,0 +1,20 @@
+package model
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestNewReplid(t *testing.T) {
+	pattern := regexp.MustCompile("^[0-9a-f]{40}$")
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		replid := NewReplid()
+		if !pattern.MatchString(replid) {
+			t.Fatalf("expected 40 hex characters, actual=%q", replid)
+		} else if seen[replid] {
+			t.Fatalf("expected random replids, %q generated twice", replid)
+		}
+		seen[replid] = true
+	}
+}
@@ -0,0 +1,192 @@
+package model
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// Policies of maxmemory-policy, which tell the keys to evict once the
+// memory is over maxmemory. The volatile ones only evict keys with an
+// expire time, noeviction refuses the writes instead.
+const (
+	PolicyNoEviction     = "noeviction"
+	PolicyAllKeysLRU     = "allkeys-lru"
+	PolicyVolatileLRU    = "volatile-lru"
+	PolicyAllKeysLFU     = "allkeys-lfu"
+	PolicyVolatileLFU    = "volatile-lfu"
+	PolicyAllKeysRandom  = "allkeys-random"
+	PolicyVolatileRandom = "volatile-random"
+	PolicyVolatileTTL    = "volatile-ttl"
+)
+
+const (
+	// DefaultMaxmemorySamples is how many keys of each database are
+	// sampled for an eviction.
+	DefaultMaxmemorySamples = 5
+	// EvictionPoolSize is how many candidates an EvictionPool keeps.
+	EvictionPoolSize = 16
+)
+
+// IsEvictionPolicy tells if policy is one of the maxmemory policies.
+func IsEvictionPolicy(policy string) bool {
+	switch policy {
+	case PolicyNoEviction, PolicyAllKeysLRU, PolicyVolatileLRU, PolicyAllKeysLFU,
+		PolicyVolatileLFU, PolicyAllKeysRandom, PolicyVolatileRandom, PolicyVolatileTTL:
+		return true
+	}
+	return false
+}
+
+// IsLFUPolicy tells if policy evicts by access counter, rather than by
+// idle time.
+func IsLFUPolicy(policy string) bool {
+	return policy == PolicyAllKeysLFU || policy == PolicyVolatileLFU
+}
+
+type evictionCandidate struct {
+	db  int
+	key string
+	// idle is the score of the key, the higher the better to evict
+	idle int64
+}
+
+// EvictionPool approximates LRU, LFU and TTL eviction as Redis does: each
+// eviction samples a few keys of every database, which compete with the
+// best candidates of the previous samples kept by the pool.
+type EvictionPool struct {
+	// candidates by ascending idle score
+	candidates []evictionCandidate
+	// nextDB is where random evictions look first
+	nextDB int
+}
+
+// Evict deletes a key chosen by policy, sampling samples keys of every
+// database, and returns its database and name. It tells false when there
+// is no key to evict, always under noeviction.
+func (p *EvictionPool) Evict(storage *RedisStorage, policy string, samples int, now int64) (int, string, bool) {
+	volatile := strings.HasPrefix(policy, "volatile-")
+	if samples <= 0 {
+		samples = DefaultMaxmemorySamples
+	}
+	switch policy {
+	case PolicyAllKeysRandom, PolicyVolatileRandom:
+		return p.evictRandom(storage, volatile)
+	case PolicyAllKeysLRU, PolicyVolatileLRU, PolicyAllKeysLFU, PolicyVolatileLFU, PolicyVolatileTTL:
+	default:
+		return 0, "", false
+	}
+
+	for {
+		sampled := 0
+		for db, mem := range storage.Databases() {
+			sampled += p.populate(storage, db, mem, policy, volatile, samples, now)
+		}
+		if sampled == 0 && len(p.candidates) == 0 {
+			return 0, "", false
+		}
+		// the best candidate may be gone since it was sampled, the next
+		// one is tried then
+		for i := len(p.candidates) - 1; i >= 0; i-- {
+			c := p.candidates[i]
+			p.candidates = p.candidates[:i]
+			if storage.DeleteFrom(c.db, c.key) {
+				return c.db, c.key, true
+			}
+		}
+	}
+}
+
+// populate samples keys of database db into the pool and returns how many
+// it sampled.
+func (p *EvictionPool) populate(storage *RedisStorage, db int, mem map[string]*RedisBucket, policy string, volatile bool, samples int, now int64) int {
+	sampled := 0
+	visitKeys(storage, db, mem, volatile, func(key string, bucket *RedisBucket) bool {
+		sampled++
+
+		var idle int64
+		switch policy {
+		case PolicyAllKeysLRU, PolicyVolatileLRU:
+			idle = now - bucket.AccessAt
+		case PolicyAllKeysLFU, PolicyVolatileLFU:
+			idle = math.MaxUint8 - int64(bucket.LFU(now, storage.LFUDecayTime))
+		case PolicyVolatileTTL:
+			idle = math.MaxInt64 - bucket.ExpireAt
+		}
+		p.insert(evictionCandidate{db: db, key: key, idle: idle})
+		return sampled < samples
+	})
+	return sampled
+}
+
+// visitKeys hands the keys of database db to f until it returns false,
+// from a random place. Volatile policies only visit the keys with an
+// expire time, from the index of the storage, as Redis samples the
+// expires of a database rather than all of its keys.
+func visitKeys(storage *RedisStorage, db int, mem map[string]*RedisBucket, volatile bool, f func(key string, bucket *RedisBucket) bool) {
+	if !volatile {
+		// maps are iterated from a random place
+		for key, bucket := range mem {
+			if !f(key, bucket) {
+				return
+			}
+		}
+		return
+	}
+	index := storage.volatileKeys(db)
+	for key := range index {
+		bucket, ok := mem[key]
+		if !ok || bucket.ExpireAt == math.MaxInt64 {
+			// changed without the storage
+			delete(index, key)
+			continue
+		}
+		if !f(key, bucket) {
+			return
+		}
+	}
+}
+
+// insert adds a candidate to the pool, dropping the worst one when it is
+// full. A candidate worse than all of a full pool is left out.
+func (p *EvictionPool) insert(c evictionCandidate) {
+	for i, other := range p.candidates {
+		if other.db == c.db && other.key == c.key {
+			p.candidates = append(p.candidates[:i], p.candidates[i+1:]...)
+			break
+		}
+	}
+	i := sort.Search(len(p.candidates), func(i int) bool {
+		return p.candidates[i].idle >= c.idle
+	})
+	if len(p.candidates) == EvictionPoolSize {
+		if i == 0 {
+			return
+		}
+		copy(p.candidates, p.candidates[1:i])
+		p.candidates[i-1] = c
+		return
+	}
+	p.candidates = append(p.candidates, evictionCandidate{})
+	copy(p.candidates[i+1:], p.candidates[i:])
+	p.candidates[i] = c
+}
+
+// evictRandom deletes a key of the next database holding one, so that
+// every database gives up keys in turn.
+func (p *EvictionPool) evictRandom(storage *RedisStorage, volatile bool) (int, string, bool) {
+	dbs := storage.Databases()
+	for i := range dbs {
+		db, victim, found := (p.nextDB+i)%len(dbs), "", false
+		visitKeys(storage, db, dbs[db], volatile, func(key string, _ *RedisBucket) bool {
+			victim, found = key, true
+			return false
+		})
+		if found {
+			p.nextDB = db + 1
+			storage.DeleteFrom(db, victim)
+			return db, victim, true
+		}
+	}
+	return 0, "", false
+}
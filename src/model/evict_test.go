@@ -0,0 +1,143 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestEvictionPool_Evict(t *testing.T) {
+	const now = 10 * 60_000
+	newStorage := func() *RedisStorage {
+		s := NewRedisStorage(2)
+		set := func(key string, expireAt, accessAt int64, freq uint8) {
+			s.Set(key, &RedisBucket{Value: []byte("1"), ExpireAt: expireAt, Freq: freq}, accessAt)
+		}
+		set("old", math.MaxInt64, 1000, 50)
+		set("rare", math.MaxInt64, now, 1)
+		set("soon", now+10, now, 50)
+		s.Select(1)
+		set("later", now+1000, 5000, 50)
+		s.Select(0)
+		return s
+	}
+
+	tests := []struct {
+		name   string
+		policy string
+		// evicted keys, in order, as db/key
+		evicted []string
+	}{
+		{
+			name:    "allkeys-lru",
+			policy:  PolicyAllKeysLRU,
+			evicted: []string{"0/old", "1/later"},
+		},
+		{
+			name:    "volatile-lru",
+			policy:  PolicyVolatileLRU,
+			evicted: []string{"1/later", "0/soon", ""},
+		},
+		{
+			name:    "allkeys-lfu",
+			policy:  PolicyAllKeysLFU,
+			evicted: []string{"0/rare"},
+		},
+		{
+			name:    "volatile-ttl",
+			policy:  PolicyVolatileTTL,
+			evicted: []string{"0/soon", "1/later", ""},
+		},
+		{
+			name:    "noeviction",
+			policy:  PolicyNoEviction,
+			evicted: []string{""},
+		},
+	}
+
+	for _, tt := range tests {
+		s, pool := newStorage(), &EvictionPool{}
+		for i, expected := range tt.evicted {
+			actual := ""
+			if db, key, ok := pool.Evict(s, tt.policy, 10, now); ok {
+				actual = fmt.Sprintf("%d/%s", db, key)
+			}
+			if actual != expected {
+				t.Errorf("case %s: expected eviction %d to be %q, actual=%q", tt.name, i, expected, actual)
+			}
+		}
+	}
+}
+
+func TestEvictionPool_Evict_SparseVolatile(t *testing.T) {
+	for _, policy := range []string{PolicyVolatileLRU, PolicyVolatileTTL, PolicyVolatileRandom} {
+		s := NewRedisStorage(1)
+		for i := 0; i < 10000; i++ {
+			s.Set(fmt.Sprintf("key:%d", i), &RedisBucket{Value: []byte("1"), ExpireAt: math.MaxInt64}, 0)
+		}
+		for i := 0; i < 100; i++ {
+			s.Set(fmt.Sprintf("volatile:%d", i), &RedisBucket{Value: []byte("1"), ExpireAt: 1000}, 0)
+		}
+
+		// volatile keys are sampled from the index, so every eviction
+		// finds one however few they are
+		pool := &EvictionPool{}
+		for i := 0; i < 100; i++ {
+			if _, key, ok := pool.Evict(s, policy, 1, 0); !ok || !strings.HasPrefix(key, "volatile:") {
+				t.Errorf("case %s: expected eviction %d to evict a volatile key, actual=%q", policy, i, key)
+				break
+			}
+		}
+		if _, key, ok := pool.Evict(s, policy, 1, 0); ok || s.Keys() != 10000 {
+			t.Errorf("case %s: expected only the volatile keys to be evicted, actual=%q of %d keys", policy, key, s.Keys())
+		}
+	}
+}
+
+func TestEvictionPool_EvictRandom(t *testing.T) {
+	s := NewRedisStorage(2)
+	s.Set("a", &RedisBucket{Value: []byte("1"), ExpireAt: math.MaxInt64}, 0)
+	s.Set("b", &RedisBucket{Value: []byte("1"), ExpireAt: 1000}, 0)
+	s.Select(1)
+	s.Set("c", &RedisBucket{Value: []byte("1"), ExpireAt: math.MaxInt64}, 0)
+
+	pool := &EvictionPool{}
+	if db, key, ok := pool.Evict(s, PolicyVolatileRandom, 5, 0); !ok || db != 0 || key != "b" {
+		t.Errorf("expected b to be evicted, actual=%d/%s", db, key)
+	} else if _, _, ok := pool.Evict(s, PolicyVolatileRandom, 5, 0); ok {
+		t.Errorf("expected no volatile key left")
+	}
+	// the databases give up keys in turn
+	if db, _, ok := pool.Evict(s, PolicyAllKeysRandom, 5, 0); !ok || db != 1 {
+		t.Errorf("expected a key of db 1 to be evicted, actual db=%d", db)
+	} else if db, _, ok := pool.Evict(s, PolicyAllKeysRandom, 5, 0); !ok || db != 0 {
+		t.Errorf("expected a key of db 0 to be evicted, actual db=%d", db)
+	} else if s.Keys() != 0 || s.Used != 0 {
+		t.Errorf("expected no key left, actual=%d keys of %d bytes", s.Keys(), s.Used)
+	}
+}
+
+func TestEvictionPool_insert(t *testing.T) {
+	pool := &EvictionPool{}
+	for i := 0; i < EvictionPoolSize+4; i++ {
+		// idle scores 0, 10, ... then 5, 15, ...
+		idle := int64(i%(EvictionPoolSize/2)*10 + i/(EvictionPoolSize/2)*5)
+		pool.insert(evictionCandidate{key: fmt.Sprint(i), idle: idle})
+	}
+	pool.insert(evictionCandidate{key: "0", idle: 1000})
+	if len(pool.candidates) != EvictionPoolSize {
+		t.Fatalf("expected a full pool, actual=%d", len(pool.candidates))
+	}
+	for i := 1; i < len(pool.candidates); i++ {
+		if pool.candidates[i-1].idle > pool.candidates[i].idle {
+			t.Fatalf("expected ascending idle scores, actual=%v", pool.candidates)
+		}
+	}
+	if best := pool.candidates[EvictionPoolSize-1]; best.key != "0" || best.idle != 1000 {
+		t.Errorf("expected the updated candidate 0 to be the best, actual=%v", best)
+	}
+	if worst := pool.candidates[0]; worst.idle == 0 {
+		t.Errorf("expected the worst candidates to be dropped, actual=%v", pool.candidates)
+	}
+}
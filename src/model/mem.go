@@ -1,10 +1,592 @@
 package model
 
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"strconv"
+	"unsafe"
+)
+
+// DefaultDatabases is the number of databases of a server.
+const DefaultDatabases = 16
+
+// RedisStorage holds the numbered databases of a server. Commands work on
+// Mem, the selected database DB, which stays the authority for it: DBs
+// only catches up with Mem on Select and Databases. A storage without DBs
+// has the single database Mem.
 type RedisStorage struct {
 	Mem map[string]*RedisBucket
+	DB  int
+	DBs []map[string]*RedisBucket
+
+	// Used approximates the memory of the keys of every database, see
+	// KeySize. Set, Delete and the methods replacing whole databases keep
+	// it up to date, changing Mem directly does not.
+	Used int64
+	// Peak is the highest Used
+	Peak int64
+	// LFULogFactor and LFUDecayTime tune the access counters of the keys,
+	// see RedisBucket.Touch
+	LFULogFactor int
+	LFUDecayTime int
+
+	// volatile indexes the keys with an expire time of every database,
+	// for the volatile eviction policies. The methods changing keys keep
+	// it up to date, it may still list keys since changed directly.
+	volatile []map[string]struct{}
+}
+
+// NewRedisStorage returns databases empty databases, the first selected.
+func NewRedisStorage(databases int) *RedisStorage {
+	s := &RedisStorage{
+		DBs:          make([]map[string]*RedisBucket, databases),
+		LFULogFactor: DefaultLFULogFactor,
+		LFUDecayTime: DefaultLFUDecayTime,
+	}
+	for i := range s.DBs {
+		s.DBs[i] = make(map[string]*RedisBucket)
+	}
+	s.Mem = s.DBs[0]
+	return s
+}
+
+// Lookup returns the key of the selected database, nil when it is missing
+// or expired, and counts the access. An expired key is deleted.
+func (s *RedisStorage) Lookup(key string, now int64) *RedisBucket {
+	bucket, ok := s.Mem[key]
+	if !ok {
+		return nil
+	} else if bucket.ExpireAt < now {
+		s.Delete(key)
+		return nil
+	}
+	bucket.Touch(now, s.LFULogFactor, s.LFUDecayTime)
+	return bucket
+}
+
+// Set adds or replaces a key of the selected database, accessed now.
+func (s *RedisStorage) Set(key string, bucket *RedisBucket, now int64) {
+	if old, ok := s.Mem[key]; ok {
+		s.Used -= KeySize(key, old)
+	}
+	bucket.AccessAt = now
+	if bucket.Freq == 0 {
+		bucket.Freq = LFUInitVal
+	}
+	s.Mem[key] = bucket
+	s.Used += KeySize(key, bucket)
+	if s.Used > s.Peak {
+		s.Peak = s.Used
+	}
+	if bucket.ExpireAt != math.MaxInt64 {
+		s.volatileKeys(s.DB)[key] = struct{}{}
+	} else {
+		delete(s.volatileKeys(s.DB), key)
+	}
+}
+
+// Delete removes a key of the selected database, it tells if there was
+// one.
+func (s *RedisStorage) Delete(key string) bool {
+	return s.DeleteFrom(s.DB, key)
+}
+
+// DeleteFrom removes a key of database db, it tells if there was one.
+func (s *RedisStorage) DeleteFrom(db int, key string) bool {
+	mem := s.Databases()[db]
+	bucket, ok := mem[key]
+	if ok {
+		delete(mem, key)
+		delete(s.volatileKeys(db), key)
+		s.Used -= KeySize(key, bucket)
+	}
+	return ok
+}
+
+// MoveKey moves a key of the selected database to database db, replacing
+// any key there, it tells if there was one to move. The key keeps its
+// access and takes the same memory.
+func (s *RedisStorage) MoveKey(key string, db int) bool {
+	bucket, ok := s.Mem[key]
+	if !ok {
+		return false
+	}
+	s.DeleteFrom(db, key)
+	s.Databases()[db][key] = bucket
+	s.Used += KeySize(key, bucket)
+	if _, volatile := s.volatileKeys(s.DB)[key]; volatile {
+		s.volatileKeys(db)[key] = struct{}{}
+	}
+	return s.Delete(key)
+}
+
+// volatileKeys returns the index of the keys with an expire time of
+// database db.
+func (s *RedisStorage) volatileKeys(db int) map[string]struct{} {
+	n := len(s.DBs)
+	if n == 0 {
+		n = 1
+	}
+	if len(s.volatile) != n {
+		s.volatile = append(s.volatile, make([]map[string]struct{}, n-len(s.volatile))...)
+	}
+	if s.volatile[db] == nil {
+		s.volatile[db] = make(map[string]struct{})
+	}
+	return s.volatile[db]
+}
+
+// Databases returns every database by index.
+func (s *RedisStorage) Databases() []map[string]*RedisBucket {
+	if s.DBs == nil {
+		return []map[string]*RedisBucket{s.Mem}
+	}
+	s.DBs[s.DB] = s.Mem
+	return s.DBs
+}
+
+// Select makes db the selected database, it tells if there is such a
+// database.
+func (s *RedisStorage) Select(db int) bool {
+	dbs := s.Databases()
+	if db < 0 || db >= len(dbs) {
+		return false
+	}
+	s.DB, s.Mem = db, dbs[db]
+	return true
+}
+
+// SwapDB exchanges the keys of two databases, it tells if both exist.
+func (s *RedisStorage) SwapDB(a, b int) bool {
+	dbs := s.Databases()
+	if a < 0 || a >= len(dbs) || b < 0 || b >= len(dbs) {
+		return false
+	}
+	dbs[a], dbs[b] = dbs[b], dbs[a]
+	s.Mem = dbs[s.DB]
+	va, vb := s.volatileKeys(a), s.volatileKeys(b)
+	s.volatile[a], s.volatile[b] = vb, va
+	return true
+}
+
+// FlushDB empties the selected database.
+func (s *RedisStorage) FlushDB() {
+	for key, bucket := range s.Mem {
+		s.Used -= KeySize(key, bucket)
+	}
+	s.Mem = make(map[string]*RedisBucket)
+	if s.DB < len(s.volatile) {
+		s.volatile[s.DB] = nil
+	}
+}
+
+// FlushAll empties every database.
+func (s *RedisStorage) FlushAll() {
+	dbs := s.Databases()
+	for i := range dbs {
+		dbs[i] = make(map[string]*RedisBucket)
+	}
+	s.Mem = dbs[s.DB]
+	s.Used = 0
+	s.volatile = nil
+}
+
+// Reset replaces the keys of every database with dbs, by index, e.g. with
+// a snapshot, as accessed now unless their access is known already. It
+// fails when dbs has more databases than s.
+func (s *RedisStorage) Reset(dbs map[int]map[string]*RedisBucket, now int64) error {
+	current := s.Databases()
+	for db := range dbs {
+		if db < 0 || db >= len(current) {
+			return fmt.Errorf("database %d is out of range, there are %d", db, len(current))
+		}
+	}
+	s.Used, s.volatile = 0, nil
+	for i := range current {
+		mem, ok := dbs[i]
+		if !ok {
+			mem = make(map[string]*RedisBucket)
+		}
+		for key, bucket := range mem {
+			if bucket.AccessAt == 0 {
+				bucket.AccessAt, bucket.Freq = now, LFUInitVal
+			}
+			s.Used += KeySize(key, bucket)
+			if bucket.ExpireAt != math.MaxInt64 {
+				s.volatileKeys(i)[key] = struct{}{}
+			}
+		}
+		current[i] = mem
+	}
+	s.Mem = current[s.DB]
+	if s.Used > s.Peak {
+		s.Peak = s.Used
+	}
+	return nil
+}
+
+// Keys counts the keys of every database.
+func (s *RedisStorage) Keys() int {
+	n := 0
+	for _, mem := range s.Databases() {
+		n += len(mem)
+	}
+	return n
 }
 
+// VisitKeyspace reports the databases holding keys in the order of INFO
+// keyspace, avg_ttl is the mean time to live in milliseconds of the keys
+// with an expire time as of now.
+func (s *RedisStorage) VisitKeyspace(now int64, f func(name string, value interface{})) {
+	for db, mem := range s.Databases() {
+		if len(mem) == 0 {
+			continue
+		}
+		var expires, ttl int64
+		for _, bucket := range mem {
+			if bucket.ExpireAt != math.MaxInt64 {
+				expires++
+				if bucket.ExpireAt > now {
+					ttl += bucket.ExpireAt - now
+				}
+			}
+		}
+		if expires > 0 {
+			ttl /= expires
+		}
+		f(fmt.Sprintf("db%d", db), fmt.Sprintf("keys=%d,expires=%d,avg_ttl=%d", len(mem), expires, ttl))
+	}
+}
+
+// ValueType is the type of the value of a key.
+type ValueType byte
+
+const (
+	TypeString ValueType = iota
+	TypeList
+	TypeSet
+	TypeZSet
+	TypeHash
+	TypeStream
+)
+
+func (t ValueType) String() string {
+	switch t {
+	case TypeString:
+		return "string"
+	case TypeList:
+		return "list"
+	case TypeSet:
+		return "set"
+	case TypeZSet:
+		return "zset"
+	case TypeHash:
+		return "hash"
+	case TypeStream:
+		return "stream"
+	}
+	return "unknown"
+}
+
+// RedisBucket holds a key. The value is in the field of its Type, a
+// string in Value.
 type RedisBucket struct {
+	Type     ValueType
 	Value    []byte
+	List     [][]byte
+	Set      map[string]struct{}
+	ZSet     map[string]float64
+	Hash     map[string][]byte
+	Stream   *Stream
 	ExpireAt int64
+
+	// AccessAt is the last access in milliseconds and Freq the logarithmic
+	// access counter, for eviction
+	AccessAt int64
+	Freq     uint8
+}
+
+const (
+	// LFUInitVal is the access counter of a new key, so that it has a
+	// chance to be accessed again before it is evicted.
+	LFUInitVal = 5
+	// DefaultLFULogFactor makes a counter of 255 take about a million
+	// accesses.
+	DefaultLFULogFactor = 10
+	// DefaultLFUDecayTime decrements the counters once a minute.
+	DefaultLFUDecayTime = 1
+)
+
+// Touch records an access at now. The access counter first decays, see
+// LFU, then grows with a probability of 1/((counter-LFUInitVal)*logFactor+1),
+// so it stands for the log of the accesses.
+func (b *RedisBucket) Touch(now int64, logFactor, decayTime int) {
+	counter := b.LFU(now, decayTime)
+	if counter < math.MaxUint8 {
+		base := float64(counter) - LFUInitVal
+		if base < 0 {
+			base = 0
+		}
+		if rand.Float64() < 1/(base*float64(logFactor)+1) {
+			counter++
+		}
+	}
+	b.AccessAt, b.Freq = now, counter
+}
+
+// LFU returns the access counter as of now, less one for each decayTime
+// minutes since the last access. A decayTime of 0 never decays it.
+func (b *RedisBucket) LFU(now int64, decayTime int) uint8 {
+	if decayTime <= 0 {
+		return b.Freq
+	}
+	periods := (now - b.AccessAt) / 60_000 / int64(decayTime)
+	if periods >= int64(b.Freq) {
+		return 0
+	}
+	return b.Freq - uint8(periods)
+}
+
+var (
+	// bucketSize is the memory of a bucket without its value
+	bucketSize = int64(unsafe.Sizeof(RedisBucket{}))
+)
+
+const (
+	// entryOverhead approximates what a key takes in a map besides its
+	// name and bucket, and elementOverhead what an element of a value
+	// takes besides its bytes
+	entryOverhead   = 16
+	elementOverhead = 24
+)
+
+// KeySize approximates the memory of a key: its name, its bucket and the
+// elements of its value.
+func KeySize(key string, b *RedisBucket) int64 {
+	return SampledKeySize(key, b, 0)
+}
+
+// SampledKeySize approximates KeySize from the first samples elements of
+// the value, or all of them when samples is 0, as MEMORY USAGE does.
+func SampledKeySize(key string, b *RedisBucket, samples int) int64 {
+	var (
+		size         = entryOverhead + int64(len(key)) + bucketSize
+		sampled, sum int64
+		elements     = int64(b.Len())
+		sample       = func(n int) bool {
+			sum += int64(n)
+			sampled++
+			return samples <= 0 || sampled < int64(samples)
+		}
+	)
+	switch b.Type {
+	case TypeList:
+		for _, element := range b.List {
+			if !sample(elementOverhead + len(element)) {
+				break
+			}
+		}
+	case TypeSet:
+		for member := range b.Set {
+			if !sample(elementOverhead + len(member)) {
+				break
+			}
+		}
+	case TypeZSet:
+		for member := range b.ZSet {
+			if !sample(elementOverhead + 8 + len(member)) {
+				break
+			}
+		}
+	case TypeHash:
+		for field, value := range b.Hash {
+			if !sample(2*elementOverhead + len(field) + len(value)) {
+				break
+			}
+		}
+	case TypeStream:
+		for _, entry := range b.Stream.Entries {
+			n := elementOverhead + int(unsafe.Sizeof(entry.ID))
+			for _, field := range entry.Fields {
+				n += elementOverhead + len(field)
+			}
+			if !sample(n) {
+				break
+			}
+		}
+	default:
+		return size + int64(len(b.Value))
+	}
+	if sampled > 0 {
+		size += sum * elements / sampled
+	}
+	return size
+}
+
+const (
+	// listpackMaxEntries and listpackMaxValue bound the small aggregates
+	// Redis encodes as listpacks, intsetMaxEntries the sets of integers
+	// encoded as intsets and listpackMaxBytes the lists of a single
+	// listpack, with the defaults of redis.conf.
+	listpackMaxEntries = 128
+	listpackMaxValue   = 64
+	listpackMaxBytes   = 8 << 10
+	intsetMaxEntries   = 512
+)
+
+// Encoding returns how Redis would encode the value, as OBJECT ENCODING
+// reports it. Values are not encoded here, so it is only a hint of their
+// size.
+func (b *RedisBucket) Encoding() string {
+	switch b.Type {
+	case TypeList:
+		size := 0
+		for _, element := range b.List {
+			size += len(element)
+		}
+		if size <= listpackMaxBytes {
+			return "listpack"
+		}
+		return "quicklist"
+	case TypeSet:
+		ints, small := len(b.Set) <= intsetMaxEntries, len(b.Set) <= listpackMaxEntries
+		for member := range b.Set {
+			if _, err := strconv.ParseInt(member, 10, 64); err != nil {
+				ints = false
+			}
+			small = small && len(member) <= listpackMaxValue
+		}
+		switch {
+		case ints:
+			return "intset"
+		case small:
+			return "listpack"
+		}
+		return "hashtable"
+	case TypeZSet:
+		if smallValues(len(b.ZSet), func(f func(string) bool) {
+			for member := range b.ZSet {
+				if !f(member) {
+					return
+				}
+			}
+		}) {
+			return "listpack"
+		}
+		return "skiplist"
+	case TypeHash:
+		if smallValues(len(b.Hash), func(f func(string) bool) {
+			for field, value := range b.Hash {
+				if !f(field) || !f(string(value)) {
+					return
+				}
+			}
+		}) {
+			return "listpack"
+		}
+		return "hashtable"
+	case TypeStream:
+		return "stream"
+	}
+	if len(b.Value) <= 20 {
+		if _, err := strconv.ParseInt(string(b.Value), 10, 64); err == nil {
+			return "int"
+		}
+	}
+	if len(b.Value) <= 44 {
+		return "embstr"
+	}
+	return "raw"
+}
+
+// smallValues tells if n values, visited by each until f returns false,
+// fit a listpack.
+func smallValues(n int, each func(f func(string) bool)) bool {
+	small := n <= listpackMaxEntries
+	each(func(value string) bool {
+		small = small && len(value) <= listpackMaxValue
+		return small
+	})
+	return small
+}
+
+// Len returns the size of the value: bytes of a string, elements of the
+// other types.
+func (b *RedisBucket) Len() int {
+	switch b.Type {
+	case TypeList:
+		return len(b.List)
+	case TypeSet:
+		return len(b.Set)
+	case TypeZSet:
+		return len(b.ZSet)
+	case TypeHash:
+		return len(b.Hash)
+	case TypeStream:
+		return len(b.Stream.Entries)
+	}
+	return len(b.Value)
+}
+
+// KeyspaceSummary describes a keyspace by type, for offline checks.
+type KeyspaceSummary struct {
+	Keys    int
+	Expires int
+	// Expired counts keys whose expire time is before the summary
+	Expired int
+	Types   map[ValueType]int
+	// Largest holds the key of each type with the largest Len
+	Largest map[ValueType]string
+	Sizes   map[ValueType]int
+}
+
+// Summarize describes mem as of now, in unix milliseconds.
+func Summarize(mem map[string]*RedisBucket, now int64) *KeyspaceSummary {
+	summary := &KeyspaceSummary{
+		Types:   make(map[ValueType]int),
+		Largest: make(map[ValueType]string),
+		Sizes:   make(map[ValueType]int),
+	}
+	for key, bucket := range mem {
+		summary.Keys++
+		if bucket.ExpireAt < now {
+			summary.Expired++
+		} else if bucket.ExpireAt != math.MaxInt64 {
+			summary.Expires++
+		}
+		summary.Types[bucket.Type]++
+		size, largest := bucket.Len(), summary.Largest[bucket.Type]
+		// ties go to the first key in order, so the summary is stable
+		if _, ok := summary.Sizes[bucket.Type]; !ok || size > summary.Sizes[bucket.Type] ||
+			(size == summary.Sizes[bucket.Type] && key < largest) {
+			summary.Largest[bucket.Type] = key
+			summary.Sizes[bucket.Type] = size
+		}
+	}
+	return summary
+}
+
+// Report writes the summary as lines for the check tools, one per type.
+func (s *KeyspaceSummary) Report(writer io.Writer) error {
+	if _, err := fmt.Fprintf(writer, "[info] %d keys read\n[info] %d expires\n[info] %d already expired\n",
+		s.Keys, s.Expires, s.Expired); err != nil {
+		return err
+	}
+	for typ := TypeString; typ <= TypeStream; typ++ {
+		count, ok := s.Types[typ]
+		if !ok {
+			continue
+		}
+		unit := "elements"
+		if typ == TypeString {
+			unit = "bytes"
+		}
+		if _, err := fmt.Fprintf(writer, "[info] %s: %d keys, largest '%s' of %d %s\n",
+			typ, count, s.Largest[typ], s.Sizes[typ], unit); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -0,0 +1,292 @@
+package model
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSummarize(t *testing.T) {
+	mem := map[string]*RedisBucket{
+		"a":       {Value: []byte("12345"), ExpireAt: math.MaxInt64},
+		"b":       {Value: []byte("12345"), ExpireAt: 2000},
+		"c":       {Value: []byte("1"), ExpireAt: 500},
+		"list":    {Type: TypeList, List: [][]byte{[]byte("x"), []byte("y")}, ExpireAt: math.MaxInt64},
+		"stream":  {Type: TypeStream, Stream: &Stream{}, ExpireAt: math.MaxInt64},
+		"hash":    {Type: TypeHash, Hash: map[string][]byte{"f": nil}, ExpireAt: math.MaxInt64},
+		"bighash": {Type: TypeHash, Hash: map[string][]byte{"f": nil, "g": nil}, ExpireAt: math.MaxInt64},
+	}
+	summary := Summarize(mem, 1000)
+	if summary.Keys != 7 || summary.Expires != 1 || summary.Expired != 1 {
+		t.Errorf("unexpected counts %+v", summary)
+	}
+	expected := map[ValueType]struct {
+		count   int
+		largest string
+		size    int
+	}{
+		TypeString: {count: 3, largest: "a", size: 5},
+		TypeList:   {count: 1, largest: "list", size: 2},
+		TypeHash:   {count: 2, largest: "bighash", size: 2},
+		TypeStream: {count: 1, largest: "stream", size: 0},
+	}
+	for typ, e := range expected {
+		if summary.Types[typ] != e.count || summary.Largest[typ] != e.largest || summary.Sizes[typ] != e.size {
+			t.Errorf("case %s: expected %d keys, largest %s of %d, actual=%d, %s of %d",
+				typ, e.count, e.largest, e.size, summary.Types[typ], summary.Largest[typ], summary.Sizes[typ])
+		}
+	}
+	if _, ok := summary.Types[TypeSet]; ok {
+		t.Errorf("expected no set")
+	}
+}
+
+func TestKeyspaceSummary_Report(t *testing.T) {
+	mem := map[string]*RedisBucket{
+		"a":    {Value: []byte("12345"), ExpireAt: 2000},
+		"list": {Type: TypeList, List: [][]byte{[]byte("x")}, ExpireAt: math.MaxInt64},
+	}
+	var buf bytes.Buffer
+	if err := Summarize(mem, 1000).Report(&buf); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	expected := "[info] 2 keys read\n[info] 1 expires\n[info] 0 already expired\n" +
+		"[info] string: 1 keys, largest 'a' of 5 bytes\n" +
+		"[info] list: 1 keys, largest 'list' of 1 elements\n"
+	if buf.String() != expected {
+		t.Errorf("expected=%q, actual=%q", expected, buf.String())
+	}
+}
+
+func TestRedisStorage_Databases(t *testing.T) {
+	s := NewRedisStorage(3)
+	s.Mem["a"] = &RedisBucket{Value: []byte("0"), ExpireAt: math.MaxInt64}
+	if !s.Select(2) || s.DB != 2 || len(s.Mem) != 0 {
+		t.Fatalf("expected to select the empty db 2, actual db=%d keys=%d", s.DB, len(s.Mem))
+	}
+	s.Mem["b"] = &RedisBucket{Value: []byte("2"), ExpireAt: 2000}
+	s.Mem["c"] = &RedisBucket{Value: []byte("2"), ExpireAt: math.MaxInt64}
+	if s.Select(3) || s.Select(-1) || s.DB != 2 {
+		t.Errorf("expected out of range dbs not to be selected, actual db=%d", s.DB)
+	}
+	if s.Keys() != 3 {
+		t.Errorf("expected 3 keys, actual=%d", s.Keys())
+	}
+
+	var keyspace []string
+	s.VisitKeyspace(1000, func(name string, value interface{}) {
+		keyspace = append(keyspace, fmt.Sprintf("%s:%v", name, value))
+	})
+	expected := []string{"db0:keys=1,expires=0,avg_ttl=0", "db2:keys=2,expires=1,avg_ttl=1000"}
+	if !reflect.DeepEqual(keyspace, expected) {
+		t.Errorf("expected keyspace %v, actual=%v", expected, keyspace)
+	}
+
+	if !s.SwapDB(0, 2) || len(s.Mem) != 1 || s.Mem["a"] == nil {
+		t.Errorf("expected the selected db 2 to hold the keys of db 0, actual=%v", s.Mem)
+	}
+	if s.SwapDB(0, 3) {
+		t.Errorf("expected no swap with an out of range db")
+	}
+	s.FlushDB()
+	if s.Keys() != 2 {
+		t.Errorf("expected 2 keys after FLUSHDB, actual=%d", s.Keys())
+	}
+	s.FlushAll()
+	if s.Keys() != 0 || len(s.Mem) != 0 {
+		t.Errorf("expected no keys after FLUSHALL, actual=%d", s.Keys())
+	}
+}
+
+func TestRedisStorage_Reset(t *testing.T) {
+	s := NewRedisStorage(2)
+	s.Mem["old"] = &RedisBucket{Value: []byte("x"), ExpireAt: math.MaxInt64}
+	s.Select(1)
+	err := s.Reset(map[int]map[string]*RedisBucket{
+		1: {"a": {Value: []byte("1"), ExpireAt: math.MaxInt64}},
+	}, 1000)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	} else if s.Mem["a"] == nil || len(s.Databases()[0]) != 0 {
+		t.Errorf("expected the keys of the snapshot only, actual=%v", s.Databases())
+	} else if s.Used != KeySize("a", s.Mem["a"]) {
+		t.Errorf("expected the memory of a, actual=%d", s.Used)
+	} else if s.Mem["a"].AccessAt != 1000 || s.Mem["a"].Freq != LFUInitVal {
+		t.Errorf("expected a to be accessed when loaded, actual=%+v", s.Mem["a"])
+	}
+	if err := s.Reset(map[int]map[string]*RedisBucket{2: {}}, 1000); err == nil {
+		t.Errorf("expected an error for db 2 of 2")
+	}
+}
+
+func TestRedisStorage_VolatileKeys(t *testing.T) {
+	s := NewRedisStorage(2)
+	volatile := func(db int) []string {
+		var keys []string
+		for key := range s.volatileKeys(db) {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		return keys
+	}
+	check := func(name string, expected0, expected1 []string) {
+		if actual := volatile(0); !reflect.DeepEqual(actual, expected0) {
+			t.Errorf("case %s: expected volatile keys %v in db 0, actual=%v", name, expected0, actual)
+		}
+		if actual := volatile(1); !reflect.DeepEqual(actual, expected1) {
+			t.Errorf("case %s: expected volatile keys %v in db 1, actual=%v", name, expected1, actual)
+		}
+	}
+
+	s.Set("a", &RedisBucket{Value: []byte("1"), ExpireAt: 1000}, 0)
+	s.Set("b", &RedisBucket{Value: []byte("1"), ExpireAt: 1000}, 0)
+	s.Set("c", &RedisBucket{Value: []byte("1"), ExpireAt: math.MaxInt64}, 0)
+	check("set", []string{"a", "b"}, nil)
+	s.Set("b", &RedisBucket{Value: []byte("2"), ExpireAt: math.MaxInt64}, 0)
+	check("persist", []string{"a"}, nil)
+	s.MoveKey("a", 1)
+	check("move", nil, []string{"a"})
+	s.SwapDB(0, 1)
+	check("swapdb", []string{"a"}, nil)
+	s.Delete("a")
+	check("delete", nil, nil)
+	s.Set("d", &RedisBucket{Value: []byte("1"), ExpireAt: 1000}, 0)
+	s.FlushDB()
+	check("flushdb", nil, nil)
+
+	err := s.Reset(map[int]map[string]*RedisBucket{
+		0: {"e": {Value: []byte("1"), ExpireAt: math.MaxInt64}},
+		1: {"f": {Value: []byte("1"), ExpireAt: 1000}},
+	}, 0)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	check("reset", nil, []string{"f"})
+}
+
+func TestRedisStorage_Used(t *testing.T) {
+	s := NewRedisStorage(2)
+	s.Set("a", &RedisBucket{Value: []byte("12345"), ExpireAt: math.MaxInt64}, 1000)
+	size := s.Used
+	if size != KeySize("a", s.Mem["a"]) || size <= 6 {
+		t.Fatalf("expected the size of a, actual=%d", size)
+	} else if s.Mem["a"].AccessAt != 1000 || s.Mem["a"].Freq != LFUInitVal {
+		t.Errorf("expected a new key to be accessed, actual=%+v", s.Mem["a"])
+	}
+	s.Set("a", &RedisBucket{Value: []byte("123456789"), ExpireAt: math.MaxInt64}, 1000)
+	if s.Used != size+4 {
+		t.Errorf("expected the replaced value to count, actual=%d", s.Used)
+	}
+	s.Select(1)
+	s.Set("b", &RedisBucket{Value: []byte("1"), ExpireAt: 500}, 0)
+	if s.Lookup("b", 1000) != nil || s.Keys() != 1 || s.Used != size+4 {
+		t.Errorf("expected the expired key to be deleted, actual=%d keys of %d bytes", s.Keys(), s.Used)
+	}
+	s.FlushDB()
+	if s.Used != size+4 {
+		t.Errorf("expected an empty db to take nothing, actual=%d", s.Used)
+	}
+	if !s.DeleteFrom(0, "a") || s.DeleteFrom(0, "a") || s.Used != 0 {
+		t.Errorf("expected a to be deleted once, actual=%d bytes", s.Used)
+	}
+}
+
+func TestRedisBucket_Touch(t *testing.T) {
+	const minute = 60_000
+	b := &RedisBucket{AccessAt: 0, Freq: LFUInitVal}
+	// without a log factor, every access counts
+	for i := 0; i < 10; i++ {
+		b.Touch(int64(i), 0, 1)
+	}
+	if b.Freq != LFUInitVal+10 || b.AccessAt != 9 {
+		t.Errorf("expected 10 accesses, actual=%+v", b)
+	}
+	if freq := b.LFU(9+3*minute, 1); freq != LFUInitVal+7 {
+		t.Errorf("expected a decay of 3, actual=%d", freq)
+	} else if freq := b.LFU(9+3*minute, 2); freq != LFUInitVal+9 {
+		t.Errorf("expected a decay of 1, actual=%d", freq)
+	} else if freq := b.LFU(9+3*minute, 0); freq != LFUInitVal+10 {
+		t.Errorf("expected no decay, actual=%d", freq)
+	} else if freq := b.LFU(1000*minute, 1); freq != 0 {
+		t.Errorf("expected a full decay, actual=%d", freq)
+	}
+
+	// with the default factor, high counters grow slowly
+	b = &RedisBucket{Freq: 100}
+	for i := 0; i < 100; i++ {
+		b.Touch(0, DefaultLFULogFactor, 0)
+	}
+	if b.Freq > 110 {
+		t.Errorf("expected about 1000 accesses per step, actual=%d", b.Freq)
+	}
+	b = &RedisBucket{Freq: math.MaxUint8}
+	b.Touch(0, 0, 0)
+	if b.Freq != math.MaxUint8 {
+		t.Errorf("expected the counter to saturate, actual=%d", b.Freq)
+	}
+}
+
+func TestRedisStorage_Peak(t *testing.T) {
+	s := NewRedisStorage(1)
+	s.Set("a", &RedisBucket{Value: []byte("12345"), ExpireAt: math.MaxInt64}, 0)
+	peak := s.Used
+	s.Delete("a")
+	if s.Used != 0 || s.Peak != peak {
+		t.Errorf("expected a peak of %d, actual=%d", peak, s.Peak)
+	}
+}
+
+func TestRedisBucket_Encoding(t *testing.T) {
+	var (
+		many   = map[string]struct{}{}
+		hash   = map[string][]byte{"f": []byte("v")}
+		scores = map[string]float64{"m": 1}
+	)
+	for i := 0; i < listpackMaxEntries+1; i++ {
+		many[strconv.Itoa(i)] = struct{}{}
+	}
+	tests := []struct {
+		name     string
+		bucket   *RedisBucket
+		encoding string
+	}{
+		{name: "int", bucket: &RedisBucket{Value: []byte("-42")}, encoding: "int"},
+		{name: "embstr", bucket: &RedisBucket{Value: []byte("value")}, encoding: "embstr"},
+		{name: "raw", bucket: &RedisBucket{Value: bytes.Repeat([]byte("1"), 45)}, encoding: "raw"},
+		{name: "small list", bucket: &RedisBucket{Type: TypeList, List: [][]byte{[]byte("a")}}, encoding: "listpack"},
+		{name: "large list", bucket: &RedisBucket{Type: TypeList, List: [][]byte{make([]byte, listpackMaxBytes+1)}}, encoding: "quicklist"},
+		{name: "intset", bucket: &RedisBucket{Type: TypeSet, Set: many}, encoding: "intset"},
+		{name: "small set", bucket: &RedisBucket{Type: TypeSet, Set: map[string]struct{}{"a": {}}}, encoding: "listpack"},
+		{name: "small zset", bucket: &RedisBucket{Type: TypeZSet, ZSet: scores}, encoding: "listpack"},
+		{name: "large zset", bucket: &RedisBucket{Type: TypeZSet, ZSet: map[string]float64{strings.Repeat("m", 65): 1}}, encoding: "skiplist"},
+		{name: "small hash", bucket: &RedisBucket{Type: TypeHash, Hash: hash}, encoding: "listpack"},
+		{name: "large hash", bucket: &RedisBucket{Type: TypeHash, Hash: map[string][]byte{"f": make([]byte, 65)}}, encoding: "hashtable"},
+		{name: "stream", bucket: &RedisBucket{Type: TypeStream, Stream: &Stream{}}, encoding: "stream"},
+	}
+
+	for _, tt := range tests {
+		if actual := tt.bucket.Encoding(); actual != tt.encoding {
+			t.Errorf("case %s: expected %s but got %s", tt.name, tt.encoding, actual)
+		}
+	}
+}
+
+func TestSampledKeySize(t *testing.T) {
+	b := &RedisBucket{Type: TypeList}
+	for i := 0; i < 10; i++ {
+		b.List = append(b.List, []byte("element"))
+	}
+	// elements of the same size extrapolate to the full size
+	if full, sampled := KeySize("k", b), SampledKeySize("k", b, 3); full != sampled {
+		t.Errorf("expected %d bytes, actual=%d", full, sampled)
+	}
+	b.List[0] = make([]byte, 1000)
+	if full, sampled := KeySize("k", b), SampledKeySize("k", b, 1); sampled <= full {
+		t.Errorf("expected the first element to weigh for all, actual=%d of %d", sampled, full)
+	}
+}
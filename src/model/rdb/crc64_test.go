@@ -0,0 +1,36 @@
+package rdb
+
+import (
+	"testing"
+)
+
+func TestChecksum(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected uint64
+	}{
+		{
+			name:     "empty",
+			input:    "",
+			expected: 0,
+		},
+		{
+			name:     "check value of crc-64-jones",
+			input:    "123456789",
+			expected: 0xe9c6d914c4b8d9ca,
+		},
+	}
+
+	for _, tt := range tests {
+		if actual := Checksum(0, []byte(tt.input)); actual != tt.expected {
+			t.Errorf("case %s: expected %016x, actual=%016x", tt.name, tt.expected, actual)
+		}
+	}
+
+	// updating piece by piece is the same
+	crc := Checksum(0, []byte("1234"))
+	if crc = Checksum(crc, []byte("56789")); crc != 0xe9c6d914c4b8d9ca {
+		t.Errorf("expected %016x piece by piece, actual=%016x", uint64(0xe9c6d914c4b8d9ca), crc)
+	}
+}
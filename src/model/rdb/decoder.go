@@ -0,0 +1,619 @@
+package rdb
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+)
+
+// Snapshot is the content of an RDB file.
+type Snapshot struct {
+	Version int
+	Aux     map[string]string
+	DBs     map[int]map[string]*model.RedisBucket
+	// Functions holds the code of the function libraries, which are not
+	// loaded
+	Functions []string
+	// Skipped lists the keys of module types, which cannot be loaded
+	Skipped []SkippedKey
+}
+
+// SkippedKey is a key whose value was skipped.
+type SkippedKey struct {
+	DB     int
+	Key    string
+	Module string
+}
+
+// DB returns the keys of db, never nil.
+func (s *Snapshot) DB(db int) map[string]*model.RedisBucket {
+	if mem, ok := s.DBs[db]; ok {
+		return mem
+	}
+	return make(map[string]*model.RedisBucket)
+}
+
+// Decoder reads the RDB format, counting the bytes consumed so errors can
+// point at the corrupt offset.
+type Decoder struct {
+	reader *bufio.Reader
+	offset int64
+	crc    uint64
+	// key whose value is being read
+	key []byte
+}
+
+func NewDecoder(reader io.Reader) *Decoder {
+	if r, ok := reader.(*bufio.Reader); ok {
+		return &Decoder{reader: r}
+	}
+	return &Decoder{reader: bufio.NewReader(reader)}
+}
+
+// Offset returns how many bytes have been consumed.
+func (d *Decoder) Offset() int64 {
+	return d.offset
+}
+
+// Key returns the key whose value was being read when Decode failed.
+func (d *Decoder) Key() (string, bool) {
+	return string(d.key), d.key != nil
+}
+
+// Load reads a whole RDB file, up to and including the checksum.
+func Load(reader io.Reader) (*Snapshot, error) {
+	return NewDecoder(reader).Decode()
+}
+
+func (d *Decoder) Decode() (*Snapshot, error) {
+	version, err := d.readHeader()
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &Snapshot{
+		Version: version,
+		Aux:     make(map[string]string),
+		DBs:     make(map[int]map[string]*model.RedisBucket),
+	}
+
+	var (
+		db       = 0
+		expireAt = int64(math.MaxInt64)
+	)
+	for {
+		opcode, err := d.readByte()
+		if err != nil {
+			return nil, err
+		}
+		switch opcode {
+		case OpcodeEOF:
+			if version >= 5 {
+				expected := d.crc
+				b, err := d.readFull(8)
+				if err != nil {
+					return nil, err
+				}
+				// zero when the writer had checksums disabled
+				if crc := binary.LittleEndian.Uint64(b); crc != 0 && crc != expected {
+					return nil, &CorruptError{Offset: d.offset - 8, Msg: fmt.Sprintf("wrong checksum %016x, expected %016x", crc, expected)}
+				}
+			}
+			return snapshot, nil
+		case OpcodeAux:
+			key, err := d.readString()
+			if err != nil {
+				return nil, err
+			}
+			value, err := d.readString()
+			if err != nil {
+				return nil, err
+			}
+			snapshot.Aux[string(key)] = string(value)
+		case OpcodeSelectDB:
+			n, err := d.readLength()
+			if err != nil {
+				return nil, err
+			}
+			db = int(n)
+		case OpcodeResizeDB:
+			size, err := d.readLength()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := d.readLength(); err != nil {
+				return nil, err
+			}
+			if _, ok := snapshot.DBs[db]; !ok {
+				snapshot.DBs[db] = make(map[string]*model.RedisBucket, capHint(size))
+			}
+		case OpcodeExpireTimeMs:
+			b, err := d.readFull(8)
+			if err != nil {
+				return nil, err
+			}
+			expireAt = int64(binary.LittleEndian.Uint64(b))
+		case OpcodeExpireTime:
+			b, err := d.readFull(4)
+			if err != nil {
+				return nil, err
+			}
+			expireAt = int64(binary.LittleEndian.Uint32(b)) * 1000
+		case OpcodeIdle:
+			if _, err := d.readLength(); err != nil {
+				return nil, err
+			}
+		case OpcodeFreq:
+			if _, err := d.readByte(); err != nil {
+				return nil, err
+			}
+		case OpcodeFunction2:
+			code, err := d.readString()
+			if err != nil {
+				return nil, err
+			}
+			snapshot.Functions = append(snapshot.Functions, string(code))
+		case OpcodeModuleAux:
+			if _, err := d.readLength(); err != nil {
+				return nil, err
+			}
+			if whenOpcode, err := d.readLength(); err != nil {
+				return nil, err
+			} else if whenOpcode != moduleOpcodeUInt {
+				return nil, d.corrupt(fmt.Sprintf("unexpected module aux when opcode %d", whenOpcode))
+			}
+			if _, err := d.readLength(); err != nil {
+				return nil, err
+			}
+			if err := d.skipModuleValue(); err != nil {
+				return nil, err
+			}
+		default:
+			key, err := d.readString()
+			if err != nil {
+				return nil, err
+			}
+			d.key = key
+			if opcode == TypeModule2 {
+				module, err := d.readModuleValue()
+				if err != nil {
+					return nil, err
+				}
+				snapshot.Skipped = append(snapshot.Skipped, SkippedKey{DB: db, Key: string(key), Module: module})
+				d.key, expireAt = nil, math.MaxInt64
+				continue
+			}
+			bucket, err := d.readValue(opcode)
+			if err != nil {
+				return nil, err
+			}
+			mem, ok := snapshot.DBs[db]
+			if !ok {
+				mem = make(map[string]*model.RedisBucket)
+				snapshot.DBs[db] = mem
+			}
+			bucket.ExpireAt = expireAt
+			mem[string(key)] = bucket
+			d.key, expireAt = nil, math.MaxInt64
+		}
+	}
+}
+
+// readValue reads a value of type typ, in any encoding Redis writes.
+func (d *Decoder) readValue(typ byte) (*model.RedisBucket, error) {
+	switch typ {
+	case TypeString:
+		value, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		return &model.RedisBucket{Value: value}, nil
+	case TypeList:
+		elements, err := d.readStrings(1)
+		if err != nil {
+			return nil, err
+		}
+		return &model.RedisBucket{Type: model.TypeList, List: elements}, nil
+	case TypeSet:
+		members, err := d.readStrings(1)
+		if err != nil {
+			return nil, err
+		}
+		return newSet(members), nil
+	case TypeZSet, TypeZSet2:
+		n, err := d.readLength()
+		if err != nil {
+			return nil, err
+		}
+		zset := make(map[string]float64, capHint(n))
+		for i := uint64(0); i < n; i++ {
+			member, err := d.readString()
+			if err != nil {
+				return nil, err
+			}
+			var score float64
+			if typ == TypeZSet2 {
+				b, err := d.readFull(8)
+				if err != nil {
+					return nil, err
+				}
+				score = math.Float64frombits(binary.LittleEndian.Uint64(b))
+			} else if score, err = d.readStringDouble(); err != nil {
+				return nil, err
+			}
+			zset[string(member)] = score
+		}
+		return &model.RedisBucket{Type: model.TypeZSet, ZSet: zset}, nil
+	case TypeHash:
+		pairs, err := d.readStrings(2)
+		if err != nil {
+			return nil, err
+		}
+		return newHash(pairs), nil
+	case TypeListQuicklist, TypeListQuicklist2:
+		return d.readQuicklist(typ)
+	case TypeStreamListpacks, TypeStreamListpacks2, TypeStreamListpacks3:
+		stream, err := d.readStream(typ)
+		if err != nil {
+			return nil, err
+		}
+		return &model.RedisBucket{Type: model.TypeStream, Stream: stream}, nil
+	case TypeModule:
+		return nil, d.corrupt("module values of rdb type 6 cannot be skipped")
+	}
+
+	// the other encodings are a blob in a string
+	parse, ok := map[byte]func([]byte) ([][]byte, error){
+		TypeHashZipmap:   zipmapEntries,
+		TypeListZiplist:  ziplistEntries,
+		TypeSetIntset:    intsetEntries,
+		TypeZSetZiplist:  ziplistEntries,
+		TypeHashZiplist:  ziplistEntries,
+		TypeHashListpack: listpackEntries,
+		TypeZSetListpack: listpackEntries,
+		TypeSetListpack:  listpackEntries,
+	}[typ]
+	if !ok {
+		return nil, d.corrupt(fmt.Sprintf("unsupported opcode 0x%02x", typ))
+	}
+	blob, err := d.readString()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := parse(blob)
+	if err != nil {
+		return nil, d.corrupt(err.Error())
+	}
+	switch typ {
+	case TypeListZiplist:
+		return &model.RedisBucket{Type: model.TypeList, List: entries}, nil
+	case TypeSetIntset, TypeSetListpack:
+		return newSet(entries), nil
+	case TypeZSetZiplist, TypeZSetListpack:
+		if len(entries)%2 != 0 {
+			return nil, d.corrupt("zset with a member without score")
+		}
+		zset := make(map[string]float64, len(entries)/2)
+		for i := 0; i < len(entries); i += 2 {
+			score, err := strconv.ParseFloat(string(entries[i+1]), 64)
+			if err != nil {
+				return nil, d.corrupt(fmt.Sprintf("bad zset score %q", entries[i+1]))
+			}
+			zset[string(entries[i])] = score
+		}
+		return &model.RedisBucket{Type: model.TypeZSet, ZSet: zset}, nil
+	}
+	if len(entries)%2 != 0 {
+		return nil, d.corrupt("hash with a field without value")
+	}
+	return newHash(entries), nil
+}
+
+// readStrings reads a length, then that many groups of strings.
+func (d *Decoder) readStrings(group uint64) ([][]byte, error) {
+	n, err := d.readLength()
+	if err != nil {
+		return nil, err
+	}
+	values := make([][]byte, 0, capHint(n*group))
+	for i := uint64(0); i < n*group; i++ {
+		value, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// readStringDouble reads a score of the first zset type: a length, or 253,
+// 254 and 255 for nan, +inf and -inf, then as many ASCII bytes.
+func (d *Decoder) readStringDouble() (float64, error) {
+	n, err := d.readByte()
+	if err != nil {
+		return 0, err
+	}
+	switch n {
+	case 253:
+		return math.NaN(), nil
+	case 254:
+		return math.Inf(1), nil
+	case 255:
+		return math.Inf(-1), nil
+	}
+	b, err := d.readFull(int(n))
+	if err != nil {
+		return 0, err
+	}
+	score, err := strconv.ParseFloat(string(b), 64)
+	if err != nil {
+		return 0, d.corrupt(fmt.Sprintf("bad zset score %q", b))
+	}
+	return score, nil
+}
+
+// readQuicklist reads a list of nodes: ziplists for the first quicklist,
+// and for the second either a listpack or a single plain element.
+func (d *Decoder) readQuicklist(typ byte) (*model.RedisBucket, error) {
+	n, err := d.readLength()
+	if err != nil {
+		return nil, err
+	}
+	bucket := &model.RedisBucket{Type: model.TypeList}
+	for i := uint64(0); i < n; i++ {
+		container := uint64(quicklistNodePacked)
+		if typ == TypeListQuicklist2 {
+			if container, err = d.readLength(); err != nil {
+				return nil, err
+			}
+		}
+		blob, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+
+		var entries [][]byte
+		switch {
+		case container == quicklistNodePlain:
+			entries = [][]byte{blob}
+		case container != quicklistNodePacked:
+			return nil, d.corrupt(fmt.Sprintf("unknown quicklist container %d", container))
+		case typ == TypeListQuicklist:
+			entries, err = ziplistEntries(blob)
+		default:
+			entries, err = listpackEntries(blob)
+		}
+		if err != nil {
+			return nil, d.corrupt(err.Error())
+		} else if len(entries) == 0 {
+			return nil, d.corrupt("empty quicklist node")
+		}
+		bucket.List = append(bucket.List, entries...)
+	}
+	return bucket, nil
+}
+
+// readModuleValue skips the value of a module type and returns the name
+// of the module.
+func (d *Decoder) readModuleValue() (string, error) {
+	id, err := d.readLength()
+	if err != nil {
+		return "", err
+	}
+	return moduleName(id), d.skipModuleValue()
+}
+
+func (d *Decoder) skipModuleValue() error {
+	for {
+		opcode, err := d.readLength()
+		if err != nil {
+			return err
+		}
+		switch opcode {
+		case moduleOpcodeEOF:
+			return nil
+		case moduleOpcodeSInt, moduleOpcodeUInt:
+			_, err = d.readLength()
+		case moduleOpcodeFloat:
+			_, err = d.readFull(4)
+		case moduleOpcodeDouble:
+			_, err = d.readFull(8)
+		case moduleOpcodeString:
+			_, err = d.readString()
+		default:
+			return d.corrupt(fmt.Sprintf("unknown module opcode %d", opcode))
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// moduleName decodes the 9 characters of a module type name from the 54
+// high bits of its id, the 10 others being the encoding version.
+func moduleName(id uint64) string {
+	const charset = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"
+	name := make([]byte, 9)
+	for i := range name {
+		name[i] = charset[(id>>(64-6*(i+1)))&63]
+	}
+	return string(name)
+}
+
+func newSet(members [][]byte) *model.RedisBucket {
+	set := make(map[string]struct{}, len(members))
+	for _, member := range members {
+		set[string(member)] = struct{}{}
+	}
+	return &model.RedisBucket{Type: model.TypeSet, Set: set}
+}
+
+func newHash(pairs [][]byte) *model.RedisBucket {
+	hash := make(map[string][]byte, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		hash[string(pairs[i])] = pairs[i+1]
+	}
+	return &model.RedisBucket{Type: model.TypeHash, Hash: hash}
+}
+
+// capHint bounds a length read from the file before allocating for it.
+func capHint(n uint64) int {
+	if n > 1<<16 {
+		return 1 << 16
+	}
+	return int(n)
+}
+
+func (d *Decoder) readHeader() (int, error) {
+	b, err := d.readFull(len(Magic) + 4)
+	if err != nil {
+		return 0, err
+	}
+	if string(b[:len(Magic)]) != Magic {
+		return 0, &CorruptError{Offset: 0, Msg: "wrong signature"}
+	}
+	version, err := strconv.Atoi(string(b[len(Magic):]))
+	if err != nil || version < 1 || version > Version {
+		return 0, &CorruptError{Offset: int64(len(Magic)), Msg: fmt.Sprintf("unsupported version %q", b[len(Magic):])}
+	}
+	return version, nil
+}
+
+func (d *Decoder) readByte() (byte, error) {
+	b, err := d.reader.ReadByte()
+	if err != nil {
+		return 0, d.unexpected(err)
+	}
+	d.offset++
+	d.crc = Checksum(d.crc, []byte{b})
+	return b, nil
+}
+
+func (d *Decoder) readFull(n int) ([]byte, error) {
+	b := make([]byte, n)
+	read, err := io.ReadFull(d.reader, b)
+	d.offset += int64(read)
+	d.crc = Checksum(d.crc, b[:read])
+	if err != nil {
+		return nil, d.unexpected(err)
+	}
+	return b, nil
+}
+
+// readLengthOrEncoding returns either a length, or a special string
+// encoding when encoded is true.
+func (d *Decoder) readLengthOrEncoding() (n uint64, encoded bool, err error) {
+	first, err := d.readByte()
+	if err != nil {
+		return 0, false, err
+	}
+	switch first >> 6 {
+	case len6Bit:
+		return uint64(first & 0x3F), false, nil
+	case len14Bit:
+		next, err := d.readByte()
+		if err != nil {
+			return 0, false, err
+		}
+		return uint64(first&0x3F)<<8 | uint64(next), false, nil
+	case lenEnc:
+		return uint64(first & 0x3F), true, nil
+	}
+	switch first {
+	case len32Bit:
+		b, err := d.readFull(4)
+		if err != nil {
+			return 0, false, err
+		}
+		return uint64(binary.BigEndian.Uint32(b)), false, nil
+	case len64Bit:
+		b, err := d.readFull(8)
+		if err != nil {
+			return 0, false, err
+		}
+		return binary.BigEndian.Uint64(b), false, nil
+	}
+	return 0, false, d.corrupt(fmt.Sprintf("unknown length encoding 0x%02x", first))
+}
+
+func (d *Decoder) readLength() (uint64, error) {
+	n, encoded, err := d.readLengthOrEncoding()
+	if err != nil {
+		return 0, err
+	} else if encoded {
+		return 0, d.corrupt("unexpected string encoding as length")
+	}
+	return n, nil
+}
+
+func (d *Decoder) readString() ([]byte, error) {
+	n, encoded, err := d.readLengthOrEncoding()
+	if err != nil {
+		return nil, err
+	}
+	if !encoded {
+		if n > math.MaxInt32 {
+			return nil, d.corrupt(fmt.Sprintf("string length %d too large", n))
+		}
+		return d.readFull(int(n))
+	}
+
+	switch n {
+	case encInt8:
+		b, err := d.readFull(1)
+		if err != nil {
+			return nil, err
+		}
+		return strconv.AppendInt(nil, int64(int8(b[0])), 10), nil
+	case encInt16:
+		b, err := d.readFull(2)
+		if err != nil {
+			return nil, err
+		}
+		return strconv.AppendInt(nil, int64(int16(binary.LittleEndian.Uint16(b))), 10), nil
+	case encInt32:
+		b, err := d.readFull(4)
+		if err != nil {
+			return nil, err
+		}
+		return strconv.AppendInt(nil, int64(int32(binary.LittleEndian.Uint32(b))), 10), nil
+	case encLZF:
+		compressedLen, err := d.readLength()
+		if err != nil {
+			return nil, err
+		}
+		size, err := d.readLength()
+		if err != nil {
+			return nil, err
+		}
+		if compressedLen > math.MaxInt32 || size > math.MaxInt32 {
+			return nil, d.corrupt(fmt.Sprintf("lzf string length %d too large", size))
+		}
+		compressed, err := d.readFull(int(compressedLen))
+		if err != nil {
+			return nil, err
+		}
+		value, err := lzfDecompress(compressed, int(size))
+		if err != nil {
+			return nil, d.corrupt(err.Error())
+		}
+		return value, nil
+	}
+	return nil, d.corrupt(fmt.Sprintf("unknown string encoding %d", n))
+}
+
+func (d *Decoder) corrupt(msg string) error {
+	return &CorruptError{Offset: d.offset, Msg: msg}
+}
+
+func (d *Decoder) unexpected(err error) error {
+	if errors.Is(err, io.EOF) {
+		err = io.ErrUnexpectedEOF
+	}
+	return fmt.Errorf("failed to read rdb at offset %d: %w", d.offset, err)
+}
@@ -0,0 +1,360 @@
+package rdb
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"math"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+)
+
+// emptyRDB is what a redis 7.2 master sends to a replica of an empty
+// keyspace.
+const emptyRDB = "UkVESVMwMDEx+glyZWRpcy12ZXIFNy4yLjD6CnJlZGlzLWJpdHPAQPoFY3RpbWXCbQi8ZfoIdXNlZC1tZW3CsMQQAPoIYW9mLWJhc2XAAP/wbjv+wP9aog=="
+
+func TestLoad(t *testing.T) {
+	empty, _ := base64.StdEncoding.DecodeString(emptyRDB)
+	wrongChecksum := append([]byte{}, empty...)
+	wrongChecksum[len(wrongChecksum)-1] ^= 0xff
+
+	tests := []struct {
+		name     string
+		input    []byte
+		aux      map[string]string
+		keys     map[string]string
+		expireAt map[string]int64
+		isError  bool
+	}{
+		{
+			name:  "empty redis 7.2 snapshot",
+			input: empty,
+			aux: map[string]string{
+				"redis-ver":  "7.2.0",
+				"redis-bits": "64",
+				"ctime":      "1706821741",
+				"used-mem":   "1098928",
+				"aof-base":   "0",
+			},
+			keys: map[string]string{},
+		},
+		{
+			name: "strings with expire",
+			input: []byte("REDIS0011" +
+				"\xfe\x00\xfb\x03\x02" +
+				"\x00\x03foo\x03bar" +
+				"\xfc\x00\x9c\xef\x12\x7e\x01\x00\x00\x00\x03baz\xc1\x39\x30" +
+				"\xfd\x00\x00\x00\x80\x00\x03neg\xc0\xfe" +
+				"\xff\x00\x00\x00\x00\x00\x00\x00\x00"),
+			aux: map[string]string{},
+			keys: map[string]string{
+				"foo": "bar",
+				"baz": "12345",
+				"neg": "-2",
+			},
+			expireAt: map[string]int64{
+				"foo": math.MaxInt64,
+				"baz": 1640995200000,
+				"neg": 2147483648000,
+			},
+		},
+		{
+			name: "lzf compressed string",
+			input: []byte("REDIS0011" +
+				"\x00\x03lzf\xc3\x05\x0c\x00a\xe0\x02\x00" +
+				"\xff\x00\x00\x00\x00\x00\x00\x00\x00"),
+			aux: map[string]string{},
+			keys: map[string]string{
+				"lzf": "aaaaaaaaaaaa",
+			},
+		},
+		{
+			name:    "corrupt lzf string",
+			input:   []byte("REDIS0011\x00\x03lzf\xc3\x04\x0c\x00a\x20\x05\xff"),
+			isError: true,
+		},
+		{
+			name:    "wrong checksum",
+			input:   wrongChecksum,
+			isError: true,
+		},
+		{
+			name:    "wrong signature",
+			input:   []byte("RADIS0011\xff"),
+			isError: true,
+		},
+		{
+			name:    "newer version",
+			input:   []byte("REDIS0099\xff"),
+			isError: true,
+		},
+		{
+			name:    "truncated",
+			input:   []byte("REDIS0011\x00\x03foo\x05ba"),
+			isError: true,
+		},
+		{
+			name:    "unsupported type",
+			input:   []byte("REDIS0011\x0e\x03foo"),
+			isError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		snapshot, err := Load(bytes.NewReader(tt.input))
+		if tt.isError {
+			if err == nil {
+				t.Errorf("case %s: expected error, actual=nil", tt.name)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("case %s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		for k, v := range tt.aux {
+			if snapshot.Aux[k] != v {
+				t.Errorf("case %s: aux %s expected=%s, actual=%s", tt.name, k, v, snapshot.Aux[k])
+			}
+		}
+		mem := snapshot.DB(0)
+		if len(mem) != len(tt.keys) {
+			t.Errorf("case %s: expected %d keys, actual=%d", tt.name, len(tt.keys), len(mem))
+		}
+		for k, v := range tt.keys {
+			if bucket, ok := mem[k]; !ok || string(bucket.Value) != v {
+				t.Errorf("case %s: key %s expected=%s, actual=%v", tt.name, k, v, bucket)
+			} else if exp, ok := tt.expireAt[k]; ok && bucket.ExpireAt != exp {
+				t.Errorf("case %s: key %s expireAt expected=%d, actual=%d", tt.name, k, exp, bucket.ExpireAt)
+			}
+		}
+	}
+}
+
+func TestLoad_CorruptOffset(t *testing.T) {
+	decoder := NewDecoder(bytes.NewReader([]byte("REDIS0011\x00\x03foo\xc5")))
+	_, err := decoder.Decode()
+	var corrupt *CorruptError
+	if !errors.As(err, &corrupt) {
+		t.Fatalf("expected CorruptError, actual=%v", err)
+	} else if corrupt.Offset != 15 {
+		t.Errorf("expected offset 15, actual=%d", corrupt.Offset)
+	}
+	if key, ok := decoder.Key(); !ok || key != "foo" {
+		t.Errorf("expected the error while reading foo, actual=%q", key)
+	}
+
+	decoder = NewDecoder(bytes.NewReader([]byte("REDIS0011\x00\x03foo\x03bar\xc5")))
+	if _, err := decoder.Decode(); err == nil {
+		t.Fatalf("expected an error")
+	} else if key, ok := decoder.Key(); ok {
+		t.Errorf("expected no key being read, actual=%q", key)
+	}
+}
+
+// The golden files in testdata hold every type in the encodings the Redis
+// version of each file writes.
+func TestLoad_Golden(t *testing.T) {
+	never := int64(math.MaxInt64)
+	tests := []struct {
+		name      string
+		file      string
+		version   int
+		keys      map[string]*model.RedisBucket
+		functions []string
+		skipped   []SkippedKey
+	}{
+		{
+			name:    "ziplist, zipmap and intset of redis 2.8",
+			file:    "ziplist.rdb",
+			version: 6,
+			keys: map[string]*model.RedisBucket{
+				"list-zl": listBucket("a", "12", "-5", "0", "1000", "100000", "2147483647",
+					"9223372036854775807", "-9223372036854775808", strings.Repeat("x", 100), strings.Repeat("y", 300), "last"),
+				"list-zl-lzf": listBucket(strings.Repeat("abc", 10), strings.Repeat("abc", 10), "7"),
+				"set-is16":    setBucket("-3", "1", "2"),
+				"set-is64":    setBucket("1", "5000000000"),
+				"zset-zl":     {Type: model.TypeZSet, ZSet: map[string]float64{"a": 1, "b": 2.5, "c": math.Inf(-1)}, ExpireAt: never},
+				"hash-zl":     {Type: model.TypeHash, Hash: map[string][]byte{"f1": []byte("v1"), "f2": []byte("2")}, ExpireAt: 4102444800000},
+				"hash-zm":     hashBucket("k1", "v1", "key2", "value2"),
+				"list":        listBucket("1", "two"),
+				"set":         setBucket("m1", "m2"),
+				"zset":        {Type: model.TypeZSet, ZSet: map[string]float64{"a": 1.5, "b": math.Inf(1), "c": math.Inf(-1)}, ExpireAt: never},
+				"hash":        hashBucket("f", "v"),
+				"string":      {Value: []byte("-1234"), ExpireAt: never},
+			},
+		},
+		{
+			name:    "quicklist and first streams of redis 5.0",
+			file:    "quicklist.rdb",
+			version: 9,
+			keys: map[string]*model.RedisBucket{
+				"list-ql":  listBucket("a", "b", "1", "2", "3"),
+				"zset2":    {Type: model.TypeZSet, ZSet: map[string]float64{"x": 1, "y": -2.5}, ExpireAt: 4102444800123},
+				"set-is32": setBucket("-70000", "70000"),
+				"stream-v1": {Type: model.TypeStream, ExpireAt: never, Stream: &model.Stream{
+					Entries: []model.StreamEntry{
+						streamEntry(1700000000000, 0, "name", "alice", "age", "30"),
+						streamEntry(1700000000000, 1, "name", "bob", "age", "40"),
+						streamEntry(1700000000005, 0, "city", "paris"),
+					},
+					LastID:       model.StreamID{Ms: 1700000000006},
+					FirstID:      model.StreamID{Ms: 1700000000000},
+					EntriesAdded: 3,
+					Groups: []*model.StreamGroup{{
+						Name:        "g1",
+						LastID:      model.StreamID{Ms: 1700000000000, Seq: 1},
+						EntriesRead: -1,
+						Pending: []*model.StreamPending{
+							{ID: model.StreamID{Ms: 1700000000000}, DeliveryTime: 1700000001000, DeliveryCount: 2, Consumer: "c1"},
+						},
+						Consumers: []*model.StreamConsumer{
+							{Name: "c1", SeenTime: 1700000002000, ActiveTime: 1700000002000},
+						},
+					}},
+				}},
+			},
+		},
+		{
+			name:    "listpacks, module types and functions of redis 7.2",
+			file:    "listpack.rdb",
+			version: 11,
+			keys: map[string]*model.RedisBucket{
+				"list-ql2": listBucket("a", "1", "-4000", "30000", "8000000", "2000000000", "-9000000000000",
+					strings.Repeat("z", 100), "plain-element", strings.Repeat("end", 10), strings.Repeat("end", 10)),
+				"set-lp":  setBucket("a", "b", "3"),
+				"set-is":  setBucket("100000", "200000"),
+				"zset-lp": {Type: model.TypeZSet, ZSet: map[string]float64{"m1": 1, "m2": 1.5}, ExpireAt: never},
+				"zset-sl": {Type: model.TypeZSet, ZSet: map[string]float64{"big": 3.25}, ExpireAt: never},
+				"hash-lp": {Type: model.TypeHash, Hash: map[string][]byte{"f1": []byte("v1"), "f2": []byte("12345")}, ExpireAt: 4102444800000},
+				"hash":    hashBucket("a", "1", "b", "2"),
+				"string":  {Value: []byte("value"), ExpireAt: never},
+				"stream": {Type: model.TypeStream, ExpireAt: never, Stream: &model.Stream{
+					Entries: []model.StreamEntry{
+						streamEntry(1700000000000, 0, "f", "v1"),
+						streamEntry(1700000000010, 5, "f", "a", "g", "b"),
+						streamEntry(1700000000011, 0, "h", "c"),
+					},
+					LastID:       model.StreamID{Ms: 1700000000011},
+					FirstID:      model.StreamID{Ms: 1700000000000},
+					MaxDeletedID: model.StreamID{Ms: 1700000000000, Seq: 1},
+					EntriesAdded: 4,
+					Groups: []*model.StreamGroup{
+						{
+							Name:        "g1",
+							LastID:      model.StreamID{Ms: 1700000000010, Seq: 5},
+							EntriesRead: 2,
+							Pending: []*model.StreamPending{
+								{ID: model.StreamID{Ms: 1700000000000}, DeliveryTime: 1700000001000, DeliveryCount: 1, Consumer: "alice"},
+								{ID: model.StreamID{Ms: 1700000000010, Seq: 5}, DeliveryTime: 1700000001500, DeliveryCount: 3, Consumer: "alice"},
+							},
+							Consumers: []*model.StreamConsumer{
+								{Name: "alice", SeenTime: 1700000002000, ActiveTime: 1700000001500},
+								{Name: "bob", SeenTime: 1700000003000, ActiveTime: 1700000000500},
+							},
+						},
+						{Name: "g2"},
+					},
+				}},
+			},
+			functions: []string{"#!lua name=mylib\nredis.register_function('f', function() return 1 end)"},
+			skipped:   []SkippedKey{{DB: 0, Key: "module-key", Module: "mymodtype"}},
+		},
+		{
+			name:    "second streams of redis 7.0",
+			file:    "stream.rdb",
+			version: 10,
+			keys: map[string]*model.RedisBucket{
+				"stream-v2": {Type: model.TypeStream, ExpireAt: never, Stream: &model.Stream{
+					Entries: []model.StreamEntry{
+						streamEntry(5, 1, "k", "one"),
+						streamEntry(6, 0, "k", "two"),
+					},
+					LastID:       model.StreamID{Ms: 7, Seq: 3},
+					FirstID:      model.StreamID{Ms: 5, Seq: 1},
+					MaxDeletedID: model.StreamID{Ms: 7, Seq: 3},
+					EntriesAdded: 5,
+					Groups: []*model.StreamGroup{{
+						Name:        "readers",
+						LastID:      model.StreamID{Ms: 6},
+						EntriesRead: 2,
+						Consumers: []*model.StreamConsumer{
+							{Name: "idle", SeenTime: 1700000004000, ActiveTime: 1700000004000},
+						},
+					}},
+				}},
+				"empty-stream": {Type: model.TypeStream, ExpireAt: never, Stream: &model.Stream{}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		snapshot, err := LoadFile(filepath.Join("testdata", tt.file))
+		if err != nil {
+			t.Errorf("case %s: failed to load: %v", tt.name, err)
+			continue
+		}
+		if snapshot.Version != tt.version {
+			t.Errorf("case %s: expected version %d, actual=%d", tt.name, tt.version, snapshot.Version)
+		}
+		mem := snapshot.DB(0)
+		if len(mem) != len(tt.keys) {
+			t.Errorf("case %s: expected %d keys, actual=%d", tt.name, len(tt.keys), len(mem))
+		}
+		for key, expected := range tt.keys {
+			if actual := mem[key]; !reflect.DeepEqual(actual, expected) {
+				t.Errorf("case %s: expected %s=%+v, actual=%+v", tt.name, key, expected, actual)
+			}
+		}
+		if !reflect.DeepEqual(snapshot.Functions, tt.functions) {
+			t.Errorf("case %s: expected functions %q, actual=%q", tt.name, tt.functions, snapshot.Functions)
+		} else if !reflect.DeepEqual(snapshot.Skipped, tt.skipped) {
+			t.Errorf("case %s: expected skipped %v, actual=%v", tt.name, tt.skipped, snapshot.Skipped)
+		}
+
+		// dumped again in the encodings of this version, nothing is lost
+		var buf bytes.Buffer
+		if err := Dump(&buf, &model.RedisStorage{Mem: mem}); err != nil {
+			t.Errorf("case %s: failed to dump: %v", tt.name, err)
+		} else if again, err := Load(&buf); err != nil {
+			t.Errorf("case %s: failed to load the dump: %v", tt.name, err)
+		} else if !reflect.DeepEqual(again.DB(0), mem) {
+			t.Errorf("case %s: expected the dump to load the same keys", tt.name)
+		}
+	}
+}
+
+func listBucket(elements ...string) *model.RedisBucket {
+	return &model.RedisBucket{Type: model.TypeList, List: byteSlices(elements), ExpireAt: math.MaxInt64}
+}
+
+func setBucket(members ...string) *model.RedisBucket {
+	set := make(map[string]struct{}, len(members))
+	for _, member := range members {
+		set[member] = struct{}{}
+	}
+	return &model.RedisBucket{Type: model.TypeSet, Set: set, ExpireAt: math.MaxInt64}
+}
+
+func hashBucket(pairs ...string) *model.RedisBucket {
+	hash := make(map[string][]byte, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		hash[pairs[i]] = []byte(pairs[i+1])
+	}
+	return &model.RedisBucket{Type: model.TypeHash, Hash: hash, ExpireAt: math.MaxInt64}
+}
+
+func streamEntry(ms, seq uint64, fields ...string) model.StreamEntry {
+	return model.StreamEntry{ID: model.StreamID{Ms: ms, Seq: seq}, Fields: byteSlices(fields)}
+}
+
+func byteSlices(values []string) [][]byte {
+	b := make([][]byte, 0, len(values))
+	for _, value := range values {
+		b = append(b, []byte(value))
+	}
+	return b
+}
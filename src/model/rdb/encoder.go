@@ -0,0 +1,266 @@
+package rdb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+)
+
+// Encoder writes the RDB format piece by piece, keeping the first error.
+// Strings are stored as integers or LZF compressed when it is shorter.
+type Encoder struct {
+	writer io.Writer
+	buf    []byte
+	crc    uint64
+	err    error
+}
+
+func NewEncoder(writer io.Writer) *Encoder {
+	return &Encoder{writer: writer}
+}
+
+func (e *Encoder) Err() error {
+	return e.err
+}
+
+func (e *Encoder) flush() error {
+	if e.err == nil && len(e.buf) > 0 {
+		e.crc = Checksum(e.crc, e.buf)
+		_, e.err = e.writer.Write(e.buf)
+	}
+	e.buf = e.buf[:0]
+	return e.err
+}
+
+// WriteHeader writes the magic string and the format version.
+func (e *Encoder) WriteHeader() error {
+	e.buf = append(e.buf, fmt.Sprintf("%s%04d", Magic, Version)...)
+	return e.flush()
+}
+
+func (e *Encoder) WriteAux(key, value string) error {
+	e.buf = append(e.buf, OpcodeAux)
+	e.appendString([]byte(key))
+	e.appendString([]byte(value))
+	return e.flush()
+}
+
+func (e *Encoder) WriteSelectDB(db int) error {
+	e.buf = append(e.buf, OpcodeSelectDB)
+	e.appendLength(uint64(db))
+	return e.flush()
+}
+
+func (e *Encoder) WriteResizeDB(size, expires int) error {
+	e.buf = append(e.buf, OpcodeResizeDB)
+	e.appendLength(uint64(size))
+	e.appendLength(uint64(expires))
+	return e.flush()
+}
+
+// WriteString writes a string key, with its expire time in unix
+// milliseconds unless it is math.MaxInt64.
+func (e *Encoder) WriteString(key string, value []byte, expireAt int64) error {
+	if expireAt != math.MaxInt64 {
+		e.buf = append(e.buf, OpcodeExpireTimeMs)
+		e.buf = binary.LittleEndian.AppendUint64(e.buf, uint64(expireAt))
+	}
+	e.buf = append(e.buf, TypeString)
+	e.appendString([]byte(key))
+	e.appendString(value)
+	return e.flush()
+}
+
+// WriteBucket writes a key of any type, with its expire time unless it is
+// math.MaxInt64. Lists are written as quicklists of listpacks, streams in
+// the third version, other types in their plain encoding.
+func (e *Encoder) WriteBucket(key string, bucket *model.RedisBucket) error {
+	if bucket.ExpireAt != math.MaxInt64 {
+		e.buf = append(e.buf, OpcodeExpireTimeMs)
+		e.buf = binary.LittleEndian.AppendUint64(e.buf, uint64(bucket.ExpireAt))
+	}
+	e.buf = append(e.buf, valueType(bucket))
+	e.appendString([]byte(key))
+	e.appendValue(bucket)
+	return e.flush()
+}
+
+func valueType(bucket *model.RedisBucket) byte {
+	switch bucket.Type {
+	case model.TypeList:
+		return TypeListQuicklist2
+	case model.TypeSet:
+		return TypeSet
+	case model.TypeZSet:
+		return TypeZSet2
+	case model.TypeHash:
+		return TypeHash
+	case model.TypeStream:
+		return TypeStreamListpacks3
+	}
+	return TypeString
+}
+
+// appendValue encodes the value of bucket as valueType says. Members of
+// sets, zsets and hashes are sorted so a dump is reproducible.
+func (e *Encoder) appendValue(bucket *model.RedisBucket) {
+	switch bucket.Type {
+	case model.TypeList:
+		nodes := (len(bucket.List) + listNodeMaxEntries - 1) / listNodeMaxEntries
+		e.appendLength(uint64(nodes))
+		var lp []byte
+		for start := 0; start < len(bucket.List); start += listNodeMaxEntries {
+			node := bucket.List[start:]
+			if len(node) > listNodeMaxEntries {
+				node = node[:listNodeMaxEntries]
+			}
+			e.appendLength(quicklistNodePacked)
+			lp = appendListpack(lp[:0], node)
+			e.appendString(lp)
+		}
+	case model.TypeSet:
+		e.appendLength(uint64(len(bucket.Set)))
+		for _, member := range sortedKeys(bucket.Set) {
+			e.appendString([]byte(member))
+		}
+	case model.TypeZSet:
+		e.appendLength(uint64(len(bucket.ZSet)))
+		for _, member := range sortedKeys(bucket.ZSet) {
+			e.appendString([]byte(member))
+			e.buf = binary.LittleEndian.AppendUint64(e.buf, math.Float64bits(bucket.ZSet[member]))
+		}
+	case model.TypeHash:
+		e.appendLength(uint64(len(bucket.Hash)))
+		for _, field := range sortedKeys(bucket.Hash) {
+			e.appendString([]byte(field))
+			e.appendString(bucket.Hash[field])
+		}
+	case model.TypeStream:
+		e.appendStream(bucket.Stream)
+	default:
+		e.appendString(bucket.Value)
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// WriteEOF ends the file with the checksum of everything written before.
+func (e *Encoder) WriteEOF() error {
+	e.buf = append(e.buf, OpcodeEOF)
+	if e.flush() != nil {
+		return e.err
+	}
+	e.buf = binary.LittleEndian.AppendUint64(e.buf, e.crc)
+	return e.flush()
+}
+
+func (e *Encoder) appendLength(n uint64) {
+	switch {
+	case n < 1<<6:
+		e.buf = append(e.buf, byte(n))
+	case n < 1<<14:
+		e.buf = append(e.buf, byte(n>>8)|len14Bit<<6, byte(n))
+	case n <= math.MaxUint32:
+		e.buf = append(e.buf, len32Bit)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(n))
+	default:
+		e.buf = append(e.buf, len64Bit)
+		e.buf = binary.BigEndian.AppendUint64(e.buf, n)
+	}
+}
+
+func (e *Encoder) appendString(s []byte) {
+	if len(s) <= 11 && e.appendInt(s) {
+		return
+	}
+	if len(s) > 20 {
+		if compressed := lzfCompress(s); compressed != nil {
+			e.buf = append(e.buf, lenEnc<<6|encLZF)
+			e.appendLength(uint64(len(compressed)))
+			e.appendLength(uint64(len(s)))
+			e.buf = append(e.buf, compressed...)
+			return
+		}
+	}
+	e.appendLength(uint64(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+// appendInt stores s as an integer when it reads back the same.
+func (e *Encoder) appendInt(s []byte) bool {
+	n, ok := canonicalInt(s)
+	if !ok || n < math.MinInt32 || n > math.MaxInt32 {
+		return false
+	}
+	switch {
+	case n >= math.MinInt8 && n <= math.MaxInt8:
+		e.buf = append(e.buf, lenEnc<<6|encInt8, byte(n))
+	case n >= math.MinInt16 && n <= math.MaxInt16:
+		e.buf = append(e.buf, lenEnc<<6|encInt16)
+		e.buf = binary.LittleEndian.AppendUint16(e.buf, uint16(n))
+	default:
+		e.buf = append(e.buf, lenEnc<<6|encInt32)
+		e.buf = binary.LittleEndian.AppendUint32(e.buf, uint32(n))
+	}
+	return true
+}
+
+// Dump writes the live keys of every database of storage as a snapshot.
+func Dump(writer io.Writer, storage *model.RedisStorage) error {
+	return dump(writer, storage, nil)
+}
+
+// DumpStream writes a snapshot for a replica, recording streamDB, the
+// database the replication stream following the snapshot writes to.
+func DumpStream(writer io.Writer, storage *model.RedisStorage, streamDB int) error {
+	return dump(writer, storage, map[string]string{AuxReplStreamDB: strconv.Itoa(streamDB)})
+}
+
+func dump(writer io.Writer, storage *model.RedisStorage, aux map[string]string) error {
+	var (
+		encoder = NewEncoder(writer)
+		now     = time.Now().UnixMilli()
+	)
+	_ = encoder.WriteHeader()
+	_ = encoder.WriteAux("redis-ver", "7.2.0")
+	_ = encoder.WriteAux("redis-bits", strconv.Itoa(strconv.IntSize))
+	_ = encoder.WriteAux("ctime", strconv.FormatInt(now/1000, 10))
+	for _, field := range sortedKeys(aux) {
+		_ = encoder.WriteAux(field, aux[field])
+	}
+	for db, mem := range storage.Databases() {
+		keys, expires := make([]string, 0, len(mem)), 0
+		for key, bucket := range mem {
+			if bucket.ExpireAt < now {
+				continue
+			}
+			keys = append(keys, key)
+			if bucket.ExpireAt != math.MaxInt64 {
+				expires++
+			}
+		}
+		if len(keys) == 0 {
+			continue
+		}
+		sort.Strings(keys)
+		_ = encoder.WriteSelectDB(db)
+		_ = encoder.WriteResizeDB(len(keys), expires)
+		for _, key := range keys {
+			_ = encoder.WriteBucket(key, mem[key])
+		}
+	}
+	return encoder.WriteEOF()
+}
@@ -0,0 +1,186 @@
+package rdb
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+)
+
+func TestDump(t *testing.T) {
+	future := time.Now().Add(time.Hour).UnixMilli()
+	tests := []struct {
+		name     string
+		mem      map[string]*model.RedisBucket
+		expected map[string]*model.RedisBucket
+	}{
+		{
+			name:     "empty",
+			mem:      map[string]*model.RedisBucket{},
+			expected: map[string]*model.RedisBucket{},
+		},
+		{
+			name: "strings",
+			mem: map[string]*model.RedisBucket{
+				"foo":   {Value: []byte("bar"), ExpireAt: math.MaxInt64},
+				"empty": {Value: []byte{}, ExpireAt: math.MaxInt64},
+				"ttl":   {Value: []byte("1"), ExpireAt: future},
+				"long":  {Value: []byte(strings.Repeat("x", 70000)), ExpireAt: math.MaxInt64},
+				"mid":   {Value: []byte(strings.Repeat("y", 300)), ExpireAt: math.MaxInt64},
+			},
+			expected: map[string]*model.RedisBucket{
+				"foo":   {Value: []byte("bar"), ExpireAt: math.MaxInt64},
+				"empty": {Value: []byte{}, ExpireAt: math.MaxInt64},
+				"ttl":   {Value: []byte("1"), ExpireAt: future},
+				"long":  {Value: []byte(strings.Repeat("x", 70000)), ExpireAt: math.MaxInt64},
+				"mid":   {Value: []byte(strings.Repeat("y", 300)), ExpireAt: math.MaxInt64},
+			},
+		},
+		{
+			name: "integers and compressible strings",
+			mem: map[string]*model.RedisBucket{
+				"1":      {Value: []byte("-5"), ExpireAt: math.MaxInt64},
+				"int16":  {Value: []byte("12345"), ExpireAt: math.MaxInt64},
+				"int32":  {Value: []byte("-2147483648"), ExpireAt: math.MaxInt64},
+				"big":    {Value: []byte("2147483648"), ExpireAt: math.MaxInt64},
+				"padded": {Value: []byte("007"), ExpireAt: math.MaxInt64},
+				"lzf":    {Value: []byte(strings.Repeat("abc", 50)), ExpireAt: math.MaxInt64},
+			},
+			expected: map[string]*model.RedisBucket{
+				"1":      {Value: []byte("-5"), ExpireAt: math.MaxInt64},
+				"int16":  {Value: []byte("12345"), ExpireAt: math.MaxInt64},
+				"int32":  {Value: []byte("-2147483648"), ExpireAt: math.MaxInt64},
+				"big":    {Value: []byte("2147483648"), ExpireAt: math.MaxInt64},
+				"padded": {Value: []byte("007"), ExpireAt: math.MaxInt64},
+				"lzf":    {Value: []byte(strings.Repeat("abc", 50)), ExpireAt: math.MaxInt64},
+			},
+		},
+		{
+			name: "expired keys are skipped",
+			mem: map[string]*model.RedisBucket{
+				"gone": {Value: []byte("bar"), ExpireAt: 1},
+				"kept": {Value: []byte("baz"), ExpireAt: math.MaxInt64},
+			},
+			expected: map[string]*model.RedisBucket{
+				"kept": {Value: []byte("baz"), ExpireAt: math.MaxInt64},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := Dump(&buf, &model.RedisStorage{Mem: tt.mem}); err != nil {
+			t.Errorf("case %s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if !bytes.HasPrefix(buf.Bytes(), []byte("REDIS0011")) {
+			t.Errorf("case %s: unexpected header %q", tt.name, buf.Bytes()[:9])
+		}
+		snapshot, err := Load(&buf)
+		if err != nil {
+			t.Errorf("case %s: failed to load: %v", tt.name, err)
+			continue
+		}
+		mem := snapshot.DB(0)
+		if len(mem) != len(tt.expected) {
+			t.Errorf("case %s: expected %d keys, actual=%d", tt.name, len(tt.expected), len(mem))
+		}
+		for k, exp := range tt.expected {
+			actual, ok := mem[k]
+			if !ok || !bytes.Equal(actual.Value, exp.Value) || actual.ExpireAt != exp.ExpireAt {
+				t.Errorf("case %s: key %s expected=%v, actual=%v", tt.name, k, exp, actual)
+			}
+		}
+	}
+}
+
+func TestDumpStream(t *testing.T) {
+	storage := model.NewRedisStorage(4)
+	storage.DBs[1]["one"] = &model.RedisBucket{Value: []byte("1"), ExpireAt: math.MaxInt64}
+	storage.Select(3)
+	storage.Mem["three"] = &model.RedisBucket{Value: []byte("3"), ExpireAt: math.MaxInt64}
+
+	var buf bytes.Buffer
+	if err := DumpStream(&buf, storage, 2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	snapshot, err := Load(&buf)
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	if snapshot.Aux[AuxReplStreamDB] != "2" {
+		t.Errorf("expected stream db 2, actual=%q", snapshot.Aux[AuxReplStreamDB])
+	}
+	if len(snapshot.DBs) != 2 {
+		t.Errorf("expected the databases with keys only, actual=%v", snapshot.DBs)
+	}
+	if _, ok := snapshot.DB(1)["one"]; !ok {
+		t.Errorf("expected key one in db 1")
+	} else if _, ok := snapshot.DB(3)["three"]; !ok {
+		t.Errorf("expected key three in db 3")
+	}
+}
+
+func TestEncoder_WriteString(t *testing.T) {
+	tests := []struct {
+		name     string
+		key      string
+		value    string
+		expected string
+	}{
+		{
+			name:     "raw",
+			key:      "k",
+			value:    "bar",
+			expected: "\x00\x01k\x03bar",
+		},
+		{
+			name:     "int8",
+			key:      "k",
+			value:    "-2",
+			expected: "\x00\x01k\xc0\xfe",
+		},
+		{
+			name:     "int16",
+			key:      "k",
+			value:    "12345",
+			expected: "\x00\x01k\xc1\x39\x30",
+		},
+		{
+			name:     "int32",
+			key:      "k",
+			value:    "2147483647",
+			expected: "\x00\x01k\xc2\xff\xff\xff\x7f",
+		},
+		{
+			name:     "not canonical",
+			key:      "k",
+			value:    "+1",
+			expected: "\x00\x01k\x02+1",
+		},
+		{
+			name:     "integer key",
+			key:      "7",
+			value:    "bar",
+			expected: "\x00\xc0\x07\x03bar",
+		},
+		{
+			name:     "lzf",
+			key:      "k",
+			value:    strings.Repeat("a", 24),
+			expected: "\x00\x01k\xc3\x05\x18\x00a\xe0\x0e\x00",
+		},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := NewEncoder(&buf).WriteString(tt.key, []byte(tt.value), math.MaxInt64); err != nil {
+			t.Errorf("case %s: unexpected error: %v", tt.name, err)
+		} else if actual := buf.String(); actual != tt.expected {
+			t.Errorf("case %s: expected %q, actual=%q", tt.name, tt.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package rdb
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+)
+
+// SaveFile dumps storage to path. The snapshot is written to a temporary
+// file in the same directory and renamed over path once synced, so path
+// always holds a complete snapshot.
+func SaveFile(path string, storage *model.RedisStorage) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), fmt.Sprintf("temp-%d-*.rdb", os.Getpid()))
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	writer := bufio.NewWriter(tmp)
+	if err = Dump(writer, storage); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	} else if err = writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	} else if err = tmp.Sync(); err != nil {
+		return fmt.Errorf("failed to sync snapshot: %w", err)
+	} else if err = tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close snapshot: %w", err)
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to rename snapshot: %w", err)
+	}
+	return nil
+}
+
+// LoadFile reads the snapshot at path, errors wrap os.ErrNotExist when
+// there is none.
+func LoadFile(path string) (*Snapshot, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return Load(file)
+}
@@ -0,0 +1,46 @@
+package rdb
+
+import (
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+)
+
+func TestSaveFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "dump.rdb")
+	if _, err := LoadFile(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected ErrNotExist before the first save, actual=%v", err)
+	}
+
+	for _, value := range []string{"first", "second"} {
+		storage := &model.RedisStorage{Mem: map[string]*model.RedisBucket{
+			"foo": {Value: []byte(value), ExpireAt: math.MaxInt64},
+		}}
+		if err := SaveFile(path, storage); err != nil {
+			t.Fatalf("failed to save %s: %v", value, err)
+		}
+		snapshot, err := LoadFile(path)
+		if err != nil {
+			t.Fatalf("failed to load %s: %v", value, err)
+		} else if bucket := snapshot.DB(0)["foo"]; bucket == nil || string(bucket.Value) != value {
+			t.Errorf("expected foo=%s, actual=%v", value, bucket)
+		}
+	}
+
+	// no temp file is left behind
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to read dir: %v", err)
+	} else if len(entries) != 1 {
+		t.Errorf("expected only dump.rdb, actual=%v", entries)
+	}
+
+	if err := SaveFile(filepath.Join(dir, "missing", "dump.rdb"), &model.RedisStorage{}); err == nil {
+		t.Errorf("expected an error for a missing directory")
+	}
+}
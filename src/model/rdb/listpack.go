@@ -0,0 +1,186 @@
+package rdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// listpackEntries returns the elements of a listpack: its total bytes and
+// number of elements, then entries of an encoding, the data and the
+// length of both backwards, then 0xFF. Integers are returned as decimal
+// strings.
+func listpackEntries(lp []byte) ([][]byte, error) {
+	if len(lp) < 7 {
+		return nil, errors.New("listpack too short")
+	} else if size := binary.LittleEndian.Uint32(lp); int(size) != len(lp) {
+		return nil, fmt.Errorf("listpack of %d bytes claims %d", len(lp), size)
+	}
+	count := binary.LittleEndian.Uint16(lp[4:])
+
+	var (
+		entries [][]byte
+		p       = 6
+	)
+	for {
+		if p >= len(lp) {
+			return nil, errors.New("listpack without end")
+		} else if lp[p] == 0xFF {
+			if p != len(lp)-1 {
+				return nil, errors.New("listpack with trailing bytes")
+			}
+			break
+		}
+		entry, size, err := listpackEntry(lp[p:])
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+		p += size + backlenSize(size)
+	}
+	// a count of 0xFFFF means too many to count
+	if count != 0xFFFF && int(count) != len(entries) {
+		return nil, fmt.Errorf("listpack of %d entries claims %d", len(entries), count)
+	}
+	return entries, nil
+}
+
+func listpackEntry(b []byte) (value []byte, size int, err error) {
+	var n int
+	switch first := b[0]; {
+	case first&0x80 == 0:
+		return strconv.AppendInt(nil, int64(first), 10), 1, nil
+	case first&0xC0 == 0x80:
+		n, size = int(first&0x3F), 1
+	case first&0xE0 == 0xC0:
+		if len(b) < 2 {
+			return nil, 0, errors.New("listpack entry out of range")
+		}
+		v := int64(first&0x1F)<<8 | int64(b[1])
+		if v >= 1<<12 {
+			v -= 1 << 13
+		}
+		return strconv.AppendInt(nil, v, 10), 2, nil
+	case first&0xF0 == 0xE0:
+		if len(b) < 2 {
+			return nil, 0, errors.New("listpack entry out of range")
+		}
+		n, size = int(first&0x0F)<<8|int(b[1]), 2
+	case first == 0xF0:
+		if len(b) < 5 {
+			return nil, 0, errors.New("listpack entry out of range")
+		}
+		n, size = int(binary.LittleEndian.Uint32(b[1:])), 5
+	case first == 0xF1:
+		return readInt(b, 1, 2)
+	case first == 0xF2:
+		return readInt(b, 1, 3)
+	case first == 0xF3:
+		return readInt(b, 1, 4)
+	case first == 0xF4:
+		return readInt(b, 1, 8)
+	default:
+		return nil, 0, fmt.Errorf("unknown listpack encoding 0x%02x", first)
+	}
+	if n < 0 || size+n > len(b) {
+		return nil, 0, errors.New("listpack entry out of range")
+	}
+	return append([]byte(nil), b[size:size+n]...), size + n, nil
+}
+
+// backlenSize returns how many bytes store the length of an entry of n
+// bytes, backwards.
+func backlenSize(n int) int {
+	switch {
+	case n <= 127:
+		return 1
+	case n < 16383:
+		return 2
+	case n < 2097151:
+		return 3
+	case n < 268435455:
+		return 4
+	}
+	return 5
+}
+
+// appendListpack encodes entries as a listpack, integers in their compact
+// form.
+func appendListpack(buf []byte, entries [][]byte) []byte {
+	start := len(buf)
+	buf = append(buf, 0, 0, 0, 0, 0, 0)
+	for _, entry := range entries {
+		buf = appendListpackEntry(buf, entry)
+	}
+	buf = append(buf, 0xFF)
+	count := len(entries)
+	if count > 0xFFFF {
+		count = 0xFFFF
+	}
+	binary.LittleEndian.PutUint32(buf[start:], uint32(len(buf)-start))
+	binary.LittleEndian.PutUint16(buf[start+4:], uint16(count))
+	return buf
+}
+
+func appendListpackEntry(buf []byte, entry []byte) []byte {
+	start := len(buf)
+	if n, ok := canonicalInt(entry); ok {
+		switch {
+		case n >= 0 && n <= 127:
+			buf = append(buf, byte(n))
+		case n >= -4096 && n <= 4095:
+			u := uint16(n) & 0x1FFF
+			buf = append(buf, 0xC0|byte(u>>8), byte(u))
+		case n >= math.MinInt16 && n <= math.MaxInt16:
+			buf = append(buf, 0xF1)
+			buf = binary.LittleEndian.AppendUint16(buf, uint16(n))
+		case n >= -1<<23 && n < 1<<23:
+			buf = append(buf, 0xF2, byte(n), byte(n>>8), byte(n>>16))
+		case n >= math.MinInt32 && n <= math.MaxInt32:
+			buf = append(buf, 0xF3)
+			buf = binary.LittleEndian.AppendUint32(buf, uint32(n))
+		default:
+			buf = append(buf, 0xF4)
+			buf = binary.LittleEndian.AppendUint64(buf, uint64(n))
+		}
+	} else {
+		switch n := len(entry); {
+		case n < 1<<6:
+			buf = append(buf, 0x80|byte(n))
+		case n < 1<<12:
+			buf = append(buf, 0xE0|byte(n>>8), byte(n))
+		default:
+			buf = append(buf, 0xF0)
+			buf = binary.LittleEndian.AppendUint32(buf, uint32(n))
+		}
+		buf = append(buf, entry...)
+	}
+
+	switch l := len(buf) - start; {
+	case l <= 127:
+		buf = append(buf, byte(l))
+	case l < 16383:
+		buf = append(buf, byte(l>>7), byte(l&127)|128)
+	case l < 2097151:
+		buf = append(buf, byte(l>>14), byte((l>>7)&127)|128, byte(l&127)|128)
+	case l < 268435455:
+		buf = append(buf, byte(l>>21), byte((l>>14)&127)|128, byte((l>>7)&127)|128, byte(l&127)|128)
+	default:
+		buf = append(buf, byte(l>>28), byte((l>>21)&127)|128, byte((l>>14)&127)|128, byte((l>>7)&127)|128, byte(l&127)|128)
+	}
+	return buf
+}
+
+// canonicalInt parses s as an integer when it reads back the same.
+func canonicalInt(s []byte) (int64, bool) {
+	if len(s) == 0 || len(s) > 20 {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(string(s), 10, 64)
+	if err != nil || strconv.FormatInt(n, 10) != string(s) {
+		return 0, false
+	}
+	return n, true
+}
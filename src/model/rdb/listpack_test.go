@@ -0,0 +1,72 @@
+package rdb
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestListpack(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry string
+		// bytes of the entry, backlen included
+		size int
+	}{
+		{name: "7 bit uint", entry: "127", size: 2},
+		{name: "13 bit int", entry: "128", size: 3},
+		{name: "negative 13 bit int", entry: "-4096", size: 3},
+		{name: "16 bit int", entry: "4096", size: 4},
+		{name: "24 bit int", entry: "-8388608", size: 5},
+		{name: "32 bit int", entry: "8388608", size: 6},
+		{name: "64 bit int", entry: "-9223372036854775808", size: 10},
+		{name: "not canonical", entry: "007", size: 5},
+		{name: "empty string", entry: "", size: 2},
+		{name: "6 bit string", entry: strings.Repeat("a", 63), size: 65},
+		{name: "12 bit string", entry: strings.Repeat("a", 64), size: 67},
+		{name: "32 bit string", entry: strings.Repeat("a", 4096), size: 4103},
+		{name: "3 byte backlen", entry: strings.Repeat("a", 20000), size: 20008},
+	}
+
+	for _, tt := range tests {
+		lp := appendListpack(nil, [][]byte{[]byte(tt.entry)})
+		if size := len(lp) - 7; size != tt.size {
+			t.Errorf("case %s: expected an entry of %d bytes, actual=%d", tt.name, tt.size, size)
+		}
+		entries, err := listpackEntries(lp)
+		if err != nil {
+			t.Errorf("case %s: unexpected error: %v", tt.name, err)
+		} else if len(entries) != 1 || string(entries[0]) != tt.entry {
+			t.Errorf("case %s: expected %q back, actual=%q", tt.name, tt.entry, entries)
+		}
+	}
+}
+
+func TestListpackEntries_Corrupt(t *testing.T) {
+	valid := appendListpack(nil, [][]byte{[]byte("a"), []byte("1")})
+	tests := []struct {
+		name  string
+		input []byte
+	}{
+		{name: "too short", input: valid[:5]},
+		{name: "wrong total bytes", input: append(append([]byte{}, valid[:len(valid)-1]...), 0, 0xFF)},
+		{name: "wrong count", input: append(append([]byte{}, valid[:4]...), append([]byte{3, 0}, valid[6:]...)...)},
+		{name: "string out of range", input: []byte{10, 0, 0, 0, 1, 0, 0x85, 'a', 2, 0xFF}},
+		{name: "unknown encoding", input: []byte{9, 0, 0, 0, 1, 0, 0xF5, 1, 0xFF}},
+		{name: "without end", input: []byte{8, 0, 0, 0, 1, 0, 0x01, 0x01}},
+	}
+
+	for _, tt := range tests {
+		if _, err := listpackEntries(tt.input); err == nil {
+			t.Errorf("case %s: expected error but got nil", tt.name)
+		}
+	}
+}
+
+func TestCanonicalInt(t *testing.T) {
+	for input, ok := range map[string]bool{"0": true, "-1": true, "9223372036854775807": true, "": false,
+		"-0": false, "01": false, "+1": false, "9223372036854775808": false, " 1": false} {
+		if _, actual := canonicalInt([]byte(input)); actual != ok {
+			t.Errorf("case %q: expected %v, actual=%v", input, ok, actual)
+		}
+	}
+}
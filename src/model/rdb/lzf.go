@@ -0,0 +1,123 @@
+package rdb
+
+import (
+	"errors"
+)
+
+// LZF as in liblzf, which Redis uses for strings longer than 20 bytes.
+// A control byte below 32 is followed by that many plus one literal bytes,
+// otherwise it starts a back reference: the top 3 bits are the length
+// minus 2, 7 meaning a length byte follows, and the low 5 bits and the
+// next byte are the distance minus 1.
+const (
+	lzfHashLog = 14
+	lzfMaxLit  = 1 << 5
+	lzfMaxOff  = 1 << 13
+	lzfMaxRef  = 1<<8 + 1<<3
+)
+
+var errLZFCorrupt = errors.New("corrupt lzf data")
+
+// lzfCompress returns the compressed in, or nil when it would not save at
+// least 4 bytes.
+func lzfCompress(in []byte) []byte {
+	if len(in) <= 4 {
+		return nil
+	}
+	var (
+		htab  = make([]int32, 1<<lzfHashLog)
+		out   = make([]byte, 0, len(in))
+		limit = len(in) - 4
+		lit   = 0
+		ip    = 0
+	)
+	flushLiterals := func(end int) {
+		for lit < end {
+			n := end - lit
+			if n > lzfMaxLit {
+				n = lzfMaxLit
+			}
+			out = append(out, byte(n-1))
+			out = append(out, in[lit:lit+n]...)
+			lit += n
+		}
+	}
+
+	for ip+2 < len(in) {
+		h := (uint32(in[ip])<<16 | uint32(in[ip+1])<<8 | uint32(in[ip+2])) * 2654435761 >> (32 - lzfHashLog)
+		ref := int(htab[h]) - 1
+		htab[h] = int32(ip + 1)
+		off := ip - ref - 1
+		if ref < 0 || off >= lzfMaxOff ||
+			in[ref] != in[ip] || in[ref+1] != in[ip+1] || in[ref+2] != in[ip+2] {
+			ip++
+			continue
+		}
+
+		n := 3
+		for ip+n < len(in) && n < lzfMaxRef && in[ref+n] == in[ip+n] {
+			n++
+		}
+		flushLiterals(ip)
+		if n-2 < 7 {
+			out = append(out, byte(off>>8)|byte(n-2)<<5)
+		} else {
+			out = append(out, byte(off>>8)|7<<5, byte(n-2-7))
+		}
+		out = append(out, byte(off))
+		ip += n
+		lit = ip
+		if len(out) >= limit {
+			return nil
+		}
+	}
+	flushLiterals(len(in))
+	if len(out) >= limit {
+		return nil
+	}
+	return out
+}
+
+// lzfDecompress expands in, which must give exactly size bytes.
+func lzfDecompress(in []byte, size int) ([]byte, error) {
+	out := make([]byte, 0, size)
+	for ip := 0; ip < len(in); {
+		ctrl := int(in[ip])
+		ip++
+		if ctrl < lzfMaxLit {
+			n := ctrl + 1
+			if ip+n > len(in) || len(out)+n > size {
+				return nil, errLZFCorrupt
+			}
+			out = append(out, in[ip:ip+n]...)
+			ip += n
+			continue
+		}
+
+		n := ctrl >> 5
+		if n == 7 {
+			if ip >= len(in) {
+				return nil, errLZFCorrupt
+			}
+			n += int(in[ip])
+			ip++
+		}
+		n += 2
+		if ip >= len(in) {
+			return nil, errLZFCorrupt
+		}
+		ref := len(out) - (ctrl&0x1f)<<8 - int(in[ip]) - 1
+		ip++
+		if ref < 0 || len(out)+n > size {
+			return nil, errLZFCorrupt
+		}
+		// byte by byte, the reference may overlap what it produces
+		for i := 0; i < n; i++ {
+			out = append(out, out[ref+i])
+		}
+	}
+	if len(out) != size {
+		return nil, errLZFCorrupt
+	}
+	return out, nil
+}
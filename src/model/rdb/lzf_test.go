@@ -0,0 +1,111 @@
+package rdb
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestLZF(t *testing.T) {
+	random := make([]byte, 4096)
+	rand.New(rand.NewSource(1)).Read(random)
+	tests := []struct {
+		name       string
+		input      []byte
+		compressed bool
+	}{
+		{
+			name:       "repeated byte",
+			input:      []byte(strings.Repeat("a", 1000)),
+			compressed: true,
+		},
+		{
+			name:       "repeated words",
+			input:      []byte(strings.Repeat("hello world, ", 100)),
+			compressed: true,
+		},
+		{
+			name:       "long literal runs between references",
+			input:      append(append(append([]byte{}, random[:100]...), random[:100]...), random[:300]...),
+			compressed: true,
+		},
+		{
+			name:       "random data does not shrink",
+			input:      random,
+			compressed: false,
+		},
+		{
+			name:       "too short",
+			input:      []byte("aaaa"),
+			compressed: false,
+		},
+	}
+
+	for _, tt := range tests {
+		compressed := lzfCompress(tt.input)
+		if (compressed != nil) != tt.compressed {
+			t.Errorf("case %s: expected compressed %v, actual=%v", tt.name, tt.compressed, compressed != nil)
+			continue
+		} else if compressed == nil {
+			continue
+		}
+		if len(compressed) >= len(tt.input)-4 {
+			t.Errorf("case %s: expected at least 4 bytes saved, actual %d -> %d", tt.name, len(tt.input), len(compressed))
+		}
+		actual, err := lzfDecompress(compressed, len(tt.input))
+		if err != nil {
+			t.Errorf("case %s: failed to decompress: %v", tt.name, err)
+		} else if !bytes.Equal(actual, tt.input) {
+			t.Errorf("case %s: round trip changed the data", tt.name)
+		}
+	}
+}
+
+func TestLZFDecompress(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []byte
+		size     int
+		expected string
+		isError  bool
+	}{
+		{
+			name:     "literal and overlapping reference",
+			input:    []byte{0x00, 'a', 0xe0, 0x02, 0x00},
+			size:     12,
+			expected: "aaaaaaaaaaaa",
+		},
+		{
+			name:    "reference before the start",
+			input:   []byte{0x00, 'a', 0x20, 0x05},
+			size:    4,
+			isError: true,
+		},
+		{
+			name:    "truncated literal",
+			input:   []byte{0x05, 'a'},
+			size:    6,
+			isError: true,
+		},
+		{
+			name:    "size mismatch",
+			input:   []byte{0x01, 'a', 'b'},
+			size:    3,
+			isError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		actual, err := lzfDecompress(tt.input, tt.size)
+		if tt.isError {
+			if err == nil {
+				t.Errorf("case %s: expected error, actual=%q", tt.name, actual)
+			}
+		} else if err != nil {
+			t.Errorf("case %s: unexpected error: %v", tt.name, err)
+		} else if string(actual) != tt.expected {
+			t.Errorf("case %s: expected %q, actual=%q", tt.name, tt.expected, actual)
+		}
+	}
+}
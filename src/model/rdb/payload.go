@@ -0,0 +1,59 @@
+package rdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+)
+
+// ErrPayloadVersion means a DUMP payload is from a newer RDB version, or
+// its checksum does not match.
+var ErrPayloadVersion = errors.New("payload version or checksum are wrong")
+
+// DumpValue serializes the value of bucket as DUMP does: the RDB type and
+// value, then the RDB version on 2 bytes and the CRC64 of all the rest on
+// 8 bytes, both little endian. The expire time is not part of it.
+func DumpValue(bucket *model.RedisBucket) []byte {
+	e := &Encoder{}
+	e.buf = append(e.buf, valueType(bucket))
+	e.appendValue(bucket)
+	e.buf = binary.LittleEndian.AppendUint16(e.buf, Version)
+	return binary.LittleEndian.AppendUint64(e.buf, Checksum(0, e.buf))
+}
+
+// LoadValue reads a payload written by DumpValue, or by Redis up to the
+// supported RDB version. The bucket never expires.
+func LoadValue(payload []byte) (*model.RedisBucket, error) {
+	if len(payload) < 10 {
+		return nil, ErrPayloadVersion
+	}
+	footer := payload[len(payload)-10:]
+	if binary.LittleEndian.Uint16(footer) > Version ||
+		binary.LittleEndian.Uint64(footer[2:]) != Checksum(0, payload[:len(payload)-8]) {
+		return nil, ErrPayloadVersion
+	}
+
+	reader := bytes.NewReader(payload[:len(payload)-10])
+	d := NewDecoder(reader)
+	typ, err := d.readByte()
+	if err != nil {
+		return nil, err
+	}
+	if typ == TypeModule2 {
+		return nil, d.corrupt("module values cannot be restored")
+	}
+	bucket, err := d.readValue(typ)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := d.reader.ReadByte(); !errors.Is(err, io.EOF) {
+		return nil, d.corrupt(fmt.Sprintf("unexpected data after the value of type %d", typ))
+	}
+	bucket.ExpireAt = math.MaxInt64
+	return bucket, nil
+}
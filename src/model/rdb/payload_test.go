@@ -0,0 +1,88 @@
+package rdb
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+)
+
+func TestDumpValue(t *testing.T) {
+	never := int64(math.MaxInt64)
+	tests := []struct {
+		name   string
+		bucket *model.RedisBucket
+	}{
+		{name: "string", bucket: &model.RedisBucket{Value: []byte("value"), ExpireAt: never}},
+		{name: "int string", bucket: &model.RedisBucket{Value: []byte("-12"), ExpireAt: never}},
+		{name: "list", bucket: listBucket("a", "1", "b")},
+		{name: "set", bucket: setBucket("a", "b")},
+		{name: "zset", bucket: &model.RedisBucket{Type: model.TypeZSet, ZSet: map[string]float64{"a": 1.5, "b": math.Inf(-1)}, ExpireAt: never}},
+		{name: "hash", bucket: hashBucket("f", "v")},
+		{name: "stream", bucket: &model.RedisBucket{Type: model.TypeStream, ExpireAt: never, Stream: &model.Stream{
+			Entries:      []model.StreamEntry{streamEntry(1, 0, "f", "v")},
+			LastID:       model.StreamID{Ms: 1},
+			FirstID:      model.StreamID{Ms: 1},
+			EntriesAdded: 1,
+		}}},
+	}
+
+	for _, tt := range tests {
+		payload := DumpValue(tt.bucket)
+		if bucket, err := LoadValue(payload); err != nil {
+			t.Errorf("case %s: failed to load: %v", tt.name, err)
+		} else if !reflect.DeepEqual(bucket, tt.bucket) {
+			t.Errorf("case %s: expected=%+v, actual=%+v", tt.name, tt.bucket, bucket)
+		}
+	}
+}
+
+func TestLoadValue(t *testing.T) {
+	valid := DumpValue(&model.RedisBucket{Value: []byte("value")})
+	newer := append([]byte{}, valid...)
+	newer[len(newer)-10] = Version + 1
+	trailing := append([]byte{TypeString, 1, 'a', 'b'}, Version, 0)
+	trailing = append(trailing, make([]byte, 8)...)
+	crc := Checksum(0, trailing[:len(trailing)-8])
+	for i := 0; i < 8; i++ {
+		trailing[len(trailing)-8+i] = byte(crc >> (8 * i))
+	}
+
+	tests := []struct {
+		name    string
+		payload []byte
+		value   string
+		// the error is ErrPayloadVersion
+		isVersion bool
+		isError   bool
+	}{
+		{
+			// DUMP of the integer 10 by redis 5.0, in RDB version 9
+			name:    "redis",
+			payload: []byte("\x00\xc0\n\t\x00\xbem\x06\x89Z(\x00\n"),
+			value:   "10",
+		},
+		{name: "dumped", payload: valid, value: "value"},
+		{name: "too short", payload: valid[:9], isVersion: true, isError: true},
+		{name: "newer version", payload: newer, isVersion: true, isError: true},
+		{name: "wrong checksum", payload: append(append([]byte{}, valid[:len(valid)-1]...), valid[len(valid)-1]^1), isVersion: true, isError: true},
+		{name: "trailing data", payload: trailing, isError: true},
+	}
+
+	for _, tt := range tests {
+		bucket, err := LoadValue(tt.payload)
+		if err != nil {
+			if !tt.isError {
+				t.Errorf("case %s: unexpected error: %v", tt.name, err)
+			} else if errors.Is(err, ErrPayloadVersion) != tt.isVersion {
+				t.Errorf("case %s: expected version error %v, actual=%v", tt.name, tt.isVersion, err)
+			}
+		} else if tt.isError {
+			t.Errorf("case %s: expected error but got nil", tt.name)
+		} else if string(bucket.Value) != tt.value || bucket.ExpireAt != math.MaxInt64 {
+			t.Errorf("case %s: expected %s, actual=%+v", tt.name, tt.value, bucket)
+		}
+	}
+}
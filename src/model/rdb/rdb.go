@@ -0,0 +1,88 @@
+package rdb
+
+import (
+	"fmt"
+)
+
+const (
+	Magic   = "REDIS"
+	Version = 11
+
+	// AuxReplStreamDB is the database a replica continues the replication
+	// stream with after loading a snapshot
+	AuxReplStreamDB = "repl-stream-db"
+
+	OpcodeFunction2    = 0xF5
+	OpcodeFunction     = 0xF6
+	OpcodeModuleAux    = 0xF7
+	OpcodeIdle         = 0xF8
+	OpcodeFreq         = 0xF9
+	OpcodeAux          = 0xFA
+	OpcodeResizeDB     = 0xFB
+	OpcodeExpireTimeMs = 0xFC
+	OpcodeExpireTime   = 0xFD
+	OpcodeSelectDB     = 0xFE
+	OpcodeEOF          = 0xFF
+
+	TypeString           = 0
+	TypeList             = 1
+	TypeSet              = 2
+	TypeZSet             = 3
+	TypeHash             = 4
+	TypeZSet2            = 5
+	TypeModule           = 6
+	TypeModule2          = 7
+	TypeHashZipmap       = 9
+	TypeListZiplist      = 10
+	TypeSetIntset        = 11
+	TypeZSetZiplist      = 12
+	TypeHashZiplist      = 13
+	TypeListQuicklist    = 14
+	TypeStreamListpacks  = 15
+	TypeHashListpack     = 16
+	TypeZSetListpack     = 17
+	TypeListQuicklist2   = 18
+	TypeStreamListpacks2 = 19
+	TypeSetListpack      = 20
+	TypeStreamListpacks3 = 21
+
+	// quicklist nodes hold one element as is, or a listpack
+	quicklistNodePlain  = 1
+	quicklistNodePacked = 2
+	// elements per listpack node of a list
+	listNodeMaxEntries = 128
+
+	streamItemFlagDeleted    = 1
+	streamItemFlagSameFields = 2
+	// entries per listpack node of a stream
+	streamNodeMaxEntries = 100
+
+	// module values are made of opcodes up to moduleOpcodeEOF
+	moduleOpcodeEOF    = 0
+	moduleOpcodeSInt   = 1
+	moduleOpcodeUInt   = 2
+	moduleOpcodeFloat  = 3
+	moduleOpcodeDouble = 4
+	moduleOpcodeString = 5
+
+	// the two most significant bits of a length tell how it is encoded
+	len6Bit  = 0
+	len14Bit = 1
+	len32Bit = 0x80
+	len64Bit = 0x81
+	lenEnc   = 3
+
+	encInt8  = 0
+	encInt16 = 1
+	encInt32 = 2
+	encLZF   = 3
+)
+
+type CorruptError struct {
+	Offset int64
+	Msg    string
+}
+
+func (e *CorruptError) Error() string {
+	return fmt.Sprintf("corrupt rdb at offset %d: %s", e.Offset, e.Msg)
+}
@@ -0,0 +1,393 @@
+package rdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+
+	"github.com/codecrafters-io/redis-starter-go/src/model"
+)
+
+// readStream reads a stream: its listpack nodes keyed by their master ID,
+// the stream metadata, then the consumer groups. The second version adds
+// the first ID, the max deleted ID, the entries added and the entries
+// read of groups; the third the active time of consumers.
+func (d *Decoder) readStream(typ byte) (*model.Stream, error) {
+	nodes, err := d.readLength()
+	if err != nil {
+		return nil, err
+	}
+	stream := &model.Stream{}
+	for i := uint64(0); i < nodes; i++ {
+		key, err := d.readString()
+		if err != nil {
+			return nil, err
+		} else if len(key) != 16 {
+			return nil, d.corrupt(fmt.Sprintf("stream node key of %d bytes", len(key)))
+		}
+		lp, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		entries, err := listpackEntries(lp)
+		if err != nil {
+			return nil, d.corrupt(err.Error())
+		}
+		if stream.Entries, err = appendStreamNode(stream.Entries, decodeStreamID(key), entries); err != nil {
+			return nil, d.corrupt(err.Error())
+		}
+	}
+
+	length, err := d.readLength()
+	if err != nil {
+		return nil, err
+	} else if length != uint64(len(stream.Entries)) {
+		return nil, d.corrupt(fmt.Sprintf("stream of %d entries claims %d", len(stream.Entries), length))
+	}
+	if stream.LastID, err = d.readStreamID(); err != nil {
+		return nil, err
+	}
+	if typ >= TypeStreamListpacks2 {
+		if stream.FirstID, err = d.readStreamID(); err != nil {
+			return nil, err
+		} else if stream.MaxDeletedID, err = d.readStreamID(); err != nil {
+			return nil, err
+		} else if stream.EntriesAdded, err = d.readLength(); err != nil {
+			return nil, err
+		}
+	} else {
+		if len(stream.Entries) > 0 {
+			stream.FirstID = stream.Entries[0].ID
+		}
+		stream.EntriesAdded = length
+	}
+
+	groups, err := d.readLength()
+	if err != nil {
+		return nil, err
+	}
+	for i := uint64(0); i < groups; i++ {
+		group, err := d.readStreamGroup(typ)
+		if err != nil {
+			return nil, err
+		}
+		stream.Groups = append(stream.Groups, group)
+	}
+	return stream, nil
+}
+
+func (d *Decoder) readStreamGroup(typ byte) (*model.StreamGroup, error) {
+	name, err := d.readString()
+	if err != nil {
+		return nil, err
+	}
+	group := &model.StreamGroup{Name: string(name), EntriesRead: -1}
+	if group.LastID, err = d.readStreamID(); err != nil {
+		return nil, err
+	}
+	if typ >= TypeStreamListpacks2 {
+		n, err := d.readLength()
+		if err != nil {
+			return nil, err
+		}
+		group.EntriesRead = int64(n)
+	}
+
+	n, err := d.readLength()
+	if err != nil {
+		return nil, err
+	}
+	pending := make(map[model.StreamID]*model.StreamPending, capHint(n))
+	for i := uint64(0); i < n; i++ {
+		raw, err := d.readFull(16)
+		if err != nil {
+			return nil, err
+		}
+		entry := &model.StreamPending{ID: decodeStreamID(raw)}
+		if entry.DeliveryTime, err = d.readMillis(); err != nil {
+			return nil, err
+		} else if entry.DeliveryCount, err = d.readLength(); err != nil {
+			return nil, err
+		}
+		group.Pending = append(group.Pending, entry)
+		pending[entry.ID] = entry
+	}
+
+	if n, err = d.readLength(); err != nil {
+		return nil, err
+	}
+	for i := uint64(0); i < n; i++ {
+		name, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		consumer := &model.StreamConsumer{Name: string(name)}
+		if consumer.SeenTime, err = d.readMillis(); err != nil {
+			return nil, err
+		}
+		consumer.ActiveTime = consumer.SeenTime
+		if typ >= TypeStreamListpacks3 {
+			if consumer.ActiveTime, err = d.readMillis(); err != nil {
+				return nil, err
+			}
+		}
+		owned, err := d.readLength()
+		if err != nil {
+			return nil, err
+		}
+		for j := uint64(0); j < owned; j++ {
+			raw, err := d.readFull(16)
+			if err != nil {
+				return nil, err
+			}
+			entry, ok := pending[decodeStreamID(raw)]
+			if !ok {
+				return nil, d.corrupt(fmt.Sprintf("consumer %s owns %s which is not pending", name, decodeStreamID(raw)))
+			}
+			entry.Consumer = consumer.Name
+		}
+		group.Consumers = append(group.Consumers, consumer)
+	}
+	return group, nil
+}
+
+func (d *Decoder) readStreamID() (model.StreamID, error) {
+	ms, err := d.readLength()
+	if err != nil {
+		return model.StreamID{}, err
+	}
+	seq, err := d.readLength()
+	return model.StreamID{Ms: ms, Seq: seq}, err
+}
+
+// readMillis reads a unix time in milliseconds.
+func (d *Decoder) readMillis() (int64, error) {
+	b, err := d.readFull(8)
+	if err != nil {
+		return 0, err
+	}
+	return int64(binary.LittleEndian.Uint64(b)), nil
+}
+
+func decodeStreamID(raw []byte) model.StreamID {
+	return model.StreamID{
+		Ms:  binary.BigEndian.Uint64(raw),
+		Seq: binary.BigEndian.Uint64(raw[8:]),
+	}
+}
+
+// appendStreamNode appends the entries of a listpack node which are not
+// deleted. The node starts with a master entry: the count of entries, of
+// deleted ones, the master fields and a 0. Then each entry has flags, its
+// ID relative to the master ID, its values alone when it has the master
+// fields or else its fields and values, and how many elements it took.
+func appendStreamNode(entries []model.StreamEntry, master model.StreamID, lp [][]byte) ([]model.StreamEntry, error) {
+	p := 0
+	next := func() ([]byte, error) {
+		if p >= len(lp) {
+			return nil, fmt.Errorf("stream node truncated after %d elements", p)
+		}
+		p++
+		return lp[p-1], nil
+	}
+	nextInt := func() (int64, error) {
+		b, err := next()
+		if err != nil {
+			return 0, err
+		}
+		n, err := strconv.ParseInt(string(b), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("stream node element %d is not an integer", p-1)
+		}
+		return n, nil
+	}
+
+	count, err := nextInt()
+	if err != nil {
+		return nil, err
+	}
+	deleted, err := nextInt()
+	if err != nil {
+		return nil, err
+	}
+	numFields, err := nextInt()
+	if err != nil {
+		return nil, err
+	} else if numFields < 0 || numFields > int64(len(lp)) {
+		return nil, fmt.Errorf("stream node with %d master fields", numFields)
+	}
+	fields := make([][]byte, numFields)
+	for i := range fields {
+		if fields[i], err = next(); err != nil {
+			return nil, err
+		}
+	}
+	if end, err := nextInt(); err != nil || end != 0 {
+		return nil, fmt.Errorf("stream node master entry not terminated")
+	}
+
+	live, dead := int64(0), int64(0)
+	for p < len(lp) {
+		flags, err := nextInt()
+		if err != nil {
+			return nil, err
+		}
+		msDiff, err := nextInt()
+		if err != nil {
+			return nil, err
+		}
+		seqDiff, err := nextInt()
+		if err != nil {
+			return nil, err
+		}
+		entry := model.StreamEntry{
+			ID: model.StreamID{Ms: master.Ms + uint64(msDiff), Seq: master.Seq + uint64(seqDiff)},
+		}
+		if flags&streamItemFlagSameFields != 0 {
+			for _, field := range fields {
+				value, err := next()
+				if err != nil {
+					return nil, err
+				}
+				entry.Fields = append(entry.Fields, field, value)
+			}
+		} else {
+			n, err := nextInt()
+			if err != nil {
+				return nil, err
+			} else if n < 0 || n > int64(len(lp)) {
+				return nil, fmt.Errorf("stream entry with %d fields", n)
+			}
+			for i := int64(0); i < 2*n; i++ {
+				b, err := next()
+				if err != nil {
+					return nil, err
+				}
+				entry.Fields = append(entry.Fields, b)
+			}
+		}
+		if _, err := nextInt(); err != nil {
+			return nil, err
+		}
+		if flags&streamItemFlagDeleted != 0 {
+			dead++
+			continue
+		}
+		live++
+		entries = append(entries, entry)
+	}
+	if live != count || dead != deleted {
+		return nil, fmt.Errorf("stream node of %d entries and %d deleted claims %d and %d", live, dead, count, deleted)
+	}
+	return entries, nil
+}
+
+// appendStream encodes a stream in the third version, with nodes of up to
+// streamNodeMaxEntries entries. Entries with the fields of the first entry
+// of their node only store their values.
+func (e *Encoder) appendStream(stream *model.Stream) {
+	entries := stream.Entries
+	nodes := (len(entries) + streamNodeMaxEntries - 1) / streamNodeMaxEntries
+	e.appendLength(uint64(nodes))
+	var lp [][]byte
+	for start := 0; start < len(entries); start += streamNodeMaxEntries {
+		node := entries[start:]
+		if len(node) > streamNodeMaxEntries {
+			node = node[:streamNodeMaxEntries]
+		}
+		master := node[0].ID
+		fields := make([][]byte, 0, len(node[0].Fields)/2)
+		for i := 0; i < len(node[0].Fields); i += 2 {
+			fields = append(fields, node[0].Fields[i])
+		}
+
+		lp = append(lp[:0], itoa(int64(len(node))), itoa(0), itoa(int64(len(fields))))
+		lp = append(lp, fields...)
+		lp = append(lp, itoa(0))
+		for _, entry := range node {
+			flags, n := int64(0), len(entry.Fields)/2
+			if sameFields(entry.Fields, fields) {
+				flags = streamItemFlagSameFields
+			}
+			lp = append(lp, itoa(flags), itoa(int64(entry.ID.Ms-master.Ms)), itoa(int64(entry.ID.Seq-master.Seq)))
+			if flags == streamItemFlagSameFields {
+				for i := 1; i < len(entry.Fields); i += 2 {
+					lp = append(lp, entry.Fields[i])
+				}
+				lp = append(lp, itoa(int64(n+3)))
+			} else {
+				lp = append(lp, itoa(int64(n)))
+				lp = append(lp, entry.Fields...)
+				lp = append(lp, itoa(int64(2*n+4)))
+			}
+		}
+
+		key := binary.BigEndian.AppendUint64(nil, master.Ms)
+		key = binary.BigEndian.AppendUint64(key, master.Seq)
+		e.appendString(key)
+		e.appendString(appendListpack(nil, lp))
+	}
+
+	e.appendLength(uint64(len(entries)))
+	e.appendStreamID(stream.LastID)
+	e.appendStreamID(stream.FirstID)
+	e.appendStreamID(stream.MaxDeletedID)
+	e.appendLength(stream.EntriesAdded)
+
+	e.appendLength(uint64(len(stream.Groups)))
+	for _, group := range stream.Groups {
+		e.appendString([]byte(group.Name))
+		e.appendStreamID(group.LastID)
+		e.appendLength(uint64(group.EntriesRead))
+		e.appendLength(uint64(len(group.Pending)))
+		for _, entry := range group.Pending {
+			e.appendRawStreamID(entry.ID)
+			e.buf = binary.LittleEndian.AppendUint64(e.buf, uint64(entry.DeliveryTime))
+			e.appendLength(entry.DeliveryCount)
+		}
+		e.appendLength(uint64(len(group.Consumers)))
+		for _, consumer := range group.Consumers {
+			e.appendString([]byte(consumer.Name))
+			e.buf = binary.LittleEndian.AppendUint64(e.buf, uint64(consumer.SeenTime))
+			e.buf = binary.LittleEndian.AppendUint64(e.buf, uint64(consumer.ActiveTime))
+			var owned []model.StreamID
+			for _, entry := range group.Pending {
+				if entry.Consumer == consumer.Name {
+					owned = append(owned, entry.ID)
+				}
+			}
+			e.appendLength(uint64(len(owned)))
+			for _, id := range owned {
+				e.appendRawStreamID(id)
+			}
+		}
+	}
+}
+
+func (e *Encoder) appendStreamID(id model.StreamID) {
+	e.appendLength(id.Ms)
+	e.appendLength(id.Seq)
+}
+
+func (e *Encoder) appendRawStreamID(id model.StreamID) {
+	e.buf = binary.BigEndian.AppendUint64(e.buf, id.Ms)
+	e.buf = binary.BigEndian.AppendUint64(e.buf, id.Seq)
+}
+
+// sameFields tells if the fields of fieldValues are fields, in order.
+func sameFields(fieldValues [][]byte, fields [][]byte) bool {
+	if len(fieldValues) != 2*len(fields) {
+		return false
+	}
+	for i, field := range fields {
+		if !bytes.Equal(fieldValues[2*i], field) {
+			return false
+		}
+	}
+	return true
+}
+
+func itoa(n int64) []byte {
+	return strconv.AppendInt(nil, n, 10)
+}
@@ -0,0 +1,193 @@
+package rdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// The compact encodings of older Redis versions, stored as RDB strings.
+// Integers are returned as decimal strings, as Redis reads them back.
+
+// ziplistEntries returns the elements of a ziplist: zlbytes, zltail and
+// zllen, then entries of a previous entry length, an encoding and the
+// data, then 0xFF.
+func ziplistEntries(zl []byte) ([][]byte, error) {
+	if len(zl) < 11 {
+		return nil, errors.New("ziplist too short")
+	} else if size := binary.LittleEndian.Uint32(zl); int(size) != len(zl) {
+		return nil, fmt.Errorf("ziplist of %d bytes claims %d", len(zl), size)
+	}
+	count := binary.LittleEndian.Uint16(zl[8:])
+
+	var (
+		entries [][]byte
+		p       = 10
+	)
+	for {
+		if p >= len(zl) {
+			return nil, errors.New("ziplist without end")
+		} else if zl[p] == 0xFF {
+			if p != len(zl)-1 {
+				return nil, errors.New("ziplist with trailing bytes")
+			}
+			break
+		}
+		if zl[p] == 0xFE {
+			p += 5
+		} else {
+			p++
+		}
+		if p >= len(zl) {
+			return nil, errors.New("ziplist entry out of range")
+		}
+		entry, size, err := ziplistEntry(zl[p:])
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+		p += size
+	}
+	// a count of 0xFFFF means too many to count
+	if count != 0xFFFF && int(count) != len(entries) {
+		return nil, fmt.Errorf("ziplist of %d entries claims %d", len(entries), count)
+	}
+	return entries, nil
+}
+
+func ziplistEntry(b []byte) (value []byte, size int, err error) {
+	var n int
+	switch first := b[0]; {
+	case first>>6 == 0:
+		n, size = int(first&0x3F), 1
+	case first>>6 == 1:
+		if len(b) < 2 {
+			return nil, 0, errors.New("ziplist entry out of range")
+		}
+		n, size = int(first&0x3F)<<8|int(b[1]), 2
+	case first == 0x80:
+		if len(b) < 5 {
+			return nil, 0, errors.New("ziplist entry out of range")
+		}
+		n, size = int(binary.BigEndian.Uint32(b[1:])), 5
+	case first == 0xC0:
+		return readInt(b, 1, 2)
+	case first == 0xD0:
+		return readInt(b, 1, 4)
+	case first == 0xE0:
+		return readInt(b, 1, 8)
+	case first == 0xF0:
+		return readInt(b, 1, 3)
+	case first == 0xFE:
+		return readInt(b, 1, 1)
+	case first >= 0xF1 && first <= 0xFD:
+		// 4 bits immediate, from 1 for 0
+		return strconv.AppendInt(nil, int64(first&0x0F)-1, 10), 1, nil
+	default:
+		return nil, 0, fmt.Errorf("unknown ziplist encoding 0x%02x", first)
+	}
+	if n < 0 || size+n > len(b) {
+		return nil, 0, errors.New("ziplist entry out of range")
+	}
+	return append([]byte(nil), b[size:size+n]...), size + n, nil
+}
+
+// readInt reads a little endian signed integer of width bytes after a
+// header of skip bytes.
+func readInt(b []byte, skip, width int) ([]byte, int, error) {
+	if len(b) < skip+width {
+		return nil, 0, errors.New("integer out of range")
+	}
+	var u uint64
+	for i := width - 1; i >= 0; i-- {
+		u = u<<8 | uint64(b[skip+i])
+	}
+	// sign extend
+	shift := 64 - 8*width
+	n := int64(u<<shift) >> shift
+	return strconv.AppendInt(nil, n, 10), skip + width, nil
+}
+
+// zipmapEntries returns the keys and values of a zipmap, alternately: a
+// count, then each key and value prefixed by its length, a value also
+// followed by unused bytes, then 0xFF.
+func zipmapEntries(zm []byte) ([][]byte, error) {
+	if len(zm) < 2 {
+		return nil, errors.New("zipmap too short")
+	}
+	var (
+		entries [][]byte
+		p       = 1
+	)
+	for {
+		if p >= len(zm) {
+			return nil, errors.New("zipmap without end")
+		} else if zm[p] == 0xFF {
+			if p != len(zm)-1 {
+				return nil, errors.New("zipmap with trailing bytes")
+			}
+			break
+		}
+		for i := 0; i < 2; i++ {
+			n, size, err := zipmapLength(zm[p:])
+			if err != nil {
+				return nil, err
+			}
+			p += size
+			free := 0
+			if i == 1 {
+				if p >= len(zm) {
+					return nil, errors.New("zipmap entry out of range")
+				}
+				free = int(zm[p])
+				p++
+			}
+			if n < 0 || p+n+free > len(zm) {
+				return nil, errors.New("zipmap entry out of range")
+			}
+			entries = append(entries, append([]byte(nil), zm[p:p+n]...))
+			p += n + free
+		}
+	}
+	return entries, nil
+}
+
+func zipmapLength(b []byte) (n int, size int, err error) {
+	switch {
+	case len(b) == 0:
+		return 0, 0, errors.New("zipmap entry out of range")
+	case b[0] < 254:
+		return int(b[0]), 1, nil
+	case b[0] == 254 && len(b) >= 5:
+		return int(binary.LittleEndian.Uint32(b[1:])), 5, nil
+	}
+	return 0, 0, errors.New("bad zipmap length")
+}
+
+// intsetEntries returns the members of an intset: the width of its
+// integers, their count, then the sorted integers.
+func intsetEntries(is []byte) ([][]byte, error) {
+	if len(is) < 8 {
+		return nil, errors.New("intset too short")
+	}
+	width := int(binary.LittleEndian.Uint32(is))
+	count := int(binary.LittleEndian.Uint32(is[4:]))
+	switch width {
+	case 2, 4, 8:
+	default:
+		return nil, fmt.Errorf("intset of unknown width %d", width)
+	}
+	if count < 0 || len(is) != 8+width*count {
+		return nil, fmt.Errorf("intset of %d bytes claims %d integers of %d bytes", len(is), count, width)
+	}
+	entries := make([][]byte, 0, count)
+	for i := 0; i < count; i++ {
+		entry, _, err := readInt(is, 8+i*width, width)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
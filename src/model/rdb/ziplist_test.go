@@ -0,0 +1,130 @@
+package rdb
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestZiplistEntries(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   []byte
+		output  []string
+		isError bool
+	}{
+		{
+			name:   "empty",
+			input:  []byte{11, 0, 0, 0, 10, 0, 0, 0, 0, 0, 0xFF},
+			output: nil,
+		},
+		{
+			// "ab", 7 as an immediate, -2 as int8, 0x123456 as int24
+			name: "strings and integers",
+			input: []byte{25, 0, 0, 0, 19, 0, 0, 0, 4, 0,
+				0, 2, 'a', 'b',
+				4, 0xF8,
+				1, 0xFE, 0xFE,
+				3, 0xF0, 0x56, 0x34, 0x12,
+				0xFF},
+			output: []string{"ab", "7", "-2", "1193046"},
+		},
+		{
+			name:   "unknown count is not checked",
+			input:  []byte{14, 0, 0, 0, 10, 0, 0, 0, 0xFF, 0xFF, 0, 1, 'a', 0xFF},
+			output: []string{"a"},
+		},
+		{
+			name:    "wrong count",
+			input:   []byte{14, 0, 0, 0, 10, 0, 0, 0, 2, 0, 0, 1, 'a', 0xFF},
+			isError: true,
+		},
+		{
+			name:    "string out of range",
+			input:   []byte{14, 0, 0, 0, 10, 0, 0, 0, 1, 0, 0, 5, 'a', 0xFF},
+			isError: true,
+		},
+		{
+			name:    "unknown encoding",
+			input:   []byte{13, 0, 0, 0, 10, 0, 0, 0, 1, 0, 0, 0xC5, 0xFF},
+			isError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		entries, err := ziplistEntries(tt.input)
+		if tt.isError {
+			if err == nil {
+				t.Errorf("case %s: expected error but got nil", tt.name)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("case %s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if actual := strings.Join(toStrings(entries), ","); actual != strings.Join(tt.output, ",") {
+			t.Errorf("case %s: expected %q but got %q", tt.name, tt.output, actual)
+		}
+	}
+}
+
+func TestIntsetEntries(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   []byte
+		output  []string
+		isError bool
+	}{
+		{
+			name:   "16 bit",
+			input:  []byte{2, 0, 0, 0, 2, 0, 0, 0, 0xFF, 0xFF, 1, 0},
+			output: []string{"-1", "1"},
+		},
+		{
+			name:    "unknown width",
+			input:   []byte{3, 0, 0, 0, 1, 0, 0, 0, 1, 0, 0},
+			isError: true,
+		},
+		{
+			name:    "wrong count",
+			input:   []byte{2, 0, 0, 0, 2, 0, 0, 0, 1, 0},
+			isError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		entries, err := intsetEntries(tt.input)
+		if tt.isError {
+			if err == nil {
+				t.Errorf("case %s: expected error but got nil", tt.name)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("case %s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if actual := strings.Join(toStrings(entries), ","); actual != strings.Join(tt.output, ",") {
+			t.Errorf("case %s: expected %q but got %q", tt.name, tt.output, actual)
+		}
+	}
+}
+
+func TestZipmapEntries(t *testing.T) {
+	valid := []byte{1, 1, 'k', 2, 1, 'v', 'w', 0, 0xFF}
+	entries, err := zipmapEntries(valid)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if actual := strings.Join(toStrings(entries), ","); actual != "k,vw" {
+		t.Errorf("expected the key and value, actual=%q", actual)
+	}
+	if _, err := zipmapEntries(valid[:len(valid)-1]); err == nil {
+		t.Errorf("expected a zipmap without end to fail")
+	}
+}
+
+func toStrings(entries [][]byte) []string {
+	s := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		s = append(s, string(entry))
+	}
+	return s
+}
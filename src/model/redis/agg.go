@@ -15,12 +15,14 @@ const (
 	ArrayLeading = '*'
 	MapLeading   = '%'
 	SetLeading   = '~'
+	PushLeading  = '>'
 )
 
 var (
 	_ RedisObject = &Array{}
 	_ RedisObject = &Map{}
 	_ RedisObject = &Set{}
+	_ RedisObject = &Push{}
 
 	StringLeadings []byte
 )
@@ -42,10 +44,14 @@ func visitAllAggTypeBuilder(visitor func(func() RedisObject)) {
 	visitor(func() RedisObject {
 		return &Set{}
 	})
+	visitor(func() RedisObject {
+		return &Push{}
+	})
 }
 
 type Array struct {
 	elements []RedisObject
+	null     bool
 }
 
 func NewArray(elements ...RedisObject) *Array {
@@ -55,6 +61,17 @@ func NewArray(elements ...RedisObject) *Array {
 
 }
 
+// NewNullArray returns the RESP2 null array "*-1".
+func NewNullArray() *Array {
+	return &Array{
+		null: true,
+	}
+}
+
+func (a *Array) IsNull() bool {
+	return a.null
+}
+
 func (a *Array) Len() int {
 	return len(a.elements)
 }
@@ -86,9 +103,12 @@ func (a *Array) Read(reader concept.Reader) error {
 	count, err := readSize(reader)
 	if err != nil {
 		return err
+	} else if count < 0 {
+		a.elements, a.null = nil, true
+		return nil
 	}
 
-	a.elements = make([]RedisObject, count)
+	a.elements, a.null = make([]RedisObject, count), false
 	for i := 0; i < count; i++ {
 		obj, err := ReadObject(reader)
 		if err != nil {
@@ -101,6 +121,9 @@ func (a *Array) Read(reader concept.Reader) error {
 }
 
 func (a *Array) String() string {
+	if a.null {
+		return "Array(null)"
+	}
 	builder := strings.Builder{}
 	builder.WriteString("Array[")
 	for i, obj := range a.elements {
@@ -114,6 +137,9 @@ func (a *Array) String() string {
 }
 
 func (a *Array) Write(writer io.Writer) error {
+	if a.null {
+		return writeBytes(writer, []byte("*-1"))
+	}
 	if err := writeByte(writer, a.Leading()); err != nil {
 		return err
 	}
@@ -130,10 +156,30 @@ func (a *Array) Write(writer io.Writer) error {
 	return nil
 }
 
+func (a *Array) Append(buf []byte) []byte {
+	if a.null {
+		return append(buf, "*-1"+Endline...)
+	}
+	buf = appendSize(append(buf, a.Leading()), len(a.elements))
+	for _, obj := range a.elements {
+		buf = obj.Append(buf)
+	}
+	return buf
+}
+
 type Map struct {
 	elements map[string]RedisObject
 }
 
+func NewMap(elements map[string]RedisObject) *Map {
+	if elements == nil {
+		elements = make(map[string]RedisObject)
+	}
+	return &Map{
+		elements: elements,
+	}
+}
+
 func (m *Map) Len() int {
 	return len(m.elements)
 }
@@ -144,9 +190,18 @@ func (m *Map) Get(key string) (RedisObject, bool) {
 }
 
 func (m *Map) Set(key string, value RedisObject) {
+	if m.elements == nil {
+		m.elements = make(map[string]RedisObject)
+	}
 	m.elements[key] = value
 }
 
+func (m *Map) Visit(f func(key string, value RedisObject)) {
+	for key, value := range m.elements {
+		f(key, value)
+	}
+}
+
 func (m *Map) Leading() byte {
 	return MapLeading
 }
@@ -236,10 +291,53 @@ func (m *Map) Write(writer io.Writer) error {
 	return nil
 }
 
+func (m *Map) Append(buf []byte) []byte {
+	buf = appendSize(append(buf, m.Leading()), len(m.elements))
+	var keyObj BulkString
+	for key, obj := range m.elements {
+		keyObj.value = []byte(key)
+		buf = obj.Append(keyObj.Append(buf))
+	}
+	return buf
+}
+
 type Set struct {
 	elements map[string]RedisObject
 }
 
+func NewSet(elements ...RedisObject) *Set {
+	s := &Set{
+		elements: make(map[string]RedisObject, len(elements)),
+	}
+	for _, obj := range elements {
+		s.Add(obj)
+	}
+	return s
+}
+
+func (s *Set) Len() int {
+	return len(s.elements)
+}
+
+// Add inserts obj into the set and reports whether it was absent.
+func (s *Set) Add(obj RedisObject) bool {
+	if s.elements == nil {
+		s.elements = make(map[string]RedisObject)
+	}
+	key := hashKey(obj)
+	if _, ok := s.elements[key]; ok {
+		return false
+	}
+	s.elements[key] = obj
+	return true
+}
+
+func (s *Set) Visit(f func(value RedisObject)) {
+	for _, obj := range s.elements {
+		f(obj)
+	}
+}
+
 func (s *Set) Leading() byte {
 	return SetLeading
 }
@@ -278,22 +376,16 @@ func (s *Set) Read(reader concept.Reader) error {
 	}
 
 	s.elements = make(map[string]RedisObject, count)
-	h := HashFunc()
 	for i := 0; i < count; i++ {
 		obj, err := ReadObject(reader)
 		if err != nil {
 			return err
 		}
-		obj.Hash(h)
-		hashKey := string(h.Sum(nil))
-		h.Reset()
-
-		if _, ok := s.elements[hashKey]; ok {
+		if !s.Add(obj) {
 			return &SyntaxError{
 				Msg: fmt.Sprintf("duplicated element: %v", obj),
 			}
 		}
-		s.elements[hashKey] = obj
 	}
 
 	return nil
@@ -315,3 +407,108 @@ func (s *Set) Write(writer io.Writer) error {
 
 	return nil
 }
+
+func (s *Set) Append(buf []byte) []byte {
+	buf = appendSize(append(buf, s.Leading()), len(s.elements))
+	for _, obj := range s.elements {
+		buf = obj.Append(buf)
+	}
+	return buf
+}
+
+func hashKey(obj RedisObject) string {
+	h := HashFunc()
+	obj.Hash(h)
+	return string(h.Sum(nil))
+}
+
+// Push is the RESP3 out-of-band message, such as pub/sub messages and
+// client tracking invalidations, which a client must not take as a reply.
+type Push struct {
+	elements []RedisObject
+}
+
+func NewPush(elements ...RedisObject) *Push {
+	return &Push{
+		elements: elements,
+	}
+}
+
+func (p *Push) Len() int {
+	return len(p.elements)
+}
+
+func (p *Push) Get(index int) RedisObject {
+	return p.elements[index]
+}
+
+func (p *Push) Leading() byte {
+	return PushLeading
+}
+
+func (p *Push) Hash(h hash.Hash) {
+	h.Write([]byte{p.Leading()})
+	for _, obj := range p.elements {
+		obj.Hash(h)
+	}
+}
+
+func (p *Push) Read(reader concept.Reader) error {
+	if err := readExpected(reader, []byte{p.Leading()}); err != nil {
+		return err
+	}
+
+	count, err := readSize(reader)
+	if err != nil {
+		return err
+	}
+
+	p.elements = make([]RedisObject, count)
+	for i := 0; i < count; i++ {
+		obj, err := ReadObject(reader)
+		if err != nil {
+			return err
+		}
+		p.elements[i] = obj
+	}
+
+	return nil
+}
+
+func (p *Push) String() string {
+	builder := strings.Builder{}
+	builder.WriteString("Push[")
+	for i, obj := range p.elements {
+		if i > 0 {
+			builder.WriteString(ElemSep)
+		}
+		builder.WriteString(obj.String())
+	}
+	builder.WriteString("]")
+	return builder.String()
+}
+
+func (p *Push) Write(writer io.Writer) error {
+	if err := writeByte(writer, p.Leading()); err != nil {
+		return err
+	}
+	if err := writeSize(writer, len(p.elements)); err != nil {
+		return err
+	}
+
+	for _, obj := range p.elements {
+		if err := obj.Write(writer); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *Push) Append(buf []byte) []byte {
+	buf = appendSize(append(buf, p.Leading()), len(p.elements))
+	for _, obj := range p.elements {
+		buf = obj.Append(buf)
+	}
+	return buf
+}
@@ -49,6 +49,13 @@ func TestArray_Read(t *testing.T) {
 			expected: "Array[BulkString{hello}]",
 			isError:  false,
 		},
+		{
+			name:     "array with empty bulkstring",
+			a:        &Array{},
+			line:     []byte("*2\r\n$0\r\n\r\n$5\r\nhello\r\n"),
+			expected: "Array[BulkString{}, BulkString{hello}]",
+			isError:  false,
+		},
 		{
 			name:     "array with integer",
 			a:        &Array{},
@@ -91,6 +98,13 @@ func TestArray_Read(t *testing.T) {
 			expected: "Array[Integer{123}, SimpleString{hello}, BulkString{world}]",
 			isError:  false,
 		},
+		{
+			name:     "null array",
+			a:        &Array{},
+			line:     []byte("*-1\r\n"),
+			expected: "Array(null)",
+			isError:  false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -164,6 +178,11 @@ func TestArray_Write(t *testing.T) {
 			a:        &Array{elements: []RedisObject{&Integer{value: 123}, &SimpleString{value: "hello"}, &BulkString{value: []byte("world")}}},
 			expected: "*3\r\n:123\r\n+hello\r\n$5\r\nworld\r\n",
 		},
+		{
+			name:     "null array",
+			a:        NewNullArray(),
+			expected: "*-1\r\n",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -438,3 +457,51 @@ func TestSet_Read(t *testing.T) {
 		})
 	}
 }
+
+func TestPush_Read(t *testing.T) {
+	tests := []struct {
+		name     string
+		p        *Push
+		line     []byte
+		expected string
+		isError  bool
+	}{
+		{
+			name:     "pubsub message",
+			p:        &Push{},
+			line:     []byte(">3\r\n$7\r\nmessage\r\n$2\r\nch\r\n$2\r\nhi\r\n"),
+			expected: "Push[BulkString{message}, BulkString{ch}, BulkString{hi}]",
+			isError:  false,
+		},
+		{
+			name:    "unexpected leading",
+			p:       &Push{},
+			line:    []byte("*1\r\n:1\r\n"),
+			isError: true,
+		},
+		{
+			name:    "push size insufficient",
+			p:       &Push{},
+			line:    []byte(">2\r\n:1\r\n"),
+			isError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := bufio.NewReader(strings.NewReader(string(tt.line)))
+			err := tt.p.Read(reader)
+			if tt.isError {
+				if err == nil {
+					t.Errorf("case %s: expected error but got nil", tt.name)
+				}
+			} else if err != nil {
+				t.Errorf("case %s: expected no error but got %v", tt.name, err)
+			} else if actual := tt.p.String(); actual != tt.expected {
+				t.Errorf("case %s: expected=%s, actual=%s", tt.name, tt.expected, actual)
+			} else if actual := string(tt.p.Append(nil)); actual != string(tt.line) {
+				t.Errorf("case %s: expected=%q, actual=%q", tt.name, tt.line, actual)
+			}
+		})
+	}
+}
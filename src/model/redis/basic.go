@@ -91,6 +91,10 @@ func (s *SimpleString) Write(writer io.Writer) (err error) {
 	return
 }
 
+func (s *SimpleString) Append(buf []byte) []byte {
+	return appendLine(append(buf, s.Leading()), s.value)
+}
+
 type SimpleError struct {
 	value string
 }
@@ -126,6 +130,10 @@ func (s *SimpleError) Write(writer io.Writer) (err error) {
 	return
 }
 
+func (s *SimpleError) Append(buf []byte) []byte {
+	return appendLine(append(buf, s.Leading()), s.value)
+}
+
 type Integer struct {
 	value int64
 }
@@ -165,6 +173,10 @@ func (i *Integer) Write(writer io.Writer) (err error) {
 	return
 }
 
+func (i *Integer) Append(buf []byte) []byte {
+	return appendInt64(append(buf, i.Leading()), i.value)
+}
+
 type BulkString struct {
 	value []byte
 }
@@ -223,6 +235,14 @@ func (b *BulkString) Write(writer io.Writer) error {
 	return nil
 }
 
+func (b *BulkString) Append(buf []byte) []byte {
+	if b.IsNull() {
+		return append(buf, "$-1"+Endline...)
+	}
+	buf = appendSize(append(buf, b.Leading()), len(b.value))
+	return append(append(buf, b.value...), Endline...)
+}
+
 type BulkError struct {
 	value []byte
 }
@@ -265,6 +285,11 @@ func (e *BulkError) Write(writer io.Writer) error {
 	return nil
 }
 
+func (e *BulkError) Append(buf []byte) []byte {
+	buf = appendSize(append(buf, e.Leading()), len(e.value))
+	return append(append(buf, e.value...), Endline...)
+}
+
 var (
 	nullBuf = []byte{(&Null{}).Leading()}
 	Nil     = NewNull()
@@ -300,6 +325,10 @@ func (n *Null) Write(writer io.Writer) error {
 	return writeBytes(writer, nullBuf)
 }
 
+func (n *Null) Append(buf []byte) []byte {
+	return append(append(buf, nullBuf...), Endline...)
+}
+
 type Boolean struct {
 	value bool
 }
@@ -313,6 +342,10 @@ func NewBoolean(value bool) *Boolean {
 	return &Boolean{value: value}
 }
 
+func (b *Boolean) AsBool() bool {
+	return b.value
+}
+
 func (b *Boolean) Leading() byte {
 	return '#'
 }
@@ -357,6 +390,13 @@ func (b *Boolean) Write(writer io.Writer) error {
 	}
 }
 
+func (b *Boolean) Append(buf []byte) []byte {
+	if b.value {
+		return append(buf, "#t"+Endline...)
+	}
+	return append(buf, "#f"+Endline...)
+}
+
 type Double struct {
 	value float64
 }
@@ -365,6 +405,10 @@ func NewDouble(value float64) *Double {
 	return &Double{value: value}
 }
 
+func (d *Double) AsFloat64() float64 {
+	return d.value
+}
+
 func (d *Double) Leading() byte {
 	return ','
 }
@@ -407,3 +451,8 @@ func (d *Double) Write(writer io.Writer) error {
 	}
 	return nil
 }
+
+func (d *Double) Append(buf []byte) []byte {
+	buf = strconv.AppendFloat(append(buf, d.Leading()), d.value, 'f', -1, 64)
+	return append(buf, Endline...)
+}
@@ -306,6 +306,13 @@ func TestBulkString_Read(t *testing.T) {
 			isError:  false,
 			expected: "BulkString{endline\r\n}",
 		},
+		{
+			name:     "string larger than reader buffer",
+			b:        &BulkString{},
+			line:     []byte(fmt.Sprintf("$5000\r\n%s\r\n", strings.Repeat("a", 5000))),
+			isError:  false,
+			expected: fmt.Sprintf("BulkString{%s}", strings.Repeat("a", 5000)),
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
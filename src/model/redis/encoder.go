@@ -0,0 +1,78 @@
+package redis
+
+import (
+	"io"
+)
+
+const (
+	// encoderShrinkSize is the capacity above which a flushed buffer is
+	// dropped instead of reused, so one huge reply does not pin memory.
+	encoderShrinkSize = 64 * 1024
+)
+
+var (
+	_ io.Writer = &Encoder{}
+)
+
+// Encoder appends RESP encoded objects into an output buffer and writes
+// the whole buffer to the underlying writer on Flush.
+type Encoder struct {
+	writer io.Writer
+	buf    []byte
+}
+
+func NewEncoder(writer io.Writer) *Encoder {
+	return &Encoder{
+		writer: writer,
+		buf:    make([]byte, 0, 4096),
+	}
+}
+
+func (e *Encoder) Encode(obj RedisObject) error {
+	e.buf = obj.Append(e.buf)
+	return nil
+}
+
+// Write appends raw bytes to the output buffer.
+func (e *Encoder) Write(p []byte) (int, error) {
+	e.buf = append(e.buf, p...)
+	return len(p), nil
+}
+
+func (e *Encoder) Buffered() int {
+	return len(e.buf)
+}
+
+func (e *Encoder) Bytes() []byte {
+	return e.buf
+}
+
+func (e *Encoder) Reset(writer io.Writer) {
+	e.writer = writer
+	e.buf = e.buf[:0]
+}
+
+func (e *Encoder) Flush() error {
+	if len(e.buf) == 0 {
+		return nil
+	}
+	n, err := e.writer.Write(e.buf)
+	if err == nil && n != len(e.buf) {
+		err = io.ErrShortWrite
+	}
+	if cap(e.buf) > encoderShrinkSize {
+		e.buf = make([]byte, 0, 4096)
+	} else {
+		e.buf = e.buf[:0]
+	}
+	return err
+}
+
+// WriteObject writes obj through the encoder fast path when writer is an
+// *Encoder, and falls back to obj.Write otherwise.
+func WriteObject(writer io.Writer, obj RedisObject) error {
+	if enc, ok := writer.(*Encoder); ok {
+		return enc.Encode(obj)
+	}
+	return obj.Write(writer)
+}
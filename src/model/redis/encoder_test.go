@@ -0,0 +1,167 @@
+package redis
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestEncoder_Encode(t *testing.T) {
+	tests := []struct {
+		name string
+		obj  RedisObject
+	}{
+		{
+			name: "simple string",
+			obj:  NewSimpleString("OK"),
+		},
+		{
+			name: "simple error",
+			obj:  NewSimpleError("ERR unknown command"),
+		},
+		{
+			name: "integer",
+			obj:  NewInteger(-1000),
+		},
+		{
+			name: "bulk string",
+			obj:  NewBulkString([]byte("hello")),
+		},
+		{
+			name: "empty bulk string",
+			obj:  NewBulkString([]byte{}),
+		},
+		{
+			name: "null bulk string",
+			obj:  NewBulkString(nil),
+		},
+		{
+			name: "bulk error",
+			obj:  NewBulkError([]byte("SYNTAX invalid")),
+		},
+		{
+			name: "null",
+			obj:  NewNull(),
+		},
+		{
+			name: "boolean",
+			obj:  NewArray(True, False),
+		},
+		{
+			name: "double",
+			obj:  NewDouble(1.23),
+		},
+		{
+			name: "nested array",
+			obj:  NewArray(NewInteger(1), NewArray(NewSimpleString("a"), NewBulkString([]byte("b")))),
+		},
+		{
+			name: "map",
+			obj:  &Map{elements: map[string]RedisObject{"key": NewInteger(1)}},
+		},
+		{
+			name: "set",
+			obj:  &Set{elements: map[string]RedisObject{"a": NewBulkString([]byte("a"))}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expected := &strings.Builder{}
+			if err := tt.obj.Write(expected); err != nil {
+				t.Fatalf("case %s: unexpected error: %v", tt.name, err)
+			}
+
+			actual := &strings.Builder{}
+			enc := NewEncoder(actual)
+			if err := enc.Encode(tt.obj); err != nil {
+				t.Errorf("case %s: unexpected error: %v", tt.name, err)
+			} else if actual.Len() != 0 {
+				t.Errorf("case %s: expected nothing written before flush, actual=%q", tt.name, actual.String())
+			} else if err := enc.Flush(); err != nil {
+				t.Errorf("case %s: unexpected flush error: %v", tt.name, err)
+			} else if actual.String() != expected.String() {
+				t.Errorf("case %s: expected=%q, actual=%q", tt.name, expected.String(), actual.String())
+			} else if enc.Buffered() != 0 {
+				t.Errorf("case %s: expected empty buffer after flush, actual=%d", tt.name, enc.Buffered())
+			}
+		})
+	}
+}
+
+type countingWriter struct {
+	writes int
+	bytes  int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	w.bytes += len(p)
+	return len(p), nil
+}
+
+func TestEncoder_Flush(t *testing.T) {
+	writer := &countingWriter{}
+	enc := NewEncoder(writer)
+	for i := 0; i < 100; i++ {
+		if err := WriteObject(enc, NewBulkString([]byte("value"))); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if writer.writes != 0 {
+		t.Errorf("expected no writes before flush, actual=%d", writer.writes)
+	}
+	if err := enc.Flush(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if writer.writes != 1 {
+		t.Errorf("expected one write per flush, actual=%d", writer.writes)
+	}
+	if expected := 100 * len("$5\r\nvalue\r\n"); writer.bytes != expected {
+		t.Errorf("expected=%d bytes, actual=%d", expected, writer.bytes)
+	}
+}
+
+var benchmarkReply = NewArray(
+	NewBulkString([]byte("key")),
+	NewBulkString([]byte("value")),
+	NewInteger(123456),
+	NewSimpleString("OK"),
+	NewArray(NewDouble(1.5), NewNull(), True),
+)
+
+func BenchmarkRedisObject_Write(b *testing.B) {
+	writer := bufio.NewWriter(io.Discard)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := benchmarkReply.Write(writer); err != nil {
+			b.Fatal(err)
+		}
+	}
+	_ = writer.Flush()
+}
+
+func BenchmarkRedisObject_WriteUnbuffered(b *testing.B) {
+	writer := &countingWriter{}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := benchmarkReply.Write(writer); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEncoder_Encode(b *testing.B) {
+	enc := NewEncoder(io.Discard)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := enc.Encode(benchmarkReply); err != nil {
+			b.Fatal(err)
+		}
+		if enc.Buffered() > 4096 {
+			_ = enc.Flush()
+		}
+	}
+	_ = enc.Flush()
+}
@@ -1,6 +1,9 @@
 package redis
 
-import "fmt"
+import (
+	"fmt"
+	"reflect"
+)
 
 var (
 	ErrUnexpectedLeading = &UnexpectedLeadingError{}
@@ -35,3 +38,42 @@ type SyntaxError struct {
 func (e *SyntaxError) Error() string {
 	return fmt.Sprintf("syntax error: %s", e.Msg)
 }
+
+// ReplyError is an error reply (simple or bulk error) sent by the peer.
+type ReplyError struct {
+	Msg string
+}
+
+func (e *ReplyError) Error() string {
+	return e.Msg
+}
+
+type UnsupportedTypeError struct {
+	Type reflect.Type
+}
+
+func (e *UnsupportedTypeError) Error() string {
+	return fmt.Sprintf("unsupported type %v", e.Type)
+}
+
+type UnmarshalTypeError struct {
+	Object string
+	Type   reflect.Type
+}
+
+func (e *UnmarshalTypeError) Error() string {
+	return fmt.Sprintf("cannot unmarshal %s into value of type %v", e.Object, e.Type)
+}
+
+type InvalidUnmarshalError struct {
+	Type reflect.Type
+}
+
+func (e *InvalidUnmarshalError) Error() string {
+	if e.Type == nil {
+		return "unmarshal into nil"
+	} else if e.Type.Kind() != reflect.Pointer {
+		return fmt.Sprintf("unmarshal into non-pointer %v", e.Type)
+	}
+	return fmt.Sprintf("unmarshal into nil %v", e.Type)
+}
@@ -0,0 +1,514 @@
+package redis
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+)
+
+// JSONMode selects the JSON representation of a RedisObject.
+type JSONMode int
+
+const (
+	// JSONTagged wraps every value in a single-key object naming its RESP
+	// type, e.g. {"bulk_string":"hello"}, so it decodes back losslessly.
+	JSONTagged JSONMode = iota
+	// JSONPlain maps values to their natural JSON form for humans, e.g.
+	// "hello", and only keeps errors distinguishable as {"error":"..."}.
+	JSONPlain
+)
+
+const (
+	jsonTagSimpleString = "simple_string"
+	jsonTagSimpleError  = "simple_error"
+	jsonTagInteger      = "integer"
+	jsonTagBulkString   = "bulk_string"
+	jsonTagBulkString64 = "bulk_string_base64"
+	jsonTagBulkError    = "bulk_error"
+	jsonTagBulkError64  = "bulk_error_base64"
+	jsonTagNull         = "null"
+	jsonTagBoolean      = "boolean"
+	jsonTagDouble       = "double"
+	jsonTagArray        = "array"
+	jsonTagMap          = "map"
+	jsonTagSet          = "set"
+	jsonTagPush         = "push"
+
+	jsonPlainError = "error"
+)
+
+var (
+	_ json.Marshaler   = &SimpleString{}
+	_ json.Unmarshaler = &SimpleString{}
+	_ json.Marshaler   = &Set{}
+	_ json.Unmarshaler = &Set{}
+)
+
+// EncodeJSON returns the JSON representation of obj. Map keys and set
+// elements are sorted, so equal objects always encode to equal bytes.
+func EncodeJSON(obj RedisObject, mode JSONMode) ([]byte, error) {
+	value, err := jsonValueOf(obj, mode)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
+
+// DecodeJSON parses the JSON representation of a RedisObject. Plain JSON
+// is mapped back on a best-effort basis: strings become bulk strings and
+// numbers integers when they have no fraction or exponent.
+func DecodeJSON(data []byte, mode JSONMode) (RedisObject, error) {
+	if mode == JSONPlain {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		var value any
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+		return objectOfPlainJSON(value)
+	}
+	return objectOfTaggedJSON(data)
+}
+
+func jsonValueOf(obj RedisObject, mode JSONMode) (any, error) {
+	var (
+		tag   string
+		value any
+	)
+	switch obj := obj.(type) {
+	case *SimpleString:
+		tag, value = jsonTagSimpleString, obj.value
+	case *SimpleError:
+		if mode == JSONPlain {
+			return map[string]any{jsonPlainError: obj.value}, nil
+		}
+		tag, value = jsonTagSimpleError, obj.value
+	case *Integer:
+		tag, value = jsonTagInteger, obj.value
+	case *BulkString:
+		switch {
+		case obj.IsNull():
+			tag, value = jsonTagBulkString, nil
+		case mode == JSONTagged && !utf8.Valid(obj.value):
+			tag, value = jsonTagBulkString64, base64.StdEncoding.EncodeToString(obj.value)
+		default:
+			tag, value = jsonTagBulkString, string(obj.value)
+		}
+	case *BulkError:
+		switch {
+		case mode == JSONPlain:
+			return map[string]any{jsonPlainError: string(obj.value)}, nil
+		case !utf8.Valid(obj.value):
+			tag, value = jsonTagBulkError64, base64.StdEncoding.EncodeToString(obj.value)
+		default:
+			tag, value = jsonTagBulkError, string(obj.value)
+		}
+	case *Null:
+		tag, value = jsonTagNull, nil
+	case *Boolean:
+		tag, value = jsonTagBoolean, obj.value
+	case *Double:
+		tag, value = jsonTagDouble, jsonDouble(obj.value)
+	case *Array:
+		if obj.IsNull() {
+			tag, value = jsonTagArray, nil
+			break
+		}
+		values, err := jsonValuesOf(obj.elements, mode)
+		if err != nil {
+			return nil, err
+		}
+		tag, value = jsonTagArray, values
+	case *Push:
+		values, err := jsonValuesOf(obj.elements, mode)
+		if err != nil {
+			return nil, err
+		}
+		tag, value = jsonTagPush, values
+	case *Map:
+		values := make(map[string]any, len(obj.elements))
+		for key, elem := range obj.elements {
+			v, err := jsonValueOf(elem, mode)
+			if err != nil {
+				return nil, err
+			}
+			values[key] = v
+		}
+		tag, value = jsonTagMap, values
+	case *Set:
+		values := make([]json.RawMessage, 0, len(obj.elements))
+		for _, elem := range obj.elements {
+			buf, err := EncodeJSON(elem, mode)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, buf)
+		}
+		sort.Slice(values, func(i, j int) bool {
+			return bytes.Compare(values[i], values[j]) < 0
+		})
+		tag, value = jsonTagSet, values
+	default:
+		return nil, fmt.Errorf("unsupported redis object %T", obj)
+	}
+
+	if mode == JSONPlain {
+		return value, nil
+	}
+	return map[string]any{tag: value}, nil
+}
+
+func jsonValuesOf(elements []RedisObject, mode JSONMode) ([]any, error) {
+	values := make([]any, len(elements))
+	for i, elem := range elements {
+		v, err := jsonValueOf(elem, mode)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// jsonDouble keeps infinities and NaN, which JSON numbers can not hold,
+// as the strings RESP3 uses for them.
+func jsonDouble(value float64) any {
+	switch {
+	case math.IsInf(value, 1):
+		return "inf"
+	case math.IsInf(value, -1):
+		return "-inf"
+	case math.IsNaN(value):
+		return "nan"
+	}
+	return value
+}
+
+func objectOfTaggedJSON(data []byte) (RedisObject, error) {
+	var tagged map[string]json.RawMessage
+	if err := json.Unmarshal(data, &tagged); err != nil {
+		return nil, err
+	} else if len(tagged) != 1 {
+		return nil, &SyntaxError{
+			Msg: fmt.Sprintf("expected a single type key, got %d keys", len(tagged)),
+		}
+	}
+
+	var (
+		tag string
+		raw json.RawMessage
+	)
+	for tag, raw = range tagged {
+		break
+	}
+
+	switch tag {
+	case jsonTagSimpleString:
+		var value string
+		err := json.Unmarshal(raw, &value)
+		return NewSimpleString(value), err
+	case jsonTagSimpleError:
+		var value string
+		err := json.Unmarshal(raw, &value)
+		return NewSimpleError(value), err
+	case jsonTagInteger:
+		var value int64
+		err := json.Unmarshal(raw, &value)
+		return NewInteger(value), err
+	case jsonTagBulkString, jsonTagBulkError:
+		var value *string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, err
+		}
+		var buf []byte
+		if value != nil {
+			buf = []byte(*value)
+		}
+		if tag == jsonTagBulkError {
+			return NewBulkError(buf), nil
+		}
+		return NewBulkString(buf), nil
+	case jsonTagBulkString64, jsonTagBulkError64:
+		var value []byte
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, err
+		}
+		if tag == jsonTagBulkError64 {
+			return NewBulkError(value), nil
+		}
+		return NewBulkString(value), nil
+	case jsonTagNull:
+		return NewNull(), nil
+	case jsonTagBoolean:
+		var value bool
+		err := json.Unmarshal(raw, &value)
+		return NewBoolean(value), err
+	case jsonTagDouble:
+		value, err := parseJSONDouble(raw)
+		return NewDouble(value), err
+	case jsonTagArray, jsonTagSet, jsonTagPush:
+		var raws []json.RawMessage
+		if err := json.Unmarshal(raw, &raws); err != nil {
+			return nil, err
+		} else if raws == nil && tag == jsonTagArray {
+			return NewNullArray(), nil
+		}
+		elements := make([]RedisObject, len(raws))
+		for i, elemRaw := range raws {
+			elem, err := objectOfTaggedJSON(elemRaw)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = elem
+		}
+		switch tag {
+		case jsonTagSet:
+			return NewSet(elements...), nil
+		case jsonTagPush:
+			return NewPush(elements...), nil
+		}
+		return NewArray(elements...), nil
+	case jsonTagMap:
+		var raws map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &raws); err != nil {
+			return nil, err
+		}
+		m := NewMap(make(map[string]RedisObject, len(raws)))
+		for key, elemRaw := range raws {
+			elem, err := objectOfTaggedJSON(elemRaw)
+			if err != nil {
+				return nil, err
+			}
+			m.Set(key, elem)
+		}
+		return m, nil
+	default:
+		return nil, &SyntaxError{
+			Msg: fmt.Sprintf("unknown type key %q", tag),
+		}
+	}
+}
+
+func parseJSONDouble(raw json.RawMessage) (float64, error) {
+	var value any
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return 0, err
+	}
+	switch value := value.(type) {
+	case float64:
+		return value, nil
+	case string:
+		switch value {
+		case "inf":
+			return math.Inf(1), nil
+		case "-inf":
+			return math.Inf(-1), nil
+		case "nan":
+			return math.NaN(), nil
+		}
+	}
+	return 0, &SyntaxError{
+		Msg: fmt.Sprintf("unexpected double value %s", string(raw)),
+	}
+}
+
+func objectOfPlainJSON(value any) (RedisObject, error) {
+	switch value := value.(type) {
+	case nil:
+		return NewNull(), nil
+	case bool:
+		return NewBoolean(value), nil
+	case string:
+		return NewBulkString([]byte(value)), nil
+	case json.Number:
+		if i, err := strconv.ParseInt(value.String(), 10, 64); err == nil {
+			return NewInteger(i), nil
+		}
+		f, err := value.Float64()
+		return NewDouble(f), err
+	case []any:
+		elements := make([]RedisObject, len(value))
+		for i, elem := range value {
+			obj, err := objectOfPlainJSON(elem)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = obj
+		}
+		return NewArray(elements...), nil
+	case map[string]any:
+		if msg, ok := value[jsonPlainError].(string); ok && len(value) == 1 {
+			return NewSimpleError(msg), nil
+		}
+		m := NewMap(make(map[string]RedisObject, len(value)))
+		for key, elem := range value {
+			obj, err := objectOfPlainJSON(elem)
+			if err != nil {
+				return nil, err
+			}
+			m.Set(key, obj)
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("unsupported json value %T", value)
+}
+
+// unmarshalTypedJSON decodes tagged JSON into target, which must be the
+// RESP type the tag names.
+func unmarshalTypedJSON(data []byte, target RedisObject) (RedisObject, error) {
+	obj, err := objectOfTaggedJSON(data)
+	if err != nil {
+		return nil, err
+	} else if obj.Leading() != target.Leading() {
+		return nil, &SyntaxError{
+			Msg: fmt.Sprintf("cannot unmarshal %v into %T", obj, target),
+		}
+	}
+	return obj, nil
+}
+
+func (s *SimpleString) MarshalJSON() ([]byte, error) {
+	return EncodeJSON(s, JSONTagged)
+}
+
+func (s *SimpleString) UnmarshalJSON(data []byte) error {
+	obj, err := unmarshalTypedJSON(data, s)
+	if err == nil {
+		*s = *obj.(*SimpleString)
+	}
+	return err
+}
+
+func (s *SimpleError) MarshalJSON() ([]byte, error) {
+	return EncodeJSON(s, JSONTagged)
+}
+
+func (s *SimpleError) UnmarshalJSON(data []byte) error {
+	obj, err := unmarshalTypedJSON(data, s)
+	if err == nil {
+		*s = *obj.(*SimpleError)
+	}
+	return err
+}
+
+func (i *Integer) MarshalJSON() ([]byte, error) {
+	return EncodeJSON(i, JSONTagged)
+}
+
+func (i *Integer) UnmarshalJSON(data []byte) error {
+	obj, err := unmarshalTypedJSON(data, i)
+	if err == nil {
+		*i = *obj.(*Integer)
+	}
+	return err
+}
+
+func (b *BulkString) MarshalJSON() ([]byte, error) {
+	return EncodeJSON(b, JSONTagged)
+}
+
+func (b *BulkString) UnmarshalJSON(data []byte) error {
+	obj, err := unmarshalTypedJSON(data, b)
+	if err == nil {
+		*b = *obj.(*BulkString)
+	}
+	return err
+}
+
+func (e *BulkError) MarshalJSON() ([]byte, error) {
+	return EncodeJSON(e, JSONTagged)
+}
+
+func (e *BulkError) UnmarshalJSON(data []byte) error {
+	obj, err := unmarshalTypedJSON(data, e)
+	if err == nil {
+		*e = *obj.(*BulkError)
+	}
+	return err
+}
+
+func (n *Null) MarshalJSON() ([]byte, error) {
+	return EncodeJSON(n, JSONTagged)
+}
+
+func (n *Null) UnmarshalJSON(data []byte) error {
+	_, err := unmarshalTypedJSON(data, n)
+	return err
+}
+
+func (b *Boolean) MarshalJSON() ([]byte, error) {
+	return EncodeJSON(b, JSONTagged)
+}
+
+func (b *Boolean) UnmarshalJSON(data []byte) error {
+	obj, err := unmarshalTypedJSON(data, b)
+	if err == nil {
+		*b = *obj.(*Boolean)
+	}
+	return err
+}
+
+func (d *Double) MarshalJSON() ([]byte, error) {
+	return EncodeJSON(d, JSONTagged)
+}
+
+func (d *Double) UnmarshalJSON(data []byte) error {
+	obj, err := unmarshalTypedJSON(data, d)
+	if err == nil {
+		*d = *obj.(*Double)
+	}
+	return err
+}
+
+func (a *Array) MarshalJSON() ([]byte, error) {
+	return EncodeJSON(a, JSONTagged)
+}
+
+func (a *Array) UnmarshalJSON(data []byte) error {
+	obj, err := unmarshalTypedJSON(data, a)
+	if err == nil {
+		*a = *obj.(*Array)
+	}
+	return err
+}
+
+func (m *Map) MarshalJSON() ([]byte, error) {
+	return EncodeJSON(m, JSONTagged)
+}
+
+func (m *Map) UnmarshalJSON(data []byte) error {
+	obj, err := unmarshalTypedJSON(data, m)
+	if err == nil {
+		*m = *obj.(*Map)
+	}
+	return err
+}
+
+func (s *Set) MarshalJSON() ([]byte, error) {
+	return EncodeJSON(s, JSONTagged)
+}
+
+func (s *Set) UnmarshalJSON(data []byte) error {
+	obj, err := unmarshalTypedJSON(data, s)
+	if err == nil {
+		*s = *obj.(*Set)
+	}
+	return err
+}
+
+func (p *Push) MarshalJSON() ([]byte, error) {
+	return EncodeJSON(p, JSONTagged)
+}
+
+func (p *Push) UnmarshalJSON(data []byte) error {
+	obj, err := unmarshalTypedJSON(data, p)
+	if err == nil {
+		*p = *obj.(*Push)
+	}
+	return err
+}
@@ -0,0 +1,213 @@
+package redis
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestEncodeJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		obj    RedisObject
+		tagged string
+		plain  string
+	}{
+		{
+			name:   "simple string",
+			obj:    NewSimpleString("OK"),
+			tagged: `{"simple_string":"OK"}`,
+			plain:  `"OK"`,
+		},
+		{
+			name:   "simple error",
+			obj:    NewSimpleError("ERR x"),
+			tagged: `{"simple_error":"ERR x"}`,
+			plain:  `{"error":"ERR x"}`,
+		},
+		{
+			name:   "integer",
+			obj:    NewInteger(-5),
+			tagged: `{"integer":-5}`,
+			plain:  `-5`,
+		},
+		{
+			name:   "bulk string",
+			obj:    NewBulkString([]byte("hello")),
+			tagged: `{"bulk_string":"hello"}`,
+			plain:  `"hello"`,
+		},
+		{
+			name:   "binary bulk string",
+			obj:    NewBulkString([]byte{0xff, 0x00}),
+			tagged: `{"bulk_string_base64":"/wA="}`,
+			plain:  `"�\u0000"`,
+		},
+		{
+			name:   "null bulk string",
+			obj:    NewBulkString(nil),
+			tagged: `{"bulk_string":null}`,
+			plain:  `null`,
+		},
+		{
+			name:   "bulk error",
+			obj:    NewBulkError([]byte("SYNTAX x")),
+			tagged: `{"bulk_error":"SYNTAX x"}`,
+			plain:  `{"error":"SYNTAX x"}`,
+		},
+		{
+			name:   "null",
+			obj:    NewNull(),
+			tagged: `{"null":null}`,
+			plain:  `null`,
+		},
+		{
+			name:   "boolean",
+			obj:    NewBoolean(true),
+			tagged: `{"boolean":true}`,
+			plain:  `true`,
+		},
+		{
+			name:   "double",
+			obj:    NewDouble(1.5),
+			tagged: `{"double":1.5}`,
+			plain:  `1.5`,
+		},
+		{
+			name:   "infinite double",
+			obj:    NewDouble(math.Inf(-1)),
+			tagged: `{"double":"-inf"}`,
+			plain:  `"-inf"`,
+		},
+		{
+			name:   "array",
+			obj:    NewArray(NewInteger(1), NewSimpleString("a")),
+			tagged: `{"array":[{"integer":1},{"simple_string":"a"}]}`,
+			plain:  `[1,"a"]`,
+		},
+		{
+			name:   "map",
+			obj:    NewMap(map[string]RedisObject{"b": NewInteger(2), "a": NewInteger(1)}),
+			tagged: `{"map":{"a":{"integer":1},"b":{"integer":2}}}`,
+			plain:  `{"a":1,"b":2}`,
+		},
+		{
+			name:   "set",
+			obj:    NewSet(NewInteger(3), NewInteger(1), NewInteger(2)),
+			tagged: `{"set":[{"integer":1},{"integer":2},{"integer":3}]}`,
+			plain:  `[1,2,3]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual, err := EncodeJSON(tt.obj, JSONTagged); err != nil {
+				t.Errorf("case %s: unexpected error: %v", tt.name, err)
+			} else if string(actual) != tt.tagged {
+				t.Errorf("case %s: tagged expected=%s, actual=%s", tt.name, tt.tagged, actual)
+			}
+			if actual, err := EncodeJSON(tt.obj, JSONPlain); err != nil {
+				t.Errorf("case %s: unexpected error: %v", tt.name, err)
+			} else if string(actual) != tt.plain {
+				t.Errorf("case %s: plain expected=%s, actual=%s", tt.name, tt.plain, actual)
+			}
+
+			// the tagged form is lossless
+			if decoded, err := DecodeJSON([]byte(tt.tagged), JSONTagged); err != nil {
+				t.Errorf("case %s: unexpected decode error: %v", tt.name, err)
+			} else if decoded.Leading() != tt.obj.Leading() {
+				t.Errorf("case %s: decoded expected=%v, actual=%v", tt.name, tt.obj, decoded)
+			} else if reencoded, _ := EncodeJSON(decoded, JSONTagged); string(reencoded) != tt.tagged {
+				t.Errorf("case %s: re-encoded expected=%s, actual=%s", tt.name, tt.tagged, reencoded)
+			}
+		})
+	}
+}
+
+func TestDecodeJSON_Plain(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		isError  bool
+	}{
+		{
+			name:     "string",
+			input:    `"hello"`,
+			expected: "BulkString{hello}",
+		},
+		{
+			name:     "integer",
+			input:    `12`,
+			expected: "Integer{12}",
+		},
+		{
+			name:     "double",
+			input:    `1.25`,
+			expected: "Double{1.25}",
+		},
+		{
+			name:     "error",
+			input:    `{"error":"ERR x"}`,
+			expected: "SimpleError{ERR x}",
+		},
+		{
+			name:     "nested",
+			input:    `[null,true,{"k":[1]}]`,
+			expected: "Array[Null{}, Boolean{true}, Map{k: Array[Integer{1}]}]",
+		},
+		{
+			name:    "malformed",
+			input:   `[`,
+			isError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj, err := DecodeJSON([]byte(tt.input), JSONPlain)
+			if tt.isError {
+				if err == nil {
+					t.Errorf("case %s: expected error but got %v", tt.name, obj)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("case %s: unexpected error: %v", tt.name, err)
+			} else if actual := obj.String(); actual != tt.expected {
+				t.Errorf("case %s: expected=%s, actual=%s", tt.name, tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestRedisObject_MarshalJSON(t *testing.T) {
+	type fixture struct {
+		Reply *Array `json:"reply"`
+		Count *Integer
+	}
+	value := fixture{
+		Reply: NewArray(NewBulkString([]byte("a")), NewNull()),
+		Count: NewInteger(2),
+	}
+	buf, err := json.Marshal(value)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `{"reply":{"array":[{"bulk_string":"a"},{"null":null}]},"Count":{"integer":2}}`
+	if string(buf) != expected {
+		t.Errorf("expected=%s, actual=%s", expected, buf)
+	}
+
+	var decoded fixture
+	if err := json.Unmarshal(buf, &decoded); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if decoded.Reply.String() != value.Reply.String() || decoded.Count.AsInt64() != 2 {
+		t.Errorf("expected=%v, actual=%v", value, decoded)
+	}
+
+	var mismatch Integer
+	if err := json.Unmarshal([]byte(`{"bulk_string":"a"}`), &mismatch); err == nil {
+		t.Errorf("expected error when decoding a bulk string into an integer")
+	}
+}
@@ -0,0 +1,749 @@
+package redis
+
+import (
+	"encoding"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	TagName = "resp"
+)
+
+// Marshaler is implemented by types which build their own RESP form.
+type Marshaler interface {
+	MarshalRESP() (RedisObject, error)
+}
+
+// Unmarshaler is implemented by types which decode their own RESP form.
+type Unmarshaler interface {
+	UnmarshalRESP(obj RedisObject) error
+}
+
+var (
+	redisObjectType     = reflect.TypeOf((*RedisObject)(nil)).Elem()
+	marshalerType       = reflect.TypeOf((*Marshaler)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+	structFieldsCache sync.Map // reflect.Type -> []structField
+)
+
+// Marshal converts a Go value into a RedisObject.
+//
+// Strings become bulk strings, []byte bulk strings, integers integers,
+// floats doubles, bools booleans, slices and arrays arrays, maps maps
+// (map[K]struct{} becomes a set) and structs maps keyed by field name or
+// by the name in their `resp:"name,omitempty"` tag. Nil pointers, slices,
+// maps and interfaces become null.
+func Marshal(v any) (RedisObject, error) {
+	return marshalValue(reflect.ValueOf(v))
+}
+
+func marshalValue(v reflect.Value) (RedisObject, error) {
+	if !v.IsValid() {
+		return Nil, nil
+	}
+
+	t := v.Type()
+	if t.Implements(redisObjectType) || t.Implements(marshalerType) {
+		if isNilable(v.Kind()) && v.IsNil() {
+			return Nil, nil
+		}
+		switch i := v.Interface().(type) {
+		case RedisObject:
+			return i, nil
+		case Marshaler:
+			return i.MarshalRESP()
+		}
+	} else if v.CanAddr() && reflect.PointerTo(t).Implements(marshalerType) {
+		return v.Addr().Interface().(Marshaler).MarshalRESP()
+	}
+
+	switch v.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		if v.IsNil() {
+			return Nil, nil
+		}
+		return marshalValue(v.Elem())
+	case reflect.String:
+		return NewBulkString([]byte(v.String())), nil
+	case reflect.Bool:
+		return NewBoolean(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return NewInteger(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u := v.Uint()
+		if u > math.MaxInt64 {
+			return nil, fmt.Errorf("value %d of type %v overflows integer", u, t)
+		}
+		return NewInteger(int64(u)), nil
+	case reflect.Float32, reflect.Float64:
+		return NewDouble(v.Float()), nil
+	case reflect.Slice:
+		if v.IsNil() {
+			return Nil, nil
+		} else if t.Elem().Kind() == reflect.Uint8 {
+			return NewBulkString(append([]byte{}, v.Bytes()...)), nil
+		}
+		return marshalSequence(v)
+	case reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			buf := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(buf), v)
+			return NewBulkString(buf), nil
+		}
+		return marshalSequence(v)
+	case reflect.Map:
+		if v.IsNil() {
+			return Nil, nil
+		}
+		return marshalMap(v)
+	case reflect.Struct:
+		return marshalStruct(v)
+	default:
+		return nil, &UnsupportedTypeError{Type: t}
+	}
+}
+
+func marshalSequence(v reflect.Value) (RedisObject, error) {
+	elements := make([]RedisObject, v.Len())
+	for i := range elements {
+		obj, err := marshalValue(v.Index(i))
+		if err != nil {
+			return nil, err
+		}
+		elements[i] = obj
+	}
+	return NewArray(elements...), nil
+}
+
+func marshalMap(v reflect.Value) (RedisObject, error) {
+	// map[K]struct{} is the idiomatic go set
+	if elemType := v.Type().Elem(); elemType.Kind() == reflect.Struct && elemType.NumField() == 0 {
+		set := NewSet()
+		iter := v.MapRange()
+		for iter.Next() {
+			obj, err := marshalValue(iter.Key())
+			if err != nil {
+				return nil, err
+			}
+			set.Add(obj)
+		}
+		return set, nil
+	}
+
+	m := NewMap(make(map[string]RedisObject, v.Len()))
+	iter := v.MapRange()
+	for iter.Next() {
+		key, err := marshalMapKey(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		obj, err := marshalValue(iter.Value())
+		if err != nil {
+			return nil, err
+		}
+		m.Set(key, obj)
+	}
+	return m, nil
+}
+
+func marshalMapKey(v reflect.Value) (string, error) {
+	if v.Type().Implements(textMarshalerType) {
+		buf, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(buf), err
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	default:
+		return "", &UnsupportedTypeError{Type: v.Type()}
+	}
+}
+
+func marshalStruct(v reflect.Value) (RedisObject, error) {
+	fields := cachedStructFields(v.Type())
+	m := NewMap(make(map[string]RedisObject, len(fields)))
+	for _, f := range fields {
+		fv := v.FieldByIndex(f.index)
+		if f.omitEmpty && isEmptyValue(fv) {
+			continue
+		}
+		obj, err := marshalValue(fv)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", f.name, err)
+		}
+		m.Set(f.name, obj)
+	}
+	return m, nil
+}
+
+// Unmarshal stores obj into the value pointed to by v, the inverse of
+// Marshal. Integers, doubles and booleans are also accepted from their
+// string forms, and maps and structs from flat key-value arrays, as RESP2
+// servers reply. Error replies are returned as *ReplyError.
+func Unmarshal(obj RedisObject, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return &InvalidUnmarshalError{Type: reflect.TypeOf(v)}
+	}
+	return unmarshalValue(obj, rv.Elem())
+}
+
+func unmarshalValue(obj RedisObject, v reflect.Value) error {
+	switch o := obj.(type) {
+	case *SimpleError:
+		return &ReplyError{Msg: o.value}
+	case *BulkError:
+		return &ReplyError{Msg: string(o.value)}
+	}
+	if v.Type() == redisObjectType {
+		v.Set(reflect.ValueOf(obj))
+		return nil
+	}
+
+	null := isNull(obj)
+	u, v := indirect(v, null)
+	if u != nil {
+		return u.UnmarshalRESP(obj)
+	} else if null {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	} else if v.Type() == redisObjectType {
+		v.Set(reflect.ValueOf(obj))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			return unmarshalTypeError(obj, v)
+		}
+		if value := toInterface(obj); value != nil {
+			v.Set(reflect.ValueOf(value))
+		} else {
+			v.Set(reflect.Zero(v.Type()))
+		}
+	case reflect.String:
+		s, ok := stringOf(obj)
+		if !ok {
+			return unmarshalTypeError(obj, v)
+		}
+		v.SetString(s)
+	case reflect.Bool:
+		b, ok := boolOf(obj)
+		if !ok {
+			return unmarshalTypeError(obj, v)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, ok := int64Of(obj)
+		if !ok || v.OverflowInt(i) {
+			return unmarshalTypeError(obj, v)
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		i, ok := int64Of(obj)
+		if !ok || i < 0 || v.OverflowUint(uint64(i)) {
+			return unmarshalTypeError(obj, v)
+		}
+		v.SetUint(uint64(i))
+	case reflect.Float32, reflect.Float64:
+		f, ok := float64Of(obj)
+		if !ok || v.OverflowFloat(f) {
+			return unmarshalTypeError(obj, v)
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			buf, ok := bytesOf(obj)
+			if !ok {
+				return unmarshalTypeError(obj, v)
+			}
+			v.SetBytes(append([]byte{}, buf...))
+			return nil
+		}
+		elements, ok := sequenceOf(obj)
+		if !ok {
+			return unmarshalTypeError(obj, v)
+		}
+		slice := reflect.MakeSlice(v.Type(), len(elements), len(elements))
+		for i, elem := range elements {
+			if err := unmarshalValue(elem, slice.Index(i)); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+	case reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			buf, ok := bytesOf(obj)
+			if !ok {
+				return unmarshalTypeError(obj, v)
+			}
+			v.Set(reflect.Zero(v.Type()))
+			reflect.Copy(v, reflect.ValueOf(buf))
+			return nil
+		}
+		elements, ok := sequenceOf(obj)
+		if !ok {
+			return unmarshalTypeError(obj, v)
+		}
+		v.Set(reflect.Zero(v.Type()))
+		for i := 0; i < len(elements) && i < v.Len(); i++ {
+			if err := unmarshalValue(elements[i], v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		return unmarshalMap(obj, v)
+	case reflect.Struct:
+		return unmarshalStruct(obj, v)
+	default:
+		return &UnsupportedTypeError{Type: v.Type()}
+	}
+
+	return nil
+}
+
+func unmarshalMap(obj RedisObject, v reflect.Value) error {
+	t := v.Type()
+	if v.IsNil() {
+		v.Set(reflect.MakeMap(t))
+	}
+
+	// a set fills the keys of map[K]struct{} or map[K]bool
+	if set, ok := obj.(*Set); ok {
+		var elemValue reflect.Value
+		switch {
+		case t.Elem().Kind() == reflect.Struct && t.Elem().NumField() == 0:
+			elemValue = reflect.Zero(t.Elem())
+		case t.Elem().Kind() == reflect.Bool:
+			elemValue = reflect.ValueOf(true).Convert(t.Elem())
+		default:
+			return unmarshalTypeError(obj, v)
+		}
+		var err error
+		set.Visit(func(elem RedisObject) {
+			if err != nil {
+				return
+			}
+			key := reflect.New(t.Key()).Elem()
+			if err = unmarshalValue(elem, key); err == nil {
+				v.SetMapIndex(key, elemValue)
+			}
+		})
+		return err
+	}
+
+	keys, values, ok := pairsOf(obj)
+	if !ok {
+		return unmarshalTypeError(obj, v)
+	}
+	for i, key := range keys {
+		keyValue := reflect.New(t.Key()).Elem()
+		if err := unmarshalMapKey(key, keyValue); err != nil {
+			return err
+		}
+		elemValue := reflect.New(t.Elem()).Elem()
+		if err := unmarshalValue(values[i], elemValue); err != nil {
+			return err
+		}
+		v.SetMapIndex(keyValue, elemValue)
+	}
+	return nil
+}
+
+func unmarshalMapKey(key string, v reflect.Value) error {
+	if reflect.PointerTo(v.Type()).Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(key))
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(key)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(key, 10, 64)
+		if err != nil || v.OverflowInt(i) {
+			return &UnmarshalTypeError{Object: fmt.Sprintf("key %q", key), Type: v.Type()}
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		i, err := strconv.ParseUint(key, 10, 64)
+		if err != nil || v.OverflowUint(i) {
+			return &UnmarshalTypeError{Object: fmt.Sprintf("key %q", key), Type: v.Type()}
+		}
+		v.SetUint(i)
+	default:
+		return &UnsupportedTypeError{Type: v.Type()}
+	}
+	return nil
+}
+
+func unmarshalStruct(obj RedisObject, v reflect.Value) error {
+	keys, values, ok := pairsOf(obj)
+	if !ok {
+		return unmarshalTypeError(obj, v)
+	}
+	fields := cachedStructFields(v.Type())
+	for i, key := range keys {
+		f := lookupField(fields, key)
+		if f == nil {
+			continue
+		}
+		if err := unmarshalValue(values[i], v.FieldByIndex(f.index)); err != nil {
+			return fmt.Errorf("field %s: %w", f.name, err)
+		}
+	}
+	return nil
+}
+
+// indirect walks down v allocating nil pointers until it reaches a
+// non-pointer value, stopping early at an Unmarshaler. For null objects
+// it stops at the first settable pointer so it can be set to nil.
+func indirect(v reflect.Value, null bool) (Unmarshaler, reflect.Value) {
+	for {
+		if v.Kind() == reflect.Interface && !v.IsNil() {
+			if e := v.Elem(); e.Kind() == reflect.Pointer && !e.IsNil() && !null {
+				v = e
+				continue
+			}
+		}
+		if v.Kind() != reflect.Pointer || (null && v.CanSet()) {
+			break
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		if u, ok := v.Interface().(Unmarshaler); ok {
+			return u, reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	if v.CanAddr() {
+		if u, ok := v.Addr().Interface().(Unmarshaler); ok {
+			return u, reflect.Value{}
+		}
+	}
+	return nil, v
+}
+
+func unmarshalTypeError(obj RedisObject, v reflect.Value) error {
+	return &UnmarshalTypeError{Object: obj.String(), Type: v.Type()}
+}
+
+func isNull(obj RedisObject) bool {
+	switch obj := obj.(type) {
+	case nil, *Null:
+		return true
+	case *BulkString:
+		return obj.IsNull()
+	case *Array:
+		return obj.IsNull()
+	}
+	return false
+}
+
+func isNilable(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Pointer, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		return true
+	}
+	return false
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Pointer:
+		return v.IsNil()
+	}
+	return false
+}
+
+func toInterface(obj RedisObject) any {
+	switch obj := obj.(type) {
+	case *SimpleString:
+		return obj.value
+	case *BulkString:
+		if obj.IsNull() {
+			return nil
+		}
+		return string(obj.value)
+	case *Integer:
+		return obj.value
+	case *Double:
+		return obj.value
+	case *Boolean:
+		return obj.value
+	case *Array:
+		if obj.IsNull() {
+			return nil
+		}
+		values := make([]any, len(obj.elements))
+		for i, elem := range obj.elements {
+			values[i] = toInterface(elem)
+		}
+		return values
+	case *Push:
+		values := make([]any, len(obj.elements))
+		for i, elem := range obj.elements {
+			values[i] = toInterface(elem)
+		}
+		return values
+	case *Set:
+		values := make([]any, 0, len(obj.elements))
+		for _, elem := range obj.elements {
+			values = append(values, toInterface(elem))
+		}
+		return values
+	case *Map:
+		values := make(map[string]any, len(obj.elements))
+		for key, elem := range obj.elements {
+			values[key] = toInterface(elem)
+		}
+		return values
+	}
+	return nil
+}
+
+func stringOf(obj RedisObject) (string, bool) {
+	switch obj := obj.(type) {
+	case *SimpleString:
+		return obj.value, true
+	case *BulkString:
+		return string(obj.value), true
+	case *Integer:
+		return strconv.FormatInt(obj.value, 10), true
+	case *Double:
+		return strconv.FormatFloat(obj.value, 'f', -1, 64), true
+	}
+	return "", false
+}
+
+func bytesOf(obj RedisObject) ([]byte, bool) {
+	switch obj := obj.(type) {
+	case *BulkString:
+		return obj.value, true
+	case *SimpleString:
+		return []byte(obj.value), true
+	}
+	return nil, false
+}
+
+func boolOf(obj RedisObject) (bool, bool) {
+	switch obj := obj.(type) {
+	case *Boolean:
+		return obj.value, true
+	case *Integer:
+		return obj.value != 0, true
+	case *SimpleString, *BulkString:
+		s, _ := stringOf(obj)
+		b, err := strconv.ParseBool(s)
+		return b, err == nil
+	}
+	return false, false
+}
+
+func int64Of(obj RedisObject) (int64, bool) {
+	switch obj := obj.(type) {
+	case *Integer:
+		return obj.value, true
+	case *SimpleString, *BulkString:
+		s, _ := stringOf(obj)
+		i, err := strconv.ParseInt(s, 10, 64)
+		return i, err == nil
+	}
+	return 0, false
+}
+
+func float64Of(obj RedisObject) (float64, bool) {
+	switch obj := obj.(type) {
+	case *Double:
+		return obj.value, true
+	case *Integer:
+		return float64(obj.value), true
+	case *SimpleString, *BulkString:
+		s, _ := stringOf(obj)
+		f, err := strconv.ParseFloat(s, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func sequenceOf(obj RedisObject) ([]RedisObject, bool) {
+	switch obj := obj.(type) {
+	case *Array:
+		return obj.elements, true
+	case *Push:
+		return obj.elements, true
+	case *Set:
+		elements := make([]RedisObject, 0, len(obj.elements))
+		for _, elem := range obj.elements {
+			elements = append(elements, elem)
+		}
+		return elements, true
+	}
+	return nil, false
+}
+
+// pairsOf returns the keys and values of a map, or of a flat array of
+// alternating keys and values.
+func pairsOf(obj RedisObject) ([]string, []RedisObject, bool) {
+	switch obj := obj.(type) {
+	case *Map:
+		keys := make([]string, 0, len(obj.elements))
+		for key := range obj.elements {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		values := make([]RedisObject, len(keys))
+		for i, key := range keys {
+			values[i] = obj.elements[key]
+		}
+		return keys, values, true
+	case *Array:
+		if len(obj.elements)%2 != 0 {
+			return nil, nil, false
+		}
+		keys := make([]string, 0, len(obj.elements)/2)
+		values := make([]RedisObject, 0, len(obj.elements)/2)
+		for i := 0; i < len(obj.elements); i += 2 {
+			key, ok := stringOf(obj.elements[i])
+			if !ok {
+				return nil, nil, false
+			}
+			keys = append(keys, key)
+			values = append(values, obj.elements[i+1])
+		}
+		return keys, values, true
+	}
+	return nil, nil, false
+}
+
+type structField struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+func lookupField(fields []structField, name string) *structField {
+	for i := range fields {
+		if fields[i].name == name {
+			return &fields[i]
+		}
+	}
+	for i := range fields {
+		if strings.EqualFold(fields[i].name, name) {
+			return &fields[i]
+		}
+	}
+	return nil
+}
+
+func cachedStructFields(t reflect.Type) []structField {
+	if fields, ok := structFieldsCache.Load(t); ok {
+		return fields.([]structField)
+	}
+	fields, _ := structFieldsCache.LoadOrStore(t, typeStructFields(t))
+	return fields.([]structField)
+}
+
+// typeStructFields lists the fields of t, flattening untagged embedded
+// structs. A shallower field hides deeper fields of the same name.
+func typeStructFields(t reflect.Type) []structField {
+	type candidate struct {
+		structField
+		depth int
+	}
+	var (
+		candidates []candidate
+		walk       func(t reflect.Type, index []int)
+	)
+	walk = func(t reflect.Type, index []int) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() && !(f.Anonymous && f.Type.Kind() == reflect.Struct) {
+				continue
+			}
+			tag := f.Tag.Get(TagName)
+			if tag == "-" {
+				continue
+			}
+			name, opts, _ := strings.Cut(tag, ",")
+			fieldIndex := append(append([]int{}, index...), i)
+			if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+				walk(f.Type, fieldIndex)
+				continue
+			} else if !f.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			candidates = append(candidates, candidate{
+				structField: structField{
+					name:      name,
+					index:     fieldIndex,
+					omitEmpty: hasTagOption(opts, "omitempty"),
+				},
+				depth: len(fieldIndex),
+			})
+		}
+	}
+	walk(t, nil)
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].depth < candidates[j].depth
+	})
+	seen := make(map[string]bool, len(candidates))
+	fields := make([]structField, 0, len(candidates))
+	for _, c := range candidates {
+		if seen[c.name] {
+			continue
+		}
+		seen[c.name] = true
+		fields = append(fields, c.structField)
+	}
+	sort.SliceStable(fields, func(i, j int) bool {
+		return lessIndex(fields[i].index, fields[j].index)
+	})
+	return fields
+}
+
+func hasTagOption(opts string, option string) bool {
+	for opts != "" {
+		var opt string
+		opt, opts, _ = strings.Cut(opts, ",")
+		if opt == option {
+			return true
+		}
+	}
+	return false
+}
+
+func lessIndex(a, b []int) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return len(a) < len(b)
+}
@@ -0,0 +1,408 @@
+package redis
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type marshalInner struct {
+	Port int `resp:"port"`
+}
+
+type marshalEmbedded struct {
+	Zone string `resp:"zone"`
+}
+
+type marshalOuter struct {
+	marshalEmbedded
+	Name    string            `resp:"name"`
+	Tags    []string          `resp:"tags,omitempty"`
+	Inner   *marshalInner     `resp:"inner,omitempty"`
+	Labels  map[string]string `resp:"labels,omitempty"`
+	Skipped string            `resp:"-"`
+	Ratio   float64
+	Enabled bool `resp:"enabled"`
+	hidden  int
+}
+
+type marshalCustom struct {
+	value string
+}
+
+func (c marshalCustom) MarshalRESP() (RedisObject, error) {
+	return NewSimpleString("custom:" + c.value), nil
+}
+
+func (c *marshalCustom) UnmarshalRESP(obj RedisObject) error {
+	s, ok := obj.(*SimpleString)
+	if !ok {
+		return errors.New("expected simple string")
+	}
+	c.value = strings.TrimPrefix(s.AsString(), "custom:")
+	return nil
+}
+
+func TestMarshal(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    any
+		expected string
+		isError  bool
+	}{
+		{
+			name:     "string",
+			value:    "hello",
+			expected: "$5\r\nhello\r\n",
+		},
+		{
+			name:     "bytes",
+			value:    []byte("hello"),
+			expected: "$5\r\nhello\r\n",
+		},
+		{
+			name:     "byte array",
+			value:    [2]byte{'o', 'k'},
+			expected: "$2\r\nok\r\n",
+		},
+		{
+			name:     "int",
+			value:    -12,
+			expected: ":-12\r\n",
+		},
+		{
+			name:     "uint8",
+			value:    uint8(200),
+			expected: ":200\r\n",
+		},
+		{
+			name:    "uint64 overflow",
+			value:   uint64(1 << 63),
+			isError: true,
+		},
+		{
+			name:     "float",
+			value:    1.5,
+			expected: ",1.5\r\n",
+		},
+		{
+			name:     "bool",
+			value:    true,
+			expected: "#t\r\n",
+		},
+		{
+			name:     "nil",
+			value:    nil,
+			expected: "_\r\n",
+		},
+		{
+			name:     "nil pointer",
+			value:    (*int)(nil),
+			expected: "_\r\n",
+		},
+		{
+			name:     "pointer",
+			value:    &[]int{1, 2},
+			expected: "*2\r\n:1\r\n:2\r\n",
+		},
+		{
+			name:     "slice of interfaces",
+			value:    []any{"a", 1, nil},
+			expected: "*3\r\n$1\r\na\r\n:1\r\n_\r\n",
+		},
+		{
+			name:     "map",
+			value:    map[int]string{1: "one"},
+			expected: "%1\r\n$1\r\n1\r\n$3\r\none\r\n",
+		},
+		{
+			name:     "set",
+			value:    map[string]struct{}{"a": {}},
+			expected: "~1\r\n$1\r\na\r\n",
+		},
+		{
+			name:     "struct with omitempty",
+			value:    marshalInner{Port: 6379},
+			expected: "%1\r\n$4\r\nport\r\n:6379\r\n",
+		},
+		{
+			name:     "redis object",
+			value:    NewSimpleString("OK"),
+			expected: "+OK\r\n",
+		},
+		{
+			name:     "marshaler",
+			value:    marshalCustom{value: "x"},
+			expected: "+custom:x\r\n",
+		},
+		{
+			name:    "unsupported",
+			value:   make(chan int),
+			isError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj, err := Marshal(tt.value)
+			if tt.isError {
+				if err == nil {
+					t.Errorf("case %s: expected error but got %v", tt.name, obj)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("case %s: unexpected error: %v", tt.name, err)
+			} else if actual := string(obj.Append(nil)); actual != tt.expected {
+				t.Errorf("case %s: expected=%q, actual=%q", tt.name, tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestMarshal_Struct(t *testing.T) {
+	value := marshalOuter{
+		marshalEmbedded: marshalEmbedded{Zone: "z1"},
+		Name:            "node",
+		Skipped:         "skipped",
+		Ratio:           0.5,
+		hidden:          1,
+	}
+	obj, err := Marshal(value)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m, ok := obj.(*Map)
+	if !ok {
+		t.Fatalf("expected *Map but got %v", obj)
+	}
+
+	keys := make(map[string]string)
+	m.Visit(func(key string, value RedisObject) {
+		keys[key] = value.String()
+	})
+	expected := map[string]string{
+		"zone":    "BulkString{z1}",
+		"name":    "BulkString{node}",
+		"Ratio":   "Double{0.5}",
+		"enabled": "Boolean{false}",
+	}
+	if !reflect.DeepEqual(keys, expected) {
+		t.Errorf("expected=%v, actual=%v", expected, keys)
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	tests := []struct {
+		name     string
+		obj      RedisObject
+		target   func() any
+		expected any
+		isError  bool
+	}{
+		{
+			name:     "bulk string to string",
+			obj:      NewBulkString([]byte("hello")),
+			target:   func() any { return new(string) },
+			expected: "hello",
+		},
+		{
+			name:     "simple string to bytes",
+			obj:      NewSimpleString("OK"),
+			target:   func() any { return new([]byte) },
+			expected: []byte("OK"),
+		},
+		{
+			name:     "integer to int8",
+			obj:      NewInteger(12),
+			target:   func() any { return new(int8) },
+			expected: int8(12),
+		},
+		{
+			name:    "integer overflow",
+			obj:     NewInteger(1000),
+			target:  func() any { return new(int8) },
+			isError: true,
+		},
+		{
+			name:    "negative to uint",
+			obj:     NewInteger(-1),
+			target:  func() any { return new(uint) },
+			isError: true,
+		},
+		{
+			name:     "bulk string to int",
+			obj:      NewBulkString([]byte("42")),
+			target:   func() any { return new(int) },
+			expected: 42,
+		},
+		{
+			name:     "double to float32",
+			obj:      NewDouble(1.5),
+			target:   func() any { return new(float32) },
+			expected: float32(1.5),
+		},
+		{
+			name:     "integer to bool",
+			obj:      NewInteger(1),
+			target:   func() any { return new(bool) },
+			expected: true,
+		},
+		{
+			name:     "null to pointer",
+			obj:      NewNull(),
+			target:   func() any { v := new(int); return &v },
+			expected: (*int)(nil),
+		},
+		{
+			name:     "integer to pointer",
+			obj:      NewInteger(7),
+			target:   func() any { return new(*int) },
+			expected: func() *int { v := 7; return &v }(),
+		},
+		{
+			name:     "array to slice",
+			obj:      NewArray(NewInteger(1), NewBulkString([]byte("2"))),
+			target:   func() any { return new([]int) },
+			expected: []int{1, 2},
+		},
+		{
+			name:     "array to fixed array",
+			obj:      NewArray(NewInteger(1), NewInteger(2), NewInteger(3)),
+			target:   func() any { return new([2]int) },
+			expected: [2]int{1, 2},
+		},
+		{
+			name:     "map to map",
+			obj:      NewMap(map[string]RedisObject{"1": NewBulkString([]byte("one"))}),
+			target:   func() any { return new(map[int]string) },
+			expected: map[int]string{1: "one"},
+		},
+		{
+			name:     "flat array to map",
+			obj:      NewArray(NewBulkString([]byte("a")), NewInteger(1), NewBulkString([]byte("b")), NewInteger(2)),
+			target:   func() any { return new(map[string]int) },
+			expected: map[string]int{"a": 1, "b": 2},
+		},
+		{
+			name:     "set to map",
+			obj:      NewSet(NewBulkString([]byte("a")), NewBulkString([]byte("b"))),
+			target:   func() any { return new(map[string]struct{}) },
+			expected: map[string]struct{}{"a": {}, "b": {}},
+		},
+		{
+			name: "map to struct",
+			obj: NewMap(map[string]RedisObject{
+				"zone":    NewBulkString([]byte("z1")),
+				"name":    NewBulkString([]byte("node")),
+				"tags":    NewArray(NewSimpleString("a")),
+				"inner":   NewMap(map[string]RedisObject{"port": NewInteger(6379)}),
+				"ratio":   NewDouble(0.5),
+				"enabled": True,
+				"unknown": NewInteger(1),
+			}),
+			target: func() any { return new(marshalOuter) },
+			expected: marshalOuter{
+				marshalEmbedded: marshalEmbedded{Zone: "z1"},
+				Name:            "node",
+				Tags:            []string{"a"},
+				Inner:           &marshalInner{Port: 6379},
+				Ratio:           0.5,
+				Enabled:         true,
+			},
+		},
+		{
+			name:     "flat array to struct",
+			obj:      NewArray(NewBulkString([]byte("port")), NewBulkString([]byte("6380"))),
+			target:   func() any { return new(marshalInner) },
+			expected: marshalInner{Port: 6380},
+		},
+		{
+			name:     "interface",
+			obj:      NewArray(NewInteger(1), NewBulkString([]byte("a")), NewMap(map[string]RedisObject{"k": True}), NewNull()),
+			target:   func() any { return new(any) },
+			expected: []any{int64(1), "a", map[string]any{"k": true}, nil},
+		},
+		{
+			name:     "redis object",
+			obj:      NewInteger(3),
+			target:   func() any { return new(RedisObject) },
+			expected: NewInteger(3),
+		},
+		{
+			name:     "unmarshaler",
+			obj:      NewSimpleString("custom:x"),
+			target:   func() any { return new(marshalCustom) },
+			expected: marshalCustom{value: "x"},
+		},
+		{
+			name:    "error reply",
+			obj:     NewSimpleError("ERR failed"),
+			target:  func() any { return new(string) },
+			isError: true,
+		},
+		{
+			name:    "type mismatch",
+			obj:     NewArray(),
+			target:  func() any { return new(int) },
+			isError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := tt.target()
+			err := Unmarshal(tt.obj, target)
+			if tt.isError {
+				if err == nil {
+					t.Errorf("case %s: expected error but got nil", tt.name)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("case %s: unexpected error: %v", tt.name, err)
+			} else if actual := reflect.ValueOf(target).Elem().Interface(); !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("case %s: expected=%#v, actual=%#v", tt.name, tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestUnmarshal_Invalid(t *testing.T) {
+	var value int
+	for _, target := range []any{nil, value, (*int)(nil)} {
+		var invalid *InvalidUnmarshalError
+		if err := Unmarshal(NewInteger(1), target); !errors.As(err, &invalid) {
+			t.Errorf("target %#v: expected InvalidUnmarshalError but got %v", target, err)
+		}
+	}
+
+	var reply *ReplyError
+	if err := Unmarshal(NewSimpleError("ERR x"), &value); !errors.As(err, &reply) || reply.Msg != "ERR x" {
+		t.Errorf("expected ReplyError but got %v", err)
+	}
+}
+
+func TestMarshal_RoundTrip(t *testing.T) {
+	value := marshalOuter{
+		marshalEmbedded: marshalEmbedded{Zone: "z1"},
+		Name:            "node",
+		Tags:            []string{"a", "b"},
+		Inner:           &marshalInner{Port: 1},
+		Labels:          map[string]string{"k": "v"},
+		Ratio:           2.25,
+		Enabled:         true,
+	}
+	obj, err := Marshal(value)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var actual marshalOuter
+	if err := Unmarshal(obj, &actual); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(actual, value) {
+		t.Errorf("expected=%#v, actual=%#v", value, actual)
+	}
+}
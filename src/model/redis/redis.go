@@ -19,6 +19,7 @@ type RedisObject interface {
 	String() string
 	Read(reader concept.Reader) error
 	Write(writer io.Writer) error
+	Append(buf []byte) []byte
 	Leading() byte
 	Hash(hash.Hash)
 }
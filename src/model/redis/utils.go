@@ -1,6 +1,7 @@
 package redis
 
 import (
+	"bytes"
 	"io"
 	"strconv"
 
@@ -9,10 +10,8 @@ import (
 
 func readExpected(reader concept.Reader, expected []byte) error {
 	buf := make([]byte, len(expected))
-	if n, err := reader.Read(buf); err != nil {
+	if _, err := io.ReadFull(reader, buf); err != nil {
 		return err
-	} else if n != len(expected) {
-		return io.ErrUnexpectedEOF
 	}
 	for i, b := range buf {
 		if b != expected[i] {
@@ -25,14 +24,24 @@ func readExpected(reader concept.Reader, expected []byte) error {
 	return nil
 }
 
+// readLine reads a line without its line ending. Unlike bufio's ReadLine,
+// a line cut by the end of the stream is an io.ErrUnexpectedEOF rather than
+// a complete line.
+func readLine(reader concept.Reader) ([]byte, error) {
+	line, err := reader.ReadBytes('\n')
+	if err == io.EOF && len(line) > 0 {
+		return nil, io.ErrUnexpectedEOF
+	} else if err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(line[:len(line)-1], []byte{'\r'}), nil
+}
+
 func readSimpleString(reader concept.Reader) (string, error) {
-	line, isPrefix, err := reader.ReadLine()
+	line, err := readLine(reader)
 	if err != nil {
 		return "", err
 	}
-	if isPrefix {
-		return "", io.ErrShortBuffer
-	}
 
 	return string(line), nil
 }
@@ -43,14 +52,10 @@ func readBulkString(reader concept.Reader) ([]byte, error) {
 		return nil, err
 	} else if size <= -1 {
 		return nil, nil
-	} else if size == 0 {
-		return []byte{}, nil
 	}
 	buf := make([]byte, size)
-	if n, err := reader.Read(buf); err != nil {
+	if _, err := io.ReadFull(reader, buf); err != nil {
 		return nil, err
-	} else if n != size {
-		return nil, io.ErrUnexpectedEOF
 	}
 	if err = readExpected(reader, []byte(Endline)); err != nil {
 		return nil, err
@@ -93,11 +98,9 @@ func readSize(reader concept.Reader) (int, error) {
 }
 
 func readInt64(reader concept.Reader) (int64, error) {
-	sizeLine, isPrefix, err := reader.ReadLine()
+	sizeLine, err := readLine(reader)
 	if err != nil {
 		return 0, err
-	} else if isPrefix {
-		return 0, io.ErrShortBuffer
 	} else if len(sizeLine) == 0 {
 		return 0, io.ErrUnexpectedEOF
 	} else if size, err := strconv.ParseInt(string(sizeLine), 10, 64); err != nil {
@@ -118,3 +121,15 @@ func writeInt64(writer io.Writer, value int64) error {
 
 	return nil
 }
+
+func appendLine(buf []byte, value string) []byte {
+	return append(append(buf, value...), Endline...)
+}
+
+func appendSize(buf []byte, size int) []byte {
+	return appendInt64(buf, int64(size))
+}
+
+func appendInt64(buf []byte, value int64) []byte {
+	return append(strconv.AppendInt(buf, value, 10), Endline...)
+}
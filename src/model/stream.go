@@ -0,0 +1,59 @@
+package model
+
+import (
+	"fmt"
+)
+
+// StreamID identifies a stream entry, as <ms>-<seq>.
+type StreamID struct {
+	Ms  uint64
+	Seq uint64
+}
+
+func (id StreamID) String() string {
+	return fmt.Sprintf("%d-%d", id.Ms, id.Seq)
+}
+
+// Stream is a list of entries in ID order, along with its consumer
+// groups.
+type Stream struct {
+	Entries []StreamEntry
+	// LastID is the ID of the last entry ever added, which may have been
+	// deleted since
+	LastID       StreamID
+	FirstID      StreamID
+	MaxDeletedID StreamID
+	EntriesAdded uint64
+	Groups       []*StreamGroup
+}
+
+// StreamEntry holds the fields and values of an entry, alternately.
+type StreamEntry struct {
+	ID     StreamID
+	Fields [][]byte
+}
+
+type StreamGroup struct {
+	Name   string
+	LastID StreamID
+	// EntriesRead is -1 when unknown
+	EntriesRead int64
+	// Pending lists the entries delivered but not acknowledged yet, in ID
+	// order
+	Pending   []*StreamPending
+	Consumers []*StreamConsumer
+}
+
+type StreamPending struct {
+	ID            StreamID
+	DeliveryTime  int64
+	DeliveryCount uint64
+	// Consumer is the name of the consumer the entry was delivered to
+	Consumer string
+}
+
+type StreamConsumer struct {
+	Name       string
+	SeenTime   int64
+	ActiveTime int64
+}